@@ -14,6 +14,8 @@ type Config struct {
 	Log       LogConfig       `mapstructure:"log"`
 	WeChatPay WeChatPayConfig `mapstructure:"wechat_pay"`
 	Alipay    AlipayConfig    `mapstructure:"alipay"`
+	Payment   PaymentConfig   `mapstructure:"payment"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
 }
 
 type ServerConfig struct {
@@ -58,6 +60,7 @@ type WeChatPayConfig struct {
 	MchSerialNumber string `mapstructure:"mch_serial_number"`
 	PrivateKeyPath  string `mapstructure:"private_key_path"`
 	NotifyURL       string `mapstructure:"notify_url"`
+	RefundNotifyURL string `mapstructure:"refund_notify_url"`
 }
 
 // AlipayConfig 支付宝配置
@@ -70,6 +73,18 @@ type AlipayConfig struct {
 	ReturnURL       string `mapstructure:"return_url"`
 }
 
+// PaymentConfig 支付通用配置
+type PaymentConfig struct {
+	MockEnabled bool   `mapstructure:"mock_enabled"` // 启用本地模拟支付，生产环境必须关闭
+	MockSecret  string `mapstructure:"mock_secret"`  // 模拟回调签名密钥，为空时每次启动随机生成
+	BillDir     string `mapstructure:"bill_dir"`     // 对账单目录，文件名为 {支付方式}_{YYYYMMDD}.csv
+}
+
+// WebhookConfig Webhook 推送配置
+type WebhookConfig struct {
+	SecretKey string `mapstructure:"secret_key"` // 签名密钥的 AES 加密密钥，长度为 16、24 或 32 字节
+}
+
 func Load() *Config {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
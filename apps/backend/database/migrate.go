@@ -41,9 +41,28 @@ func AutoMigrate(db *gorm.DB) error {
 		// Order models
 		&models.Order{},
 		&models.OrderItem{},
+		&models.Checkout{},
+		&models.PaymentAllocation{},
+		&models.PaymentCallbackInbox{},
+		&models.Refund{},
+		&models.ReconciliationBatch{},
+		&models.ReconciliationDiscrepancy{},
+		&models.SettlementStatement{},
+		&models.SettlementStatementItem{},
+		&models.SettlementAdjustment{},
+		&models.SupplierLedgerEntry{},
+		&models.StoreCreditAccount{},
+		&models.StoreCreditBill{},
+		&models.StoreCreditTransaction{},
+		&models.ServiceFeeRule{},
+		&models.WebhookAttempt{},
+		&models.WebhookTemplate{},
+		&models.WebhookEndpoint{},
 		&models.OrderCancelRequest{},
 		&models.OrderStatusLog{},
 		&models.OperationLog{},
+		&models.AdminNotification{},
+		&models.TargetNotification{},
 
 		// Media models
 		&models.MediaImage{},
@@ -49,51 +49,57 @@ type Admin struct {
 // Store 门店表
 type Store struct {
 	BaseModel
-	UserID         uint    `gorm:"uniqueIndex;not null" json:"user_id"`
-	User           *User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	StoreNo        string  `gorm:"type:varchar(20);uniqueIndex" json:"store_no"`
-	Name           string  `gorm:"type:varchar(100);not null" json:"name"`
-	Logo           string  `gorm:"type:varchar(500)" json:"logo"`
-	Province       string  `gorm:"type:varchar(50)" json:"province"`
-	City           string  `gorm:"type:varchar(50)" json:"city"`
-	District       string  `gorm:"type:varchar(50)" json:"district"`
-	Address        string  `gorm:"type:varchar(200)" json:"address"`
-	Latitude       float64 `gorm:"type:decimal(10,7)" json:"latitude"`
-	Longitude      float64 `gorm:"type:decimal(10,7)" json:"longitude"`
-	ContactName    string  `gorm:"type:varchar(50);not null" json:"contact_name"`
-	ContactPhone   string  `gorm:"type:varchar(20);not null" json:"contact_phone"`
-	MarkupEnabled  bool    `gorm:"default:true" json:"markup_enabled"`
-	WebhookURL     string  `gorm:"type:varchar(500);column:wechat_webhook_url" json:"webhook_url"`
-	WebhookEnabled bool    `gorm:"default:false" json:"webhook_enabled"`
-	Status         bool    `gorm:"default:true" json:"status"`
-}
-
-// Supplier 供应商表
-type Supplier struct {
-	BaseModel
-	UserID         uint     `gorm:"not null" json:"user_id"`
+	UserID         uint     `gorm:"uniqueIndex;not null" json:"user_id"`
 	User           *User    `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	SupplierNo     string   `gorm:"type:varchar(20);uniqueIndex" json:"supplier_no"`
+	StoreNo        string   `gorm:"type:varchar(20);uniqueIndex" json:"store_no"`
 	Name           string   `gorm:"type:varchar(100);not null" json:"name"`
-	DisplayName    string   `gorm:"type:varchar(100)" json:"display_name"`
 	Logo           string   `gorm:"type:varchar(500)" json:"logo"`
+	Province       string   `gorm:"type:varchar(50)" json:"province"`
+	City           string   `gorm:"type:varchar(50)" json:"city"`
+	District       string   `gorm:"type:varchar(50)" json:"district"`
+	Address        string   `gorm:"type:varchar(200)" json:"address"`
+	Latitude       float64  `gorm:"type:decimal(10,7)" json:"latitude"`
+	Longitude      float64  `gorm:"type:decimal(10,7)" json:"longitude"`
 	ContactName    string   `gorm:"type:varchar(50);not null" json:"contact_name"`
 	ContactPhone   string   `gorm:"type:varchar(20);not null" json:"contact_phone"`
-	MinOrderAmount float64  `gorm:"type:decimal(10,2);default:0" json:"min_order_amount"`
-	DeliveryDays   []int    `gorm:"type:json" json:"delivery_days"`
-	DeliveryMode   string   `gorm:"type:enum('self_delivery','express_delivery')" json:"delivery_mode"`
-	ManagementMode string   `gorm:"type:enum('self','managed','webhook','api')" json:"management_mode"`
-	HasBackend     bool     `gorm:"default:true" json:"has_backend"`
+	MarkupEnabled  bool     `gorm:"default:true" json:"markup_enabled"`
 	WebhookURL     string   `gorm:"type:varchar(500);column:wechat_webhook_url" json:"webhook_url"`
 	WebhookEnabled bool     `gorm:"default:false" json:"webhook_enabled"`
 	WebhookEvents  []string `gorm:"type:json" json:"webhook_events"`
-	APIEndpoint    string   `gorm:"type:varchar(500)" json:"api_endpoint"`
-	APISecretKey   string   `gorm:"type:varchar(100)" json:"-"`
-	MarkupEnabled  bool     `gorm:"default:true" json:"markup_enabled"`
-	Remark         string   `gorm:"type:text" json:"remark"`
 	Status         bool     `gorm:"default:true" json:"status"`
 }
 
+// Supplier 供应商表
+type Supplier struct {
+	BaseModel
+	UserID                 uint       `gorm:"not null" json:"user_id"`
+	User                   *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	SupplierNo             string     `gorm:"type:varchar(20);uniqueIndex" json:"supplier_no"`
+	Name                   string     `gorm:"type:varchar(100);not null" json:"name"`
+	DisplayName            string     `gorm:"type:varchar(100)" json:"display_name"`
+	Logo                   string     `gorm:"type:varchar(500)" json:"logo"`
+	ContactName            string     `gorm:"type:varchar(50);not null" json:"contact_name"`
+	ContactPhone           string     `gorm:"type:varchar(20);not null" json:"contact_phone"`
+	MinOrderAmount         float64    `gorm:"type:decimal(10,2);default:0" json:"min_order_amount"`
+	DeliveryDays           []int      `gorm:"type:json" json:"delivery_days"`
+	DeliveryMode           string     `gorm:"type:enum('self_delivery','express_delivery')" json:"delivery_mode"`
+	ManagementMode         string     `gorm:"type:enum('self','managed','webhook','api')" json:"management_mode"`
+	HasBackend             bool       `gorm:"default:true" json:"has_backend"`
+	WebhookURL             string     `gorm:"type:varchar(500);column:wechat_webhook_url" json:"webhook_url"`
+	WebhookEnabled         bool       `gorm:"default:false" json:"webhook_enabled"`
+	WebhookEvents          []string   `gorm:"type:json" json:"webhook_events"`
+	WebhookRetryTimes      int        `gorm:"default:3" json:"webhook_retry_times"`
+	WebhookRetryInterval   int        `gorm:"default:60" json:"webhook_retry_interval"`
+	WebhookTimeout         int        `gorm:"default:30" json:"webhook_timeout"`
+	APIEndpoint            string     `gorm:"type:varchar(500)" json:"api_endpoint"`
+	APISecretKey           string     `gorm:"type:varchar(100)" json:"-"`
+	WebhookSecret          string     `gorm:"type:varchar(255)" json:"-"`
+	WebhookSecretRotatedAt *time.Time `json:"webhook_secret_rotated_at"`
+	MarkupEnabled          bool       `gorm:"default:true" json:"markup_enabled"`
+	Remark                 string     `gorm:"type:text" json:"remark"`
+	Status                 bool       `gorm:"default:true" json:"status"`
+}
+
 // DeliveryArea 配送区域表
 type DeliveryArea struct {
 	BaseModel
@@ -177,15 +183,18 @@ type Order struct {
 	Store                *Store      `gorm:"foreignKey:StoreID" json:"store,omitempty"`
 	SupplierID           uint        `gorm:"not null;index" json:"supplier_id"`
 	Supplier             *Supplier   `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	CheckoutID           *uint       `gorm:"index" json:"checkout_id"`
 	GoodsAmount          float64     `gorm:"type:decimal(10,2);not null" json:"goods_amount"`
 	ServiceFee           float64     `gorm:"type:decimal(10,2);default:0" json:"service_fee"`
+	ServiceFeeRuleID     *uint       `json:"service_fee_rule_id"`
+	ServiceFeeRuleName   string      `gorm:"type:varchar(100)" json:"service_fee_rule_name"`
 	TotalAmount          float64     `gorm:"type:decimal(10,2);not null" json:"total_amount"`
 	SupplierAmount       float64     `gorm:"type:decimal(10,2)" json:"supplier_amount"`
 	MarkupTotal          float64     `gorm:"type:decimal(10,2);default:0" json:"markup_total"`
 	ItemCount            int         `json:"item_count"`
 	Status               string      `gorm:"type:enum('pending_payment','pending_confirm','confirmed','delivering','completed','cancelled');index" json:"status"`
-	PaymentStatus        string      `gorm:"type:enum('unpaid','paid','refunded');default:'unpaid';index" json:"payment_status"`
-	PaymentMethod        string      `gorm:"type:enum('wechat','alipay')" json:"payment_method"`
+	PaymentStatus        string      `gorm:"type:enum('unpaid','paid','refunded','partial_refund');default:'unpaid';index" json:"payment_status"`
+	PaymentMethod        string      `gorm:"type:enum('wechat','alipay','mock','credit')" json:"payment_method"`
 	PaymentTime          *time.Time  `json:"payment_time"`
 	PaymentNo            string      `gorm:"type:varchar(50)" json:"payment_no"`
 	OrderSource          string      `gorm:"type:enum('app','web','h5')" json:"order_source"`
@@ -225,6 +234,7 @@ type OrderItem struct {
 	Quantity      int          `gorm:"not null" json:"quantity"`
 	UnitPrice     float64      `gorm:"type:decimal(10,2);not null" json:"unit_price"`
 	MarkupAmount  float64      `gorm:"type:decimal(10,2);default:0" json:"markup_amount"`
+	MarkupRuleID  *uint        `gorm:"index" json:"markup_rule_id"`
 	FinalPrice    float64      `gorm:"type:decimal(10,2);not null" json:"final_price"`
 	Subtotal      float64      `gorm:"type:decimal(10,2);not null" json:"subtotal"`
 }
@@ -247,12 +257,13 @@ type PaymentRecord struct {
 	OrderID          uint       `gorm:"not null;index" json:"order_id"`
 	Order            *Order     `gorm:"foreignKey:OrderID" json:"order,omitempty"`
 	OrderNo          string     `gorm:"type:varchar(30);not null" json:"order_no"`
+	CheckoutID       *uint      `gorm:"index" json:"checkout_id"`
 	PaymentNo        string     `gorm:"type:varchar(50);uniqueIndex;not null" json:"payment_no"`
-	PaymentMethod    string     `gorm:"type:enum('wechat','alipay');not null" json:"payment_method"`
+	PaymentMethod    string     `gorm:"type:enum('wechat','alipay','mock');not null" json:"payment_method"`
 	GoodsAmount      float64    `gorm:"type:decimal(10,2);not null" json:"goods_amount"`
 	ServiceFee       float64    `gorm:"type:decimal(10,2);default:0" json:"service_fee"`
 	Amount           float64    `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Status           string     `gorm:"type:enum('pending','success','failed','refunded','partial_refund');default:'pending';index" json:"status"`
+	Status           string     `gorm:"type:enum('pending','success','failed','refunded','partial_refund','expired');default:'pending';index" json:"status"`
 	QRCodeURL        string     `gorm:"type:varchar(500)" json:"qrcode_url"`
 	QRCodeExpireTime *time.Time `json:"qrcode_expire_time"`
 	TradeNo          string     `gorm:"type:varchar(100);index" json:"trade_no"`
@@ -280,6 +291,7 @@ type PriceMarkup struct {
 	Material    *Material  `gorm:"foreignKey:MaterialID" json:"material,omitempty"`
 	MarkupType  string     `gorm:"type:enum('fixed','percent');not null" json:"markup_type"`
 	MarkupValue float64    `gorm:"type:decimal(10,4);not null" json:"markup_value"`
+	Tiers       JSON       `gorm:"type:json" json:"tiers"`
 	MinMarkup   float64    `gorm:"type:decimal(10,2)" json:"min_markup"`
 	MaxMarkup   float64    `gorm:"type:decimal(10,2)" json:"max_markup"`
 	Priority    int        `gorm:"default:0;index" json:"priority"`
@@ -312,7 +324,7 @@ type WebhookLog struct {
 	RequestBody    JSON       `gorm:"type:json" json:"request_body"`
 	ResponseCode   int        `json:"response_code"`
 	ResponseBody   string     `gorm:"type:text" json:"response_body"`
-	Status         string     `gorm:"type:enum('pending','success','failed');default:'pending';index" json:"status"`
+	Status         string     `gorm:"type:enum('pending','success','failed','dead');default:'pending';index" json:"status"`
 	RetryCount     int        `gorm:"default:0" json:"retry_count"`
 	MaxRetryCount  int        `gorm:"default:3" json:"max_retry_count"`
 	NextRetryAt    *time.Time `gorm:"index" json:"next_retry_at"`
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/project/backend/config"
+	"github.com/project/backend/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -70,6 +71,25 @@ func RunMigrations(db *gorm.DB) {
 		&WechatBinding{},
 		&ImageMatchRule{},
 		&MediaImage{},
+		&models.AdminNotification{},
+		&models.TargetNotification{},
+		&models.Checkout{},
+		&models.PaymentAllocation{},
+		&models.PaymentCallbackInbox{},
+		&models.Refund{},
+		&models.ReconciliationBatch{},
+		&models.ReconciliationDiscrepancy{},
+		&models.SettlementStatement{},
+		&models.SettlementStatementItem{},
+		&models.SettlementAdjustment{},
+		&models.SupplierLedgerEntry{},
+		&models.StoreCreditAccount{},
+		&models.StoreCreditBill{},
+		&models.StoreCreditTransaction{},
+		&models.ServiceFeeRule{},
+		&models.WebhookAttempt{},
+		&models.WebhookTemplate{},
+		&models.WebhookEndpoint{},
 	)
 
 	if err != nil {
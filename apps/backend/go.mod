@@ -17,6 +17,7 @@ require (
 	github.com/wechatpay-apiv3/wechatpay-go v0.2.18
 	go.uber.org/zap v1.26.0
 	golang.org/x/crypto v0.46.0
+	golang.org/x/text v0.32.0
 	golang.org/x/time v0.5.0
 	gorm.io/driver/mysql v1.5.2
 	gorm.io/gorm v1.25.5
@@ -68,7 +69,6 @@ require (
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9 // indirect
 	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
-	golang.org/x/text v0.32.0 // indirect
 	golang.org/x/tools v0.39.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
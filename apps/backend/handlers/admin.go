@@ -494,6 +494,7 @@ func UpdateSupplier(db *gorm.DB) echo.HandlerFunc {
 			WebhookRetryTimes    *int     `json:"webhookRetryTimes"`
 			WebhookRetryInterval *int     `json:"webhookRetryInterval"`
 			WebhookTimeout       *int     `json:"webhookTimeout"`
+			SettlementCycle      string   `json:"settlementCycle"`
 		}
 
 		var req UpdateSupplierRequest
@@ -554,6 +555,13 @@ func UpdateSupplier(db *gorm.DB) echo.HandlerFunc {
 		if req.WebhookTimeout != nil {
 			updates["webhook_timeout"] = *req.WebhookTimeout
 		}
+		if req.SettlementCycle != "" {
+			cycle := models.SettlementCycle(req.SettlementCycle)
+			if cycle != models.SettlementCycleWeekly && cycle != models.SettlementCycleMonthly {
+				return ErrorResponse(c, http.StatusBadRequest, "结算周期只能是 weekly 或 monthly")
+			}
+			updates["settlement_cycle"] = cycle
+		}
 
 		if err := db.Model(&models.Supplier{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 			return ErrorResponse(c, http.StatusInternalServerError, "更新供应商失败")
@@ -962,6 +970,24 @@ func GetSystemConfigs(db *gorm.DB) echo.HandlerFunc {
 				Description: "订单自动完成时间（天）",
 				UpdatedAt:   time.Now(),
 			},
+			{
+				Key:         "order_confirm_timeout",
+				Value:       "120",
+				Description: "供应商确认时限（分钟），超时通知管理员",
+				UpdatedAt:   time.Now(),
+			},
+			{
+				Key:         "order_confirm_cancel_after",
+				Value:       "360",
+				Description: "确认超时后自动取消并退款时间（分钟）",
+				UpdatedAt:   time.Now(),
+			},
+			{
+				Key:         "order_cutoff_time",
+				Value:       "18:00",
+				Description: "每日截单时间（HH:MM），截单后下单顺延一天配送",
+				UpdatedAt:   time.Now(),
+			},
 		}
 
 		return SuccessResponse(c, configs)
@@ -999,6 +1025,9 @@ func UpdateSystemConfig(db *gorm.DB) echo.HandlerFunc {
 			"refresh_token_expire_days",
 			"order_auto_confirm_hours",
 			"order_auto_complete_days",
+			"order_confirm_timeout",
+			"order_confirm_cancel_after",
+			"order_cutoff_time",
 		}
 
 		isValidKey := false
@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/project/backend/models"
+	"github.com/project/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -268,18 +270,18 @@ func (h *AdminDashboardHandler) AuditCancelRequest(c echo.Context) error {
 		})
 	}
 
-	// 审核取消申请
+	// 审核取消申请，批准时由状态机取消订单
+	cancelService := services.NewOrderCancelService(h.db)
 	if req.Approved {
-		// 批准取消，状态已经是取消，无需操作
+		err = cancelService.ApproveCancelRequest(id, GetAdminID(c), "")
 	} else {
-		// 拒绝取消，恢复订单状态
-		if err := h.db.Model(&models.Order{}).Where("id = ?", id).
-			Update("status", models.OrderStatusPendingConfirm).Error; err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"code":    500,
-				"message": "审核失败",
-			})
-		}
+		err = cancelService.RejectCancelRequest(id, GetAdminID(c), req.RejectReason)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "审核失败: " + err.Error(),
+		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -320,29 +322,8 @@ func (h *AdminDashboardHandler) RestoreOrder(c echo.Context) error {
 	}
 
 	// 恢复已取消的订单
-	var order models.Order
-	if err := h.db.First(&order, id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"code":    404,
-			"message": "订单不存在",
-		})
-	}
-
-	if order.Status != models.OrderStatusCancelled {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"code":    400,
-			"message": "只能恢复已取消的订单",
-		})
-	}
-
-	if err := h.db.Model(&order).Updates(map[string]interface{}{
-		"status":        models.OrderStatusPendingConfirm,
-		"cancel_reason": nil,
-	}).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"code":    500,
-			"message": "恢复失败",
-		})
+	if err := services.NewAdminDashboardService(h.db).RestoreOrder(id, req.Reason, GetAdminID(c)); err != nil {
+		return adminOrderTransitionError(c, err, "恢复失败")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -383,29 +364,8 @@ func (h *AdminDashboardHandler) AdminCancelOrder(c echo.Context) error {
 	}
 
 	// 管理员直接取消订单
-	var order models.Order
-	if err := h.db.First(&order, id).Error; err != nil {
-		return c.JSON(http.StatusNotFound, map[string]interface{}{
-			"code":    404,
-			"message": "订单不存在",
-		})
-	}
-
-	if order.Status == models.OrderStatusCancelled {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"code":    400,
-			"message": "订单已取消",
-		})
-	}
-
-	if err := h.db.Model(&order).Updates(map[string]interface{}{
-		"status":        models.OrderStatusCancelled,
-		"cancel_reason": req.Reason,
-	}).Error; err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"code":    500,
-			"message": "取消失败",
-		})
+	if err := services.NewAdminDashboardService(h.db).AdminCancelOrder(id, req.Reason, GetAdminID(c)); err != nil {
+		return adminOrderTransitionError(c, err, "取消失败")
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -414,6 +374,22 @@ func (h *AdminDashboardHandler) AdminCancelOrder(c echo.Context) error {
 	})
 }
 
+// adminOrderTransitionError 状态机错误转换为响应
+func adminOrderTransitionError(c echo.Context, err error, fallback string) error {
+	var invalid *services.InvalidTransitionError
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"code": 404, "message": "订单不存在"})
+	case errors.Is(err, services.ErrOrderConcurrentUpdate):
+		return c.JSON(http.StatusConflict, map[string]interface{}{"code": 409, "message": err.Error()})
+	case errors.As(err, &invalid):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"code": 400, "message": invalid.Error()})
+	case errors.Is(err, services.ErrOrderRestoreHasPayment):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{"code": 400, "message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{"code": 500, "message": fallback})
+}
+
 // GetCancelledOrders 获取已取消订单列表
 // @Summary 获取已取消订单列表
 // @Tags 管理员-订单管理
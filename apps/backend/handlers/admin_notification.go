@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetAdminNotifications 获取管理员通知列表
+func GetAdminNotifications(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		unreadOnly := c.QueryParam("unread") == "1" || c.QueryParam("unread") == "true"
+
+		notifications, total, err := services.NewAdminNotificationService(db).List(page, pageSize, unreadOnly)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, notifications, total, page, pageSize)
+	}
+}
+
+// GetAdminNotificationUnreadCount 获取未读通知数量
+func GetAdminNotificationUnreadCount(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		return SuccessResponse(c, map[string]int64{
+			"count": services.NewAdminNotificationService(db).UnreadCount(),
+		})
+	}
+}
+
+// MarkAdminNotificationRead 标记通知已读
+func MarkAdminNotificationRead(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的通知ID")
+		}
+
+		if err := services.NewAdminNotificationService(db).MarkRead(id, GetAdminID(c)); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "操作失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
+
+// MarkAllAdminNotificationsRead 全部标记已读
+func MarkAllAdminNotificationsRead(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		if err := services.NewAdminNotificationService(db).MarkAllRead(GetAdminID(c)); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "操作失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
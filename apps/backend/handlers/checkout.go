@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// CreateCheckout 购物车结算，按供应商拆分为多个子订单
+func CreateCheckout(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		storeID := GetStoreID(c)
+		if storeID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		type SupplierRemark struct {
+			SupplierID uint64 `json:"supplierId"`
+			Remark     string `json:"remark"`
+		}
+
+		type CheckoutRequest struct {
+			SupplierIDs   []uint64              `json:"supplierIds"`
+			Remarks       []SupplierRemark      `json:"remarks"`
+			DeliveryInfo  services.DeliveryInfo `json:"deliveryInfo"`
+			OrderSource   string                `json:"orderSource" validate:"omitempty,oneof=app web h5"`
+			PaymentMethod string                `json:"paymentMethod" validate:"omitempty,oneof=online credit"`
+		}
+
+		var req CheckoutRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		remarks := make(map[uint64]string, len(req.Remarks))
+		for _, r := range req.Remarks {
+			remarks[r.SupplierID] = r.Remark
+		}
+
+		result, err := services.NewCheckoutService(db, redis).Checkout(c.Request().Context(), &services.CheckoutRequest{
+			StoreID:      storeID,
+			SupplierIDs:  req.SupplierIDs,
+			Remarks:      remarks,
+			DeliveryInfo: req.DeliveryInfo,
+			OrderSource:  models.OrderSource(req.OrderSource),
+			Credit:       req.PaymentMethod == string(models.PaymentMethodCredit),
+		})
+		if err != nil {
+			var rejected *services.CheckoutRejectedError
+			var priceChanged *services.PriceChangedError
+			var creditRejected *services.CreditRejectedError
+			switch {
+			case errors.As(err, &creditRejected):
+				return creditRejectedResponse(c, creditRejected)
+			case errors.As(err, &rejected):
+				return c.JSON(http.StatusUnprocessableEntity, Response{
+					Code:      http.StatusUnprocessableEntity,
+					Message:   rejected.Error(),
+					Data:      rejected,
+					Timestamp: time.Now().Unix(),
+				})
+			case errors.As(err, &priceChanged):
+				return c.JSON(http.StatusConflict, Response{
+					Code:      http.StatusConflict,
+					Message:   priceChanged.Error(),
+					Data:      priceChanged,
+					Timestamp: time.Now().Unix(),
+				})
+			case errors.Is(err, services.ErrCartEmpty), errors.Is(err, services.ErrStoreNotFound):
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "结算失败")
+		}
+
+		orders := make([]map[string]interface{}, 0, len(result.Orders))
+		for _, order := range result.Orders {
+			orders = append(orders, map[string]interface{}{
+				"orderId":     order.ID,
+				"orderNo":     order.OrderNo,
+				"supplierId":  order.SupplierID,
+				"totalAmount": order.TotalAmount,
+				"status":      order.Status,
+				"serviceFee":  order.ServiceFee,
+				"feeRuleId":   order.ServiceFeeRuleID,
+				"feeRuleName": order.ServiceFeeRuleName,
+			})
+		}
+
+		return SuccessResponse(c, map[string]interface{}{
+			"checkoutId":  result.Checkout.ID,
+			"checkoutNo":  result.Checkout.CheckoutNo,
+			"totalAmount": result.Checkout.TotalAmount,
+			"status":      result.Checkout.Status,
+			"orders":      orders,
+		})
+	}
+}
+
+// GetCartSummary 购物车结算预览，按供应商展示服务端价格、服务费及适用的服务费规则
+func GetCartSummary(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		storeID := GetStoreID(c)
+		if storeID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		preview, err := services.NewCheckoutService(db, redis).Preview(c.Request().Context(), storeID)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车汇总失败")
+		}
+
+		return SuccessResponse(c, preview)
+	}
+}
+
+// GetCheckoutDetail 获取结算单详情
+func GetCheckoutDetail(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		storeID := GetStoreID(c)
+		if storeID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		checkoutID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的结算单ID")
+		}
+
+		checkout, err := services.NewCheckoutService(db, nil).GetCheckout(storeID, checkoutID)
+		if err != nil {
+			if errors.Is(err, services.ErrCheckoutNotFound) {
+				return ErrorResponse(c, http.StatusNotFound, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessResponse(c, checkout)
+	}
+}
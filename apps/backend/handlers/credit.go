@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetCreditAccounts 获取门店赊账账户列表
+func GetCreditAccounts(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		status := models.CreditAccountStatus(c.QueryParam("status"))
+		accounts, total, err := services.NewCreditService(db).ListAccounts(page, pageSize, status)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, accounts, total, page, pageSize)
+	}
+}
+
+// GetCreditAccount 获取门店赊账账户及可用额度，门店只能查看自己的账户
+func GetCreditAccount(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var storeID uint64
+		if IsStore(c) {
+			storeID = GetStoreID(c)
+		} else if IsAdmin(c) {
+			id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, "无效的门店ID")
+			}
+			storeID = id
+		} else {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		account, err := services.NewCreditService(db).GetAccount(storeID)
+		if err != nil {
+			return creditErrorResponse(c, err, "查询失败")
+		}
+
+		return SuccessResponse(c, map[string]interface{}{
+			"account":   account,
+			"available": account.AvailableCredit(),
+		})
+	}
+}
+
+// SaveCreditAccount 开通或修改门店赊账额度和账期
+func SaveCreditAccount(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		storeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的门店ID")
+		}
+
+		type CreditAccountRequest struct {
+			CreditLimit float64 `json:"creditLimit" validate:"gte=0"`
+			BillCycle   string  `json:"billCycle" validate:"omitempty,oneof=weekly monthly"`
+			TermDays    int     `json:"termDays" validate:"gte=0,lte=180"`
+		}
+
+		var req CreditAccountRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		var count int64
+		db.Model(&models.Store{}).Where("id = ?", storeID).Count(&count)
+		if count == 0 {
+			return ErrorResponse(c, http.StatusNotFound, "门店不存在")
+		}
+
+		account, err := services.NewCreditService(db).SaveAccount(&services.CreditAccountRequest{
+			StoreID:     storeID,
+			CreditLimit: req.CreditLimit,
+			BillCycle:   models.SettlementCycle(req.BillCycle),
+			TermDays:    req.TermDays,
+			OperatorID:  GetAdminID(c),
+		})
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "保存失败")
+		}
+
+		return SuccessResponse(c, account)
+	}
+}
+
+// UpdateCreditAccountStatus 冻结、恢复或关闭门店赊账
+func UpdateCreditAccountStatus(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		storeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的门店ID")
+		}
+
+		type StatusRequest struct {
+			Status string `json:"status" validate:"required,oneof=active blocked closed"`
+			Reason string `json:"reason" validate:"max=200"`
+		}
+
+		var req StatusRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		account, err := services.NewCreditService(db).SetAccountStatus(storeID, models.CreditAccountStatus(req.Status), req.Reason, GetAdminID(c))
+		if err != nil {
+			return creditErrorResponse(c, err, "更新失败")
+		}
+
+		return SuccessResponse(c, account)
+	}
+}
+
+// GetCreditBills 获取赊账账单列表，门店只能看到自己的账单
+func GetCreditBills(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		query := &services.CreditBillQuery{Status: models.CreditBillStatus(c.QueryParam("status"))}
+		if IsStore(c) {
+			query.StoreID = GetStoreID(c)
+		} else if IsAdmin(c) {
+			query.StoreID, _ = strconv.ParseUint(c.QueryParam("storeId"), 10, 64)
+		} else {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		bills, total, err := services.NewCreditService(db).ListBills(page, pageSize, query)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, bills, total, page, pageSize)
+	}
+}
+
+// GetCreditBill 获取赊账账单详情及流水
+func GetCreditBill(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的账单ID")
+		}
+
+		var storeID uint64
+		if IsStore(c) {
+			storeID = GetStoreID(c)
+		} else if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		bill, err := services.NewCreditService(db).GetBill(id, storeID)
+		if err != nil {
+			return creditErrorResponse(c, err, "查询失败")
+		}
+
+		return SuccessResponse(c, bill)
+	}
+}
+
+// RepayCreditBill 登记赊账账单还款
+func RepayCreditBill(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的账单ID")
+		}
+
+		type RepayRequest struct {
+			Amount    float64 `json:"amount" validate:"required,gt=0"`
+			Reference string  `json:"reference" validate:"required,max=100"`
+			Remark    string  `json:"remark" validate:"max=200"`
+		}
+
+		var req RepayRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		bill, err := services.NewCreditService(db).Repay(id, &services.CreditRepayment{
+			Amount:     req.Amount,
+			Reference:  req.Reference,
+			Remark:     req.Remark,
+			OperatorID: GetAdminID(c),
+		})
+		if err != nil {
+			return creditErrorResponse(c, err, "还款登记失败")
+		}
+
+		return SuccessResponse(c, bill)
+	}
+}
+
+// GetCreditTransactions 获取门店赊账流水
+func GetCreditTransactions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var storeID uint64
+		if IsStore(c) {
+			storeID = GetStoreID(c)
+		} else if IsAdmin(c) {
+			id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, "无效的门店ID")
+			}
+			storeID = id
+		} else {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		transactions, total, err := services.NewCreditService(db).ListTransactions(storeID, page, pageSize)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, transactions, total, page, pageSize)
+	}
+}
+
+// creditRejectedResponse 赊账下单被拒绝，返回额度信息
+func creditRejectedResponse(c echo.Context, rejected *services.CreditRejectedError) error {
+	return c.JSON(http.StatusUnprocessableEntity, Response{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   rejected.Error(),
+		Data:      rejected,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+// creditErrorResponse 赊账错误转换为响应
+func creditErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrCreditAccountNotFound), errors.Is(err, services.ErrCreditBillNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrCreditBillPaid), errors.Is(err, services.ErrRepaymentAmountInvalid):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// ExportPriceMarkups 导出加价规则，格式与导入模板一致，修改后可直接导入
+func ExportPriceMarkups(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var params services.PriceMarkupQueryParams
+		if err := c.Bind(&params); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+
+		data, err := services.NewMarkupImportService(db).Export(&params)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "导出失败")
+		}
+
+		// 返回JSON数据，前端可以使用xlsx库生成Excel
+		return SuccessResponse(c, map[string]interface{}{
+			"fileName": "加价规则_" + time.Now().Format("20060102150405") + ".xlsx",
+			"columns":  services.MarkupImportColumns,
+			"data":     data,
+			"total":    len(data),
+		})
+	}
+}
+
+// GetMarkupImportTemplate 获取加价规则导入模板
+func GetMarkupImportTemplate(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		return SuccessResponse(c, map[string]interface{}{
+			"fileName": "加价规则导入模板.xlsx",
+			"columns":  services.MarkupImportColumns,
+			"sampleData": []map[string]interface{}{
+				{
+					"name":        "蔬菜类统一加价",
+					"category":    "蔬菜",
+					"markupType":  "percent",
+					"markupValue": 0.05,
+					"tiers":       "0-50@1=0.08;0-50@10=0.05;50-@1=0.06",
+					"minMarkup":   0.5,
+					"priority":    20,
+					"isActive":    "是",
+				},
+				{
+					"name":        "门店001面粉固定加价",
+					"store":       "S001",
+					"material":    "M0001",
+					"markupType":  "fixed",
+					"markupValue": 2,
+					"priority":    100,
+					"isActive":    "是",
+					"startTime":   "2026-01-01",
+					"endTime":     "2026-12-31 23:59:59",
+				},
+			},
+		})
+	}
+}
+
+// ImportPriceMarkups 批量导入加价规则（前端解析Excel后提交JSON）
+// dryRun 为 true 时只校验并返回行级错误；否则全部行无误时在同一事务内写入
+func ImportPriceMarkups(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		type ImportRequest struct {
+			Items  []services.MarkupImportRow `json:"items" validate:"required,min=1"`
+			DryRun bool                       `json:"dryRun"`
+		}
+
+		var req ImportRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		result, err := services.NewMarkupImportService(db).Import(&services.MarkupImportRequest{
+			Rows:   req.Items,
+			DryRun: req.DryRun,
+		}, &services.MarkupImportOperator{
+			AdminID:       GetAdminID(c),
+			IP:            c.RealIP(),
+			UserAgent:     c.Request().UserAgent(),
+			RequestURL:    c.Request().URL.String(),
+			RequestMethod: c.Request().Method,
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrMarkupImportInvalid) {
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "导入失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
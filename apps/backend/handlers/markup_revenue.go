@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// markupRevenueParams 加价收入统计查询参数，日期为 yyyy-mm-dd，默认近30天
+type markupRevenueParams struct {
+	StartDate       string `query:"startDate"`
+	EndDate         string `query:"endDate"`
+	GroupBy         string `query:"groupBy"`
+	Interval        string `query:"interval"`
+	StoreID         uint64 `query:"storeId"`
+	SupplierID      uint64 `query:"supplierId"`
+	CategoryID      uint64 `query:"categoryId"`
+	RuleID          uint64 `query:"ruleId"`
+	ExcludeRefunded bool   `query:"excludeRefunded"`
+	Limit           int    `query:"limit"`
+}
+
+// bindMarkupRevenueQuery 解析统计参数，失败时返回错误提示
+func bindMarkupRevenueQuery(c echo.Context) (*services.MarkupRevenueQuery, string) {
+	var params markupRevenueParams
+	if err := c.Bind(&params); err != nil {
+		return nil, "请求参数错误"
+	}
+
+	today := time.Now()
+	endDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	if params.EndDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if err != nil {
+			return nil, "结束日期格式错误"
+		}
+		endDate = t
+	}
+	startDate := endDate.AddDate(0, 0, -29)
+	if params.StartDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		if err != nil {
+			return nil, "开始日期格式错误"
+		}
+		startDate = t
+	}
+
+	return &services.MarkupRevenueQuery{
+		StartDate:       startDate,
+		EndDate:         endDate.AddDate(0, 0, 1),
+		GroupBy:         params.GroupBy,
+		Interval:        params.Interval,
+		StoreID:         params.StoreID,
+		SupplierID:      params.SupplierID,
+		CategoryID:      params.CategoryID,
+		RuleID:          params.RuleID,
+		ExcludeRefunded: params.ExcludeRefunded,
+		Limit:           params.Limit,
+	}, ""
+}
+
+// GetMarkupRevenue 按规则、分类、供应商或门店统计加价收入
+func GetMarkupRevenue(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		query, message := bindMarkupRevenueQuery(c)
+		if query == nil {
+			return ErrorResponse(c, http.StatusBadRequest, message)
+		}
+		if query.GroupBy == "" {
+			query.GroupBy = services.MarkupRevenueByRule
+		}
+
+		report, err := services.NewMarkupRevenueService(db).Report(query)
+		if err != nil {
+			if errors.Is(err, services.ErrMarkupRevenueQueryInvalid) {
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "统计失败")
+		}
+
+		return SuccessResponse(c, report)
+	}
+}
+
+// GetMarkupRevenueTrend 加价收入趋势，指定 groupBy 时返回加价收入最高的若干个分组的趋势
+func GetMarkupRevenueTrend(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		query, message := bindMarkupRevenueQuery(c)
+		if query == nil {
+			return ErrorResponse(c, http.StatusBadRequest, message)
+		}
+		if query.Interval == "" {
+			query.Interval = services.MarkupTrendDay
+		}
+
+		trend, err := services.NewMarkupRevenueService(db).Trend(query)
+		if err != nil {
+			if errors.Is(err, services.ErrMarkupRevenueQueryInvalid) {
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "统计失败")
+		}
+
+		return SuccessResponse(c, trend)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// SimulateMarkup 用草稿加价规则重算历史订单明细，对比加价收入和门店支出变化，不保存任何数据
+func SimulateMarkup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		type SimulateRequest struct {
+			StartDate  string                   `json:"startDate" validate:"required"`
+			EndDate    string                   `json:"endDate" validate:"required"`
+			StoreID    uint64                   `json:"storeId"`
+			SupplierID uint64                   `json:"supplierId"`
+			TopN       int                      `json:"topN" validate:"gte=0,lte=100"`
+			Draft      services.MarkupRuleDraft `json:"draft"`
+		}
+
+		var req SimulateRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		startDate, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "开始日期格式错误")
+		}
+		endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "结束日期格式错误")
+		}
+
+		result, err := services.NewMarkupSimulationService(db).Simulate(&services.MarkupSimulationRequest{
+			StartDate:  startDate,
+			EndDate:    endDate.AddDate(0, 0, 1),
+			StoreID:    req.StoreID,
+			SupplierID: req.SupplierID,
+			TopN:       req.TopN,
+			Draft:      req.Draft,
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrSimulationRangeInvalid) || errors.Is(err, services.ErrSimulationRuleNotFound) ||
+				errors.Is(err, services.ErrMarkupTiersInvalid) {
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "模拟计算失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// ExplainMarkup 计算加价并返回每条规则的判定过程(范围、有效期、开关、最小/最大限制)
+func ExplainMarkup(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var req services.CalculateMarkupRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+		req.Explain = true
+
+		result, err := services.NewPriceMarkupService(db).CalculateMarkup(&req)
+		if err != nil {
+			if errors.Is(err, services.ErrStoreNotFound) || errors.Is(err, services.ErrSupplierNotFound) {
+				return ErrorResponse(c, http.StatusNotFound, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "计算失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// CheckMarkupConflicts 检查启用中的加价规则冲突：同范围同优先级的重叠规则和永远不会命中的规则
+func CheckMarkupConflicts(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		report, err := services.NewPriceMarkupService(db).CheckConflicts()
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "检查失败")
+		}
+
+		return SuccessResponse(c, report)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/project/backend/models"
+	"github.com/project/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -641,24 +642,28 @@ func GetPriceMarkups(db *gorm.DB) echo.HandlerFunc {
 func CreatePriceMarkup(db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		type CreateMarkupRequest struct {
-			Name        string            `json:"name" validate:"required"`
-			Description string            `json:"description"`
-			StoreID     *uint64           `json:"storeId"`
-			SupplierID  *uint64           `json:"supplierId"`
-			CategoryID  *uint64           `json:"categoryId"`
-			MaterialID  *uint64           `json:"materialId"`
-			MarkupType  models.MarkupType `json:"markupType" validate:"required"`
-			MarkupValue float64           `json:"markupValue" validate:"required"`
-			MinMarkup   float64           `json:"minMarkup"`
-			MaxMarkup   float64           `json:"maxMarkup"`
-			Priority    int               `json:"priority"`
-			IsActive    bool              `json:"isActive"`
+			Name        string             `json:"name" validate:"required"`
+			Description string             `json:"description"`
+			StoreID     *uint64            `json:"storeId"`
+			SupplierID  *uint64            `json:"supplierId"`
+			CategoryID  *uint64            `json:"categoryId"`
+			MaterialID  *uint64            `json:"materialId"`
+			MarkupType  models.MarkupType  `json:"markupType" validate:"required"`
+			MarkupValue float64            `json:"markupValue" validate:"required"`
+			Tiers       models.MarkupTiers `json:"tiers"`
+			MinMarkup   float64            `json:"minMarkup"`
+			MaxMarkup   float64            `json:"maxMarkup"`
+			Priority    int                `json:"priority"`
+			IsActive    bool               `json:"isActive"`
 		}
 
 		var req CreateMarkupRequest
 		if err := c.Bind(&req); err != nil {
 			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
 		}
+		if err := services.ValidateMarkupTiers(req.Tiers); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
 
 		userID := GetUserID(c)
 		markup := &models.PriceMarkup{
@@ -670,6 +675,7 @@ func CreatePriceMarkup(db *gorm.DB) echo.HandlerFunc {
 			MaterialID:  req.MaterialID,
 			MarkupType:  req.MarkupType,
 			MarkupValue: req.MarkupValue,
+			Tiers:       req.Tiers,
 			MinMarkup:   req.MinMarkup,
 			MaxMarkup:   req.MaxMarkup,
 			Priority:    req.Priority,
@@ -699,20 +705,24 @@ func UpdatePriceMarkup(db *gorm.DB) echo.HandlerFunc {
 		}
 
 		type UpdateMarkupRequest struct {
-			Name        string            `json:"name"`
-			Description string            `json:"description"`
-			MarkupType  models.MarkupType `json:"markupType"`
-			MarkupValue float64           `json:"markupValue"`
-			MinMarkup   float64           `json:"minMarkup"`
-			MaxMarkup   float64           `json:"maxMarkup"`
-			Priority    int               `json:"priority"`
-			IsActive    *bool             `json:"isActive"`
+			Name        string             `json:"name"`
+			Description string             `json:"description"`
+			MarkupType  models.MarkupType  `json:"markupType"`
+			MarkupValue float64            `json:"markupValue"`
+			Tiers       models.MarkupTiers `json:"tiers"` // 传空数组清除阶梯
+			MinMarkup   float64            `json:"minMarkup"`
+			MaxMarkup   float64            `json:"maxMarkup"`
+			Priority    int                `json:"priority"`
+			IsActive    *bool              `json:"isActive"`
 		}
 
 		var req UpdateMarkupRequest
 		if err := c.Bind(&req); err != nil {
 			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
 		}
+		if err := services.ValidateMarkupTiers(req.Tiers); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
 
 		updates := make(map[string]interface{})
 		if req.Name != "" {
@@ -727,6 +737,9 @@ func UpdatePriceMarkup(db *gorm.DB) echo.HandlerFunc {
 		if req.MarkupValue != 0 {
 			updates["markup_value"] = req.MarkupValue
 		}
+		if req.Tiers != nil {
+			updates["tiers"] = req.Tiers
+		}
 		updates["min_markup"] = req.MinMarkup
 		updates["max_markup"] = req.MaxMarkup
 		updates["priority"] = req.Priority
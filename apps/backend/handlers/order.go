@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/project/backend/models"
+	"github.com/project/backend/services"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
@@ -114,21 +116,15 @@ func UpdateOrderStatusAdmin(db *gorm.DB) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
 		}
 
-		var order models.Order
-		if err := db.First(&order, id).Error; err != nil {
-			return ErrorResponse(c, http.StatusNotFound, "订单不存在")
-		}
-
-		// 更新订单状态
-		updates := map[string]interface{}{
-			"status": models.OrderStatus(req.Status),
-		}
-		if req.Remark != "" {
-			updates["remark"] = req.Remark
-		}
-
-		if err := db.Model(&order).Updates(updates).Error; err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "更新失败")
+		order, err := services.NewOrderStateMachine(db).Transition(&services.OrderTransition{
+			OrderID:      id,
+			To:           models.OrderStatus(req.Status),
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   GetAdminID(c),
+			Remark:       req.Remark,
+		})
+		if err != nil {
+			return orderTransitionErrorResponse(c, err)
 		}
 
 		return SuccessResponse(c, order)
@@ -226,26 +222,16 @@ func ConfirmOrder(db *gorm.DB) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "无效的ID")
 		}
 
-		var order models.Order
-		if err := db.Where("id = ? AND supplier_id = ?", id, supplierID).First(&order).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return ErrorResponse(c, http.StatusNotFound, "订单不存在")
-			}
-			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
-		}
-
-		if order.Status != models.OrderStatusPendingConfirm {
-			return ErrorResponse(c, http.StatusBadRequest, "订单状态不允许确认")
-		}
-
-		now := time.Now()
-		updates := map[string]interface{}{
-			"status":       models.OrderStatusConfirmed,
-			"confirmed_at": &now,
-		}
-
-		if err := db.Model(&order).Updates(updates).Error; err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "确认订单失败")
+		_, err = services.NewOrderStateMachine(db).Transition(&services.OrderTransition{
+			OrderID:      id,
+			SupplierID:   supplierID,
+			From:         []models.OrderStatus{models.OrderStatusPendingConfirm},
+			To:           models.OrderStatusConfirmed,
+			OperatorType: models.OperatorTypeSupplier,
+			OperatorID:   supplierID,
+		})
+		if err != nil {
+			return orderTransitionErrorResponse(c, err)
 		}
 
 		return SuccessResponse(c, nil)
@@ -265,18 +251,17 @@ func DeliverOrder(db *gorm.DB) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "无效的订单ID")
 		}
 
-		var order models.Order
-		if err := db.Where("id = ? AND supplier_id = ?", id, supplierID).First(&order).Error; err != nil {
-			return ErrorResponse(c, http.StatusNotFound, "订单不存在")
-		}
-
 		// 只有已确认的订单可以开始配送
-		if order.Status != models.OrderStatusConfirmed {
-			return ErrorResponse(c, http.StatusBadRequest, "订单状态不允许配送")
-		}
-
-		if err := db.Model(&order).Update("status", models.OrderStatusDelivering).Error; err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "更新失败")
+		order, err := services.NewOrderStateMachine(db).Transition(&services.OrderTransition{
+			OrderID:      id,
+			SupplierID:   supplierID,
+			From:         []models.OrderStatus{models.OrderStatusConfirmed},
+			To:           models.OrderStatusDelivering,
+			OperatorType: models.OperatorTypeSupplier,
+			OperatorID:   supplierID,
+		})
+		if err != nil {
+			return orderTransitionErrorResponse(c, err)
 		}
 
 		return SuccessResponse(c, order)
@@ -296,30 +281,37 @@ func CompleteOrder(db *gorm.DB) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "无效的订单ID")
 		}
 
-		var order models.Order
-		if err := db.Where("id = ? AND supplier_id = ?", id, supplierID).First(&order).Error; err != nil {
-			return ErrorResponse(c, http.StatusNotFound, "订单不存在")
-		}
-
 		// 只有配送中的订单可以完成
-		if order.Status != models.OrderStatusDelivering {
-			return ErrorResponse(c, http.StatusBadRequest, "订单状态不允许完成")
-		}
-
-		now := time.Now()
-		updates := map[string]interface{}{
-			"status":       models.OrderStatusCompleted,
-			"completed_at": &now,
-		}
-
-		if err := db.Model(&order).Updates(updates).Error; err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "更新失败")
+		order, err := services.NewOrderStateMachine(db).Transition(&services.OrderTransition{
+			OrderID:      id,
+			SupplierID:   supplierID,
+			From:         []models.OrderStatus{models.OrderStatusDelivering},
+			To:           models.OrderStatusCompleted,
+			OperatorType: models.OperatorTypeSupplier,
+			OperatorID:   supplierID,
+		})
+		if err != nil {
+			return orderTransitionErrorResponse(c, err)
 		}
 
 		return SuccessResponse(c, order)
 	}
 }
 
+// orderTransitionErrorResponse 状态机错误转换为响应
+func orderTransitionErrorResponse(c echo.Context, err error) error {
+	var invalid *services.InvalidTransitionError
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		return ErrorResponse(c, http.StatusNotFound, "订单不存在")
+	case errors.Is(err, services.ErrOrderConcurrentUpdate):
+		return ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.As(err, &invalid):
+		return ErrorResponse(c, http.StatusBadRequest, invalid.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, "更新订单状态失败")
+}
+
 // GetOrdersStore 门店获取订单列表
 func GetOrdersStore(db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -412,17 +404,12 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 		}
 
 		type CreateOrderRequest struct {
-			SupplierID   uint64             `json:"supplierId" validate:"required"`
-			Items        []OrderItemRequest `json:"items" validate:"required,min=1"`
-			Remark       string             `json:"remark"`
-			DeliveryInfo struct {
-				Province string `json:"province"`
-				City     string `json:"city"`
-				District string `json:"district"`
-				Address  string `json:"address"`
-				Contact  string `json:"contact"`
-				Phone    string `json:"phone"`
-			} `json:"deliveryInfo"`
+			SupplierID   uint64                `json:"supplierId" validate:"required"`
+			Items        []OrderItemRequest    `json:"items" validate:"required,min=1"`
+			Remark       string                `json:"remark"`
+			DeliveryInfo services.DeliveryInfo `json:"deliveryInfo"`
+			// 为 credit 时使用门店赊账额度，订单直接进入待确认
+			PaymentMethod string `json:"paymentMethod" validate:"omitempty,oneof=online credit"`
 		}
 
 		var req CreateOrderRequest
@@ -434,17 +421,75 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
 		}
 
-		// 计算订单金额
-		var goodsAmount, markupTotal float64
-		var itemCount int
+		// 校验商品归属、审核上架及库存状态、起订量和递增量
+		orderLines := make([]services.OrderLine, 0, len(req.Items))
 		for _, item := range req.Items {
-			goodsAmount += item.FinalPrice * float64(item.Quantity)
-			markupTotal += item.MarkupAmount * float64(item.Quantity)
-			itemCount++
+			orderLines = append(orderLines, services.OrderLine{MaterialSkuID: item.MaterialSkuID, Quantity: item.Quantity})
+		}
+		if _, err := services.NewOrderLineService(db).CheckLines(req.SupplierID, orderLines); err != nil {
+			return orderLineErrorResponse(c, err)
 		}
 
-		// 计算服务费
-		serviceFee := goodsAmount * 0.003 // 0.3%服务费
+		// 服务端定价：以供应商报价 + 加价规则为准，客户端价格仅作比对
+		pricingInputs := make([]services.PricingItemInput, 0, len(req.Items))
+		for _, item := range req.Items {
+			pricingInputs = append(pricingInputs, services.PricingItemInput{
+				MaterialSkuID: item.MaterialSkuID,
+				Quantity:      item.Quantity,
+				UnitPrice:     item.UnitPrice,
+				MarkupAmount:  item.MarkupAmount,
+				FinalPrice:    item.FinalPrice,
+			})
+		}
+
+		pricing, err := services.NewOrderPricingService(db).PriceAndVerify(storeID, req.SupplierID, pricingInputs)
+		if err != nil {
+			var priceChanged *services.PriceChangedError
+			var notSupplied *services.SkuNotSuppliedError
+			switch {
+			case errors.As(err, &priceChanged):
+				return c.JSON(http.StatusConflict, Response{
+					Code:      http.StatusConflict,
+					Message:   priceChanged.Error(),
+					Data:      priceChanged,
+					Timestamp: time.Now().Unix(),
+				})
+			case errors.As(err, &notSupplied):
+				return ErrorResponse(c, http.StatusBadRequest, notSupplied.Error())
+			case errors.Is(err, services.ErrSupplierNotFound), errors.Is(err, services.ErrStoreNotFound):
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "计算订单价格失败")
+		}
+
+		goodsAmount := pricing.GoodsAmount
+		markupTotal := pricing.MarkupTotal
+		itemCount := len(pricing.Items)
+
+		// 校验起订金额、配送区域，并计算预计送达日期
+		delivery, err := services.NewDeliveryRuleService(db).CheckOrder(storeID, req.SupplierID, req.DeliveryInfo, goodsAmount)
+		if err != nil {
+			var rejected *services.DeliveryRejectedError
+			switch {
+			case errors.As(err, &rejected):
+				return c.JSON(http.StatusUnprocessableEntity, Response{
+					Code:      http.StatusUnprocessableEntity,
+					Message:   rejected.Error(),
+					Data:      rejected,
+					Timestamp: time.Now().Unix(),
+				})
+			case errors.Is(err, services.ErrSupplierNotFound), errors.Is(err, services.ErrStoreNotFound):
+				return ErrorResponse(c, http.StatusBadRequest, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "校验配送规则失败")
+		}
+
+		// 按服务费规则计算服务费
+		feeQuote, err := services.NewServiceFeeService(db).Calculate(storeID, req.SupplierID, pricing.Items, time.Now())
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "计算服务费失败")
+		}
+		serviceFee := feeQuote.Fee
 		totalAmount := goodsAmount + serviceFee
 		supplierAmount := goodsAmount - markupTotal
 
@@ -466,19 +511,21 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 			OrderSource:    models.OrderSourceWeb,
 		}
 
+		order.ServiceFeeRuleID = feeQuote.RuleID
+		order.ServiceFeeRuleName = feeQuote.RuleName
+
 		if req.Remark != "" {
 			order.Remark = &req.Remark
 		}
 
-		// 设置配送信息
-		if req.DeliveryInfo.Province != "" {
-			order.DeliveryProvince = &req.DeliveryInfo.Province
-			order.DeliveryCity = &req.DeliveryInfo.City
-			order.DeliveryDistrict = &req.DeliveryInfo.District
-			order.DeliveryAddress = &req.DeliveryInfo.Address
-			order.DeliveryContact = &req.DeliveryInfo.Contact
-			order.DeliveryPhone = &req.DeliveryInfo.Phone
-		}
+		// 设置配送信息，未填写时已回填门店地址
+		order.DeliveryProvince = &delivery.Delivery.Province
+		order.DeliveryCity = &delivery.Delivery.City
+		order.DeliveryDistrict = &delivery.Delivery.District
+		order.DeliveryAddress = &delivery.Delivery.Address
+		order.DeliveryContact = &delivery.Delivery.Contact
+		order.DeliveryPhone = &delivery.Delivery.Phone
+		order.ExpectedDeliveryDate = &delivery.ExpectedDeliveryDate
 
 		if err := tx.Create(order).Error; err != nil {
 			tx.Rollback()
@@ -486,14 +533,8 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 		}
 
 		// 创建订单明细
-		for _, item := range req.Items {
-			// 获取SKU信息
-			var sku models.MaterialSku
-			if err := tx.Preload("Material").First(&sku, item.MaterialSkuID).Error; err != nil {
-				tx.Rollback()
-				return ErrorResponse(c, http.StatusBadRequest, "物料SKU不存在")
-			}
-
+		for _, item := range pricing.Items {
+			sku := item.Sku
 			orderItem := &models.OrderItem{
 				OrderID:       order.ID,
 				MaterialSkuID: item.MaterialSkuID,
@@ -505,8 +546,9 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 				Quantity:      item.Quantity,
 				UnitPrice:     item.UnitPrice,
 				MarkupAmount:  item.MarkupAmount,
+				MarkupRuleID:  item.MarkupRuleID,
 				FinalPrice:    item.FinalPrice,
-				Subtotal:      item.FinalPrice * float64(item.Quantity),
+				Subtotal:      item.Subtotal,
 			}
 
 			if err := tx.Create(orderItem).Error; err != nil {
@@ -515,6 +557,18 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 			}
 		}
 
+		// 赊账下单：占用额度并跳过待支付
+		if req.PaymentMethod == string(models.PaymentMethodCredit) {
+			if err := services.NewCreditService(db).ChargeOrdersTx(tx, storeID, []*models.Order{order}); err != nil {
+				tx.Rollback()
+				var creditRejected *services.CreditRejectedError
+				if errors.As(err, &creditRejected) {
+					return creditRejectedResponse(c, creditRejected)
+				}
+				return ErrorResponse(c, http.StatusInternalServerError, "赊账下单失败")
+			}
+		}
+
 		// 清空购物车中对应供应商的商品
 		if redis != nil {
 			ctx := c.Request().Context()
@@ -525,8 +579,10 @@ func CreateOrder(db *gorm.DB, redis *redis.Client) echo.HandlerFunc {
 		tx.Commit()
 
 		return SuccessResponse(c, map[string]interface{}{
-			"orderId": order.ID,
-			"orderNo": order.OrderNo,
+			"orderId":    order.ID,
+			"orderNo":    order.OrderNo,
+			"status":     order.Status,
+			"serviceFee": feeQuote,
 		})
 	}
 }
@@ -579,43 +635,19 @@ func CancelOrder(db *gorm.DB) echo.HandlerFunc {
 		}
 
 		// 执行取消
-		tx := db.Begin()
-		now := time.Now()
-		cancelledBy := models.CancelledByStore
-
-		updates := map[string]interface{}{
-			"status":          models.OrderStatusCancelled,
-			"cancel_reason":   req.Reason,
-			"cancelled_by":    cancelledBy,
-			"cancelled_by_id": storeID,
-			"cancelled_at":    now,
-		}
-
-		if err := tx.Model(&order).Updates(updates).Error; err != nil {
-			tx.Rollback()
-			return ErrorResponse(c, http.StatusInternalServerError, "取消订单失败")
-		}
-
-		// 创建状态日志
-		fromStatus := string(order.Status)
-		toStatus := string(models.OrderStatusCancelled)
-		operatorType := models.OperatorTypeStore
-		log := &models.OrderStatusLog{
+		_, err = services.NewOrderStateMachine(db).Transition(&services.OrderTransition{
 			OrderID:      orderID,
-			FromStatus:   &fromStatus,
-			ToStatus:     toStatus,
-			OperatorType: &operatorType,
-			OperatorID:   &storeID,
-			Remark:       &req.Reason,
-		}
-
-		if err := tx.Create(log).Error; err != nil {
-			tx.Rollback()
-			return ErrorResponse(c, http.StatusInternalServerError, "记录日志失败")
+			StoreID:      storeID,
+			From:         []models.OrderStatus{models.OrderStatusPendingConfirm},
+			To:           models.OrderStatusCancelled,
+			OperatorType: models.OperatorTypeStore,
+			OperatorID:   storeID,
+			Remark:       req.Reason,
+		})
+		if err != nil {
+			return orderTransitionErrorResponse(c, err)
 		}
 
-		tx.Commit()
-
 		return SuccessResponse(c, map[string]interface{}{
 			"message": "订单已取消",
 		})
@@ -1025,3 +1057,17 @@ func ExportOrderItemsExcel(db *gorm.DB) echo.HandlerFunc {
 		})
 	}
 }
+
+// orderLineErrorResponse 订单明细校验失败时按明细返回原因
+func orderLineErrorResponse(c echo.Context, err error) error {
+	var rejected *services.OrderLineRejectedError
+	if errors.As(err, &rejected) {
+		return c.JSON(http.StatusUnprocessableEntity, Response{
+			Code:      http.StatusUnprocessableEntity,
+			Message:   rejected.Error(),
+			Data:      rejected,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, "校验商品失败")
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetPaymentCallbacks 获取支付通知收件箱
+func GetPaymentCallbacks(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		inboxes, total, err := services.NewPaymentCallbackService(db, providers).List(
+			page, pageSize,
+			models.PaymentCallbackStatus(c.QueryParam("status")),
+			c.QueryParam("paymentNo"),
+		)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, inboxes, total, page, pageSize)
+	}
+}
+
+// ReplayPaymentCallback 重放处理失败的支付通知
+func ReplayPaymentCallback(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的通知ID")
+		}
+
+		inbox, err := services.NewPaymentCallbackService(db, providers).Replay(id)
+		switch {
+		case errors.Is(err, services.ErrPaymentCallbackNotFound):
+			return ErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrPaymentCallbackNotReplayable):
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case err != nil && inbox == nil:
+			return ErrorResponse(c, http.StatusInternalServerError, "重放失败")
+		case err != nil:
+			return paymentCallbackFailedResponse(c, inbox)
+		}
+
+		return SuccessResponse(c, inbox)
+	}
+}
+
+// ReplayFailedPaymentCallbacks 批量重放处理失败的支付通知
+func ReplayFailedPaymentCallbacks(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		limit, _ := strconv.Atoi(c.QueryParam("limit"))
+		if limit <= 0 || limit > 500 {
+			limit = 100
+		}
+
+		succeeded, failed, err := services.NewPaymentCallbackService(db, providers).ReplayFailed(limit)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "重放失败")
+		}
+
+		return SuccessResponse(c, map[string]int{
+			"succeeded": succeeded,
+			"failed":    failed,
+		})
+	}
+}
+
+// ReconcilePayment 向支付渠道查单并按结果补单
+func ReconcilePayment(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		inbox, err := services.NewPaymentCallbackService(db, providers).Reconcile(c.Request().Context(), c.Param("paymentNo"))
+		if err != nil {
+			var gatewayErr *services.PaymentGatewayError
+			switch {
+			case errors.Is(err, services.ErrPaymentRecordNotFound):
+				return ErrorResponse(c, http.StatusNotFound, err.Error())
+			case errors.Is(err, services.ErrPaymentProviderUnavailable):
+				return ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+			case errors.As(err, &gatewayErr):
+				return ErrorResponse(c, http.StatusBadGateway, err.Error())
+			case inbox != nil:
+				return paymentCallbackFailedResponse(c, inbox)
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "补单失败")
+		}
+
+		return SuccessResponse(c, inbox)
+	}
+}
+
+// paymentCallbackFailedResponse 通知已入库但处理失败，返回通知详情便于排查
+func paymentCallbackFailedResponse(c echo.Context, inbox *models.PaymentCallbackInbox) error {
+	return c.JSON(http.StatusUnprocessableEntity, Response{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   inbox.ErrorMsg,
+		Data:      inbox,
+		Timestamp: time.Now().Unix(),
+	})
+}
@@ -1,14 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	"github.com/project/backend/config"
 	"github.com/project/backend/models"
 	"github.com/project/backend/services"
 	"gorm.io/gorm"
@@ -16,33 +17,21 @@ import (
 
 // PaymentHandler 支付处理器
 type PaymentHandler struct {
-	db             *gorm.DB
-	paymentService *services.PaymentService
-	wechatService  *services.WeChatPayService
-	alipayService  *services.AlipayService
+	db              *gorm.DB
+	paymentService  *services.PaymentService
+	callbackService *services.PaymentCallbackService
+	refundService   *services.RefundService
+	providers       *services.PaymentProviders
 }
 
 // NewPaymentHandler 创建支付处理器
-func NewPaymentHandler(db *gorm.DB, cfg *config.Config) *PaymentHandler {
+func NewPaymentHandler(db *gorm.DB, providers *services.PaymentProviders) *PaymentHandler {
 	handler := &PaymentHandler{
-		db:             db,
-		paymentService: services.NewPaymentService(db),
-	}
-
-	// 初始化微信支付服务(如果配置了)
-	if cfg.WeChatPay.AppID != "" && cfg.WeChatPay.MchID != "" {
-		wechatService, err := services.NewWeChatPayService(&cfg.WeChatPay)
-		if err == nil {
-			handler.wechatService = wechatService
-		}
-	}
-
-	// 初始化支付宝服务(如果配置了)
-	if cfg.Alipay.AppID != "" && cfg.Alipay.PrivateKey != "" {
-		alipayService, err := services.NewAlipayService(&cfg.Alipay)
-		if err == nil {
-			handler.alipayService = alipayService
-		}
+		db:              db,
+		paymentService:  services.NewPaymentService(db, providers),
+		callbackService: services.NewPaymentCallbackService(db, providers),
+		refundService:   services.NewRefundService(db, providers),
+		providers:       providers,
 	}
 
 	return handler
@@ -54,18 +43,19 @@ type PaymentMethodType string
 const (
 	PaymentMethodWechat PaymentMethodType = "wechat"
 	PaymentMethodAlipay PaymentMethodType = "alipay"
+	PaymentMethodMock   PaymentMethodType = "mock"
 )
 
 // CreatePaymentRequest 创建支付请求
 type CreatePaymentReq struct {
 	OrderID       uint64            `json:"orderId" validate:"required"`
-	PaymentMethod PaymentMethodType `json:"paymentMethod" validate:"required,oneof=wechat alipay"`
+	PaymentMethod PaymentMethodType `json:"paymentMethod" validate:"required,oneof=wechat alipay mock"`
 }
 
 // SwitchPaymentMethodRequest 切换支付方式请求
 type SwitchPaymentMethodRequest struct {
 	PaymentNo     string            `json:"paymentNo" validate:"required"`
-	PaymentMethod PaymentMethodType `json:"paymentMethod" validate:"required,oneof=wechat alipay"`
+	PaymentMethod PaymentMethodType `json:"paymentMethod" validate:"required,oneof=wechat alipay mock"`
 }
 
 // CreatePayment 创建支付订单
@@ -102,94 +92,166 @@ func (h *PaymentHandler) CreatePayment(c echo.Context) error {
 		})
 	}
 
-	var qrcodeURL string
-	var payURL string
-	var paymentNo string
-	expireTime := time.Now().Add(15 * time.Minute)
+	// 调用支付渠道下单并创建支付记录，支付单号与第三方商户订单号一致
+	paymentNo := newGatewayPaymentNo(req.PaymentMethod, strconv.FormatUint(order.ID, 10))
+	payment, err := h.paymentService.CreatePayment(c.Request().Context(), &services.CreatePaymentRequest{
+		PaymentNo:     paymentNo,
+		OrderID:       order.ID,
+		OrderNo:       order.OrderNo,
+		GoodsAmount:   order.GoodsAmount,
+		ServiceFee:    order.ServiceFee,
+		Amount:        order.TotalAmount,
+		PaymentMethod: services.PaymentMethod(req.PaymentMethod),
+		Subject:       fmt.Sprintf("供应链订货-订单%s", order.OrderNo),
+	})
+	if err != nil {
+		return paymentErrorResponse(c, err)
+	}
 
-	// 根据支付方式创建支付
-	switch req.PaymentMethod {
-	case PaymentMethodWechat:
-		if h.wechatService == nil {
-			return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
-				"code":    503,
-				"message": "微信支付服务未配置",
-			})
-		}
+	// 更新订单支付信息
+	h.db.Model(&order).Updates(map[string]interface{}{
+		"payment_method": string(req.PaymentMethod),
+		"payment_no":     paymentNo,
+	})
 
-		paymentNo = fmt.Sprintf("WX%s%d", time.Now().Format("20060102150405"), order.ID)
-		resp, err := h.wechatService.CreateNativePayment(c.Request().Context(), &services.NativePaymentRequest{
-			OrderNo:       paymentNo,
-			AmountInCents: int64(order.TotalAmount * 100),
-			Description:   fmt.Sprintf("供应链订货-订单%s", order.OrderNo),
-		})
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"code":    500,
-				"message": "创建微信支付失败: " + err.Error(),
-			})
-		}
-		qrcodeURL = resp.QRCodeURL
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"code":    200,
+		"message": "创建成功",
+		"data":    paymentQRCodeData(payment),
+	})
+}
 
-	case PaymentMethodAlipay:
-		if h.alipayService == nil {
-			return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
-				"code":    503,
-				"message": "支付宝服务未配置",
-			})
-		}
+// CreateCheckoutPaymentReq 结算单合并支付请求
+type CreateCheckoutPaymentReq struct {
+	CheckoutID    uint64            `json:"checkoutId" validate:"required"`
+	PaymentMethod PaymentMethodType `json:"paymentMethod" validate:"required,oneof=wechat alipay mock"`
+}
 
-		paymentNo = fmt.Sprintf("ALI%s%d", time.Now().Format("20060102150405"), order.ID)
-		resp, err := h.alipayService.CreateNativePayment(c.Request().Context(), &services.AlipayPaymentRequest{
-			OrderNo: paymentNo,
-			Amount:  order.TotalAmount,
-			Subject: fmt.Sprintf("供应链订货-订单%s", order.OrderNo),
+// CreateCheckoutPayment 结算单合并支付
+// @Summary 结算单合并支付
+// @Description 一笔支付覆盖结算单下所有待支付子订单，到账后按子订单金额分摊
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Param request body CreateCheckoutPaymentReq true "合并支付请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /store/payments/checkout [post]
+func (h *PaymentHandler) CreateCheckoutPayment(c echo.Context) error {
+	var req CreateCheckoutPaymentReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
 		})
-		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"code":    500,
-				"message": "创建支付宝支付失败: " + err.Error(),
-			})
-		}
-		qrcodeURL = resp.QRCodeURL
-		payURL = resp.PayURL
-
-	default:
+	}
+	if err := c.Validate(req); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"code":    400,
-			"message": "不支持的支付方式",
+			"message": "参数验证失败",
 		})
 	}
 
-	// 更新订单支付信息
-	h.db.Model(&order).Updates(map[string]interface{}{
-		"payment_method": string(req.PaymentMethod),
-	})
+	checkoutService := services.NewCheckoutService(h.db, nil)
+	payment, err := checkoutService.PrepareCombinedPayment(GetStoreID(c), req.CheckoutID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCheckoutNotFound):
+			return c.JSON(http.StatusNotFound, map[string]interface{}{"code": 404, "message": err.Error()})
+		case errors.Is(err, services.ErrCheckoutNotPayable):
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{"code": 400, "message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"code": 500, "message": "查询结算单失败"})
+	}
 
-	// 创建支付记录
-	h.paymentService.CreatePayment(&services.CreatePaymentRequest{
-		OrderID:       order.ID,
-		OrderNo:       order.OrderNo,
-		GoodsAmount:   order.TotalAmount,
-		ServiceFee:    0,
-		Amount:        order.TotalAmount,
-		PaymentMethod: services.PaymentMethod(req.PaymentMethod),
-	})
+	expireTime := time.Now().Add(15 * time.Minute)
+	paymentNo := newGatewayPaymentNo(req.PaymentMethod, "C"+strconv.FormatUint(payment.Checkout.ID, 10))
+	prepay, err := h.paymentService.Prepay(c.Request().Context(), services.PaymentMethod(req.PaymentMethod), paymentNo, payment.Amount,
+		fmt.Sprintf("供应链订货-结算单%s(%d个订单)", payment.Checkout.CheckoutNo, len(payment.Orders)))
+	if err != nil {
+		return paymentErrorResponse(c, err)
+	}
+
+	if err := checkoutService.RecordCombinedPayment(payment, paymentNo, services.PaymentMethod(req.PaymentMethod), prepay.QRCodeURL); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"code":    500,
+			"message": "保存支付记录失败",
+		})
+	}
+
+	orders := make([]map[string]interface{}, 0, len(payment.Orders))
+	for _, order := range payment.Orders {
+		orders = append(orders, map[string]interface{}{
+			"orderId": order.ID,
+			"orderNo": order.OrderNo,
+			"amount":  order.TotalAmount,
+		})
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"code":    200,
 		"message": "创建成功",
 		"data": map[string]interface{}{
 			"paymentNo":  paymentNo,
-			"qrcodeUrl":  qrcodeURL,
-			"payUrl":     payURL,
+			"qrcodeUrl":  prepay.QRCodeURL,
+			"payUrl":     prepay.PayURL,
 			"expireTime": expireTime.Format(time.RFC3339),
 			"expireIn":   900,
-			"amount":     order.TotalAmount,
+			"amount":     payment.Amount,
+			"orders":     orders,
 		},
 	})
 }
 
+// paymentErrorResponse 返回支付渠道或支付记录相关的错误响应
+func paymentErrorResponse(c echo.Context, err error) error {
+	var gatewayErr *services.PaymentGatewayError
+	switch {
+	case errors.Is(err, services.ErrPaymentProviderUnavailable):
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"code":    503,
+			"message": err.Error(),
+		})
+	case errors.As(err, &gatewayErr):
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"code":    500,
+			"message": gatewayErr.Error(),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"code":    404,
+			"message": "支付记录不存在",
+		})
+	}
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"code":    400,
+		"message": err.Error(),
+	})
+}
+
+// paymentQRCodeData 支付二维码响应数据
+func paymentQRCodeData(payment *services.PaymentQRCodeResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"paymentNo":  payment.PaymentNo,
+		"qrcodeUrl":  payment.QRCodeURL,
+		"payUrl":     payment.PayURL,
+		"expireTime": payment.ExpireTime.Format(time.RFC3339),
+		"expireIn":   payment.ExpireIn,
+		"amount":     payment.Amount,
+	}
+}
+
+// newGatewayPaymentNo 生成第三方商户订单号: WX/ALI/MK + 时间戳 + 业务标识
+func newGatewayPaymentNo(method PaymentMethodType, suffix string) string {
+	prefix := "WX"
+	switch method {
+	case PaymentMethodAlipay:
+		prefix = "ALI"
+	case PaymentMethodMock:
+		prefix = "MK"
+	}
+	return prefix + time.Now().Format("20060102150405") + suffix
+}
+
 // GetPaymentStatus 查询支付状态
 // @Summary 查询支付状态
 // @Tags 支付
@@ -205,72 +267,27 @@ func (h *PaymentHandler) GetPaymentStatus(c echo.Context) error {
 		})
 	}
 
-	// 首先查询本地支付记录
-	status := "pending"
-	var amount float64 = 0
-	var tradeNo string
-
-	// 根据支付流水号前缀判断支付方式并查询实际状态
-	if len(paymentNo) > 2 {
-		prefix := paymentNo[:2]
-
-		switch prefix {
-		case "WX":
-			// 微信支付查询
-			if h.wechatService != nil {
-				resp, err := h.wechatService.QueryOrder(c.Request().Context(), paymentNo)
-				if err == nil {
-					tradeNo = resp.TransactionId
-					amount = float64(resp.Amount) / 100
-					if resp.TradeState == "SUCCESS" {
-						status = "paid"
-					} else if resp.TradeState == "CLOSED" || resp.TradeState == "PAYERROR" {
-						status = "failed"
-					}
-				}
-			}
-
-		case "AL":
-			// 支付宝查询
-			if h.alipayService != nil {
-				resp, err := h.alipayService.QueryOrder(c.Request().Context(), paymentNo)
-				if err == nil {
-					tradeNo = resp.TradeNo
-					if resp.TradeStatus == "TRADE_SUCCESS" || resp.TradeStatus == "TRADE_FINISHED" {
-						status = "paid"
-					} else if resp.TradeStatus == "TRADE_CLOSED" {
-						status = "failed"
-					}
-				}
-			}
-		}
+	payment, err := h.paymentService.GetPaymentStatus(c.Request().Context(), paymentNo)
+	if err != nil {
+		return paymentErrorResponse(c, err)
 	}
 
-	// 如果第三方查询失败,从本地数据库查询
-	if amount == 0 {
-		if len(paymentNo) > 14 {
-			if orderID, err := strconv.ParseUint(paymentNo[14:], 10, 64); err == nil {
-				var order models.Order
-				if h.db.First(&order, orderID).Error == nil {
-					amount = order.TotalAmount
-					if order.Status == models.OrderStatusPendingPayment {
-						status = "pending"
-					} else {
-						status = "paid"
-					}
-				}
-			}
-		}
+	status := string(payment.Status)
+	switch payment.Status {
+	case services.PaymentStatusSuccess:
+		status = "paid"
+	case services.PaymentStatusExpired:
+		status = "failed"
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"code":    200,
 		"message": "查询成功",
 		"data": map[string]interface{}{
-			"paymentNo": paymentNo,
+			"paymentNo": payment.PaymentNo,
 			"status":    status,
-			"amount":    amount,
-			"tradeNo":   tradeNo,
+			"amount":    payment.Amount,
+			"tradeNo":   payment.TradeNo,
 		},
 	})
 }
@@ -290,18 +307,15 @@ func (h *PaymentHandler) RefreshQRCode(c echo.Context) error {
 		})
 	}
 
-	// 刷新二维码（重新生成过期时间）
-	expireTime := time.Now().Add(15 * time.Minute)
+	payment, err := h.paymentService.RefreshQRCode(c.Request().Context(), paymentNo)
+	if err != nil {
+		return paymentErrorResponse(c, err)
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"code":    200,
 		"message": "刷新成功",
-		"data": map[string]interface{}{
-			"paymentNo":  paymentNo,
-			"qrcodeUrl":  fmt.Sprintf("https://pay.example.com/qrcode/%s?t=%d", paymentNo, time.Now().Unix()),
-			"expireTime": expireTime.Format(time.RFC3339),
-			"expireIn":   900,
-		},
+		"data":    paymentQRCodeData(payment),
 	})
 }
 
@@ -322,26 +336,24 @@ func (h *PaymentHandler) SwitchPaymentMethod(c echo.Context) error {
 		})
 	}
 
-	// 切换支付方式
-	expireTime := time.Now().Add(15 * time.Minute)
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "参数验证失败",
+		})
+	}
 
-	// 尝试更新订单支付方式
-	if len(req.PaymentNo) > 14 {
-		if orderID, err := strconv.ParseUint(req.PaymentNo[14:], 10, 64); err == nil {
-			h.db.Model(&models.Order{}).Where("id = ?", orderID).Update("payment_method", string(req.PaymentMethod))
-		}
+	payment, err := h.paymentService.SwitchPaymentMethod(c.Request().Context(), req.PaymentNo, services.PaymentMethod(req.PaymentMethod))
+	if err != nil {
+		return paymentErrorResponse(c, err)
 	}
 
+	data := paymentQRCodeData(payment)
+	data["paymentMethod"] = req.PaymentMethod
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"code":    200,
 		"message": "切换成功",
-		"data": map[string]interface{}{
-			"paymentNo":     req.PaymentNo,
-			"paymentMethod": req.PaymentMethod,
-			"qrcodeUrl":     fmt.Sprintf("https://pay.example.com/qrcode/%s?method=%s", req.PaymentNo, req.PaymentMethod),
-			"expireTime":    expireTime.Format(time.RFC3339),
-			"expireIn":      900,
-		},
+		"data":    data,
 	})
 }
 
@@ -383,117 +395,180 @@ func (h *PaymentHandler) GetPaymentByOrder(c echo.Context) error {
 	})
 }
 
-// PaymentCallback 支付回调（微信/支付宝通知）
+// PaymentCallback 支付回调（微信/支付宝/模拟支付通知）
 // @Summary 支付回调
 // @Tags 支付
 // @Accept xml,json
 // @Produce json
 // @Router /payments/callback/{method} [post]
 func (h *PaymentHandler) PaymentCallback(c echo.Context) error {
-	method := c.Param("method")
-
+	method := services.PaymentMethod(c.Param("method"))
 	switch method {
-	case "wechat":
-		return h.handleWeChatCallback(c)
-	case "alipay":
-		return h.handleAlipayCallback(c)
+	case services.PaymentMethodWechat, services.PaymentMethodAlipay, services.PaymentMethodMock:
 	default:
 		return c.JSON(http.StatusBadRequest, map[string]interface{}{
 			"code":    "FAIL",
 			"message": "未知的支付方式",
 		})
 	}
+
+	// 缓存原始报文，验签失败时也写入收件箱便于排查
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return callbackAck(c, method, http.StatusBadRequest, "读取回调报文失败")
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+	notification, err := h.paymentService.VerifyCallback(c.Request().Context(), method, c.Request())
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrPaymentProviderUnavailable) {
+			status = http.StatusServiceUnavailable
+		} else {
+			h.callbackService.RecordInvalid(method, string(body), err)
+		}
+		return callbackAck(c, method, status, err.Error())
+	}
+
+	if _, err := h.callbackService.Receive(method, models.PaymentCallbackSourceCallback, notification); err != nil {
+		return callbackAck(c, method, http.StatusInternalServerError, "处理支付通知失败")
+	}
+	return callbackAck(c, method, http.StatusOK, "OK")
 }
 
-// handleWeChatCallback 处理微信支付回调
-func (h *PaymentHandler) handleWeChatCallback(c echo.Context) error {
-	if h.wechatService == nil {
-		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
-			"code":    "FAIL",
-			"message": "微信支付服务未配置",
-		})
+// RefundCallback 退款结果通知（微信/模拟支付）
+// @Summary 退款结果通知
+// @Tags 支付
+// @Accept json
+// @Produce json
+// @Router /payments/refund-callback/{method} [post]
+func (h *PaymentHandler) RefundCallback(c echo.Context) error {
+	method := services.PaymentMethod(c.Param("method"))
+	provider, err := h.providers.Get(method)
+	if err != nil {
+		return callbackAck(c, method, http.StatusServiceUnavailable, err.Error())
 	}
 
-	notification, err := h.wechatService.VerifyCallback(c.Request().Context(), c.Request())
+	notification, err := provider.VerifyRefundCallback(c.Request().Context(), c.Request())
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"code":    "FAIL",
-			"message": err.Error(),
-		})
+		return callbackAck(c, method, http.StatusBadRequest, err.Error())
 	}
 
-	// 只处理支付成功的通知
-	if notification.IsPaymentSuccess() {
-		// 序列化回调数据
-		callbackData, _ := json.Marshal(notification)
-
-		// 更新支付记录
-		if err := h.paymentService.HandlePaymentCallback(
-			notification.OutTradeNo,
-			notification.TransactionId,
-			string(callbackData),
-		); err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
-				"code":    "FAIL",
-				"message": "更新支付状态失败",
-			})
-		}
+	if err := h.refundService.HandleNotification(notification); err != nil {
+		return callbackAck(c, method, http.StatusInternalServerError, "处理退款通知失败")
+	}
+	return callbackAck(c, method, http.StatusOK, "OK")
+}
 
-		// 更新订单状态
-		h.updateOrderStatus(notification.OutTradeNo)
+// callbackAck 按渠道要求的格式应答回调，微信/模拟支付返回 JSON，支付宝返回 success/fail
+func callbackAck(c echo.Context, method services.PaymentMethod, status int, message string) error {
+	if method == services.PaymentMethodAlipay {
+		if status == http.StatusOK {
+			return c.String(status, "success")
+		}
+		return c.String(status, "fail")
 	}
 
-	// 微信要求返回这个格式
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"code":    "SUCCESS",
-		"message": "OK",
+	code := "FAIL"
+	if status == http.StatusOK {
+		code = "SUCCESS"
+	}
+	return c.JSON(status, map[string]interface{}{
+		"code":    code,
+		"message": message,
 	})
 }
 
-// handleAlipayCallback 处理支付宝回调
-func (h *PaymentHandler) handleAlipayCallback(c echo.Context) error {
-	if h.alipayService == nil {
-		return c.String(http.StatusServiceUnavailable, "fail")
+// MockPay 模拟用户扫码支付（仅启用模拟支付时注册）
+// @Summary 模拟扫码支付
+// @Description result=fail 时模拟支付失败；支付结果以签名回调的方式投递到回调处理流程
+// @Tags 支付
+// @Param paymentNo path string true "支付流水号"
+// @Param result query string false "success|fail"
+// @Success 200 {object} map[string]interface{}
+// @Router /payments/mock/{paymentNo}/pay [post]
+func (h *PaymentHandler) MockPay(c echo.Context) error {
+	mock := h.providers.Mock()
+	if mock == nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"code":    404,
+			"message": "模拟支付未启用",
+		})
 	}
 
-	notification, err := h.alipayService.VerifyCallback(c.Request())
+	paymentNo := c.Param("paymentNo")
+	succeed := c.QueryParam("result") != "fail"
+	body, signature, err := mock.SimulatePay(paymentNo, succeed)
 	if err != nil {
-		return c.String(http.StatusBadRequest, "fail")
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": err.Error(),
+		})
 	}
 
-	// 只处理支付成功的通知
-	if notification.IsPaymentSuccess() {
-		// 序列化回调数据
-		callbackData, _ := json.Marshal(notification)
-
-		// 更新支付记录
-		if err := h.paymentService.HandlePaymentCallback(
-			notification.OutTradeNo,
-			notification.TradeNo,
-			string(callbackData),
-		); err != nil {
-			return c.String(http.StatusInternalServerError, "fail")
-		}
-
-		// 更新订单状态
-		h.updateOrderStatus(notification.OutTradeNo)
+	// 与真实渠道一致：先验签再处理回调
+	req, err := http.NewRequestWithContext(c.Request().Context(), http.MethodPost, "/api/payments/callback/mock", bytes.NewReader(body))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"code": 500, "message": err.Error()})
+	}
+	req.Header.Set(services.MockSignatureHeader, signature)
+	notification, err := mock.VerifyCallback(req.Context(), req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{"code": 500, "message": err.Error()})
+	}
+	if _, err := h.callbackService.Receive(services.PaymentMethodMock, models.PaymentCallbackSourceCallback, notification); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"code":    500,
+			"message": "处理支付通知失败: " + err.Error(),
+		})
 	}
 
-	// 支付宝要求返回"success"字符串
-	return c.String(http.StatusOK, "success")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"code":    200,
+		"message": "模拟支付完成",
+		"data":    notification.ProviderTrade,
+	})
 }
 
-// updateOrderStatus 根据支付流水号更新订单状态
-func (h *PaymentHandler) updateOrderStatus(paymentNo string) {
-	// 从支付流水号提取订单ID
-	if len(paymentNo) > 14 {
-		// 格式: WX/ALI + 时间戳 + 订单ID
-		if orderID, err := strconv.ParseUint(paymentNo[14:], 10, 64); err == nil {
-			// 更新订单状态为待确认
-			h.db.Model(&models.Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
-				"status":     models.OrderStatusPendingConfirm,
-				"updated_at": time.Now(),
-			})
-		}
+// MockFailureReq 模拟渠道故障请求
+type MockFailureReq struct {
+	Operation services.MockOperation `json:"operation" validate:"required,oneof=create query close refund"`
+	Message   string                 `json:"message"`
+}
+
+// MockFailNext 令模拟渠道的下一次指定操作失败（仅启用模拟支付时注册）
+// @Summary 模拟渠道故障
+// @Tags 支付
+// @Accept json
+// @Param request body MockFailureReq true "故障设置"
+// @Success 200 {object} map[string]interface{}
+// @Router /payments/mock/failures [post]
+func (h *PaymentHandler) MockFailNext(c echo.Context) error {
+	mock := h.providers.Mock()
+	if mock == nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"code":    404,
+			"message": "模拟支付未启用",
+		})
 	}
+
+	var req MockFailureReq
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+	}
+	if err := c.Validate(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code":    400,
+			"message": "参数验证失败",
+		})
+	}
+
+	mock.FailNext(req.Operation, req.Message)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"code":    200,
+		"message": "设置成功",
+	})
 }
@@ -57,6 +57,7 @@ type CalculateMarkupRequest struct {
 	CategoryID    uint64  `json:"categoryId"`
 	MaterialID    uint64  `json:"materialId"`
 	OriginalPrice float64 `json:"originalPrice" validate:"required,gt=0"`
+	Quantity      int     `json:"quantity"`
 }
 
 // Create 创建加价规则
@@ -397,7 +398,7 @@ func (h *PriceMarkupHandler) CalculateMarkup(c echo.Context) error {
 	var appliedRule interface{}
 
 	if err := query.Order("priority DESC").First(&markup).Error; err == nil {
-		markupAmount = markup.CalculateMarkup(req.OriginalPrice)
+		markupAmount = markup.CalculateMarkup(req.OriginalPrice, req.Quantity)
 		appliedRule = markup
 	}
 
@@ -457,7 +458,7 @@ func (h *PriceMarkupHandler) StoreCalculateMarkup(c echo.Context) error {
 
 	var markupAmount float64
 	if err := query.Order("priority DESC").First(&markup).Error; err == nil {
-		markupAmount = markup.CalculateMarkup(req.OriginalPrice)
+		markupAmount = markup.CalculateMarkup(req.OriginalPrice, req.Quantity)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// RunReconciliation 对指定渠道指定日期执行对账
+// fileName 为对账单目录下的文件名，为空时使用默认文件名或从支付渠道下载
+func RunReconciliation(db *gorm.DB, providers *services.PaymentProviders, billDir string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		type ReconcileRequest struct {
+			PaymentMethod string `json:"paymentMethod" validate:"required,oneof=wechat alipay mock"`
+			BillDate      string `json:"billDate" validate:"required"`
+			FileName      string `json:"fileName" validate:"max=200"`
+		}
+
+		var req ReconcileRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		billDate, err := time.ParseInLocation("2006-01-02", req.BillDate, time.Local)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "对账日期格式错误")
+		}
+		now := time.Now()
+		if !billDate.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)) {
+			return ErrorResponse(c, http.StatusBadRequest, "只能对已结束的日期对账")
+		}
+
+		batch, err := services.NewReconciliationService(db, providers, billDir).Run(c.Request().Context(), &services.ReconcileRequest{
+			PaymentMethod: services.PaymentMethod(req.PaymentMethod),
+			BillDate:      billDate,
+			FileName:      req.FileName,
+			OperatorID:    GetAdminID(c),
+		})
+		switch {
+		case errors.Is(err, services.ErrBillUnavailable), errors.Is(err, services.ErrBillPathInvalid):
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case err != nil && batch != nil:
+			return c.JSON(http.StatusUnprocessableEntity, Response{
+				Code:      http.StatusUnprocessableEntity,
+				Message:   err.Error(),
+				Data:      batch,
+				Timestamp: time.Now().Unix(),
+			})
+		case err != nil:
+			return ErrorResponse(c, http.StatusInternalServerError, "对账失败")
+		}
+
+		return SuccessResponse(c, batch)
+	}
+}
+
+// GetReconciliations 获取对账批次列表
+func GetReconciliations(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		batches, total, err := services.NewReconciliationService(db, nil, "").List(
+			page, pageSize, services.PaymentMethod(c.QueryParam("paymentMethod")),
+		)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, batches, total, page, pageSize)
+	}
+}
+
+// GetReconciliationReport 获取对账报告及差异明细
+func GetReconciliationReport(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的对账批次ID")
+		}
+
+		query := &services.DiscrepancyQuery{Type: models.DiscrepancyType(c.QueryParam("type"))}
+		if resolved := c.QueryParam("resolved"); resolved != "" {
+			value := resolved == "true" || resolved == "1"
+			query.Resolved = &value
+		}
+
+		batch, err := services.NewReconciliationService(db, nil, "").Report(id, query)
+		if err != nil {
+			if errors.Is(err, services.ErrReconciliationNotFound) {
+				return ErrorResponse(c, http.StatusNotFound, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessResponse(c, batch)
+	}
+}
+
+// ResolveReconciliationDiscrepancy 标记对账差异已处理
+func ResolveReconciliationDiscrepancy(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的差异ID")
+		}
+
+		type ResolveRequest struct {
+			Remark string `json:"remark" validate:"required,max=500"`
+		}
+
+		var req ResolveRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		err = services.NewReconciliationService(db, nil, "").ResolveDiscrepancy(id, GetAdminID(c), req.Remark)
+		if err != nil {
+			if errors.Is(err, services.ErrDiscrepancyNotFound) {
+				return ErrorResponse(c, http.StatusNotFound, err.Error())
+			}
+			return ErrorResponse(c, http.StatusInternalServerError, "操作失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetRefunds 获取退款单列表
+func GetRefunds(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		orderID, _ := strconv.ParseUint(c.QueryParam("orderId"), 10, 64)
+
+		refunds, total, err := services.NewRefundService(db, nil).List(page, pageSize, &services.RefundQuery{
+			Status:    models.RefundStatus(c.QueryParam("status")),
+			OrderID:   orderID,
+			PaymentNo: c.QueryParam("paymentNo"),
+		})
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, refunds, total, page, pageSize)
+	}
+}
+
+// CreateOrderRefund 管理员手工退款，金额为空时退还订单剩余可退金额
+func CreateOrderRefund(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的订单ID")
+		}
+
+		type RefundRequest struct {
+			Amount float64 `json:"amount" validate:"gte=0"`
+			Reason string  `json:"reason" validate:"required,max=200"`
+		}
+
+		var req RefundRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		refund, err := services.NewRefundService(db, providers).CreateRefund(c.Request().Context(), &services.RefundRequest{
+			OrderID:      orderID,
+			Amount:       req.Amount,
+			Reason:       req.Reason,
+			Source:       models.RefundSourceAdmin,
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   GetAdminID(c),
+		})
+		if err != nil {
+			return refundErrorResponse(c, refund, err, "退款失败")
+		}
+
+		return SuccessResponse(c, refund)
+	}
+}
+
+// RetryRefund 重新提交失败的退款单
+func RetryRefund(db *gorm.DB, providers *services.PaymentProviders) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的退款单ID")
+		}
+
+		refund, err := services.NewRefundService(db, providers).Retry(c.Request().Context(), id)
+		if err != nil {
+			return refundErrorResponse(c, refund, err, "重试失败")
+		}
+
+		return SuccessResponse(c, refund)
+	}
+}
+
+// refundErrorResponse 退款错误转换为响应，退款单已创建但提交渠道失败时返回退款单
+func refundErrorResponse(c echo.Context, refund *models.Refund, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrRefundNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrOrderNotPaid),
+		errors.Is(err, services.ErrNothingToRefund),
+		errors.Is(err, services.ErrRefundAmountExceeded),
+		errors.Is(err, services.ErrRefundNotRetryable),
+		errors.Is(err, services.ErrPaymentRecordNotFound),
+		errors.Is(err, services.ErrCreditOrderNotRefundable):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case refund != nil:
+		return c.JSON(http.StatusBadGateway, Response{
+			Code:      http.StatusBadGateway,
+			Message:   err.Error(),
+			Data:      refund,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
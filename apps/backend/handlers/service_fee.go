@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetServiceFeeRules 获取服务费规则列表
+func GetServiceFeeRules(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		query := &services.ServiceFeeRuleQuery{FeeType: models.ServiceFeeType(c.QueryParam("feeType"))}
+		query.StoreID, _ = strconv.ParseUint(c.QueryParam("storeId"), 10, 64)
+		query.SupplierID, _ = strconv.ParseUint(c.QueryParam("supplierId"), 10, 64)
+		if v, err := strconv.ParseBool(c.QueryParam("isActive")); err == nil {
+			query.IsActive = &v
+		}
+
+		page, pageSize := GetPagination(c)
+		rules, total, err := services.NewServiceFeeService(db).List(page, pageSize, query)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, rules, total, page, pageSize)
+	}
+}
+
+// GetServiceFeeRule 获取服务费规则详情
+func GetServiceFeeRule(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的规则ID")
+		}
+
+		rule, err := services.NewServiceFeeService(db).Get(id)
+		if err != nil {
+			return serviceFeeErrorResponse(c, err, "查询失败")
+		}
+
+		return SuccessResponse(c, rule)
+	}
+}
+
+// CreateServiceFeeRule 创建服务费规则
+func CreateServiceFeeRule(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var req services.ServiceFeeRuleRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		rule, err := services.NewServiceFeeService(db).Create(&req, GetAdminID(c))
+		if err != nil {
+			return serviceFeeErrorResponse(c, err, "创建失败")
+		}
+
+		return SuccessResponse(c, rule)
+	}
+}
+
+// UpdateServiceFeeRule 修改服务费规则
+func UpdateServiceFeeRule(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的规则ID")
+		}
+
+		var req services.ServiceFeeRuleRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		rule, err := services.NewServiceFeeService(db).Update(id, &req)
+		if err != nil {
+			return serviceFeeErrorResponse(c, err, "更新失败")
+		}
+
+		return SuccessResponse(c, rule)
+	}
+}
+
+// DeleteServiceFeeRule 删除服务费规则
+func DeleteServiceFeeRule(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的规则ID")
+		}
+
+		if err := services.NewServiceFeeService(db).Delete(id); err != nil {
+			return serviceFeeErrorResponse(c, err, "删除失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
+
+// serviceFeeErrorResponse 服务费规则错误转换为响应
+func serviceFeeErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrServiceFeeRuleNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrServiceFeeRuleInvalid):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
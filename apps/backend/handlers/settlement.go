@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// GetSettlementStatements 获取结算单列表，供应商只能看到自己的结算单
+func GetSettlementStatements(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		query := &services.StatementQuery{Status: models.SettlementStatementStatus(c.QueryParam("status"))}
+		if IsSupplier(c) {
+			query.SupplierID = GetSupplierID(c)
+		} else if IsAdmin(c) {
+			query.SupplierID, _ = strconv.ParseUint(c.QueryParam("supplierId"), 10, 64)
+		} else {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		statements, total, err := services.NewSettlementService(db).List(page, pageSize, query)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, statements, total, page, pageSize)
+	}
+}
+
+// GetSettlementStatement 获取结算单详情及明细
+func GetSettlementStatement(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		statement, err := loadSettlementStatement(c, db)
+		if err != nil {
+			return settlementErrorResponse(c, err, "查询失败")
+		}
+		return SuccessResponse(c, statement)
+	}
+}
+
+// ExportSettlementStatement 导出结算单明细Excel
+func ExportSettlementStatement(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		statement, err := loadSettlementStatement(c, db)
+		if err != nil {
+			return settlementErrorResponse(c, err, "查询失败")
+		}
+
+		type ExportRow struct {
+			Type        string  `json:"type"`
+			OrderNo     string  `json:"orderNo"`
+			GoodsAmount float64 `json:"goodsAmount"`
+			MarkupTotal float64 `json:"markupTotal"`
+			ServiceFee  float64 `json:"serviceFee"`
+			Amount      float64 `json:"amount"`
+			Remark      string  `json:"remark"`
+			OccurredAt  string  `json:"occurredAt"`
+		}
+
+		typeMap := map[models.SettlementItemType]string{
+			models.SettlementItemOrder:      "订单",
+			models.SettlementItemRefund:     "退款",
+			models.SettlementItemAdjustment: "调整",
+		}
+
+		exportData := make([]ExportRow, 0, len(statement.Items))
+		for _, item := range statement.Items {
+			exportData = append(exportData, ExportRow{
+				Type:        typeMap[item.Type],
+				OrderNo:     item.OrderNo,
+				GoodsAmount: item.GoodsAmount,
+				MarkupTotal: item.MarkupTotal,
+				ServiceFee:  item.ServiceFee,
+				Amount:      item.Amount,
+				Remark:      item.Remark,
+				OccurredAt:  item.OccurredAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+
+		supplierName := ""
+		if statement.Supplier != nil {
+			supplierName = statement.Supplier.Name
+		}
+
+		// 返回JSON数据，前端可以使用xlsx库生成Excel
+		return SuccessResponse(c, map[string]interface{}{
+			"fileName": "结算单_" + statement.StatementNo + ".xlsx",
+			"summary": map[string]interface{}{
+				"statementNo":      statement.StatementNo,
+				"supplierName":     supplierName,
+				"periodStart":      statement.PeriodStart.Format("2006-01-02"),
+				"periodEnd":        statement.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+				"orderCount":       statement.OrderCount,
+				"orderAmount":      statement.OrderAmount,
+				"refundAmount":     statement.RefundAmount,
+				"adjustmentAmount": statement.AdjustmentAmount,
+				"payableAmount":    statement.PayableAmount,
+				"heldAmount":       statement.HeldAmount,
+				"paidAmount":       statement.PaidAmount,
+				"status":           statement.Status,
+			},
+			"columns": []map[string]string{
+				{"key": "type", "title": "类型"},
+				{"key": "orderNo", "title": "订单编号"},
+				{"key": "goodsAmount", "title": "商品金额"},
+				{"key": "markupTotal", "title": "加价金额"},
+				{"key": "serviceFee", "title": "服务费"},
+				{"key": "amount", "title": "结算金额"},
+				{"key": "remark", "title": "备注"},
+				{"key": "occurredAt", "title": "发生时间"},
+			},
+			"data":  exportData,
+			"total": len(exportData),
+		})
+	}
+}
+
+// ConfirmSettlementStatement 供应商确认结算单
+func ConfirmSettlementStatement(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsSupplier(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的结算单ID")
+		}
+
+		statement, err := services.NewSettlementService(db).Confirm(id, GetSupplierID(c), GetUserID(c))
+		if err != nil {
+			return settlementErrorResponse(c, err, "确认失败")
+		}
+
+		return SuccessResponse(c, statement)
+	}
+}
+
+// GenerateSettlementStatement 按供应商结算周期手工生成上一周期的结算单
+func GenerateSettlementStatement(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		type GenerateRequest struct {
+			SupplierID uint64 `json:"supplierId" validate:"required"`
+		}
+
+		var req GenerateRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		statement, err := services.NewSettlementService(db).GenerateForSupplier(req.SupplierID)
+		if err != nil {
+			if errors.Is(err, services.ErrStatementExists) {
+				return ErrorResponse(c, http.StatusConflict, err.Error())
+			}
+			return settlementErrorResponse(c, err, "生成结算单失败")
+		}
+
+		return SuccessResponse(c, statement)
+	}
+}
+
+// HoldSettlementAmount 暂扣结算单部分金额
+func HoldSettlementAmount(db *gorm.DB) echo.HandlerFunc {
+	return settlementLedgerHandler(db, (*services.SettlementService).Hold, "暂扣失败")
+}
+
+// ReleaseSettlementAmount 释放结算单暂扣金额
+func ReleaseSettlementAmount(db *gorm.DB) echo.HandlerFunc {
+	return settlementLedgerHandler(db, (*services.SettlementService).Release, "释放失败")
+}
+
+// PayoutSettlementStatement 标记结算单打款
+func PayoutSettlementStatement(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的结算单ID")
+		}
+
+		type PayoutRequest struct {
+			Reference string `json:"reference" validate:"required,max=100"`
+			Remark    string `json:"remark" validate:"max=200"`
+		}
+
+		var req PayoutRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		statement, err := services.NewSettlementService(db).Payout(id, &services.LedgerOperation{
+			Reference:    req.Reference,
+			Remark:       req.Remark,
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   GetAdminID(c),
+		})
+		if err != nil {
+			return settlementErrorResponse(c, err, "打款失败")
+		}
+
+		return SuccessResponse(c, statement)
+	}
+}
+
+// CreateSettlementAdjustment 录入结算调整，计入供应商下一张结算单
+func CreateSettlementAdjustment(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		type AdjustmentRequest struct {
+			SupplierID uint64  `json:"supplierId" validate:"required"`
+			Amount     float64 `json:"amount" validate:"required"`
+			Reason     string  `json:"reason" validate:"required,max=200"`
+		}
+
+		var req AdjustmentRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		var count int64
+		db.Model(&models.Supplier{}).Where("id = ?", req.SupplierID).Count(&count)
+		if count == 0 {
+			return ErrorResponse(c, http.StatusNotFound, "供应商不存在")
+		}
+
+		adjustment, err := services.NewSettlementService(db).CreateAdjustment(req.SupplierID, req.Amount, req.Reason, GetAdminID(c))
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "录入调整失败")
+		}
+
+		return SuccessResponse(c, adjustment)
+	}
+}
+
+// GetSupplierLedger 获取供应商资金台账，供应商只能看到自己的台账
+func GetSupplierLedger(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var supplierID uint64
+		if IsSupplier(c) {
+			supplierID = GetSupplierID(c)
+		} else if IsAdmin(c) {
+			id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
+			}
+			supplierID = id
+		} else {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		page, pageSize := GetPagination(c)
+		entries, total, err := services.NewSettlementService(db).Ledger(supplierID, page, pageSize)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, entries, total, page, pageSize)
+	}
+}
+
+// settlementLedgerHandler 暂扣和释放共用的处理逻辑
+func settlementLedgerHandler(db *gorm.DB, apply func(*services.SettlementService, uint64, *services.LedgerOperation) (*models.SettlementStatement, error), fallback string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的结算单ID")
+		}
+
+		type LedgerRequest struct {
+			Amount float64 `json:"amount" validate:"required,gt=0"`
+			Remark string  `json:"remark" validate:"required,max=200"`
+		}
+
+		var req LedgerRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		statement, err := apply(services.NewSettlementService(db), id, &services.LedgerOperation{
+			Amount:       req.Amount,
+			Remark:       req.Remark,
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   GetAdminID(c),
+		})
+		if err != nil {
+			return settlementErrorResponse(c, err, fallback)
+		}
+
+		return SuccessResponse(c, statement)
+	}
+}
+
+// loadSettlementStatement 按角色加载结算单，供应商只能加载自己的结算单
+func loadSettlementStatement(c echo.Context, db *gorm.DB) (*models.SettlementStatement, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, services.ErrStatementNotFound
+	}
+
+	var supplierID uint64
+	if IsSupplier(c) {
+		supplierID = GetSupplierID(c)
+	} else if !IsAdmin(c) {
+		return nil, services.ErrStatementNotFound
+	}
+	return services.NewSettlementService(db).Get(id, supplierID)
+}
+
+// settlementErrorResponse 结算错误转换为响应
+func settlementErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrStatementNotFound), errors.Is(err, services.ErrSupplierNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrStatementNotConfirmable),
+		errors.Is(err, services.ErrStatementNotPayable),
+		errors.Is(err, services.ErrStatementAlreadyPaid),
+		errors.Is(err, services.ErrNothingToSettle),
+		errors.Is(err, services.ErrNothingToPay),
+		errors.Is(err, services.ErrHoldAmountInvalid),
+		errors.Is(err, services.ErrReleaseAmountInvalid):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
@@ -1,17 +1,16 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/project/backend/models"
+	"github.com/project/backend/services"
 	"github.com/redis/go-redis/v9"
-	goredis "github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -90,7 +89,14 @@ func GetMaterialDetail(db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
-// GetMaterialSuppliers 获取物料供应商
+// storeSupplierQuote 门店看到的供应商报价，含加价后的单价
+type storeSupplierQuote struct {
+	models.SupplierMaterial
+	MarkupAmount float64 `json:"markup_amount"`
+	FinalPrice   float64 `json:"final_price"`
+}
+
+// GetMaterialSuppliers 获取物料各SKU的供应商报价及加价后价格
 func GetMaterialSuppliers(db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		storeID := GetStoreID(c)
@@ -98,56 +104,100 @@ func GetMaterialSuppliers(db *gorm.DB) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
 		}
 
-		skuID, err := strconv.ParseUint(c.Param("skuId"), 10, 64)
+		materialID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 		if err != nil {
-			return ErrorResponse(c, http.StatusBadRequest, "无效的SKU ID")
+			return ErrorResponse(c, http.StatusBadRequest, "无效的ID")
 		}
 
 		var supplierMaterials []models.SupplierMaterial
-		if err := db.Where("material_sku_id = ? AND status = ? AND audit_status = ?",
-			skuID, 1, models.AuditStatusApproved).
+		if err := db.Where("material_sku_id IN (?) AND status = ? AND audit_status = ?",
+			db.Model(&models.MaterialSku{}).Select("id").Where("material_id = ?", materialID),
+			1, models.AuditStatusApproved).
 			Preload("Supplier").
+			Preload("MaterialSku.Material").
 			Order("price ASC").
 			Find(&supplierMaterials).Error; err != nil {
 			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
 		}
 
-		return SuccessResponse(c, supplierMaterials)
+		quotes := make([]services.CatalogQuote, len(supplierMaterials))
+		for i, sm := range supplierMaterials {
+			quotes[i] = services.CatalogQuote{SupplierID: sm.SupplierID, MaterialID: materialID, Price: sm.Price}
+			if sm.MaterialSku != nil && sm.MaterialSku.Material != nil {
+				quotes[i].CategoryID = sm.MaterialSku.Material.CategoryID
+			}
+		}
+		prices, err := services.NewPriceMarkupService(db).PriceCatalog(storeID, quotes)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "计算价格失败")
+		}
+
+		result := make([]storeSupplierQuote, len(supplierMaterials))
+		for i, sm := range supplierMaterials {
+			result[i] = storeSupplierQuote{
+				SupplierMaterial: sm,
+				MarkupAmount:     prices[i].MarkupAmount,
+				FinalPrice:       prices[i].FinalPrice,
+			}
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// storeCartResponse 供应商购物车及合计金额，金额按加购时的成交价计算，以结算预览为准
+func storeCartResponse(items []services.CartItem) map[string]interface{} {
+	total := decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.Price.Mul(decimal.NewFromInt(int64(item.Quantity))))
+	}
+	return map[string]interface{}{
+		"items": items,
+		"total": total,
+	}
+}
+
+// cartErrorResponse 购物车错误转换为响应
+func cartErrorResponse(c echo.Context, err error, fallback string) error {
+	var rejected *services.OrderLineRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		return orderLineErrorResponse(c, err)
+	case errors.Is(err, services.ErrCartItemNotFound):
+		return ErrorResponse(c, http.StatusNotFound, "购物车中没有该商品")
 	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
 }
 
-// GetCart 获取购物车
-func GetCart(redis *goredis.Client) echo.HandlerFunc {
+// GetCart 获取购物车，指定 supplierId 时只返回该供应商的购物车
+func GetCart(redis *redis.Client) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		storeID := GetStoreID(c)
 		if storeID == 0 {
 			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
 		}
 
-		supplierID := c.QueryParam("supplierId")
-		if supplierID == "" {
-			return ErrorResponse(c, http.StatusBadRequest, "供应商ID不能为空")
-		}
-
-		key := fmt.Sprintf("cart:store:%d:supplier:%s", storeID, supplierID)
-		ctx := context.Background()
+		cartService := services.NewCartService(redis, nil)
+		ctx := c.Request().Context()
 
-		data, err := redis.Get(ctx, key).Result()
-		if err == goredis.Nil {
-			return SuccessResponse(c, map[string]interface{}{
-				"items": []interface{}{},
-				"total": 0,
-			})
-		} else if err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
+		if c.QueryParam("supplierId") == "" {
+			carts, err := cartService.GetAllCarts(ctx, storeID)
+			if err != nil {
+				return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
+			}
+			return SuccessResponse(c, carts)
 		}
 
-		var cart map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &cart); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "解析购物车数据失败")
+		supplierID, err := strconv.ParseUint(c.QueryParam("supplierId"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
+		}
+		items, err := cartService.GetCart(ctx, storeID, supplierID)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
 		}
 
-		return SuccessResponse(c, cart)
+		return SuccessResponse(c, storeCartResponse(items))
 	}
 }
 
@@ -178,102 +228,46 @@ func AddToCart(redis *redis.Client, db *gorm.DB) echo.HandlerFunc {
 
 		// 获取供应商物料信息
 		var supplierMaterial models.SupplierMaterial
-		if err := db.Preload("MaterialSku.Material").
-			First(&supplierMaterial, req.SupplierMaterialID).Error; err != nil {
+		if err := db.First(&supplierMaterial, req.SupplierMaterialID).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				return ErrorResponse(c, http.StatusNotFound, "供应商物料不存在")
 			}
 			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
 		}
 
-		key := fmt.Sprintf("cart:store:%d:supplier:%d", storeID, req.SupplierID)
-		ctx := context.Background()
-
-		// 获取现有购物车数据
-		var cart map[string]interface{}
-		data, err := redis.Get(ctx, key).Result()
-		if err == goredis.Nil {
-			cart = map[string]interface{}{
-				"items": []map[string]interface{}{},
-				"total": 0.0,
-			}
-		} else if err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
-		} else {
-			if err := json.Unmarshal([]byte(data), &cart); err != nil {
-				return ErrorResponse(c, http.StatusInternalServerError, "解析购物车数据失败")
-			}
+		// 按加购后的总数量校验商品归属、上架库存状态及起订量，成交价在结算时按服务端价格核对
+		cartService := services.NewCartService(redis, db)
+		ctx := c.Request().Context()
+		if err := cartService.AddToCart(ctx, storeID, req.SupplierID, supplierMaterial.MaterialSkuID,
+			req.Quantity, decimal.NewFromFloat(req.FinalPrice)); err != nil {
+			return cartErrorResponse(c, err, "保存购物车失败")
 		}
 
-		// 添加或更新购物车项
-		items := cart["items"].([]interface{})
-		found := false
-		for i, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				if uint64(itemMap["supplierMaterialId"].(float64)) == req.SupplierMaterialID {
-					itemMap["quantity"] = itemMap["quantity"].(float64) + float64(req.Quantity)
-					itemMap["subtotal"] = itemMap["quantity"].(float64) * req.FinalPrice
-					items[i] = itemMap
-					found = true
-					break
-				}
-			}
-		}
-
-		if !found {
-			newItem := map[string]interface{}{
-				"supplierMaterialId": req.SupplierMaterialID,
-				"materialSkuId":      supplierMaterial.MaterialSkuID,
-				"materialName":       supplierMaterial.MaterialSku.Material.Name,
-				"brand":              supplierMaterial.MaterialSku.Brand,
-				"spec":               supplierMaterial.MaterialSku.Spec,
-				"unit":               supplierMaterial.MaterialSku.Unit,
-				"imageUrl":           supplierMaterial.MaterialSku.ImageURL,
-				"quantity":           req.Quantity,
-				"unitPrice":          req.UnitPrice,
-				"finalPrice":         req.FinalPrice,
-				"subtotal":           req.FinalPrice * float64(req.Quantity),
-			}
-			items = append(items, newItem)
-		}
-
-		// 计算总金额
-		total := 0.0
-		for _, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				total += itemMap["subtotal"].(float64)
-			}
-		}
-
-		cart["items"] = items
-		cart["total"] = total
-
-		// 保存购物车数据
-		cartData, err := json.Marshal(cart)
+		items, err := cartService.GetCart(ctx, storeID, req.SupplierID)
 		if err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "序列化购物车数据失败")
-		}
-
-		if err := redis.Set(ctx, key, cartData, 7*24*time.Hour).Err(); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "保存购物车失败")
+			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
 		}
 
-		return SuccessResponse(c, cart)
+		return SuccessResponse(c, storeCartResponse(items))
 	}
 }
 
-// UpdateCartItem 更新购物车商品
-func UpdateCartItem(redis *redis.Client) echo.HandlerFunc {
+// UpdateCartItem 更新购物车商品数量，数量为0时移除
+func UpdateCartItem(redis *redis.Client, db *gorm.DB) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		storeID := GetStoreID(c)
 		if storeID == 0 {
 			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
 		}
 
+		skuID, err := strconv.ParseUint(c.Param("skuId"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的SKU ID")
+		}
+
 		type UpdateCartRequest struct {
-			SupplierID         uint64 `json:"supplierId" validate:"required"`
-			SupplierMaterialID uint64 `json:"supplierMaterialId" validate:"required"`
-			Quantity           int    `json:"quantity" validate:"required,min=0"`
+			SupplierID uint64 `json:"supplierId" validate:"required"`
+			Quantity   int    `json:"quantity" validate:"min=0"`
 		}
 
 		var req UpdateCartRequest
@@ -285,59 +279,18 @@ func UpdateCartItem(redis *redis.Client) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
 		}
 
-		key := fmt.Sprintf("cart:store:%d:supplier:%d", storeID, req.SupplierID)
-		ctx := context.Background()
-
-		// 获取现有购物车数据
-		data, err := redis.Get(ctx, key).Result()
-		if err != nil {
-			return ErrorResponse(c, http.StatusNotFound, "购物车不存在")
-		}
-
-		var cart map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &cart); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "解析购物车数据失败")
-		}
-
-		// 更新或删除购物车项
-		items := cart["items"].([]interface{})
-		newItems := []interface{}{}
-		for _, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				if uint64(itemMap["supplierMaterialId"].(float64)) == req.SupplierMaterialID {
-					if req.Quantity > 0 {
-						itemMap["quantity"] = float64(req.Quantity)
-						itemMap["subtotal"] = float64(req.Quantity) * itemMap["finalPrice"].(float64)
-						newItems = append(newItems, itemMap)
-					}
-				} else {
-					newItems = append(newItems, item)
-				}
-			}
-		}
-
-		// 重新计算总金额
-		total := 0.0
-		for _, item := range newItems {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				total += itemMap["subtotal"].(float64)
-			}
+		cartService := services.NewCartService(redis, db)
+		ctx := c.Request().Context()
+		if err := cartService.UpdateQuantity(ctx, storeID, req.SupplierID, skuID, req.Quantity); err != nil {
+			return cartErrorResponse(c, err, "保存购物车失败")
 		}
 
-		cart["items"] = newItems
-		cart["total"] = total
-
-		// 保存购物车数据
-		cartData, err := json.Marshal(cart)
+		items, err := cartService.GetCart(ctx, storeID, req.SupplierID)
 		if err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "序列化购物车数据失败")
-		}
-
-		if err := redis.Set(ctx, key, cartData, 7*24*time.Hour).Err(); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "保存购物车失败")
+			return ErrorResponse(c, http.StatusInternalServerError, "获取购物车失败")
 		}
 
-		return SuccessResponse(c, cart)
+		return SuccessResponse(c, storeCartResponse(items))
 	}
 }
 
@@ -349,59 +302,17 @@ func RemoveFromCart(redis *redis.Client) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
 		}
 
-		supplierID, err := strconv.ParseUint(c.Param("supplierId"), 10, 64)
-		if err != nil {
-			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
-		}
-
-		supplierMaterialID, err := strconv.ParseUint(c.Param("materialId"), 10, 64)
-		if err != nil {
-			return ErrorResponse(c, http.StatusBadRequest, "无效的物料ID")
-		}
-
-		key := fmt.Sprintf("cart:store:%d:supplier:%d", storeID, supplierID)
-		ctx := context.Background()
-
-		// 获取现有购物车数据
-		data, err := redis.Get(ctx, key).Result()
+		skuID, err := strconv.ParseUint(c.Param("skuId"), 10, 64)
 		if err != nil {
-			return ErrorResponse(c, http.StatusNotFound, "购物车不存在")
-		}
-
-		var cart map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &cart); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "解析购物车数据失败")
-		}
-
-		// 移除购物车项
-		items := cart["items"].([]interface{})
-		newItems := []interface{}{}
-		for _, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				if uint64(itemMap["supplierMaterialId"].(float64)) != supplierMaterialID {
-					newItems = append(newItems, item)
-				}
-			}
-		}
-
-		// 重新计算总金额
-		total := 0.0
-		for _, item := range newItems {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				total += itemMap["subtotal"].(float64)
-			}
+			return ErrorResponse(c, http.StatusBadRequest, "无效的SKU ID")
 		}
 
-		cart["items"] = newItems
-		cart["total"] = total
-
-		// 保存购物车数据
-		cartData, err := json.Marshal(cart)
+		supplierID, err := strconv.ParseUint(c.QueryParam("supplierId"), 10, 64)
 		if err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "序列化购物车数据失败")
+			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
 		}
 
-		if err := redis.Set(ctx, key, cartData, 7*24*time.Hour).Err(); err != nil {
+		if err := services.NewCartService(redis, nil).RemoveItem(c.Request().Context(), storeID, supplierID, skuID); err != nil {
 			return ErrorResponse(c, http.StatusInternalServerError, "保存购物车失败")
 		}
 
@@ -409,7 +320,7 @@ func RemoveFromCart(redis *redis.Client) echo.HandlerFunc {
 	}
 }
 
-// ClearCart 清空购物车
+// ClearCart 清空购物车，指定 supplierId 时只清空该供应商的购物车
 func ClearCart(redis *redis.Client) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		storeID := GetStoreID(c)
@@ -417,16 +328,30 @@ func ClearCart(redis *redis.Client) echo.HandlerFunc {
 			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
 		}
 
-		supplierID, err := strconv.ParseUint(c.Param("supplierId"), 10, 64)
-		if err != nil {
-			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
-		}
+		cartService := services.NewCartService(redis, nil)
+		ctx := c.Request().Context()
 
-		key := fmt.Sprintf("cart:store:%d:supplier:%d", storeID, supplierID)
-		ctx := context.Background()
+		var supplierIDs []uint64
+		if c.QueryParam("supplierId") != "" {
+			supplierID, err := strconv.ParseUint(c.QueryParam("supplierId"), 10, 64)
+			if err != nil {
+				return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
+			}
+			supplierIDs = append(supplierIDs, supplierID)
+		} else {
+			carts, err := cartService.GetAllCarts(ctx, storeID)
+			if err != nil {
+				return ErrorResponse(c, http.StatusInternalServerError, "清空购物车失败")
+			}
+			for _, cart := range carts {
+				supplierIDs = append(supplierIDs, cart.SupplierID)
+			}
+		}
 
-		if err := redis.Del(ctx, key).Err(); err != nil {
-			return ErrorResponse(c, http.StatusInternalServerError, "清空购物车失败")
+		for _, supplierID := range supplierIDs {
+			if err := cartService.ClearCart(ctx, storeID, supplierID); err != nil {
+				return ErrorResponse(c, http.StatusInternalServerError, "清空购物车失败")
+			}
 		}
 
 		return SuccessResponse(c, nil)
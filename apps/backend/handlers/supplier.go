@@ -7,6 +7,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/project/backend/models"
+	"github.com/project/backend/services"
 	"gorm.io/gorm"
 )
 
@@ -159,7 +160,19 @@ func UpdateSupplierMaterial(db *gorm.DB) echo.HandlerFunc {
 			updates["stock_status"] = req.StockStatus
 		}
 
-		if err := db.Model(&material).Updates(updates).Error; err != nil {
+		oldPrice := material.Price
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&material).Updates(updates).Error; err != nil {
+				return err
+			}
+			if req.Price <= 0 {
+				return nil
+			}
+			return services.EnqueuePriceWebhooksTx(tx, supplierID, []services.SupplierPriceChange{
+				{MaterialSkuID: material.MaterialSkuID, OldPrice: oldPrice, NewPrice: req.Price},
+			})
+		})
+		if err != nil {
 			return ErrorResponse(c, http.StatusInternalServerError, "更新失败")
 		}
 
@@ -703,6 +716,7 @@ func BatchUpdatePrice(db *gorm.DB) echo.HandlerFunc {
 		// 在事务中批量更新价格
 		var updatedCount int64
 		err := db.Transaction(func(tx *gorm.DB) error {
+			var changes []services.SupplierPriceChange
 			for _, skuID := range req.MaterialSkuIDs {
 				var material models.SupplierMaterial
 				if err := tx.Where("supplier_id = ? AND material_sku_id = ?", supplierID, skuID).First(&material).Error; err != nil {
@@ -726,12 +740,19 @@ func BatchUpdatePrice(db *gorm.DB) echo.HandlerFunc {
 				// 四舍五入到两位小数
 				newPrice = float64(int(newPrice*100+0.5)) / 100
 
+				// Update 会把新价格写回 material，先记下原价
+				oldPrice := material.Price
 				if err := tx.Model(&material).Update("price", newPrice).Error; err != nil {
 					return err
 				}
+				changes = append(changes, services.SupplierPriceChange{
+					MaterialSkuID: skuID,
+					OldPrice:      oldPrice,
+					NewPrice:      newPrice,
+				})
 				updatedCount++
 			}
-			return nil
+			return services.EnqueuePriceWebhooksTx(tx, supplierID, changes)
 		})
 
 		if err != nil {
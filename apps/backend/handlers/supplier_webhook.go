@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+)
+
+// RotateSupplierWebhookSecret 生成供应商新的推送签名密钥，明文只返回一次
+func RotateSupplierWebhookSecret(webhookService *services.WebhookService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
+		}
+
+		result, err := webhookService.RotateSupplierSecret(id)
+		if err != nil {
+			return supplierWebhookErrorResponse(c, err, "生成密钥失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// SendSupplierWebhookTest 向供应商的推送地址发送测试消息
+func SendSupplierWebhookTest(webhookService *services.WebhookService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的供应商ID")
+		}
+
+		result, err := webhookService.SendSupplierTest(c.Request().Context(), id)
+		if err != nil {
+			return supplierWebhookErrorResponse(c, err, "发送失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// supplierWebhookErrorResponse 供应商推送设置错误转换为响应
+func supplierWebhookErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrSupplierNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrWebhookNotEnabled):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrWebhookSecretKey):
+		return ErrorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
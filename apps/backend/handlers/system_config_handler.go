@@ -85,6 +85,10 @@ func (h *SystemConfigHandler) GetOrderConfig(c echo.Context) error {
 			"paymentTimeout":      30,
 			"serviceFeeRate":      0.03,
 			"minServiceFee":       1.0,
+			"autoCompleteDays":    7,
+			"confirmTimeout":      120,
+			"confirmCancelAfter":  360,
+			"cutoffTime":          "18:00",
 		},
 	})
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// notificationTargetID 当前登录的门店或供应商ID
+func notificationTargetID(c echo.Context, targetType models.WebhookTargetType) uint64 {
+	if targetType == models.WebhookTargetStore {
+		return GetStoreID(c)
+	}
+	return GetSupplierID(c)
+}
+
+// getTargetNotifications 门店或供应商获取通知列表
+func getTargetNotifications(db *gorm.DB, targetType models.WebhookTargetType) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		targetID := notificationTargetID(c, targetType)
+		if targetID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		page, pageSize := GetPagination(c)
+		unreadOnly := c.QueryParam("unread") == "1" || c.QueryParam("unread") == "true"
+
+		notifications, total, err := services.NewTargetNotificationService(db).List(targetType, targetID, page, pageSize, unreadOnly)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, notifications, total, page, pageSize)
+	}
+}
+
+// getTargetNotificationUnreadCount 门店或供应商获取未读通知数量
+func getTargetNotificationUnreadCount(db *gorm.DB, targetType models.WebhookTargetType) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		targetID := notificationTargetID(c, targetType)
+		if targetID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		return SuccessResponse(c, map[string]int64{
+			"count": services.NewTargetNotificationService(db).UnreadCount(targetType, targetID),
+		})
+	}
+}
+
+// markTargetNotificationRead 门店或供应商标记通知已读
+func markTargetNotificationRead(db *gorm.DB, targetType models.WebhookTargetType) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		targetID := notificationTargetID(c, targetType)
+		if targetID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的通知ID")
+		}
+
+		if err := services.NewTargetNotificationService(db).MarkRead(targetType, targetID, id); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "操作失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
+
+// markAllTargetNotificationsRead 门店或供应商全部标记已读
+func markAllTargetNotificationsRead(db *gorm.DB, targetType models.WebhookTargetType) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		targetID := notificationTargetID(c, targetType)
+		if targetID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		if err := services.NewTargetNotificationService(db).MarkAllRead(targetType, targetID); err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "操作失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
+
+// GetStoreNotifications 门店获取通知列表
+func GetStoreNotifications(db *gorm.DB) echo.HandlerFunc {
+	return getTargetNotifications(db, models.WebhookTargetStore)
+}
+
+// GetStoreNotificationUnreadCount 门店获取未读通知数量
+func GetStoreNotificationUnreadCount(db *gorm.DB) echo.HandlerFunc {
+	return getTargetNotificationUnreadCount(db, models.WebhookTargetStore)
+}
+
+// MarkStoreNotificationRead 门店标记通知已读
+func MarkStoreNotificationRead(db *gorm.DB) echo.HandlerFunc {
+	return markTargetNotificationRead(db, models.WebhookTargetStore)
+}
+
+// MarkAllStoreNotificationsRead 门店全部标记已读
+func MarkAllStoreNotificationsRead(db *gorm.DB) echo.HandlerFunc {
+	return markAllTargetNotificationsRead(db, models.WebhookTargetStore)
+}
+
+// GetSupplierNotifications 供应商获取通知列表
+func GetSupplierNotifications(db *gorm.DB) echo.HandlerFunc {
+	return getTargetNotifications(db, models.WebhookTargetSupplier)
+}
+
+// GetSupplierNotificationUnreadCount 供应商获取未读通知数量
+func GetSupplierNotificationUnreadCount(db *gorm.DB) echo.HandlerFunc {
+	return getTargetNotificationUnreadCount(db, models.WebhookTargetSupplier)
+}
+
+// MarkSupplierNotificationRead 供应商标记通知已读
+func MarkSupplierNotificationRead(db *gorm.DB) echo.HandlerFunc {
+	return markTargetNotificationRead(db, models.WebhookTargetSupplier)
+}
+
+// MarkAllSupplierNotificationsRead 供应商全部标记已读
+func MarkAllSupplierNotificationsRead(db *gorm.DB) echo.HandlerFunc {
+	return markAllTargetNotificationsRead(db, models.WebhookTargetSupplier)
+}
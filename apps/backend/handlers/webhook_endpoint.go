@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+)
+
+// webhookEndpointQuery 推送地址查询参数
+type webhookEndpointQuery struct {
+	State      string `query:"state"`
+	TargetType string `query:"targetType"`
+	TargetID   uint64 `query:"targetId"`
+}
+
+// drainWebhookEndpointRequest 死信处理请求
+type drainWebhookEndpointRequest struct {
+	Action string `json:"action" validate:"required,oneof=resend discard"`
+}
+
+// GetWebhookEndpoints 推送地址熔断状态列表，熔断中的地址排在前面
+func GetWebhookEndpoints(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var query webhookEndpointQuery
+		if err := c.Bind(&query); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+
+		page, pageSize := GetPagination(c)
+		endpoints, total, err := logService.ListEndpoints(models.WebhookCircuitState(query.State),
+			models.WebhookTargetType(query.TargetType), query.TargetID, page, pageSize)
+		if err != nil {
+			return webhookEndpointErrorResponse(c, err, "查询失败")
+		}
+
+		return SuccessPageResponse(c, endpoints, total, page, pageSize)
+	}
+}
+
+// ResetWebhookEndpoint 手动恢复已熔断的推送地址
+func ResetWebhookEndpoint(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的推送地址ID")
+		}
+
+		endpoint, err := logService.ResetEndpoint(id)
+		if err != nil {
+			return webhookEndpointErrorResponse(c, err, "恢复失败")
+		}
+
+		return SuccessResponse(c, endpoint)
+	}
+}
+
+// DrainWebhookEndpoint 重发或丢弃推送地址的死信
+func DrainWebhookEndpoint(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的推送地址ID")
+		}
+
+		var req drainWebhookEndpointRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		result, err := logService.DrainEndpoint(id, req.Action)
+		if err != nil {
+			return webhookEndpointErrorResponse(c, err, "处理失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// GetSupplierWebhookStatus 供应商查看自己推送地址的熔断状态
+func GetSupplierWebhookStatus(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		supplierID := GetSupplierID(c)
+		if supplierID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		endpoints, err := logService.TargetEndpoints(models.WebhookTargetSupplier, supplierID)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessResponse(c, endpoints)
+	}
+}
+
+// GetStoreWebhookStatus 门店查看自己推送地址的熔断状态
+func GetStoreWebhookStatus(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		storeID := GetStoreID(c)
+		if storeID == 0 {
+			return ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		}
+
+		endpoints, err := logService.TargetEndpoints(models.WebhookTargetStore, storeID)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessResponse(c, endpoints)
+	}
+}
+
+// webhookEndpointErrorResponse 推送地址错误转换为响应
+func webhookEndpointErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWebhookEndpointNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrWebhookLogQueryInvalid), errors.Is(err, services.ErrWebhookCircuitOpen),
+		errors.Is(err, services.ErrWebhookDrainInvalid):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
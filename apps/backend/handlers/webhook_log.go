@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/services"
+	"github.com/project/backend/types"
+)
+
+// resendWebhookLogsRequest 批量重发请求，ids 为空时按筛选条件重发已失败的记录
+type resendWebhookLogsRequest struct {
+	types.WebhookQueryParams
+	IDs []uint64 `json:"ids"`
+}
+
+// bindWebhookLogFilter 解析推送日志筛选条件
+func bindWebhookLogFilter(c echo.Context) (*services.WebhookLogFilter, error) {
+	var params types.WebhookQueryParams
+	if err := c.Bind(&params); err != nil {
+		return nil, errors.New("请求参数错误")
+	}
+	return services.NewWebhookLogFilter(&params)
+}
+
+// GetWebhookLogs 按目标、事件、状态和日期查询推送日志
+func GetWebhookLogs(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		filter, err := bindWebhookLogFilter(c)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+
+		page, pageSize := GetPagination(c)
+		logs, total, err := logService.List(filter, page, pageSize)
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessPageResponse(c, logs, total, page, pageSize)
+	}
+}
+
+// GetWebhookLog 推送日志详情，包含请求内容和每次尝试的响应
+func GetWebhookLog(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的记录ID")
+		}
+
+		detail, err := logService.Get(id)
+		if err != nil {
+			return webhookLogErrorResponse(c, err, "查询失败")
+		}
+
+		return SuccessResponse(c, detail)
+	}
+}
+
+// ResendWebhookLog 立即重发一条推送
+func ResendWebhookLog(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "无效的记录ID")
+		}
+
+		detail, err := logService.Resend(c.Request().Context(), id)
+		if err != nil {
+			return webhookLogErrorResponse(c, err, "重发失败")
+		}
+
+		return SuccessResponse(c, detail)
+	}
+}
+
+// ResendFailedWebhookLogs 批量重发已失败的推送，由推送任务异步投递
+func ResendFailedWebhookLogs(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var req resendWebhookLogsRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		filter, err := services.NewWebhookLogFilter(&req.WebhookQueryParams)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+
+		result, err := logService.ResendFailed(&services.WebhookResendRequest{IDs: req.IDs, Filter: filter})
+		if err != nil {
+			return webhookLogErrorResponse(c, err, "重发失败")
+		}
+
+		return SuccessResponse(c, result)
+	}
+}
+
+// GetWebhookHealth 各推送地址的成功率和平均耗时，默认统计近7天
+func GetWebhookHealth(logService *services.WebhookLogService) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		filter, err := bindWebhookLogFilter(c)
+		if err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, err.Error())
+		}
+
+		report, err := logService.Health(filter)
+		if err != nil {
+			return webhookLogErrorResponse(c, err, "统计失败")
+		}
+
+		return SuccessResponse(c, report)
+	}
+}
+
+// webhookLogErrorResponse 推送日志错误转换为响应
+func webhookLogErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrWebhookLogNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrWebhookLogQueryInvalid), errors.Is(err, services.ErrWebhookLogPending):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/models"
+	"github.com/project/backend/services"
+	"gorm.io/gorm"
+)
+
+// webhookTemplatePreviewRequest 模板预览请求，orderId 为空时使用示例数据
+type webhookTemplatePreviewRequest struct {
+	services.WebhookTemplateRequest
+	EventType models.WebhookEventType `json:"eventType" validate:"required"`
+	OrderID   uint64                  `json:"orderId"`
+}
+
+// GetWebhookTemplates 获取各事件的 Webhook 消息模板
+func GetWebhookTemplates(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		templates, err := services.NewWebhookTemplateService(db).List()
+		if err != nil {
+			return ErrorResponse(c, http.StatusInternalServerError, "查询失败")
+		}
+
+		return SuccessResponse(c, templates)
+	}
+}
+
+// UpdateWebhookTemplate 修改事件的消息模板
+func UpdateWebhookTemplate(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var req services.WebhookTemplateRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		event := models.WebhookEventType(c.Param("event"))
+		tpl, err := services.NewWebhookTemplateService(db).Save(event, &req, GetAdminID(c))
+		if err != nil {
+			return webhookTemplateErrorResponse(c, err, "保存失败")
+		}
+
+		return SuccessResponse(c, tpl)
+	}
+}
+
+// ResetWebhookTemplate 恢复事件的内置消息模板
+func ResetWebhookTemplate(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		event := models.WebhookEventType(c.Param("event"))
+		if err := services.NewWebhookTemplateService(db).Reset(event); err != nil {
+			return webhookTemplateErrorResponse(c, err, "重置失败")
+		}
+
+		return SuccessResponse(c, nil)
+	}
+}
+
+// PreviewWebhookTemplate 预览消息模板的渲染结果
+func PreviewWebhookTemplate(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !IsAdmin(c) {
+			return ErrorResponse(c, http.StatusForbidden, "无权访问")
+		}
+
+		var req webhookTemplatePreviewRequest
+		if err := c.Bind(&req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "请求参数错误")
+		}
+		if err := c.Validate(req); err != nil {
+			return ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		}
+
+		preview, err := services.NewWebhookTemplateService(db).Preview(req.EventType, &req.WebhookTemplateRequest, req.OrderID)
+		if err != nil {
+			return webhookTemplateErrorResponse(c, err, "预览失败")
+		}
+
+		return SuccessResponse(c, preview)
+	}
+}
+
+// webhookTemplateErrorResponse 消息模板错误转换为响应
+func webhookTemplateErrorResponse(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		return ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrWebhookTemplateInvalid), errors.Is(err, services.ErrWebhookEventInvalid):
+		return ErrorResponse(c, http.StatusBadRequest, err.Error())
+	}
+	return ErrorResponse(c, http.StatusInternalServerError, fallback)
+}
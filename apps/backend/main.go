@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	echoMiddleware "github.com/labstack/echo/v4/middleware"
 	"github.com/project/backend/config"
@@ -8,6 +11,7 @@ import (
 	"github.com/project/backend/docs"
 	"github.com/project/backend/middleware"
 	"github.com/project/backend/routes"
+	"github.com/project/backend/services"
 	"github.com/project/backend/utils"
 	"go.uber.org/zap"
 )
@@ -43,12 +47,45 @@ func main() {
 	e.Use(middleware.RateLimiter())
 	e.Use(middleware.ResponseFormatter())
 
+	// 初始化支付渠道
+	paymentProviders := services.InitPaymentProviders(cfg)
+	if cfg.Payment.MockEnabled {
+		logger.Warn("Mock payment provider is enabled, do not use in production")
+	}
+
+	// 加价规则索引：规则或加价开关变更后经 Redis 通知所有实例重建
+	indexCtx, stopIndex := context.WithCancel(context.Background())
+	defer stopIndex()
+	if err := services.InitMarkupIndex(indexCtx, db, redisClient, logger); err != nil {
+		logger.Warn("Failed to init markup index, pricing falls back to database", zap.Error(err))
+	}
+
 	// 注册路由
-	routes.RegisterRoutes(e, db, redisClient, logger, cfg)
+	routes.RegisterRoutes(e, db, redisClient, logger, cfg, paymentProviders)
 
 	// 配置Swagger文档
 	docs.SetupSwagger(e)
 
+	// 启动后台定时任务
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	scheduler := services.NewScheduler(redisClient, logger)
+	orderTimeoutService := services.NewOrderTimeoutService(db, paymentProviders, logger)
+	refundService := services.NewRefundService(db, paymentProviders)
+	webhookService := services.NewWebhookService(db, logger, cfg.Webhook.SecretKey)
+	reconciliationService := services.NewReconciliationService(db, paymentProviders, cfg.Payment.BillDir)
+	settlementService := services.NewSettlementService(db)
+	creditService := services.NewCreditService(db)
+	scheduler.Register("order_payment_timeout", time.Minute, orderTimeoutService.CancelExpiredUnpaidOrders)
+	scheduler.Register("order_confirm_timeout", time.Minute, orderTimeoutService.EscalateUnconfirmedOrders)
+	scheduler.Register("order_auto_complete", 10*time.Minute, orderTimeoutService.AutoCompleteDeliveredOrders)
+	scheduler.Register("refund_submit", time.Minute, refundService.SubmitPending)
+	scheduler.Register("webhook_deliver", 10*time.Second, webhookService.DeliverDue)
+	scheduler.Register("payment_reconciliation", time.Hour, reconciliationService.RunDaily)
+	scheduler.Register("settlement_generate", time.Hour, settlementService.GenerateDue)
+	scheduler.Register("credit_billing", time.Hour, creditService.RunBilling)
+	scheduler.Start(jobCtx)
+
 	// 启动服务器
 	logger.Info("Starting server", zap.String("address", cfg.Server.Address))
 	if err := e.Start(cfg.Server.Address); err != nil {
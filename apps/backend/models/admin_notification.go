@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminNotificationType represents the admin notification type
+type AdminNotificationType string
+
+const (
+	AdminNotificationOrderConfirmTimeout AdminNotificationType = "order_confirm_timeout"
+	AdminNotificationOrderAutoCancelled  AdminNotificationType = "order_auto_cancelled"
+	// 合并支付到账时子订单已不是待支付状态，已取消的自动退款，其余需人工处理
+	AdminNotificationCombinedPaymentOrphan AdminNotificationType = "combined_payment_orphan"
+	// 支付到账时订单已不是待支付状态，已取消的自动退款，其余需人工处理
+	AdminNotificationPaymentOrphan AdminNotificationType = "payment_orphan"
+	// 退款多次提交失败或被渠道拒绝，需人工处理
+	AdminNotificationRefundFailed AdminNotificationType = "refund_failed"
+	// 门店赊账账单逾期，已自动暂停赊账下单
+	AdminNotificationCreditOverdue AdminNotificationType = "credit_overdue"
+	// 推送地址连续失败已熔断，推送转入死信
+	AdminNotificationWebhookCircuitOpen AdminNotificationType = "webhook_circuit_open"
+	// 熔断的推送地址试探成功，已恢复推送
+	AdminNotificationWebhookRecovered AdminNotificationType = "webhook_recovered"
+)
+
+// AdminNotification represents the admin_notifications table
+type AdminNotification struct {
+	ID          uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	Type        AdminNotificationType `gorm:"type:varchar(50);not null;index:idx_type_related" json:"type"`
+	Title       string                `gorm:"type:varchar(100);not null" json:"title"`
+	Content     string                `gorm:"type:varchar(1000)" json:"content"`
+	RelatedType *string               `gorm:"type:varchar(30);index:idx_type_related" json:"related_type,omitempty"`
+	RelatedID   *uint64               `gorm:"index:idx_type_related" json:"related_id,omitempty"`
+	IsRead      int8                  `gorm:"type:tinyint(1);default:0;index:idx_is_read" json:"is_read"`
+	ReadBy      *uint64               `json:"read_by,omitempty"`
+	ReadAt      *time.Time            `json:"read_at,omitempty"`
+	CreatedAt   time.Time             `gorm:"index:idx_created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for AdminNotification
+func (AdminNotification) TableName() string {
+	return "admin_notifications"
+}
+
+// NewOrderNotification creates an admin notification related to an order
+func NewOrderNotification(notificationType AdminNotificationType, orderID uint64, title, content string) *AdminNotification {
+	relatedType := "order"
+	return &AdminNotification{
+		Type:        notificationType,
+		Title:       title,
+		Content:     content,
+		RelatedType: &relatedType,
+		RelatedID:   &orderID,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// NewWebhookNotification creates an admin notification related to a webhook target (store or supplier)
+func NewWebhookNotification(notificationType AdminNotificationType, targetType WebhookTargetType, targetID uint64, title, content string) *AdminNotification {
+	relatedType := string(targetType)
+	return &AdminNotification{
+		Type:        notificationType,
+		Title:       title,
+		Content:     content,
+		RelatedType: &relatedType,
+		RelatedID:   &targetID,
+		CreatedAt:   time.Now(),
+	}
+}
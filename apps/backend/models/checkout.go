@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CheckoutStatus represents checkout status types
+type CheckoutStatus string
+
+const (
+	CheckoutStatusPendingPayment CheckoutStatus = "pending_payment"
+	CheckoutStatusPaid           CheckoutStatus = "paid"
+	CheckoutStatusCancelled      CheckoutStatus = "cancelled"
+)
+
+// Checkout represents the checkouts table
+// 一次购物车结算，按供应商拆分为多个子订单，可合并支付
+type Checkout struct {
+	ID            uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	CheckoutNo    string         `gorm:"type:varchar(30);uniqueIndex;not null" json:"checkout_no"`
+	StoreID       uint64         `gorm:"index;not null" json:"store_id"`
+	OrderCount    int            `json:"order_count"`
+	GoodsAmount   float64        `gorm:"type:decimal(10,2);not null" json:"goods_amount"`
+	ServiceFee    float64        `gorm:"type:decimal(10,2);default:0" json:"service_fee"`
+	TotalAmount   float64        `gorm:"type:decimal(10,2);not null" json:"total_amount"`
+	Status        CheckoutStatus `gorm:"type:enum('pending_payment','paid','cancelled');default:'pending_payment';index" json:"status"`
+	PaymentMethod *PaymentMethod `gorm:"type:enum('wechat','alipay','mock','credit')" json:"payment_method,omitempty"`
+	PaymentNo     *string        `gorm:"type:varchar(50)" json:"payment_no,omitempty"`
+	PaymentTime   *time.Time     `json:"payment_time,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Relationships
+	Orders []*Order `gorm:"foreignKey:CheckoutID" json:"orders,omitempty"`
+}
+
+// TableName specifies the table name for Checkout
+func (Checkout) TableName() string {
+	return "checkouts"
+}
+
+// BeforeCreate hook to generate checkout number
+func (c *Checkout) BeforeCreate(tx *gorm.DB) error {
+	if c.CheckoutNo == "" {
+		c.CheckoutNo = "CK" + generateOrderNo()
+	}
+	if c.Status == "" {
+		c.Status = CheckoutStatusPendingPayment
+	}
+	return nil
+}
+
+// PaymentAllocation represents the payment_allocations table
+// 合并支付时每个子订单分摊的金额
+type PaymentAllocation struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	PaymentNo  string    `gorm:"type:varchar(50);not null;uniqueIndex:uk_payment_order" json:"payment_no"`
+	CheckoutID uint64    `gorm:"index;not null" json:"checkout_id"`
+	OrderID    uint64    `gorm:"not null;uniqueIndex:uk_payment_order;index" json:"order_id"`
+	OrderNo    string    `gorm:"type:varchar(30);not null" json:"order_no"`
+	Amount     float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for PaymentAllocation
+func (PaymentAllocation) TableName() string {
+	return "payment_allocations"
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreditAccountStatus represents store credit account status
+type CreditAccountStatus string
+
+const (
+	CreditAccountStatusActive  CreditAccountStatus = "active"
+	CreditAccountStatusBlocked CreditAccountStatus = "blocked" // 暂停赊账下单，已有欠款照常出账
+	CreditAccountStatusClosed  CreditAccountStatus = "closed"
+)
+
+// CreditBillStatus represents store credit bill status
+type CreditBillStatus string
+
+const (
+	CreditBillStatusPending CreditBillStatus = "pending" // 待还款，未到期
+	CreditBillStatusOverdue CreditBillStatus = "overdue"
+	CreditBillStatusPaid    CreditBillStatus = "paid"
+)
+
+// CreditTransactionType represents store credit transaction type
+type CreditTransactionType string
+
+const (
+	CreditTransactionCharge    CreditTransactionType = "charge"    // 赊账下单，占用额度
+	CreditTransactionReversal  CreditTransactionType = "reversal"  // 赊账订单取消，释放额度
+	CreditTransactionRepayment CreditTransactionType = "repayment" // 门店还款
+)
+
+// StoreCreditAccount represents the store_credit_accounts table
+// 已用额度 = 赊账下单金额 - 取消释放金额 - 已还款金额 - 余额抵扣金额
+type StoreCreditAccount struct {
+	ID            uint64              `gorm:"primaryKey;autoIncrement" json:"id"`
+	StoreID       uint64              `gorm:"uniqueIndex;not null" json:"store_id"`
+	CreditLimit   float64             `gorm:"type:decimal(12,2);not null" json:"credit_limit"`
+	UsedAmount    float64             `gorm:"type:decimal(12,2);default:0" json:"used_amount"`
+	CreditBalance float64             `gorm:"type:decimal(12,2);default:0" json:"credit_balance"`   // 已还款订单取消形成的余额，下次出账时抵扣
+	BillCycle     SettlementCycle     `gorm:"type:varchar(20);default:'monthly'" json:"bill_cycle"` // 出账周期，取值同供应商结算周期
+	TermDays      int                 `gorm:"default:30" json:"term_days"`                          // 出账后的还款期限(天)
+	Status        CreditAccountStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	AutoBlocked   bool                `gorm:"default:false" json:"auto_blocked"` // 因账单逾期自动冻结，还清后自动恢复
+	BlockedReason string              `gorm:"type:varchar(200)" json:"blocked_reason"`
+	BlockedAt     *time.Time          `json:"blocked_at,omitempty"`
+	OperatorID    uint64              `json:"operator_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+
+	// Relationships
+	Store *Store `gorm:"foreignKey:StoreID" json:"store,omitempty"`
+}
+
+// TableName specifies the table name for StoreCreditAccount
+func (StoreCreditAccount) TableName() string {
+	return "store_credit_accounts"
+}
+
+// AvailableCredit 可用额度
+func (a *StoreCreditAccount) AvailableCredit() float64 {
+	return math.Round((a.CreditLimit-a.UsedAmount)*100) / 100
+}
+
+// StoreCreditBill represents the store_credit_bills table
+// 账单金额 = 周期内赊账下单金额 - 取消释放金额 - 余额抵扣金额，出账后取消的订单从所属账单中冲减
+type StoreCreditBill struct {
+	ID             uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	BillNo         string           `gorm:"type:varchar(30);uniqueIndex;not null" json:"bill_no"`
+	AccountID      uint64           `gorm:"index;not null" json:"account_id"`
+	StoreID        uint64           `gorm:"not null;uniqueIndex:uk_store_period" json:"store_id"`
+	PeriodStart    time.Time        `gorm:"type:date;not null;uniqueIndex:uk_store_period" json:"period_start"`
+	PeriodEnd      time.Time        `gorm:"type:date;not null" json:"period_end"` // 不含当天
+	DueDate        time.Time        `gorm:"type:date;not null;index" json:"due_date"`
+	OrderCount     int              `json:"order_count"`
+	ChargeAmount   float64          `gorm:"type:decimal(12,2);default:0" json:"charge_amount"`
+	ReversalAmount float64          `gorm:"type:decimal(12,2);default:0" json:"reversal_amount"`
+	CarriedAmount  float64          `gorm:"type:decimal(12,2);default:0" json:"carried_amount"` // 抵扣的账户余额
+	TotalAmount    float64          `gorm:"type:decimal(12,2);not null" json:"total_amount"`
+	PaidAmount     float64          `gorm:"type:decimal(12,2);default:0" json:"paid_amount"`
+	Status         CreditBillStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	PaidAt         *time.Time       `json:"paid_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+
+	// Relationships
+	Transactions []*StoreCreditTransaction `gorm:"foreignKey:BillID" json:"transactions,omitempty"`
+}
+
+// TableName specifies the table name for StoreCreditBill
+func (StoreCreditBill) TableName() string {
+	return "store_credit_bills"
+}
+
+// BeforeCreate hook to generate bill number
+func (b *StoreCreditBill) BeforeCreate(tx *gorm.DB) error {
+	if b.BillNo == "" {
+		b.BillNo = "CB" + generateOrderNo()
+	}
+	if b.Status == "" {
+		b.Status = CreditBillStatusPending
+	}
+	return nil
+}
+
+// OutstandingAmount 账单未还金额，还款后账单又被取消冲减时超出部分已转为账户余额，不为负
+func (b *StoreCreditBill) OutstandingAmount() float64 {
+	return math.Max(math.Round((b.TotalAmount-b.PaidAmount)*100)/100, 0)
+}
+
+// StoreCreditTransaction represents the store_credit_transactions table
+// 下单和取消流水出账时记录所属账单，还款流水记录所还账单
+type StoreCreditTransaction struct {
+	ID           uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountID    uint64                `gorm:"index;not null" json:"account_id"`
+	StoreID      uint64                `gorm:"index;not null" json:"store_id"`
+	Type         CreditTransactionType `gorm:"type:varchar(20);not null" json:"type"`
+	OrderID      *uint64               `gorm:"index" json:"order_id,omitempty"`
+	OrderNo      string                `gorm:"type:varchar(30)" json:"order_no"`
+	BillID       *uint64               `gorm:"index" json:"bill_id,omitempty"`
+	Amount       float64               `gorm:"type:decimal(12,2);not null" json:"amount"`
+	UsedAfter    float64               `gorm:"type:decimal(12,2);not null" json:"used_after"` // 变动后的已用额度
+	Reference    string                `gorm:"type:varchar(100)" json:"reference"`            // 还款流水号等
+	Remark       string                `gorm:"type:varchar(200)" json:"remark"`
+	OperatorType OperatorType          `gorm:"type:varchar(20)" json:"operator_type"`
+	OperatorID   uint64                `json:"operator_id"`
+	CreatedAt    time.Time             `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for StoreCreditTransaction
+func (StoreCreditTransaction) TableName() string {
+	return "store_credit_transactions"
+}
@@ -35,6 +35,8 @@ type PaymentMethod string
 const (
 	PaymentMethodWechat PaymentMethod = "wechat"
 	PaymentMethodAlipay PaymentMethod = "alipay"
+	PaymentMethodMock   PaymentMethod = "mock"   // 本地模拟支付
+	PaymentMethodCredit PaymentMethod = "credit" // 门店赊账，按账单周期还款
 )
 
 // OrderSource represents order source types
@@ -62,15 +64,18 @@ type Order struct {
 	OrderNo              string           `gorm:"type:varchar(30);uniqueIndex;not null" json:"order_no"`
 	StoreID              uint64           `gorm:"index;not null" json:"store_id"`
 	SupplierID           uint64           `gorm:"index;not null" json:"supplier_id"`
+	CheckoutID           *uint64          `gorm:"index" json:"checkout_id,omitempty"`
 	GoodsAmount          float64          `gorm:"type:decimal(10,2);not null" json:"goods_amount"`
 	ServiceFee           float64          `gorm:"type:decimal(10,2);default:0" json:"service_fee"`
+	ServiceFeeRuleID     *uint64          `json:"service_fee_rule_id,omitempty"`
+	ServiceFeeRuleName   string           `gorm:"type:varchar(100)" json:"service_fee_rule_name"` // 下单时适用的服务费规则
 	TotalAmount          float64          `gorm:"type:decimal(10,2);not null" json:"total_amount"`
 	SupplierAmount       float64          `gorm:"type:decimal(10,2)" json:"supplier_amount"`
 	MarkupTotal          float64          `gorm:"type:decimal(10,2);default:0" json:"markup_total"`
 	ItemCount            int              `json:"item_count"`
 	Status               OrderStatus      `gorm:"type:enum('pending_payment','pending_confirm','confirmed','delivering','completed','cancelled')" json:"status"`
 	PaymentStatus        PaymentStatus    `gorm:"type:enum('unpaid','paid','refunded','partial_refund');default:'unpaid'" json:"payment_status"`
-	PaymentMethod        *PaymentMethod   `gorm:"type:enum('wechat','alipay')" json:"payment_method,omitempty"`
+	PaymentMethod        *PaymentMethod   `gorm:"type:enum('wechat','alipay','mock','credit')" json:"payment_method,omitempty"`
 	PaymentTime          *time.Time       `json:"payment_time,omitempty"`
 	PaymentNo            *string          `gorm:"type:varchar(50)" json:"payment_no,omitempty"`
 	OrderSource          OrderSource      `gorm:"type:enum('app','web','h5')" json:"order_source"`
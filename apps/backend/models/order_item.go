@@ -19,6 +19,7 @@ type OrderItem struct {
 	Quantity      int            `gorm:"not null" json:"quantity"`
 	UnitPrice     float64        `gorm:"type:decimal(10,2);not null" json:"unit_price"`
 	MarkupAmount  float64        `gorm:"type:decimal(10,2);default:0" json:"markup_amount"`
+	MarkupRuleID  *uint64        `gorm:"index" json:"markup_rule_id,omitempty"`
 	FinalPrice    float64        `gorm:"type:decimal(10,2);not null" json:"final_price"`
 	Subtotal      float64        `gorm:"type:decimal(10,2);not null" json:"subtotal"`
 	CreatedAt     time.Time      `json:"created_at"`
@@ -13,6 +13,7 @@ const (
 	PaymentRecordStatusPending PaymentStatus = "pending"
 	PaymentRecordStatusSuccess PaymentStatus = "success"
 	PaymentRecordStatusFailed  PaymentStatus = "failed"
+	PaymentRecordStatusExpired PaymentStatus = "expired"
 )
 
 // PaymentRecord 支付记录表
@@ -20,12 +21,13 @@ type PaymentRecord struct {
 	ID               uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
 	OrderID          uint64         `gorm:"not null;index:idx_order_id" json:"orderId"`
 	OrderNo          string         `gorm:"type:varchar(30);not null" json:"orderNo"`
+	CheckoutID       *uint64        `gorm:"index" json:"checkoutId,omitempty"` // 合并支付时的结算单ID
 	PaymentNo        string         `gorm:"type:varchar(50);uniqueIndex:uk_payment_no;not null" json:"paymentNo"`
-	PaymentMethod    PaymentMethod  `gorm:"type:enum('wechat','alipay');not null" json:"paymentMethod"`
+	PaymentMethod    PaymentMethod  `gorm:"type:enum('wechat','alipay','mock');not null" json:"paymentMethod"`
 	GoodsAmount      float64        `gorm:"type:decimal(10,2);not null" json:"goodsAmount"`
 	ServiceFee       float64        `gorm:"type:decimal(10,2);default:0" json:"serviceFee"`
 	Amount           float64        `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Status           PaymentStatus  `gorm:"type:enum('pending','success','failed','refunded','partial_refund');default:'pending';index:idx_status" json:"status"`
+	Status           PaymentStatus  `gorm:"type:enum('pending','success','failed','refunded','partial_refund','expired');default:'pending';index:idx_status" json:"status"`
 	QRCodeURL        string         `gorm:"type:varchar(500)" json:"qrcodeUrl,omitempty"`
 	QRCodeExpireTime *time.Time     `json:"qrcodeExpireTime,omitempty"`
 	TradeNo          string         `gorm:"type:varchar(100);index:idx_trade_no" json:"tradeNo,omitempty"`
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentCallbackStatus represents payment callback processing status
+type PaymentCallbackStatus string
+
+const (
+	PaymentCallbackStatusReceived  PaymentCallbackStatus = "received"
+	PaymentCallbackStatusProcessed PaymentCallbackStatus = "processed"
+	PaymentCallbackStatusDuplicate PaymentCallbackStatus = "duplicate" // 重复通知，未产生任何变更
+	PaymentCallbackStatusIgnored   PaymentCallbackStatus = "ignored"   // 非支付成功的通知
+	PaymentCallbackStatusFailed    PaymentCallbackStatus = "failed"    // 处理失败，可重放
+	PaymentCallbackStatusInvalid   PaymentCallbackStatus = "invalid"   // 验签失败
+)
+
+// PaymentCallbackSource represents where the notification came from
+type PaymentCallbackSource string
+
+const (
+	PaymentCallbackSourceCallback  PaymentCallbackSource = "callback"  // 渠道异步通知
+	PaymentCallbackSourceReconcile PaymentCallbackSource = "reconcile" // 管理员主动查单补单
+)
+
+// PaymentCallbackInbox represents the payment_callback_inbox table
+// 保存每一条支付通知的原始报文和处理结果，用于幂等处理、排查和重放
+type PaymentCallbackInbox struct {
+	ID            uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	PaymentMethod PaymentMethod         `gorm:"type:varchar(20);not null" json:"payment_method"`
+	Source        PaymentCallbackSource `gorm:"type:varchar(20);not null;default:'callback'" json:"source"`
+	PaymentNo     string                `gorm:"type:varchar(50);index" json:"payment_no"`
+	TradeNo       string                `gorm:"type:varchar(100)" json:"trade_no"`
+	TradeState    string                `gorm:"type:varchar(20)" json:"trade_state"`
+	Amount        float64               `gorm:"type:decimal(10,2)" json:"amount"`
+	AppID         string                `gorm:"type:varchar(50)" json:"app_id"`
+	MchID         string                `gorm:"type:varchar(50)" json:"mch_id"`
+	PayTime       *time.Time            `json:"pay_time,omitempty"`
+	RawData       string                `gorm:"type:text" json:"raw_data"`
+	Status        PaymentCallbackStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts      int                   `gorm:"default:0" json:"attempts"`
+	ErrorMsg      string                `gorm:"type:varchar(500)" json:"error_msg"`
+	ProcessedAt   *time.Time            `json:"processed_at,omitempty"`
+	CreatedAt     time.Time             `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+// TableName specifies the table name for PaymentCallbackInbox
+func (PaymentCallbackInbox) TableName() string {
+	return "payment_callback_inbox"
+}
+
+// IsReplayable 处理失败的通知可以重放
+func (p *PaymentCallbackInbox) IsReplayable() bool {
+	return p.Status == PaymentCallbackStatusFailed || p.Status == PaymentCallbackStatusReceived
+}
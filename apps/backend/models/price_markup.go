@@ -1,6 +1,8 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,6 +16,53 @@ const (
 	MarkupTypePercent MarkupType = "percent"
 )
 
+// MarkupTier 加价阶梯：基础价格在 [MinPrice, MaxPrice) 内且数量不少于 MinQuantity 时适用 MarkupValue
+type MarkupTier struct {
+	MinPrice    float64 `json:"minPrice"`
+	MaxPrice    float64 `json:"maxPrice"` // 0 表示不封顶
+	MinQuantity int     `json:"minQuantity"`
+	MarkupValue float64 `json:"markupValue"` // 与规则的加价方式一致：固定金额或比例
+}
+
+// MarkupTiers represents markup tiers as JSON array
+type MarkupTiers []MarkupTier
+
+// Scan implements the Scanner interface
+func (t *MarkupTiers) Scan(value interface{}) error {
+	if value == nil {
+		*t = []MarkupTier{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), t)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// Value implements the driver Valuer interface
+func (t MarkupTiers) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Match 查找价格和数量适用的阶梯，同一价格区间内取起始数量最大的一档
+func (t MarkupTiers) Match(price float64, quantity int) *MarkupTier {
+	if quantity < 1 {
+		quantity = 1
+	}
+	var matched *MarkupTier
+	for i := range t {
+		tier := &t[i]
+		if price < tier.MinPrice || (tier.MaxPrice > 0 && price >= tier.MaxPrice) || quantity < tier.MinQuantity {
+			continue
+		}
+		if matched == nil || tier.MinQuantity > matched.MinQuantity {
+			matched = tier
+		}
+	}
+	return matched
+}
+
 // PriceMarkup 加价规则表
 type PriceMarkup struct {
 	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -25,6 +74,7 @@ type PriceMarkup struct {
 	MaterialID  *uint64        `json:"materialId,omitempty"`
 	MarkupType  MarkupType     `gorm:"type:enum('fixed','percent');not null" json:"markupType"`
 	MarkupValue float64        `gorm:"type:decimal(10,4);not null" json:"markupValue"`
+	Tiers       MarkupTiers    `gorm:"type:json" json:"tiers,omitempty"` // 设置后按阶梯取加价值
 	MinMarkup   float64        `gorm:"type:decimal(10,2)" json:"minMarkup,omitempty"`
 	MaxMarkup   float64        `gorm:"type:decimal(10,2)" json:"maxMarkup,omitempty"`
 	Priority    int            `gorm:"default:0;index:idx_active_priority" json:"priority"`
@@ -48,14 +98,18 @@ func (PriceMarkup) TableName() string {
 	return "price_markups"
 }
 
-// CalculateMarkup 计算加价金额
-func (p *PriceMarkup) CalculateMarkup(originalPrice float64) float64 {
+// CalculateMarkup 计算单件加价金额
+func (p *PriceMarkup) CalculateMarkup(originalPrice float64, quantity int) float64 {
 	var markup float64
 
+	value := p.MarkupValue
+	if tier := p.Tiers.Match(originalPrice, quantity); tier != nil {
+		value = tier.MarkupValue
+	}
 	if p.MarkupType == MarkupTypeFixed {
-		markup = p.MarkupValue
+		markup = value
 	} else {
-		markup = originalPrice * p.MarkupValue
+		markup = originalPrice * value
 	}
 
 	// 应用最小/最大限制
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// ReconciliationStatus represents reconciliation batch status
+type ReconciliationStatus string
+
+const (
+	ReconciliationStatusCompleted ReconciliationStatus = "completed"
+	ReconciliationStatusFailed    ReconciliationStatus = "failed"
+)
+
+// ReconciliationBillSource represents where the bill file came from
+type ReconciliationBillSource string
+
+const (
+	ReconciliationBillSourceFile     ReconciliationBillSource = "file"     // 本地对账单文件
+	ReconciliationBillSourceProvider ReconciliationBillSource = "provider" // 支付渠道下载
+)
+
+// DiscrepancyType represents reconciliation discrepancy type
+type DiscrepancyType string
+
+const (
+	DiscrepancyTypeMissing        DiscrepancyType = "missing"         // 本地已支付，对账单中没有
+	DiscrepancyTypeExtra          DiscrepancyType = "extra"           // 对账单中有，本地没有已支付记录
+	DiscrepancyTypeAmountMismatch DiscrepancyType = "amount_mismatch" // 金额不一致
+)
+
+// ReconciliationBatch represents the reconciliation_batches table
+// 每个支付渠道每天一批，重新对账时覆盖上一次的结果
+type ReconciliationBatch struct {
+	ID               uint64                   `gorm:"primaryKey;autoIncrement" json:"id"`
+	PaymentMethod    PaymentMethod            `gorm:"type:varchar(20);not null;uniqueIndex:uk_method_date" json:"payment_method"`
+	BillDate         time.Time                `gorm:"type:date;not null;uniqueIndex:uk_method_date" json:"bill_date"`
+	Source           ReconciliationBillSource `gorm:"type:varchar(20);not null" json:"source"`
+	FilePath         string                   `gorm:"type:varchar(500)" json:"file_path"`
+	Status           ReconciliationStatus     `gorm:"type:varchar(20);not null;index" json:"status"`
+	BillCount        int                      `json:"bill_count"`
+	BillAmount       float64                  `gorm:"type:decimal(12,2);default:0" json:"bill_amount"`
+	RecordCount      int                      `json:"record_count"`
+	RecordAmount     float64                  `gorm:"type:decimal(12,2);default:0" json:"record_amount"`
+	MatchedCount     int                      `json:"matched_count"`
+	MissingCount     int                      `json:"missing_count"`
+	ExtraCount       int                      `json:"extra_count"`
+	MismatchCount    int                      `json:"mismatch_count"`
+	FixedCount       int                      `json:"fixed_count"` // 通过查单补正的待支付记录数
+	DiscrepancyCount int                      `json:"discrepancy_count"`
+	ErrorMsg         string                   `gorm:"type:varchar(500)" json:"error_msg"`
+	OperatorID       uint64                   `json:"operator_id"` // 0 表示定时任务
+	CreatedAt        time.Time                `json:"created_at"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+
+	// Relationships
+	Discrepancies []*ReconciliationDiscrepancy `gorm:"foreignKey:BatchID" json:"discrepancies,omitempty"`
+}
+
+// TableName specifies the table name for ReconciliationBatch
+func (ReconciliationBatch) TableName() string {
+	return "reconciliation_batches"
+}
+
+// ReconciliationDiscrepancy represents the reconciliation_discrepancies table
+type ReconciliationDiscrepancy struct {
+	ID           uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID      uint64          `gorm:"index;not null" json:"batch_id"`
+	Type         DiscrepancyType `gorm:"type:varchar(20);not null;index" json:"type"`
+	PaymentNo    string          `gorm:"type:varchar(50);index" json:"payment_no"`
+	TradeNo      string          `gorm:"type:varchar(100)" json:"trade_no"`
+	BillAmount   float64         `gorm:"type:decimal(10,2)" json:"bill_amount"`
+	RecordAmount float64         `gorm:"type:decimal(10,2)" json:"record_amount"`
+	RecordStatus string          `gorm:"type:varchar(20)" json:"record_status"`
+	TradeTime    *time.Time      `json:"trade_time,omitempty"`
+	Resolved     bool            `gorm:"default:false;index" json:"resolved"`
+	ResolvedBy   *uint64         `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time      `json:"resolved_at,omitempty"`
+	Remark       string          `gorm:"type:varchar(500)" json:"remark"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// TableName specifies the table name for ReconciliationDiscrepancy
+func (ReconciliationDiscrepancy) TableName() string {
+	return "reconciliation_discrepancies"
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefundStatus represents refund status types
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"    // 待提交渠道
+	RefundStatusProcessing RefundStatus = "processing" // 渠道处理中，等待退款通知
+	RefundStatusSuccess    RefundStatus = "success"
+	RefundStatusFailed     RefundStatus = "failed"
+)
+
+// RefundType represents refund type
+type RefundType string
+
+const (
+	RefundTypeFull    RefundType = "full"    // 退款后订单已全额退款
+	RefundTypePartial RefundType = "partial" // 部分退款
+)
+
+// RefundSource represents what triggered the refund
+type RefundSource string
+
+const (
+	RefundSourceCancel RefundSource = "cancel" // 已支付订单取消后自动退款
+	RefundSourceAdmin  RefundSource = "admin"  // 管理员手工退款
+)
+
+// Refund represents the refunds table
+// 一笔退款对应一个订单，合并支付的订单按子订单分别退款
+type Refund struct {
+	ID              uint64        `gorm:"primaryKey;autoIncrement" json:"id"`
+	RefundNo        string        `gorm:"type:varchar(50);uniqueIndex;not null" json:"refund_no"`
+	OrderID         uint64        `gorm:"index;not null" json:"order_id"`
+	OrderNo         string        `gorm:"type:varchar(30);not null" json:"order_no"`
+	PaymentNo       string        `gorm:"type:varchar(50);index;not null" json:"payment_no"`
+	PaymentMethod   PaymentMethod `gorm:"type:varchar(20);not null" json:"payment_method"`
+	Amount          float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Type            RefundType    `gorm:"type:varchar(20);not null" json:"type"`
+	Source          RefundSource  `gorm:"type:varchar(20);not null" json:"source"`
+	Reason          string        `gorm:"type:varchar(200)" json:"reason"`
+	Status          RefundStatus  `gorm:"type:varchar(20);not null;index" json:"status"`
+	GatewayRefundID string        `gorm:"type:varchar(100)" json:"gateway_refund_id"`
+	Attempts        int           `gorm:"default:0" json:"attempts"`
+	ErrorMsg        string        `gorm:"type:varchar(500)" json:"error_msg"`
+	OperatorType    OperatorType  `gorm:"type:varchar(20)" json:"operator_type"`
+	OperatorID      uint64        `json:"operator_id"`
+	RefundedAt      *time.Time    `json:"refunded_at,omitempty"`
+	CreatedAt       time.Time     `gorm:"index" json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// TableName specifies the table name for Refund
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// BeforeCreate hook to generate refund number
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.RefundNo == "" {
+		r.RefundNo = "RF" + generateOrderNo()
+	}
+	if r.Status == "" {
+		r.Status = RefundStatusPending
+	}
+	return nil
+}
+
+// IsFinished 退款已有最终结果
+func (r *Refund) IsFinished() bool {
+	return r.Status == RefundStatusSuccess || r.Status == RefundStatusFailed
+}
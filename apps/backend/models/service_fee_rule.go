@@ -0,0 +1,92 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ServiceFeeType 服务费计费方式
+type ServiceFeeType string
+
+const (
+	ServiceFeeTypePercent ServiceFeeType = "percent" // 按计费金额所在档位的费率计算
+	ServiceFeeTypeFixed   ServiceFeeType = "fixed"   // 每单固定金额
+	ServiceFeeTypeExempt  ServiceFeeType = "exempt"  // 免收，指定分类时仅该分类商品不计费
+)
+
+// ServiceFeeTier 服务费阶梯，计费金额达到 MinAmount 时适用 Rate
+type ServiceFeeTier struct {
+	MinAmount float64 `json:"minAmount"`
+	Rate      float64 `json:"rate"`
+}
+
+// ServiceFeeTiers represents service fee tiers as JSON array
+type ServiceFeeTiers []ServiceFeeTier
+
+// Scan implements the Scanner interface
+func (t *ServiceFeeTiers) Scan(value interface{}) error {
+	if value == nil {
+		*t = []ServiceFeeTier{}
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return json.Unmarshal([]byte(value.(string)), t)
+	}
+	return json.Unmarshal(bytes, t)
+}
+
+// Value implements the driver Valuer interface
+func (t ServiceFeeTiers) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// ServiceFeeRule 服务费规则表
+type ServiceFeeRule struct {
+	ID          uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string          `gorm:"type:varchar(100);not null" json:"name"`
+	Description string          `gorm:"type:varchar(500)" json:"description,omitempty"`
+	StoreID     *uint64         `gorm:"index" json:"storeId,omitempty"`
+	SupplierID  *uint64         `gorm:"index" json:"supplierId,omitempty"`
+	CategoryID  *uint64         `json:"categoryId,omitempty"` // 仅免收规则可指定
+	FeeType     ServiceFeeType  `gorm:"type:varchar(20);not null" json:"feeType"`
+	Tiers       ServiceFeeTiers `gorm:"type:json" json:"tiers,omitempty"`
+	FixedFee    float64         `gorm:"type:decimal(10,2);default:0" json:"fixedFee,omitempty"`
+	MinFee      float64         `gorm:"type:decimal(10,2);default:0" json:"minFee,omitempty"`
+	MaxFee      float64         `gorm:"type:decimal(10,2);default:0" json:"maxFee,omitempty"` // 0 表示不封顶
+	Priority    int             `gorm:"default:0" json:"priority"`
+	IsActive    bool            `gorm:"default:true;index" json:"isActive"`
+	StartTime   *time.Time      `json:"startTime,omitempty"`
+	EndTime     *time.Time      `json:"endTime,omitempty"`
+	CreatedBy   uint64          `json:"createdBy"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
+
+	// 关联
+	Store    *Store    `gorm:"foreignKey:StoreID" json:"store,omitempty"`
+	Supplier *Supplier `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+}
+
+// TableName 表名
+func (ServiceFeeRule) TableName() string {
+	return "service_fee_rules"
+}
+
+// IsValidAt 检查规则在指定时间是否有效
+func (r *ServiceFeeRule) IsValidAt(t time.Time) bool {
+	if !r.IsActive {
+		return false
+	}
+	if r.StartTime != nil && t.Before(*r.StartTime) {
+		return false
+	}
+	if r.EndTime != nil && t.After(*r.EndTime) {
+		return false
+	}
+	return true
+}
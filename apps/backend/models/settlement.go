@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SettlementCycle represents supplier settlement cycle
+type SettlementCycle string
+
+const (
+	SettlementCycleWeekly  SettlementCycle = "weekly"  // 每周一结算上一周
+	SettlementCycleMonthly SettlementCycle = "monthly" // 每月1日结算上一月
+)
+
+// SettlementStatementStatus represents settlement statement status
+type SettlementStatementStatus string
+
+const (
+	SettlementStatusPendingConfirm SettlementStatementStatus = "pending_confirm" // 待供应商确认
+	SettlementStatusConfirmed      SettlementStatementStatus = "confirmed"       // 供应商已确认，待打款
+	SettlementStatusPaid           SettlementStatementStatus = "paid"            // 已全部打款
+)
+
+// SettlementItemType represents settlement statement item type
+type SettlementItemType string
+
+const (
+	SettlementItemOrder      SettlementItemType = "order"      // 已完成订单的供应商应得金额
+	SettlementItemRefund     SettlementItemType = "refund"     // 退款中供应商承担的部分
+	SettlementItemAdjustment SettlementItemType = "adjustment" // 管理员调整
+)
+
+// SupplierLedgerEntryType represents supplier ledger entry type
+type SupplierLedgerEntryType string
+
+const (
+	LedgerEntryPayable SupplierLedgerEntryType = "payable" // 结算单生成，计入应付
+	LedgerEntryHold    SupplierLedgerEntryType = "hold"    // 应付转为暂扣
+	LedgerEntryRelease SupplierLedgerEntryType = "release" // 暂扣释放回应付
+	LedgerEntryPayout  SupplierLedgerEntryType = "payout"  // 打款
+)
+
+// ErrLedgerImmutable 台账记录只能追加，不能修改或删除
+var ErrLedgerImmutable = errors.New("供应商台账记录不可修改")
+
+// SettlementStatement represents the settlement_statements table
+// 应付金额 = 订单金额 - 退款金额 + 调整金额
+type SettlementStatement struct {
+	ID               uint64                    `gorm:"primaryKey;autoIncrement" json:"id"`
+	StatementNo      string                    `gorm:"type:varchar(30);uniqueIndex;not null" json:"statement_no"`
+	SupplierID       uint64                    `gorm:"not null;uniqueIndex:uk_supplier_period" json:"supplier_id"`
+	Cycle            SettlementCycle           `gorm:"type:varchar(20);not null" json:"cycle"`
+	PeriodStart      time.Time                 `gorm:"type:date;not null;uniqueIndex:uk_supplier_period" json:"period_start"`
+	PeriodEnd        time.Time                 `gorm:"type:date;not null" json:"period_end"` // 不含当天
+	OrderCount       int                       `json:"order_count"`
+	OrderAmount      float64                   `gorm:"type:decimal(12,2);default:0" json:"order_amount"`
+	RefundAmount     float64                   `gorm:"type:decimal(12,2);default:0" json:"refund_amount"`
+	AdjustmentAmount float64                   `gorm:"type:decimal(12,2);default:0" json:"adjustment_amount"`
+	PayableAmount    float64                   `gorm:"type:decimal(12,2);default:0" json:"payable_amount"`
+	HeldAmount       float64                   `gorm:"type:decimal(12,2);default:0" json:"held_amount"`
+	PaidAmount       float64                   `gorm:"type:decimal(12,2);default:0" json:"paid_amount"`
+	Status           SettlementStatementStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	ConfirmedBy      *uint64                   `json:"confirmed_by,omitempty"`
+	ConfirmedAt      *time.Time                `json:"confirmed_at,omitempty"`
+	PaidAt           *time.Time                `json:"paid_at,omitempty"`
+	CreatedAt        time.Time                 `json:"created_at"`
+	UpdatedAt        time.Time                 `json:"updated_at"`
+
+	// Relationships
+	Supplier *Supplier                  `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Items    []*SettlementStatementItem `gorm:"foreignKey:StatementID" json:"items,omitempty"`
+}
+
+// TableName specifies the table name for SettlementStatement
+func (SettlementStatement) TableName() string {
+	return "settlement_statements"
+}
+
+// BeforeCreate hook to generate statement number
+func (s *SettlementStatement) BeforeCreate(tx *gorm.DB) error {
+	if s.StatementNo == "" {
+		s.StatementNo = "ST" + generateOrderNo()
+	}
+	if s.Status == "" {
+		s.Status = SettlementStatusPendingConfirm
+	}
+	return nil
+}
+
+// AvailableAmount 可打款金额：应付扣除暂扣和已付
+func (s *SettlementStatement) AvailableAmount() float64 {
+	return math.Round((s.PayableAmount-s.HeldAmount-s.PaidAmount)*100) / 100
+}
+
+// SettlementStatementItem represents the settlement_statement_items table
+// 每个订单只结算一次，每笔退款和调整只扣减一次
+type SettlementStatementItem struct {
+	ID          uint64             `gorm:"primaryKey;autoIncrement" json:"id"`
+	StatementID uint64             `gorm:"index;not null" json:"statement_id"`
+	Type        SettlementItemType `gorm:"type:varchar(20);not null;uniqueIndex:uk_type_ref" json:"type"`
+	RefID       uint64             `gorm:"not null;uniqueIndex:uk_type_ref" json:"ref_id"` // 订单、退款或调整的ID
+	OrderID     uint64             `gorm:"index" json:"order_id"`
+	OrderNo     string             `gorm:"type:varchar(30)" json:"order_no"`
+	GoodsAmount float64            `gorm:"type:decimal(10,2);default:0" json:"goods_amount"`
+	MarkupTotal float64            `gorm:"type:decimal(10,2);default:0" json:"markup_total"`
+	ServiceFee  float64            `gorm:"type:decimal(10,2);default:0" json:"service_fee"`
+	Amount      float64            `gorm:"type:decimal(10,2);not null" json:"amount"` // 计入应付的金额，扣减为负数
+	Remark      string             `gorm:"type:varchar(200)" json:"remark"`
+	OccurredAt  time.Time          `json:"occurred_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// TableName specifies the table name for SettlementStatementItem
+func (SettlementStatementItem) TableName() string {
+	return "settlement_statement_items"
+}
+
+// SettlementAdjustment represents the settlement_adjustments table
+// 管理员录入的调整，在供应商下一张结算单中计入
+type SettlementAdjustment struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	SupplierID  uint64    `gorm:"index;not null" json:"supplier_id"`
+	Amount      float64   `gorm:"type:decimal(10,2);not null" json:"amount"` // 正数增加应付，负数扣减
+	Reason      string    `gorm:"type:varchar(200);not null" json:"reason"`
+	StatementID *uint64   `gorm:"index" json:"statement_id,omitempty"`
+	OperatorID  uint64    `json:"operator_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SettlementAdjustment
+func (SettlementAdjustment) TableName() string {
+	return "settlement_adjustments"
+}
+
+// SupplierLedgerEntry represents the supplier_ledger_entries table
+// 只追加的供应商资金台账，每条记录保存变动后的应付、暂扣和累计已付余额
+type SupplierLedgerEntry struct {
+	ID             uint64                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	SupplierID     uint64                  `gorm:"index;not null" json:"supplier_id"`
+	StatementID    uint64                  `gorm:"index;not null" json:"statement_id"`
+	Type           SupplierLedgerEntryType `gorm:"type:varchar(20);not null" json:"type"`
+	Amount         float64                 `gorm:"type:decimal(12,2);not null" json:"amount"`
+	PayableBalance float64                 `gorm:"type:decimal(12,2);not null" json:"payable_balance"`
+	HeldBalance    float64                 `gorm:"type:decimal(12,2);not null" json:"held_balance"`
+	PaidTotal      float64                 `gorm:"type:decimal(12,2);not null" json:"paid_total"`
+	Reference      string                  `gorm:"type:varchar(100)" json:"reference"` // 打款流水号等
+	Remark         string                  `gorm:"type:varchar(200)" json:"remark"`
+	OperatorType   OperatorType            `gorm:"type:varchar(20)" json:"operator_type"`
+	OperatorID     uint64                  `json:"operator_id"`
+	CreatedAt      time.Time               `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for SupplierLedgerEntry
+func (SupplierLedgerEntry) TableName() string {
+	return "supplier_ledger_entries"
+}
+
+// BeforeUpdate 台账不可修改
+func (SupplierLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// BeforeDelete 台账不可删除
+func (SupplierLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
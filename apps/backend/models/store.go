@@ -25,6 +25,7 @@ type Store struct {
 	MarkupEnabled    int8           `gorm:"type:tinyint(1);default:1" json:"markup_enabled"`
 	WechatWebhookURL *string        `gorm:"type:varchar(500)" json:"wechat_webhook_url"`
 	WebhookEnabled   int8           `gorm:"type:tinyint(1);default:0" json:"webhook_enabled"`
+	WebhookEvents    WebhookEvents  `gorm:"type:json" json:"webhook_events"`
 	Status           int8           `gorm:"type:tinyint(1);default:1" json:"status"`
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
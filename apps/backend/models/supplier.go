@@ -91,7 +91,10 @@ type Supplier struct {
 	WebhookTimeout       int            `gorm:"default:30" json:"webhook_timeout"`
 	APIEndpoint          *string        `gorm:"type:varchar(500)" json:"api_endpoint"`
 	APISecretKey     *string        `gorm:"type:varchar(100)" json:"-"` // Encrypted storage
+	WebhookSecret          *string    `gorm:"type:varchar(255)" json:"-"` // Encrypted storage
+	WebhookSecretRotatedAt *time.Time `json:"webhook_secret_rotated_at"`
 	MarkupEnabled    int8           `gorm:"type:tinyint(1);default:1" json:"markup_enabled"`
+	SettlementCycle  SettlementCycle `gorm:"type:varchar(20);default:'weekly'" json:"settlement_cycle"`
 	Remark           *string        `gorm:"type:text" json:"remark"`
 	Status           int8           `gorm:"type:tinyint(1);default:1" json:"status"`
 	CreatedAt        time.Time      `json:"created_at"`
@@ -146,6 +149,11 @@ func (s *Supplier) HasWebhook() bool {
 	return s.WebhookEnabled == 1 && s.WechatWebhookURL != nil && *s.WechatWebhookURL != ""
 }
 
+// UsesSignedWebhook webhook/api 模式的供应商接收签名的通用 JSON 推送，其余接收企业微信群机器人消息
+func (s *Supplier) UsesSignedWebhook() bool {
+	return s.ManagementMode == ManagementWebhook || s.ManagementMode == ManagementAPI
+}
+
 // HasAPI checks if API integration is enabled
 func (s *Supplier) HasAPI() bool {
 	return s.ManagementMode == ManagementAPI && s.APIEndpoint != nil && *s.APIEndpoint != ""
@@ -85,10 +85,13 @@ func (s *SupplierMaterial) GetDiscountRate() float64 {
 
 // ValidateQuantity checks if the quantity meets minimum and step requirements
 func (s *SupplierMaterial) ValidateQuantity(quantity int) bool {
-	if quantity < s.MinQuantity {
+	if quantity <= 0 || quantity < s.MinQuantity {
 		return false
 	}
 	// Check if quantity follows step requirement
+	if s.StepQuantity <= 1 {
+		return true
+	}
 	diff := quantity - s.MinQuantity
 	return diff%s.StepQuantity == 0
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// TargetNotification represents the target_notifications table
+// 门店和供应商的站内通知，如推送地址熔断和恢复
+type TargetNotification struct {
+	ID         uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	TargetType WebhookTargetType     `gorm:"type:varchar(20);not null;index:idx_target,priority:1" json:"target_type"`
+	TargetID   uint64                `gorm:"not null;index:idx_target,priority:2" json:"target_id"`
+	Type       AdminNotificationType `gorm:"type:varchar(50);not null" json:"type"` // 取值同管理员通知类型
+	Title      string                `gorm:"type:varchar(100);not null" json:"title"`
+	Content    string                `gorm:"type:varchar(1000)" json:"content"`
+	IsRead     int8                  `gorm:"type:tinyint(1);default:0;index:idx_target,priority:3" json:"is_read"`
+	ReadAt     *time.Time            `json:"read_at,omitempty"`
+	CreatedAt  time.Time             `gorm:"index:idx_created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for TargetNotification
+func (TargetNotification) TableName() string {
+	return "target_notifications"
+}
+
+// NewTargetNotification creates a notification for a store or supplier
+func NewTargetNotification(notificationType AdminNotificationType, targetType WebhookTargetType, targetID uint64, title, content string) *TargetNotification {
+	return &TargetNotification{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Type:       notificationType,
+		Title:      title,
+		Content:    content,
+		CreatedAt:  time.Now(),
+	}
+}
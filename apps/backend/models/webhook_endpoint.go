@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WebhookCircuitState 推送地址熔断状态
+type WebhookCircuitState string
+
+const (
+	WebhookCircuitClosed   WebhookCircuitState = "closed"    // 正常推送
+	WebhookCircuitOpen     WebhookCircuitState = "open"      // 已熔断，推送转入死信
+	WebhookCircuitHalfOpen WebhookCircuitState = "half_open" // 正在试探，成功后恢复推送
+)
+
+// WebhookEndpoint 推送地址熔断状态表，按目标和推送地址区分，首次推送失败时创建
+type WebhookEndpoint struct {
+	ID                  uint64              `gorm:"primaryKey;autoIncrement" json:"id"`
+	TargetType          WebhookTargetType   `gorm:"type:varchar(20);not null;uniqueIndex:uk_endpoint,priority:1" json:"targetType"`
+	TargetID            uint64              `gorm:"not null;uniqueIndex:uk_endpoint,priority:2" json:"targetId"`
+	WebhookURL          string              `gorm:"type:varchar(500);not null;uniqueIndex:uk_endpoint,priority:3" json:"webhookUrl"`
+	State               WebhookCircuitState `gorm:"type:varchar(20);not null;default:'closed';index:idx_state" json:"state"`
+	ConsecutiveFailures int                 `gorm:"default:0" json:"consecutiveFailures"`
+	TripCount           int                 `gorm:"default:0" json:"tripCount"` // 恢复前连续熔断的次数，决定试探间隔
+	OpenedAt            *time.Time          `json:"openedAt,omitempty"`
+	NextProbeAt         *time.Time          `json:"nextProbeAt,omitempty"` // 熔断时为下次试探时间，试探中为试探租约到期时间
+	LastError           string              `gorm:"type:varchar(500)" json:"lastError,omitempty"`
+	LastFailureAt       *time.Time          `json:"lastFailureAt,omitempty"`
+	LastSuccessAt       *time.Time          `json:"lastSuccessAt,omitempty"`
+	CreatedAt           time.Time           `json:"createdAt"`
+	UpdatedAt           time.Time           `json:"updatedAt"`
+}
+
+// TableName 表名
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
@@ -24,6 +24,7 @@ const (
 	WebhookEventOrderCompleted  WebhookEventType = "order.completed"
 	WebhookEventOrderCancelled  WebhookEventType = "order.cancelled"
 	WebhookEventOrderRestored   WebhookEventType = "order.restored"
+	WebhookEventPriceUpdated    WebhookEventType = "price.updated"
 )
 
 // WebhookStatus Webhook状态
@@ -33,6 +34,7 @@ const (
 	WebhookStatusPending WebhookStatus = "pending"
 	WebhookStatusSuccess WebhookStatus = "success"
 	WebhookStatusFailed  WebhookStatus = "failed"
+	WebhookStatusDead    WebhookStatus = "dead" // 推送地址熔断期间的推送，等待管理员处理
 )
 
 // WebhookLog Webhook推送日志表
@@ -40,14 +42,14 @@ type WebhookLog struct {
 	ID             uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
 	TargetType     WebhookTargetType `gorm:"type:enum('store','supplier');index:idx_target,priority:1" json:"targetType"`
 	TargetID       uint64            `gorm:"index:idx_target,priority:2" json:"targetId"`
-	EventType      WebhookEventType  `gorm:"type:enum('order.created','order.confirmed','order.delivering','order.completed','order.cancelled','order.restored')" json:"eventType"`
+	EventType      WebhookEventType  `gorm:"type:enum('order.created','order.confirmed','order.delivering','order.completed','order.cancelled','order.restored','price.updated')" json:"eventType"`
 	OrderID        uint64            `gorm:"index:idx_order_id" json:"orderId"`
 	WebhookURL     string            `gorm:"type:varchar(500)" json:"webhookUrl"`
 	RequestHeaders JSON              `gorm:"type:json" json:"requestHeaders,omitempty"`
 	RequestBody    JSON              `gorm:"type:json" json:"requestBody,omitempty"`
 	ResponseCode   int               `json:"responseCode,omitempty"`
 	ResponseBody   string            `gorm:"type:text" json:"responseBody,omitempty"`
-	Status         WebhookStatus     `gorm:"type:enum('pending','success','failed');default:'pending';index:idx_status;index:idx_next_retry,priority:1" json:"status"`
+	Status         WebhookStatus     `gorm:"type:enum('pending','success','failed','dead');default:'pending';index:idx_status;index:idx_next_retry,priority:1" json:"status"`
 	RetryCount     int               `gorm:"default:0" json:"retryCount"`
 	MaxRetryCount  int               `gorm:"default:3" json:"maxRetryCount"`
 	NextRetryAt    *time.Time        `gorm:"index:idx_next_retry,priority:2" json:"nextRetryAt,omitempty"`
@@ -73,7 +75,12 @@ func (w *WebhookLog) CanRetry() bool {
 
 // SetNextRetry 设置下次重试时间
 func (w *WebhookLog) SetNextRetry(intervalMinutes int) {
-	nextTime := time.Now().Add(time.Duration(intervalMinutes) * time.Minute)
+	w.SetNextRetryAfter(time.Duration(intervalMinutes) * time.Minute)
+}
+
+// SetNextRetryAfter 设置下次重试时间为 delay 之后
+func (w *WebhookLog) SetNextRetryAfter(delay time.Duration) {
+	nextTime := time.Now().Add(delay)
 	w.NextRetryAt = &nextTime
 	w.RetryCount++
 }
@@ -95,3 +102,21 @@ func (w *WebhookLog) MarkFailed(responseCode int, responseBody string, errorMsg
 	w.ErrorMsg = errorMsg
 	w.DurationMs = durationMs
 }
+
+// WebhookAttempt Webhook每次推送尝试的记录
+type WebhookAttempt struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookLogID uint64    `gorm:"not null;index:idx_webhook_log" json:"webhookLogId"`
+	Attempt      int       `gorm:"not null" json:"attempt"`
+	ResponseCode int       `json:"responseCode,omitempty"`
+	ResponseBody string    `gorm:"type:text" json:"responseBody,omitempty"`
+	Success      bool      `gorm:"not null;default:false" json:"success"`
+	ErrorMsg     string    `gorm:"type:varchar(500)" json:"errorMsg,omitempty"`
+	DurationMs   int       `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// TableName 表名
+func (WebhookAttempt) TableName() string {
+	return "webhook_attempts"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// WebhookMsgType 企业微信群机器人消息类型
+type WebhookMsgType string
+
+const (
+	WebhookMsgMarkdown WebhookMsgType = "markdown"
+	WebhookMsgText     WebhookMsgType = "text"
+)
+
+// WebhookTemplate Webhook 消息模板表，每个事件一条，未配置或停用时使用内置模板
+type WebhookTemplate struct {
+	ID             uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType      WebhookEventType `gorm:"type:varchar(50);not null;uniqueIndex:uk_event_type" json:"eventType"`
+	MsgType        WebhookMsgType   `gorm:"type:varchar(20);not null" json:"msgType"`
+	Content        string           `gorm:"type:text;not null" json:"content"`         // text/template 语法
+	MentionContact bool             `gorm:"default:false" json:"mentionContact"`       // @推送目标的联系人手机号
+	MentionMobiles JSONStringArray  `gorm:"type:json" json:"mentionMobiles,omitempty"` // 额外@的手机号，@all 表示所有人
+	IsActive       bool             `gorm:"default:true" json:"isActive"`
+	UpdatedBy      uint64           `json:"updatedBy"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	UpdatedAt      time.Time        `json:"updatedAt"`
+}
+
+// TableName 表名
+func (WebhookTemplate) TableName() string {
+	return "webhook_templates"
+}
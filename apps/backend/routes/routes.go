@@ -5,12 +5,13 @@ import (
 	"github.com/project/backend/config"
 	"github.com/project/backend/handlers"
 	"github.com/project/backend/middleware"
+	"github.com/project/backend/services"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.Logger, cfg *config.Config) {
+func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.Logger, cfg *config.Config, paymentProviders *services.PaymentProviders) {
 	// API根路由
 	api := e.Group("/api")
 
@@ -29,6 +30,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 	// 从配置中读取JWT密钥
 	jwtSecret := cfg.JWT.Secret
 	authenticated := api.Group("", middleware.AuthMiddleware(jwtSecret))
+	webhookService := services.NewWebhookService(db, logger, cfg.Webhook.SecretKey)
+	webhookLogService := services.NewWebhookLogService(db, webhookService)
 
 	// 管理员路由
 	admin := authenticated.Group("/admin", middleware.RequireRole("admin", "sub_admin"))
@@ -45,6 +48,8 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		admin.POST("/suppliers", handlers.CreateSupplier(db))
 		admin.PUT("/suppliers/:id", handlers.UpdateSupplier(db))
 		admin.DELETE("/suppliers/:id", handlers.DeleteSupplier(db))
+		admin.POST("/suppliers/:id/webhook-secret", handlers.RotateSupplierWebhookSecret(webhookService))
+		admin.POST("/suppliers/:id/webhook-test", handlers.SendSupplierWebhookTest(webhookService))
 
 		// 门店管理
 		admin.GET("/stores", handlers.GetStores(db))
@@ -73,6 +78,14 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		admin.POST("/price-markups", handlers.CreatePriceMarkup(db))
 		admin.PUT("/price-markups/:id", handlers.UpdatePriceMarkup(db))
 		admin.DELETE("/price-markups/:id", handlers.DeletePriceMarkup(db))
+		admin.POST("/price-markups/simulate", handlers.SimulateMarkup(db))
+		admin.POST("/price-markups/explain", handlers.ExplainMarkup(db))
+		admin.GET("/price-markups/conflicts", handlers.CheckMarkupConflicts(db))
+		admin.GET("/price-markups/export", handlers.ExportPriceMarkups(db))
+		admin.GET("/price-markups/import-template", handlers.GetMarkupImportTemplate(db))
+		admin.POST("/price-markups/import", handlers.ImportPriceMarkups(db))
+		admin.GET("/price-markups/revenue", handlers.GetMarkupRevenue(db))
+		admin.GET("/price-markups/revenue/trend", handlers.GetMarkupRevenueTrend(db))
 
 		// 订单管理
 		admin.GET("/orders", handlers.GetOrdersAdmin(db))
@@ -84,6 +97,75 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		// 系统配置
 		admin.GET("/configs", handlers.GetSystemConfigs(db))
 		admin.PUT("/configs", handlers.UpdateSystemConfig(db))
+
+		// 站内通知
+		admin.GET("/notifications", handlers.GetAdminNotifications(db))
+		admin.GET("/notifications/unread-count", handlers.GetAdminNotificationUnreadCount(db))
+		admin.PUT("/notifications/read-all", handlers.MarkAllAdminNotificationsRead(db))
+		admin.PUT("/notifications/:id/read", handlers.MarkAdminNotificationRead(db))
+
+		// 支付通知
+		admin.GET("/payment-callbacks", handlers.GetPaymentCallbacks(db, paymentProviders))
+		admin.POST("/payment-callbacks/replay-failed", handlers.ReplayFailedPaymentCallbacks(db, paymentProviders))
+		admin.POST("/payment-callbacks/:id/replay", handlers.ReplayPaymentCallback(db, paymentProviders))
+		admin.POST("/payments/:paymentNo/reconcile", handlers.ReconcilePayment(db, paymentProviders))
+
+		// 退款管理
+		admin.GET("/refunds", handlers.GetRefunds(db))
+		admin.POST("/orders/:id/refunds", handlers.CreateOrderRefund(db, paymentProviders))
+		admin.POST("/refunds/:id/retry", handlers.RetryRefund(db, paymentProviders))
+
+		// 支付对账
+		admin.GET("/reconciliations", handlers.GetReconciliations(db))
+		admin.POST("/reconciliations", handlers.RunReconciliation(db, paymentProviders, cfg.Payment.BillDir))
+		admin.GET("/reconciliations/:id", handlers.GetReconciliationReport(db))
+		admin.PUT("/reconciliation-discrepancies/:id/resolve", handlers.ResolveReconciliationDiscrepancy(db))
+
+		// 供应商结算
+		admin.GET("/settlements", handlers.GetSettlementStatements(db))
+		admin.POST("/settlements/generate", handlers.GenerateSettlementStatement(db))
+		admin.GET("/settlements/:id", handlers.GetSettlementStatement(db))
+		admin.GET("/settlements/:id/export", handlers.ExportSettlementStatement(db))
+		admin.POST("/settlements/:id/hold", handlers.HoldSettlementAmount(db))
+		admin.POST("/settlements/:id/release", handlers.ReleaseSettlementAmount(db))
+		admin.POST("/settlements/:id/payout", handlers.PayoutSettlementStatement(db))
+		admin.POST("/settlement-adjustments", handlers.CreateSettlementAdjustment(db))
+		admin.GET("/suppliers/:id/ledger", handlers.GetSupplierLedger(db))
+
+		// 门店赊账
+		admin.GET("/credit-accounts", handlers.GetCreditAccounts(db))
+		admin.GET("/stores/:id/credit", handlers.GetCreditAccount(db))
+		admin.PUT("/stores/:id/credit", handlers.SaveCreditAccount(db))
+		admin.PUT("/stores/:id/credit/status", handlers.UpdateCreditAccountStatus(db))
+		admin.GET("/stores/:id/credit/transactions", handlers.GetCreditTransactions(db))
+		admin.GET("/credit-bills", handlers.GetCreditBills(db))
+		admin.GET("/credit-bills/:id", handlers.GetCreditBill(db))
+		admin.POST("/credit-bills/:id/repay", handlers.RepayCreditBill(db))
+
+		// 服务费规则
+		admin.GET("/service-fee-rules", handlers.GetServiceFeeRules(db))
+		admin.POST("/service-fee-rules", handlers.CreateServiceFeeRule(db))
+		admin.GET("/service-fee-rules/:id", handlers.GetServiceFeeRule(db))
+		admin.PUT("/service-fee-rules/:id", handlers.UpdateServiceFeeRule(db))
+		admin.DELETE("/service-fee-rules/:id", handlers.DeleteServiceFeeRule(db))
+
+		// Webhook 消息模板
+		admin.GET("/webhook-templates", handlers.GetWebhookTemplates(db))
+		admin.POST("/webhook-templates/preview", handlers.PreviewWebhookTemplate(db))
+		admin.PUT("/webhook-templates/:event", handlers.UpdateWebhookTemplate(db))
+		admin.DELETE("/webhook-templates/:event", handlers.ResetWebhookTemplate(db))
+
+		// Webhook 推送日志
+		admin.GET("/webhook-logs", handlers.GetWebhookLogs(webhookLogService))
+		admin.GET("/webhook-logs/health", handlers.GetWebhookHealth(webhookLogService))
+		admin.POST("/webhook-logs/resend", handlers.ResendFailedWebhookLogs(webhookLogService))
+		admin.GET("/webhook-logs/:id", handlers.GetWebhookLog(webhookLogService))
+		admin.POST("/webhook-logs/:id/resend", handlers.ResendWebhookLog(webhookLogService))
+
+		// Webhook 推送地址熔断
+		admin.GET("/webhook-endpoints", handlers.GetWebhookEndpoints(webhookLogService))
+		admin.POST("/webhook-endpoints/:id/reset", handlers.ResetWebhookEndpoint(webhookLogService))
+		admin.POST("/webhook-endpoints/:id/drain", handlers.DrainWebhookEndpoint(webhookLogService))
 	}
 
 	// 供应商路由
@@ -115,12 +197,24 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		supplier.GET("/delivery-areas", handlers.GetDeliveryAreas(db))
 		supplier.POST("/delivery-areas", handlers.CreateDeliveryArea(db))
 		supplier.DELETE("/delivery-areas/:id", handlers.DeleteDeliveryArea(db))
+		supplier.GET("/webhook-status", handlers.GetSupplierWebhookStatus(webhookLogService))
+		supplier.GET("/notifications", handlers.GetSupplierNotifications(db))
+		supplier.GET("/notifications/unread-count", handlers.GetSupplierNotificationUnreadCount(db))
+		supplier.PUT("/notifications/read-all", handlers.MarkAllSupplierNotificationsRead(db))
+		supplier.PUT("/notifications/:id/read", handlers.MarkSupplierNotificationRead(db))
 
 		// 统计分析
 		supplier.GET("/stats/overview", handlers.GetSupplierStats(db))
 		supplier.GET("/stats/orders", handlers.GetSupplierOrderStats(db))
 		supplier.GET("/stats/materials", handlers.GetSupplierMaterialStats(db))
 
+		// 结算对账
+		supplier.GET("/settlements", handlers.GetSettlementStatements(db))
+		supplier.GET("/settlements/:id", handlers.GetSettlementStatement(db))
+		supplier.GET("/settlements/:id/export", handlers.ExportSettlementStatement(db))
+		supplier.PUT("/settlements/:id/confirm", handlers.ConfirmSettlementStatement(db))
+		supplier.GET("/ledger", handlers.GetSupplierLedger(db))
+
 		// 打印功能
 		printHandler := handlers.NewPrintHandler(db)
 		supplier.GET("/print/orders", printHandler.GetOrdersForPrint)
@@ -142,15 +236,23 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		// 购物车
 		store.GET("/cart", handlers.GetCart(redis))
 		store.POST("/cart", handlers.AddToCart(redis, db))
-		store.PUT("/cart/:skuId", handlers.UpdateCartItem(redis))
+		store.PUT("/cart/:skuId", handlers.UpdateCartItem(redis, db))
 		store.DELETE("/cart/:skuId", handlers.RemoveFromCart(redis))
 		store.DELETE("/cart", handlers.ClearCart(redis))
+		store.GET("/cart/summary", handlers.GetCartSummary(db, redis))
 
 		// 订单管理
 		store.POST("/orders", handlers.CreateOrder(db, redis))
+		store.POST("/checkouts", handlers.CreateCheckout(db, redis))
+		store.GET("/checkouts/:id", handlers.GetCheckoutDetail(db))
 		store.GET("/orders", handlers.GetOrdersStore(db))
 		store.GET("/orders/:id", handlers.GetOrderDetailStore(db))
 		store.POST("/orders/:id/cancel", handlers.CancelOrder(db))
+		store.GET("/webhook-status", handlers.GetStoreWebhookStatus(webhookLogService))
+		store.GET("/notifications", handlers.GetStoreNotifications(db))
+		store.GET("/notifications/unread-count", handlers.GetStoreNotificationUnreadCount(db))
+		store.PUT("/notifications/read-all", handlers.MarkAllStoreNotificationsRead(db))
+		store.PUT("/notifications/:id/read", handlers.MarkStoreNotificationRead(db))
 		store.POST("/orders/:id/cancel-request", handlers.SubmitCancelRequest(db))
 		store.GET("/orders/:id/cancel-request", handlers.GetCancelRequestStatus(db))
 		store.POST("/orders/:id/reorder", handlers.ReorderItems(db, redis))
@@ -160,6 +262,12 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 		store.POST("/payment/qrcode", handlers.GeneratePaymentQRCode(db))
 		store.GET("/payment/:paymentNo/status", handlers.GetPaymentStatus(db))
 
+		// 赊账
+		store.GET("/credit", handlers.GetCreditAccount(db))
+		store.GET("/credit/bills", handlers.GetCreditBills(db))
+		store.GET("/credit/bills/:id", handlers.GetCreditBill(db))
+		store.GET("/credit/transactions", handlers.GetCreditTransactions(db))
+
 		// 市场行情
 		store.GET("/market/prices", handlers.GetMarketPrices(db))
 		store.GET("/market/compare", handlers.ComparePrices(db))
@@ -182,16 +290,24 @@ func RegisterRoutes(e *echo.Echo, db *gorm.DB, redis *redis.Client, logger *zap.
 	authenticated.POST("/upload/excel", handlers.UploadExcel())
 
 	// 支付回调（无需认证）
-	paymentHandler := handlers.NewPaymentHandler(db, cfg)
+	paymentHandler := handlers.NewPaymentHandler(db, paymentProviders)
 	payments := api.Group("/payments")
 	{
 		payments.POST("/callback/:method", paymentHandler.PaymentCallback)
+		payments.POST("/refund-callback/:method", paymentHandler.RefundCallback)
+	}
+
+	// 模拟支付（仅联调/离线测试环境启用）
+	if cfg.Payment.MockEnabled {
+		payments.POST("/mock/:paymentNo/pay", paymentHandler.MockPay)
+		payments.POST("/mock/failures", paymentHandler.MockFailNext)
 	}
 
 	// 门店支付接口 (覆盖原有简化实现)
 	storePayments := authenticated.Group("/store/payments", middleware.RequireRole("store"))
 	{
 		storePayments.POST("", paymentHandler.CreatePayment)
+		storePayments.POST("/checkout", paymentHandler.CreateCheckoutPayment)
 		storePayments.GET("/:paymentNo/status", paymentHandler.GetPaymentStatus)
 		storePayments.POST("/:paymentNo/refresh", paymentHandler.RefreshQRCode)
 		storePayments.POST("/switch-method", paymentHandler.SwitchPaymentMethod)
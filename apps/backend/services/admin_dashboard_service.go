@@ -1,9 +1,12 @@
 package services
 
 import (
+	"errors"
 	"time"
 
+	"github.com/project/backend/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AdminDashboardService 管理员看板服务
@@ -203,30 +206,7 @@ func (s *AdminDashboardService) GetPendingCancelRequests(page, pageSize int) ([]
 
 // ApproveCancelRequest 批准取消申请
 func (s *AdminDashboardService) ApproveCancelRequest(requestID uint64, auditorID uint64) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 更新申请状态
-		if err := tx.Table("order_cancel_requests").
-			Where("id = ?", requestID).
-			Updates(map[string]interface{}{
-				"status":     "approved",
-				"audited_by": auditorID,
-				"audited_at": time.Now(),
-			}).Error; err != nil {
-			return err
-		}
-
-		// 获取订单ID
-		var orderID uint64
-		tx.Table("order_cancel_requests").
-			Select("order_id").
-			Where("id = ?", requestID).
-			Scan(&orderID)
-
-		// 更新订单状态为已取消
-		return tx.Table("orders").
-			Where("id = ?", orderID).
-			Update("status", "cancelled").Error
-	})
+	return NewOrderCancelService(s.db).ApproveCancelRequest(requestID, auditorID, "")
 }
 
 // RejectCancelRequest 拒绝取消申请
@@ -241,60 +221,73 @@ func (s *AdminDashboardService) RejectCancelRequest(requestID uint64, reason str
 		}).Error
 }
 
+// ErrOrderRestoreHasPayment 订单已有支付或退款，不能恢复
+var ErrOrderRestoreHasPayment = errors.New("订单已有支付或退款记录，不能恢复")
+
 // RestoreOrder 恢复已取消的订单
+// 退款按订单累计，已支付或退款过的订单恢复后再次支付将无法退款，因此只恢复未支付的订单，并清除原支付方式和支付单号
 func (s *AdminDashboardService) RestoreOrder(orderID uint64, reason string, operatorID uint64) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 更新订单状态为待付款
-		if err := tx.Table("orders").
-			Where("id = ? AND status = ?", orderID, "cancelled").
-			Updates(map[string]interface{}{
-				"status":          "pending_payment",
-				"restored_at":     time.Now(),
-				"restored_by":     operatorID,
-				"restore_reason":  reason,
-			}).Error; err != nil {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
 			return err
 		}
 
-		// 记录操作日志
-		return tx.Table("order_status_logs").Create(map[string]interface{}{
-			"order_id":    orderID,
-			"from_status": "cancelled",
-			"to_status":   "pending_payment",
-			"operator_id": operatorID,
-			"remark":      "订单恢复: " + reason,
-			"created_at":  time.Now(),
-		}).Error
+		var paid, refunds int64
+		if err := orderPaymentScope(tx.Model(&PaymentRecord{}), order.ID).
+			Where("status IN ?", []PaymentStatus{PaymentStatusSuccess, PaymentStatusRefunded, PaymentStatusPartialRefund}).
+			Count(&paid).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Refund{}).Where("order_id = ?", order.ID).Count(&refunds).Error; err != nil {
+			return err
+		}
+		if err := checkOrderRestorable(&order, paid+refunds); err != nil {
+			return err
+		}
+
+		_, err := NewOrderStateMachine(s.db).TransitionTx(tx, &OrderTransition{
+			OrderID:      orderID,
+			From:         []models.OrderStatus{models.OrderStatusCancelled},
+			To:           models.OrderStatusPendingPayment,
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   operatorID,
+			Remark:       "订单恢复: " + reason,
+			Extra: map[string]interface{}{
+				"cancel_reason":  nil,
+				"payment_status": models.PaymentStatusUnpaid,
+				"payment_method": nil,
+				"payment_no":     nil,
+				"payment_time":   nil,
+			},
+		})
+		return err
 	})
 }
 
+// checkOrderRestorable 检查订单能否恢复，paidOrRefunded 为已支付的支付记录和退款单数量
+func checkOrderRestorable(order *models.Order, paidOrRefunded int64) error {
+	if order.PaymentStatus != "" && order.PaymentStatus != models.PaymentStatusUnpaid {
+		return ErrOrderRestoreHasPayment
+	}
+	if paidOrRefunded > 0 {
+		return ErrOrderRestoreHasPayment
+	}
+	return nil
+}
+
 // AdminCancelOrder 管理员直接取消订单
 func (s *AdminDashboardService) AdminCancelOrder(orderID uint64, reason string, operatorID uint64) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 获取当前状态
-		var currentStatus string
-		tx.Table("orders").Select("status").Where("id = ?", orderID).Scan(&currentStatus)
-
-		// 更新订单状态
-		if err := tx.Table("orders").
-			Where("id = ?", orderID).
-			Updates(map[string]interface{}{
-				"status":        "cancelled",
-				"cancelled_at":  time.Now(),
-				"cancelled_by":  operatorID,
-				"cancel_reason": reason,
-			}).Error; err != nil {
-			return err
-		}
-
-		// 记录操作日志
-		return tx.Table("order_status_logs").Create(map[string]interface{}{
-			"order_id":    orderID,
-			"from_status": currentStatus,
-			"to_status":   "cancelled",
-			"operator_id": operatorID,
-			"remark":      "管理员取消: " + reason,
-			"created_at":  time.Now(),
-		}).Error
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
+		OrderID:      orderID,
+		To:           models.OrderStatusCancelled,
+		OperatorType: models.OperatorTypeAdmin,
+		OperatorID:   operatorID,
+		Remark:       "管理员取消: " + reason,
+		Extra:        map[string]interface{}{"cancel_reason": reason},
 	})
+	return err
 }
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestCheckOrderRestorable(t *testing.T) {
+	tests := []struct {
+		name           string
+		paymentStatus  models.PaymentStatus
+		paidOrRefunded int64
+		expectedErr    error
+	}{
+		{"unpaid", models.PaymentStatusUnpaid, 0, nil},
+		{"empty payment status", "", 0, nil},
+		{"paid", models.PaymentStatusPaid, 1, ErrOrderRestoreHasPayment},
+		{"refunded", models.PaymentStatusRefunded, 1, ErrOrderRestoreHasPayment},
+		{"partial refund", models.PaymentStatusPartialRefund, 1, ErrOrderRestoreHasPayment},
+		{"unpaid with paid payment record", models.PaymentStatusUnpaid, 1, ErrOrderRestoreHasPayment},
+		{"unpaid with refund", models.PaymentStatusUnpaid, 2, ErrOrderRestoreHasPayment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{Status: models.OrderStatusCancelled, PaymentStatus: tt.paymentStatus}
+			if err := checkOrderRestorable(order, tt.paidOrRefunded); err != tt.expectedErr {
+				t.Errorf("checkOrderRestorable() error = %v, expected %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
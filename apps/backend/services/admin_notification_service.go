@@ -0,0 +1,87 @@
+package services
+
+import (
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// AdminNotificationService 管理员站内通知服务
+type AdminNotificationService struct {
+	db *gorm.DB
+}
+
+// NewAdminNotificationService 创建管理员通知服务
+func NewAdminNotificationService(db *gorm.DB) *AdminNotificationService {
+	return &AdminNotificationService{db: db}
+}
+
+// Notify 发送通知
+func (s *AdminNotificationService) Notify(notification *models.AdminNotification) error {
+	return s.db.Create(notification).Error
+}
+
+// HasOrderNotification 订单是否已发送过该类型通知
+func (s *AdminNotificationService) HasOrderNotification(notificationType models.AdminNotificationType, orderID uint64) bool {
+	var count int64
+	s.db.Model(&models.AdminNotification{}).
+		Where("type = ? AND related_type = ? AND related_id = ?", notificationType, "order", orderID).
+		Count(&count)
+	return count > 0
+}
+
+// List 获取通知列表
+func (s *AdminNotificationService) List(page, pageSize int, unreadOnly bool) ([]models.AdminNotification, int64, error) {
+	var notifications []models.AdminNotification
+	var total int64
+
+	query := s.db.Model(&models.AdminNotification{})
+	if unreadOnly {
+		query = query.Where("is_read = ?", 0)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// UnreadCount 未读数量
+func (s *AdminNotificationService) UnreadCount() int64 {
+	var count int64
+	s.db.Model(&models.AdminNotification{}).Where("is_read = ?", 0).Count(&count)
+	return count
+}
+
+// MarkRead 标记已读
+func (s *AdminNotificationService) MarkRead(id, adminID uint64) error {
+	return s.db.Model(&models.AdminNotification{}).
+		Where("id = ? AND is_read = ?", id, 0).
+		Updates(map[string]interface{}{
+			"is_read": 1,
+			"read_by": adminID,
+			"read_at": time.Now(),
+		}).Error
+}
+
+// MarkAllRead 全部标记已读
+func (s *AdminNotificationService) MarkAllRead(adminID uint64) error {
+	return s.db.Model(&models.AdminNotification{}).
+		Where("is_read = ?", 0).
+		Updates(map[string]interface{}{
+			"is_read": 1,
+			"read_by": adminID,
+			"read_at": time.Now(),
+		}).Error
+}
@@ -12,6 +12,7 @@ import (
 // AlipayService 支付宝服务
 type AlipayService struct {
 	client    *alipay.Client
+	appID     string
 	notifyURL string
 	returnURL string
 }
@@ -35,6 +36,8 @@ type AlipayPaymentResponse struct {
 type AlipayNotification struct {
 	TradeNo     string // 支付宝交易号
 	OutTradeNo  string // 商户订单号
+	AppID       string // 应用ID
+	SellerID    string // 卖家支付宝用户号
 	TradeStatus string // 交易状态: WAIT_BUYER_PAY, TRADE_CLOSED, TRADE_SUCCESS, TRADE_FINISHED
 	TotalAmount string // 交易金额
 	BuyerID     string // 买家支付宝用户号
@@ -84,6 +87,7 @@ func NewAlipayService(cfg *config.AlipayConfig) (*AlipayService, error) {
 
 	return &AlipayService{
 		client:    client,
+		appID:     cfg.AppID,
 		notifyURL: cfg.NotifyURL,
 		returnURL: cfg.ReturnURL,
 	}, nil
@@ -178,6 +182,8 @@ func (s *AlipayService) VerifyCallback(req *http.Request) (*AlipayNotification,
 	notification := &AlipayNotification{
 		TradeNo:     req.Form.Get("trade_no"),
 		OutTradeNo:  req.Form.Get("out_trade_no"),
+		AppID:       req.Form.Get("app_id"),
+		SellerID:    req.Form.Get("seller_id"),
 		TradeStatus: req.Form.Get("trade_status"),
 		TotalAmount: req.Form.Get("total_amount"),
 		BuyerID:     req.Form.Get("buyer_id"),
@@ -253,6 +259,11 @@ func (s *AlipayService) CloseOrder(ctx context.Context, orderNo string) error {
 	return nil
 }
 
+// AppID 应用ID
+func (s *AlipayService) AppID() string {
+	return s.appID
+}
+
 // IsPaymentSuccess 判断支付是否成功
 func (n *AlipayNotification) IsPaymentSuccess() bool {
 	return n.TradeStatus == "TRADE_SUCCESS" || n.TradeStatus == "TRADE_FINISHED"
@@ -3,22 +3,36 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/project/backend/models"
 	"github.com/redis/go-redis/v9"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
+// ErrCartItemNotFound is returned when updating an item that is not in the cart
+var ErrCartItemNotFound = errors.New("cart item not found")
+
+// cartLineChecker validates a cart line against the supplier's quote
+type cartLineChecker interface {
+	CheckLine(supplierID, materialSkuID uint64, quantity int) (*models.SupplierMaterial, error)
+}
+
 // CartService handles shopping cart operations using Redis
 type CartService struct {
 	redis *redis.Client
+	lines cartLineChecker
 }
 
 // NewCartService creates a new cart service instance
-func NewCartService(redisClient *redis.Client) *CartService {
+// db is used to validate items against supplier quotes before they are saved
+func NewCartService(redisClient *redis.Client, db *gorm.DB) *CartService {
 	return &CartService{
 		redis: redisClient,
+		lines: NewOrderLineService(db),
 	}
 }
 
@@ -77,6 +91,11 @@ func (s *CartService) AddToCart(ctx context.Context, storeID, supplierID, skuID
 		}
 	}
 
+	// Validate supplier, availability and min/step quantity on the resulting quantity
+	if _, err := s.lines.CheckLine(supplierID, skuID, item.Quantity); err != nil {
+		return err
+	}
+
 	// Serialize and save
 	data, err := json.Marshal(item)
 	if err != nil {
@@ -103,7 +122,7 @@ func (s *CartService) UpdateQuantity(ctx context.Context, storeID, supplierID, s
 	// Get existing item
 	existing, err := s.redis.HGet(ctx, key, field).Result()
 	if err == redis.Nil {
-		return fmt.Errorf("cart item not found")
+		return ErrCartItemNotFound
 	}
 	if err != nil {
 		return fmt.Errorf("failed to get cart item: %w", err)
@@ -123,6 +142,10 @@ func (s *CartService) UpdateQuantity(ctx context.Context, storeID, supplierID, s
 		return s.RemoveItem(ctx, storeID, supplierID, skuID)
 	}
 
+	if _, err := s.lines.CheckLine(supplierID, skuID, quantity); err != nil {
+		return err
+	}
+
 	// Serialize and save
 	data, err := json.Marshal(item)
 	if err != nil {
@@ -141,6 +164,38 @@ func (s *CartService) UpdateQuantity(ctx context.Context, storeID, supplierID, s
 	return nil
 }
 
+// UpdatePrice updates the price of a cart item, keeping its quantity
+func (s *CartService) UpdatePrice(ctx context.Context, storeID, supplierID, skuID uint64, price decimal.Decimal) error {
+	key := cartKey(storeID, supplierID)
+	field := fmt.Sprintf("%d", skuID)
+
+	existing, err := s.redis.HGet(ctx, key, field).Result()
+	if err == redis.Nil {
+		return ErrCartItemNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get cart item: %w", err)
+	}
+
+	var item CartItem
+	if err := json.Unmarshal([]byte(existing), &item); err != nil {
+		return fmt.Errorf("failed to unmarshal cart item: %w", err)
+	}
+	item.Price = price
+	item.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cart item: %w", err)
+	}
+
+	if err := s.redis.HSet(ctx, key, field, string(data)).Err(); err != nil {
+		return fmt.Errorf("failed to save cart item: %w", err)
+	}
+
+	return nil
+}
+
 // RemoveItem removes an item from the cart
 func (s *CartService) RemoveItem(ctx context.Context, storeID, supplierID, skuID uint64) error {
 	key := cartKey(storeID, supplierID)
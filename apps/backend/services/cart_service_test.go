@@ -0,0 +1,226 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/project/backend/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+// fakeRedis 只实现购物车用到的哈希命令的内存 Redis
+type fakeRedis struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+func startFakeRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &fakeRedis{hashes: make(map[string]map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), Protocol: 2, DisableIndentity: true})
+	t.Cleanup(func() {
+		client.Close()
+		ln.Close()
+	})
+	return client
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		reply := f.exec(args)
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	array := func(items []string) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(items))
+		for _, item := range items {
+			b.WriteString(bulk(item))
+		}
+		return b.String()
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "HSET":
+		hash := f.hashes[args[1]]
+		if hash == nil {
+			hash = make(map[string]string)
+			f.hashes[args[1]] = hash
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			hash[args[i]] = args[i+1]
+		}
+		return ":1\r\n"
+	case "HGET":
+		value, ok := f.hashes[args[1]][args[2]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "HGETALL":
+		var items []string
+		for field, value := range f.hashes[args[1]] {
+			items = append(items, field, value)
+		}
+		return array(items)
+	case "HDEL":
+		delete(f.hashes[args[1]], args[2])
+		return ":1\r\n"
+	case "DEL":
+		delete(f.hashes, args[1])
+		return ":1\r\n"
+	case "KEYS":
+		var keys []string
+		for key, hash := range f.hashes {
+			if ok, _ := path.Match(args[1], key); ok && len(hash) > 0 {
+				keys = append(keys, key)
+			}
+		}
+		return array(keys)
+	case "EXPIRE":
+		return ":1\r\n"
+	case "HELLO":
+		return "-ERR unknown command 'HELLO'\r\n"
+	}
+	return "+OK\r\n"
+}
+
+// fakeLineChecker 按供应商和SKU放行购物车商品
+type fakeLineChecker map[uint64][]uint64
+
+func (f fakeLineChecker) CheckLine(supplierID, materialSkuID uint64, quantity int) (*models.SupplierMaterial, error) {
+	for _, skuID := range f[supplierID] {
+		if skuID == materialSkuID {
+			return &models.SupplierMaterial{SupplierID: supplierID, MaterialSkuID: materialSkuID}, nil
+		}
+	}
+	return nil, &OrderLineRejectedError{}
+}
+
+func TestCartAddThenCheckout(t *testing.T) {
+	ctx := context.Background()
+	cart := &CartService{
+		redis: startFakeRedis(t),
+		lines: fakeLineChecker{10: {100, 101}, 20: {200}},
+	}
+	checkout := &CheckoutService{cartService: cart}
+
+	if _, err := checkout.checkoutCarts(ctx, 1, nil); err != ErrCartEmpty {
+		t.Fatalf("checkoutCarts() on empty cart error = %v, expected ErrCartEmpty", err)
+	}
+
+	adds := []struct {
+		supplierID, skuID uint64
+		quantity          int
+		price             string
+	}{
+		{20, 200, 1, "8"},
+		{10, 100, 2, "12.5"},
+		{10, 100, 3, "13"},
+		{10, 101, 1, "6"},
+	}
+	for _, add := range adds {
+		if err := cart.AddToCart(ctx, 1, add.supplierID, add.skuID, add.quantity, decimal.RequireFromString(add.price)); err != nil {
+			t.Fatalf("AddToCart(%d, %d) error = %v", add.supplierID, add.skuID, err)
+		}
+	}
+	if err := cart.AddToCart(ctx, 1, 20, 100, 1, decimal.NewFromInt(1)); err == nil {
+		t.Error("AddToCart() accepted a SKU the supplier does not supply")
+	}
+	// 其他门店的购物车不参与结算
+	if err := cart.AddToCart(ctx, 2, 10, 100, 1, decimal.NewFromInt(1)); err != nil {
+		t.Fatalf("AddToCart() for other store error = %v", err)
+	}
+
+	carts, err := checkout.checkoutCarts(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("checkoutCarts() error = %v", err)
+	}
+	if len(carts) != 2 || carts[0].SupplierID != 10 || carts[1].SupplierID != 20 {
+		t.Fatalf("checkoutCarts() = %+v, expected suppliers 10 and 20", carts)
+	}
+	quantities := make(map[uint64]int)
+	for _, item := range carts[0].Items {
+		quantities[item.MaterialSkuID] = item.Quantity
+		if item.MaterialSkuID == 100 && !item.Price.Equal(decimal.NewFromInt(13)) {
+			t.Errorf("sku 100 price = %v, expected latest price 13", item.Price)
+		}
+	}
+	if quantities[100] != 5 || quantities[101] != 1 {
+		t.Errorf("supplier 10 quantities = %v, expected sku 100 x5 and sku 101 x1", quantities)
+	}
+
+	selected, err := checkout.checkoutCarts(ctx, 1, []uint64{20})
+	if err != nil || len(selected) != 1 || selected[0].SupplierID != 20 {
+		t.Errorf("checkoutCarts(supplier 20) = %+v, %v", selected, err)
+	}
+
+	if err := cart.UpdateQuantity(ctx, 1, 20, 200, 0); err != nil {
+		t.Fatalf("UpdateQuantity() to zero error = %v", err)
+	}
+	if _, err := checkout.checkoutCarts(ctx, 1, []uint64{20}); err != ErrCartEmpty {
+		t.Errorf("checkoutCarts() after removing the only item error = %v, expected ErrCartEmpty", err)
+	}
+	if err := cart.UpdateQuantity(ctx, 1, 20, 200, 1); err != ErrCartItemNotFound {
+		t.Errorf("UpdateQuantity() on missing item error = %v, expected ErrCartItemNotFound", err)
+	}
+}
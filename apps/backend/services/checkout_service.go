@@ -0,0 +1,635 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CheckoutService 购物车结算服务
+// 将门店购物车按供应商拆分为多个子订单，挂在同一个结算单下，支持合并支付
+type CheckoutService struct {
+	db             *gorm.DB
+	cartService    *CartService
+	pricingService *OrderPricingService
+}
+
+// NewCheckoutService 创建结算服务，仅处理支付时 redisClient 可传 nil
+func NewCheckoutService(db *gorm.DB, redisClient *redis.Client) *CheckoutService {
+	return &CheckoutService{
+		db:             db,
+		cartService:    NewCartService(redisClient, db),
+		pricingService: NewOrderPricingService(db),
+	}
+}
+
+var (
+	// ErrCartEmpty 购物车为空
+	ErrCartEmpty = errors.New("购物车为空")
+	// ErrCheckoutNotFound 结算单不存在
+	ErrCheckoutNotFound = errors.New("结算单不存在")
+	// ErrCheckoutNotPayable 结算单没有待支付的订单
+	ErrCheckoutNotPayable = errors.New("结算单没有待支付的订单")
+)
+
+// CheckoutRequest 结算请求
+type CheckoutRequest struct {
+	StoreID      uint64
+	SupplierIDs  []uint64          // 为空时结算整个购物车
+	Remarks      map[uint64]string // 按供应商填写的备注
+	DeliveryInfo DeliveryInfo
+	OrderSource  models.OrderSource
+	Credit       bool // 使用门店赊账额度支付，子订单直接进入待确认
+}
+
+// CheckoutIssue 某个供应商订单不满足下单条件的原因，Reason 取值见 DeliveryReason* 和 LineReason*
+type CheckoutIssue struct {
+	SupplierID     uint64  `json:"supplierId"`
+	SupplierName   string  `json:"supplierName"`
+	Reason         string  `json:"reason"`
+	Message        string  `json:"message"`
+	MaterialSkuID  uint64  `json:"materialSkuId,omitempty"`
+	Quantity       int     `json:"quantity,omitempty"`
+	MinQuantity    int     `json:"minQuantity,omitempty"`
+	StepQuantity   int     `json:"stepQuantity,omitempty"`
+	MinOrderAmount float64 `json:"minOrderAmount,omitempty"`
+	GoodsAmount    float64 `json:"goodsAmount,omitempty"`
+	Shortfall      float64 `json:"shortfall,omitempty"`
+}
+
+// CheckoutRejectedError 结算校验不通过
+type CheckoutRejectedError struct {
+	Issues []CheckoutIssue `json:"issues"`
+}
+
+func (e *CheckoutRejectedError) Error() string {
+	return fmt.Sprintf("%d个供应商订单不满足下单条件", len(e.Issues))
+}
+
+// CheckoutResult 结算结果
+type CheckoutResult struct {
+	Checkout *models.Checkout `json:"checkout"`
+	Orders   []*models.Order  `json:"orders"`
+}
+
+// supplierCheckout 单个供应商的结算数据
+type supplierCheckout struct {
+	supplier             *models.Supplier
+	pricing              *OrderPricing
+	serviceFee           *ServiceFeeQuote
+	expectedDeliveryDate time.Time
+}
+
+// Checkout 结算购物车，所有供应商均校验通过才会下单
+// 购物车价格与服务端价格不一致时刷新购物车价格并返回 *PriceChangedError
+func (s *CheckoutService) Checkout(ctx context.Context, req *CheckoutRequest) (*CheckoutResult, error) {
+	var store models.Store
+	if err := s.db.First(&store, req.StoreID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+	delivery := ResolveDeliveryInfo(&store, req.DeliveryInfo)
+	rules := NewDeliveryRuleService(s.db)
+	lines := NewOrderLineService(s.db)
+	fees := NewServiceFeeService(s.db)
+	now := time.Now()
+
+	carts, err := s.checkoutCarts(ctx, req.StoreID, req.SupplierIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []CheckoutIssue
+	var changes []PriceChange
+	groups := make([]supplierCheckout, 0, len(carts))
+
+	for _, cart := range carts {
+		var supplier models.Supplier
+		if err := s.db.First(&supplier, cart.SupplierID).Error; err != nil || !supplier.IsActive() {
+			issues = append(issues, CheckoutIssue{
+				SupplierID: cart.SupplierID,
+				Reason:     DeliveryReasonSupplierUnavailable,
+				Message:    "供应商不存在或已停用",
+			})
+			continue
+		}
+
+		inputs := make([]PricingItemInput, 0, len(cart.Items))
+		orderLines := make([]OrderLine, 0, len(cart.Items))
+		for _, item := range cart.Items {
+			inputs = append(inputs, PricingItemInput{
+				MaterialSkuID: item.MaterialSkuID,
+				Quantity:      item.Quantity,
+				FinalPrice:    item.Price.InexactFloat64(),
+			})
+			orderLines = append(orderLines, OrderLine{MaterialSkuID: item.MaterialSkuID, Quantity: item.Quantity})
+		}
+
+		if _, err := lines.CheckLines(supplier.ID, orderLines); err != nil {
+			var rejected *OrderLineRejectedError
+			if !errors.As(err, &rejected) {
+				return nil, err
+			}
+			for _, issue := range rejected.Issues {
+				issues = append(issues, CheckoutIssue{
+					SupplierID:    supplier.ID,
+					SupplierName:  supplier.Name,
+					Reason:        issue.Reason,
+					Message:       issue.Message,
+					MaterialSkuID: issue.MaterialSkuID,
+					Quantity:      issue.Quantity,
+					MinQuantity:   issue.MinQuantity,
+					StepQuantity:  issue.StepQuantity,
+				})
+			}
+			continue
+		}
+
+		pricing, err := s.pricingService.PriceItems(req.StoreID, supplier.ID, inputs)
+		if err != nil {
+			var notSupplied *SkuNotSuppliedError
+			if errors.As(err, &notSupplied) {
+				issues = append(issues, CheckoutIssue{
+					SupplierID:    supplier.ID,
+					SupplierName:  supplier.Name,
+					Reason:        LineReasonSkuNotSupplied,
+					Message:       notSupplied.Error(),
+					MaterialSkuID: notSupplied.MaterialSkuID,
+				})
+				continue
+			}
+			return nil, err
+		}
+
+		changes = append(changes, s.refreshCartPrices(ctx, req.StoreID, supplier.ID, inputs, pricing.Items)...)
+
+		check, err := rules.Check(&supplier, delivery, pricing.GoodsAmount, now)
+		if err != nil {
+			var rejected *DeliveryRejectedError
+			if !errors.As(err, &rejected) {
+				return nil, err
+			}
+			for _, v := range rejected.Violations {
+				issues = append(issues, CheckoutIssue{
+					SupplierID:     supplier.ID,
+					SupplierName:   supplier.Name,
+					Reason:         v.Reason,
+					Message:        v.Message,
+					MinOrderAmount: v.MinOrderAmount,
+					GoodsAmount:    v.GoodsAmount,
+					Shortfall:      v.Shortfall,
+				})
+			}
+			continue
+		}
+
+		serviceFee, err := fees.Calculate(req.StoreID, supplier.ID, pricing.Items, now)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, supplierCheckout{
+			supplier:             &supplier,
+			pricing:              pricing,
+			serviceFee:           serviceFee,
+			expectedDeliveryDate: check.ExpectedDeliveryDate,
+		})
+	}
+
+	if len(issues) > 0 {
+		return nil, &CheckoutRejectedError{Issues: issues}
+	}
+	if len(changes) > 0 {
+		return nil, &PriceChangedError{Changes: changes}
+	}
+
+	result, err := s.createOrders(req, delivery, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		s.cartService.ClearCart(ctx, req.StoreID, group.supplier.ID)
+	}
+	return result, nil
+}
+
+// createOrders 在同一事务中创建结算单和各供应商子订单
+func (s *CheckoutService) createOrders(req *CheckoutRequest, delivery DeliveryInfo, groups []supplierCheckout) (*CheckoutResult, error) {
+	orderSource := req.OrderSource
+	if orderSource == "" {
+		orderSource = models.OrderSourceApp
+	}
+
+	result := &CheckoutResult{Orders: make([]*models.Order, 0, len(groups))}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		goodsAmount := decimal.Zero
+		serviceFee := decimal.Zero
+		for _, group := range groups {
+			goodsAmount = goodsAmount.Add(decimal.NewFromFloat(group.pricing.GoodsAmount))
+			serviceFee = serviceFee.Add(decimal.NewFromFloat(group.serviceFee.Fee))
+		}
+
+		checkout := &models.Checkout{
+			StoreID:     req.StoreID,
+			OrderCount:  len(groups),
+			GoodsAmount: goodsAmount.InexactFloat64(),
+			ServiceFee:  serviceFee.InexactFloat64(),
+			TotalAmount: goodsAmount.Add(serviceFee).InexactFloat64(),
+			Status:      models.CheckoutStatusPendingPayment,
+		}
+		if err := tx.Create(checkout).Error; err != nil {
+			return err
+		}
+		result.Checkout = checkout
+
+		for _, group := range groups {
+			pricing := group.pricing
+			goods := decimal.NewFromFloat(pricing.GoodsAmount)
+			fee := decimal.NewFromFloat(group.serviceFee.Fee)
+			order := &models.Order{
+				StoreID:          req.StoreID,
+				SupplierID:       group.supplier.ID,
+				CheckoutID:       &checkout.ID,
+				GoodsAmount:      pricing.GoodsAmount,
+				ServiceFee:       group.serviceFee.Fee,
+				TotalAmount:      goods.Add(fee).InexactFloat64(),
+				SupplierAmount:   goods.Sub(decimal.NewFromFloat(pricing.MarkupTotal)).InexactFloat64(),
+				MarkupTotal:      pricing.MarkupTotal,
+				ItemCount:        len(pricing.Items),
+				Status:           models.OrderStatusPendingPayment,
+				PaymentStatus:    models.PaymentStatusUnpaid,
+				OrderSource:      orderSource,
+				DeliveryProvince: &delivery.Province,
+				DeliveryCity:     &delivery.City,
+				DeliveryDistrict: &delivery.District,
+				DeliveryAddress:  &delivery.Address,
+				DeliveryContact:  &delivery.Contact,
+				DeliveryPhone:    &delivery.Phone,
+			}
+			order.ServiceFeeRuleID = group.serviceFee.RuleID
+			order.ServiceFeeRuleName = group.serviceFee.RuleName
+			expectedDeliveryDate := group.expectedDeliveryDate
+			order.ExpectedDeliveryDate = &expectedDeliveryDate
+			if remark := req.Remarks[group.supplier.ID]; remark != "" {
+				order.Remark = &remark
+			}
+			if err := tx.Create(order).Error; err != nil {
+				return err
+			}
+
+			for _, item := range pricing.Items {
+				sku := item.Sku
+				orderItem := &models.OrderItem{
+					OrderID:       order.ID,
+					MaterialSkuID: item.MaterialSkuID,
+					MaterialName:  sku.Material.Name,
+					Brand:         sku.Brand,
+					Spec:          sku.Spec,
+					Unit:          sku.Unit,
+					ImageURL:      sku.ImageURL,
+					Quantity:      item.Quantity,
+					UnitPrice:     item.UnitPrice,
+					MarkupAmount:  item.MarkupAmount,
+					MarkupRuleID:  item.MarkupRuleID,
+					FinalPrice:    item.FinalPrice,
+					Subtotal:      item.Subtotal,
+				}
+				if err := tx.Create(orderItem).Error; err != nil {
+					return err
+				}
+			}
+			result.Orders = append(result.Orders, order)
+		}
+
+		if !req.Credit {
+			return nil
+		}
+		if err := NewCreditService(s.db).ChargeOrdersTx(tx, req.StoreID, result.Orders); err != nil {
+			return err
+		}
+		now := time.Now()
+		method := models.PaymentMethodCredit
+		checkout.Status = models.CheckoutStatusPaid
+		checkout.PaymentMethod = &method
+		checkout.PaymentTime = &now
+		return tx.Model(checkout).Updates(map[string]interface{}{
+			"status":         checkout.Status,
+			"payment_method": method,
+			"payment_time":   now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CartSupplierPreview 购物车中单个供应商的金额及适用的服务费规则
+type CartSupplierPreview struct {
+	SupplierID   uint64           `json:"supplierId"`
+	SupplierName string           `json:"supplierName"`
+	ItemCount    int              `json:"itemCount"`
+	GoodsAmount  float64          `json:"goodsAmount"`
+	ServiceFee   *ServiceFeeQuote `json:"serviceFee,omitempty"`
+	TotalAmount  float64          `json:"totalAmount"`
+	Message      string           `json:"message,omitempty"` // 无法计价的原因
+}
+
+// CartPreview 购物车结算预览
+type CartPreview struct {
+	Suppliers   []CartSupplierPreview `json:"suppliers"`
+	GoodsAmount float64               `json:"goodsAmount"`
+	ServiceFee  float64               `json:"serviceFee"`
+	TotalAmount float64               `json:"totalAmount"`
+}
+
+// Preview 按服务端价格汇总购物车，并计算各供应商订单的服务费；不修改购物车
+func (s *CheckoutService) Preview(ctx context.Context, storeID uint64) (*CartPreview, error) {
+	carts, err := s.cartService.GetAllCarts(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].SupplierID < carts[j].SupplierID })
+
+	fees := NewServiceFeeService(s.db)
+	now := time.Now()
+	preview := &CartPreview{Suppliers: make([]CartSupplierPreview, 0, len(carts))}
+	goodsAmount, serviceFee := decimal.Zero, decimal.Zero
+	for _, cart := range carts {
+		item := CartSupplierPreview{SupplierID: cart.SupplierID, ItemCount: len(cart.Items)}
+
+		var supplier models.Supplier
+		if err := s.db.First(&supplier, cart.SupplierID).Error; err != nil || !supplier.IsActive() {
+			item.Message = "供应商不存在或已停用"
+			preview.Suppliers = append(preview.Suppliers, item)
+			continue
+		}
+		item.SupplierName = supplier.Name
+
+		inputs := make([]PricingItemInput, 0, len(cart.Items))
+		for _, cartItem := range cart.Items {
+			inputs = append(inputs, PricingItemInput{MaterialSkuID: cartItem.MaterialSkuID, Quantity: cartItem.Quantity})
+		}
+		pricing, err := s.pricingService.PriceItems(storeID, supplier.ID, inputs)
+		if err != nil {
+			var notSupplied *SkuNotSuppliedError
+			if !errors.As(err, &notSupplied) {
+				return nil, err
+			}
+			item.Message = notSupplied.Error()
+			preview.Suppliers = append(preview.Suppliers, item)
+			continue
+		}
+
+		quote, err := fees.Calculate(storeID, supplier.ID, pricing.Items, now)
+		if err != nil {
+			return nil, err
+		}
+		goods := decimal.NewFromFloat(pricing.GoodsAmount)
+		fee := decimal.NewFromFloat(quote.Fee)
+		item.GoodsAmount = pricing.GoodsAmount
+		item.ServiceFee = quote
+		item.TotalAmount = goods.Add(fee).InexactFloat64()
+		goodsAmount = goodsAmount.Add(goods)
+		serviceFee = serviceFee.Add(fee)
+		preview.Suppliers = append(preview.Suppliers, item)
+	}
+
+	preview.GoodsAmount = goodsAmount.InexactFloat64()
+	preview.ServiceFee = serviceFee.InexactFloat64()
+	preview.TotalAmount = goodsAmount.Add(serviceFee).InexactFloat64()
+	return preview, nil
+}
+
+// refreshCartPrices 比对购物车价格，有变动时更新购物车并返回变动明细
+func (s *CheckoutService) refreshCartPrices(ctx context.Context, storeID, supplierID uint64, inputs []PricingItemInput, priced []PricedItem) []PriceChange {
+	var changes []PriceChange
+	for i, in := range inputs {
+		if i >= len(priced) {
+			break
+		}
+		p := priced[i]
+		if priceEquals(in.FinalPrice, p.FinalPrice) {
+			continue
+		}
+
+		change := PriceChange{
+			MaterialSkuID:    in.MaterialSkuID,
+			ClientFinalPrice: in.FinalPrice,
+			UnitPrice:        p.UnitPrice,
+			MarkupAmount:     p.MarkupAmount,
+			FinalPrice:       p.FinalPrice,
+		}
+		if p.Sku != nil && p.Sku.Material != nil {
+			change.MaterialName = p.Sku.Material.Name
+		}
+		changes = append(changes, change)
+
+		s.cartService.UpdatePrice(ctx, storeID, supplierID, in.MaterialSkuID, decimal.NewFromFloat(p.FinalPrice))
+	}
+	return changes
+}
+
+// GetCheckout 获取门店的结算单及子订单
+func (s *CheckoutService) GetCheckout(storeID, checkoutID uint64) (*models.Checkout, error) {
+	var checkout models.Checkout
+	err := s.db.Where("id = ? AND store_id = ?", checkoutID, storeID).
+		Preload("Orders", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Orders.Supplier").
+		First(&checkout).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, err
+	}
+	return &checkout, nil
+}
+
+// CombinedPayment 合并支付信息
+type CombinedPayment struct {
+	Checkout *models.Checkout
+	Orders   []models.Order // 待支付的子订单
+	Amount   float64        // 合并支付总金额
+}
+
+// PrepareCombinedPayment 计算结算单下待支付子订单的合并支付金额
+func (s *CheckoutService) PrepareCombinedPayment(storeID, checkoutID uint64) (*CombinedPayment, error) {
+	var checkout models.Checkout
+	if err := s.db.Where("id = ? AND store_id = ?", checkoutID, storeID).First(&checkout).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCheckoutNotFound
+		}
+		return nil, err
+	}
+	if checkout.Status != models.CheckoutStatusPendingPayment {
+		return nil, ErrCheckoutNotPayable
+	}
+
+	var orders []models.Order
+	if err := s.db.Where("checkout_id = ? AND status = ?", checkout.ID, models.OrderStatusPendingPayment).
+		Order("id ASC").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrCheckoutNotPayable
+	}
+
+	amount := decimal.Zero
+	for _, order := range orders {
+		amount = amount.Add(decimal.NewFromFloat(order.TotalAmount))
+	}
+	return &CombinedPayment{Checkout: &checkout, Orders: orders, Amount: amount.InexactFloat64()}, nil
+}
+
+// RecordCombinedPayment 保存合并支付记录，并按子订单应付金额分摊
+func (s *CheckoutService) RecordCombinedPayment(payment *CombinedPayment, paymentNo string, method PaymentMethod, qrcodeURL string) error {
+	expireTime := time.Now().Add(15 * time.Minute)
+	orderMethod := models.PaymentMethod(method)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		record := &PaymentRecord{
+			OrderID:          payment.Orders[0].ID,
+			OrderNo:          payment.Checkout.CheckoutNo,
+			CheckoutID:       &payment.Checkout.ID,
+			PaymentNo:        paymentNo,
+			PaymentMethod:    method,
+			GoodsAmount:      payment.Amount,
+			Amount:           payment.Amount,
+			Status:           PaymentStatusPending,
+			QRCodeURL:        qrcodeURL,
+			QRCodeExpireTime: &expireTime,
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+
+		orderIDs := make([]uint64, 0, len(payment.Orders))
+		for _, order := range payment.Orders {
+			allocation := &models.PaymentAllocation{
+				PaymentNo:  paymentNo,
+				CheckoutID: payment.Checkout.ID,
+				OrderID:    order.ID,
+				OrderNo:    order.OrderNo,
+				Amount:     order.TotalAmount,
+			}
+			if err := tx.Create(allocation).Error; err != nil {
+				return err
+			}
+			orderIDs = append(orderIDs, order.ID)
+		}
+
+		if err := tx.Model(&models.Order{}).Where("id IN ?", orderIDs).Updates(map[string]interface{}{
+			"payment_method": orderMethod,
+			"payment_no":     paymentNo,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(payment.Checkout).Updates(map[string]interface{}{
+			"payment_method": orderMethod,
+			"payment_no":     paymentNo,
+		}).Error
+	})
+}
+
+// ApplyCombinedPaymentTx 在事务中将合并支付分摊到各子订单并标记结算单已支付
+// 已不是待支付状态的子订单不再流转，改为通知管理员，已取消的子订单自动发起退款
+func (s *CheckoutService) ApplyCombinedPaymentTx(tx *gorm.DB, paymentNo string, paidAt time.Time) error {
+	var allocations []models.PaymentAllocation
+	if err := tx.Where("payment_no = ?", paymentNo).Order("order_id ASC").Find(&allocations).Error; err != nil {
+		return err
+	}
+	if len(allocations) == 0 {
+		return fmt.Errorf("支付单 %s 没有分摊明细", paymentNo)
+	}
+
+	stateMachine := NewOrderStateMachine(s.db)
+	for _, allocation := range allocations {
+		_, err := stateMachine.TransitionTx(tx, &OrderTransition{
+			OrderID:      allocation.OrderID,
+			From:         []models.OrderStatus{models.OrderStatusPendingPayment},
+			To:           models.OrderStatusPendingConfirm,
+			OperatorType: models.OperatorTypeSystem,
+			Remark:       "合并支付成功",
+			Extra: map[string]interface{}{
+				"payment_status": models.PaymentStatusPaid,
+				"payment_time":   paidAt,
+				"payment_no":     paymentNo,
+			},
+		})
+		var invalid *InvalidTransitionError
+		if errors.As(err, &invalid) {
+			refunded, err := refundOrphanPaymentTx(tx, allocation.OrderID, paymentNo, paidAt)
+			if err != nil {
+				return err
+			}
+			action := "需人工退款"
+			if refunded {
+				action = "已自动发起退款"
+			}
+			if err := tx.Create(models.NewOrderNotification(
+				models.AdminNotificationCombinedPaymentOrphan,
+				allocation.OrderID,
+				"合并支付订单状态异常",
+				fmt.Sprintf("订单 %s 在合并支付 %s 到账时已是%s状态，分摊金额%.2f元%s",
+					allocation.OrderNo, paymentNo, invalid.From, allocation.Amount, action),
+			)).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.Checkout{}).
+		Where("id = ? AND status = ?", allocations[0].CheckoutID, models.CheckoutStatusPendingPayment).
+		Updates(map[string]interface{}{
+			"status":       models.CheckoutStatusPaid,
+			"payment_no":   paymentNo,
+			"payment_time": paidAt,
+		}).Error
+}
+
+// checkoutCarts 读取待结算的供应商购物车，按供应商ID排序；没有商品时返回 ErrCartEmpty
+func (s *CheckoutService) checkoutCarts(ctx context.Context, storeID uint64, supplierIDs []uint64) ([]SupplierCart, error) {
+	carts, err := s.cartService.GetAllCarts(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	carts = filterSupplierCarts(carts, supplierIDs)
+	if len(carts) == 0 {
+		return nil, ErrCartEmpty
+	}
+	sort.Slice(carts, func(i, j int) bool { return carts[i].SupplierID < carts[j].SupplierID })
+	return carts, nil
+}
+
+// filterSupplierCarts 只保留指定供应商的购物车，未指定时保留全部
+func filterSupplierCarts(carts []SupplierCart, supplierIDs []uint64) []SupplierCart {
+	if len(supplierIDs) == 0 {
+		return carts
+	}
+	wanted := make(map[uint64]bool, len(supplierIDs))
+	for _, id := range supplierIDs {
+		wanted[id] = true
+	}
+	filtered := carts[:0]
+	for _, cart := range carts {
+		if wanted[cart.SupplierID] {
+			filtered = append(filtered, cart)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestFilterSupplierCarts(t *testing.T) {
+	carts := func() []SupplierCart {
+		return []SupplierCart{{SupplierID: 1}, {SupplierID: 2}, {SupplierID: 3}}
+	}
+
+	tests := []struct {
+		name        string
+		supplierIDs []uint64
+		expected    []uint64
+	}{
+		{"all when empty", nil, []uint64{1, 2, 3}},
+		{"subset", []uint64{3, 1}, []uint64{1, 3}},
+		{"unknown supplier", []uint64{9}, []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := filterSupplierCarts(carts(), tt.supplierIDs)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("filterSupplierCarts() returned %d carts, expected %d", len(result), len(tt.expected))
+			}
+			for i, cart := range result {
+				if cart.SupplierID != tt.expected[i] {
+					t.Errorf("cart[%d].SupplierID = %d, expected %d", i, cart.SupplierID, tt.expected[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,747 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 赊账错误
+var (
+	ErrCreditAccountNotFound    = errors.New("门店未开通赊账")
+	ErrCreditBillNotFound       = errors.New("赊账账单不存在")
+	ErrCreditBillPaid           = errors.New("赊账账单已还清")
+	ErrRepaymentAmountInvalid   = errors.New("还款金额必须大于0且不超过账单未还金额")
+	ErrCreditOrderNotRefundable = errors.New("赊账订单不经支付渠道退款，取消订单后额度自动释放")
+)
+
+// 赊账下单被拒绝的原因
+const (
+	CreditReasonNotOpened    = "credit_not_opened"
+	CreditReasonBlocked      = "credit_blocked"
+	CreditReasonOverdue      = "credit_overdue"
+	CreditReasonInsufficient = "credit_insufficient"
+)
+
+// CreditRejectedError 赊账下单被拒绝
+type CreditRejectedError struct {
+	Reason      string  `json:"reason"`
+	Message     string  `json:"message"`
+	CreditLimit float64 `json:"creditLimit"`
+	Available   float64 `json:"available"`
+	Required    float64 `json:"required"`
+}
+
+func (e *CreditRejectedError) Error() string {
+	return e.Message
+}
+
+// CreditService 门店赊账服务
+// 赊账订单下单即占用额度并跳过待支付，按出账周期生成账单，逾期未还自动冻结赊账下单
+type CreditService struct {
+	db *gorm.DB
+}
+
+// NewCreditService 创建赊账服务
+func NewCreditService(db *gorm.DB) *CreditService {
+	return &CreditService{db: db}
+}
+
+// CreditAccountRequest 开通或修改赊账账户
+type CreditAccountRequest struct {
+	StoreID     uint64
+	CreditLimit float64
+	BillCycle   models.SettlementCycle
+	TermDays    int
+	OperatorID  uint64
+}
+
+// CreditRepayment 还款登记
+type CreditRepayment struct {
+	Amount     float64
+	Reference  string
+	Remark     string
+	OperatorID uint64
+}
+
+// CreditBillQuery 赊账账单查询条件
+type CreditBillQuery struct {
+	StoreID uint64
+	Status  models.CreditBillStatus
+}
+
+// ChargeOrdersTx 在下单事务中以赊账方式支付订单：校验额度、占用额度，并将订单流转到待确认
+// 多个订单(购物车合并结算)按总金额一次校验，任一不满足则全部不下单
+func (s *CreditService) ChargeOrdersTx(tx *gorm.DB, storeID uint64, orders []*models.Order) error {
+	total := decimal.Zero
+	for _, order := range orders {
+		total = total.Add(decimal.NewFromFloat(order.TotalAmount))
+	}
+
+	account, err := s.lockAccountTx(tx, storeID)
+	if err != nil {
+		if errors.Is(err, ErrCreditAccountNotFound) {
+			return &CreditRejectedError{Reason: CreditReasonNotOpened, Message: err.Error(), Required: total.InexactFloat64()}
+		}
+		return err
+	}
+
+	overdue, err := hasOverdueBillTx(tx, storeID, time.Now())
+	if err != nil {
+		return err
+	}
+	if rejected := checkCredit(account, overdue, total.InexactFloat64()); rejected != nil {
+		return rejected
+	}
+
+	used := decimal.NewFromFloat(account.UsedAmount)
+	now := time.Now()
+	method := models.PaymentMethodCredit
+	machine := NewOrderStateMachine(s.db)
+	for _, order := range orders {
+		used = used.Add(decimal.NewFromFloat(order.TotalAmount))
+		orderID := order.ID
+		if err := tx.Create(&models.StoreCreditTransaction{
+			AccountID:    account.ID,
+			StoreID:      storeID,
+			Type:         models.CreditTransactionCharge,
+			OrderID:      &orderID,
+			OrderNo:      order.OrderNo,
+			Amount:       order.TotalAmount,
+			UsedAfter:    used.InexactFloat64(),
+			OperatorType: models.OperatorTypeStore,
+			OperatorID:   storeID,
+		}).Error; err != nil {
+			return err
+		}
+
+		updated, err := machine.TransitionTx(tx, &OrderTransition{
+			OrderID:      order.ID,
+			From:         []models.OrderStatus{models.OrderStatusPendingPayment},
+			To:           models.OrderStatusPendingConfirm,
+			OperatorType: models.OperatorTypeStore,
+			OperatorID:   storeID,
+			Remark:       "赊账下单",
+			Extra: map[string]interface{}{
+				"payment_method": method,
+				"payment_time":   now,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		*order = *updated
+	}
+
+	return tx.Model(account).Update("used_amount", used.InexactFloat64()).Error
+}
+
+// releaseCreditOrderTx 赊账订单取消时释放占用的额度，已释放过的不重复释放
+// 订单已出账的，从所属账单中冲减，不再另计入下一期账单
+func releaseCreditOrderTx(tx *gorm.DB, order *models.Order, t *OrderTransition) error {
+	var transactions []models.StoreCreditTransaction
+	if err := tx.Where("order_id = ? AND type IN ?", order.ID,
+		[]models.CreditTransactionType{models.CreditTransactionCharge, models.CreditTransactionReversal}).
+		Find(&transactions).Error; err != nil {
+		return err
+	}
+
+	charged := decimal.Zero
+	var billID *uint64
+	for _, transaction := range transactions {
+		switch transaction.Type {
+		case models.CreditTransactionCharge:
+			charged = charged.Add(decimal.NewFromFloat(transaction.Amount))
+			if transaction.BillID != nil {
+				billID = transaction.BillID
+			}
+		case models.CreditTransactionReversal:
+			charged = charged.Sub(decimal.NewFromFloat(transaction.Amount))
+		}
+	}
+	if !charged.IsPositive() {
+		return nil
+	}
+
+	var account models.StoreCreditAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("store_id = ?", order.StoreID).
+		First(&account).Error; err != nil {
+		return err
+	}
+
+	orderID := order.ID
+	reversal := &models.StoreCreditTransaction{
+		AccountID:    account.ID,
+		StoreID:      order.StoreID,
+		Type:         models.CreditTransactionReversal,
+		OrderID:      &orderID,
+		OrderNo:      order.OrderNo,
+		Amount:       charged.InexactFloat64(),
+		Remark:       "订单取消",
+		OperatorType: t.OperatorType,
+		OperatorID:   t.OperatorID,
+	}
+
+	if billID == nil {
+		account.UsedAmount = decimal.NewFromFloat(account.UsedAmount).Sub(charged).Round(2).InexactFloat64()
+	} else {
+		var bill models.StoreCreditBill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, *billID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		settled := reverseBilledCharge(&account, &bill, charged, now)
+		reversal.BillID = &bill.ID
+		reversal.Remark = "订单取消，冲减已出账账单"
+		if err := tx.Model(&bill).Updates(map[string]interface{}{
+			"reversal_amount": bill.ReversalAmount,
+			"total_amount":    bill.TotalAmount,
+			"status":          bill.Status,
+			"paid_at":         bill.PaidAt,
+		}).Error; err != nil {
+			return err
+		}
+		if settled {
+			if err := settleCreditBillOrdersTx(tx, bill.ID, now); err != nil {
+				return err
+			}
+			if err := resumeAutoBlockedAccountTx(tx, &account, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	reversal.UsedAfter = account.UsedAmount
+	if err := tx.Create(reversal).Error; err != nil {
+		return err
+	}
+	return tx.Model(&account).Updates(map[string]interface{}{
+		"used_amount":    account.UsedAmount,
+		"credit_balance": account.CreditBalance,
+	}).Error
+}
+
+// RunBilling 生成到期账单并标记逾期账单，供定时任务调用
+func (s *CreditService) RunBilling(ctx context.Context) error {
+	if err := s.GenerateBills(ctx); err != nil {
+		return err
+	}
+	return s.MarkOverdue(ctx)
+}
+
+// GenerateBills 为每个赊账账户生成上一个出账周期的账单，周期内没有流水的不出账
+func (s *CreditService) GenerateBills(ctx context.Context) error {
+	var accounts []models.StoreCreditAccount
+	if err := s.db.Where("status <> ?", models.CreditAccountStatusClosed).Find(&accounts).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		start, end := settlementPeriod(normalizeSettlementCycle(account.BillCycle), now)
+		if _, err := s.generateBill(account.StoreID, start, end); err != nil {
+			return fmt.Errorf("门店%d生成赊账账单失败: %w", account.StoreID, err)
+		}
+	}
+	return nil
+}
+
+// generateBill 生成账单，已生成或没有未出账流水时返回 nil
+func (s *CreditService) generateBill(storeID uint64, start, end time.Time) (*models.StoreCreditBill, error) {
+	var bill *models.StoreCreditBill
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		account, err := s.lockAccountTx(tx, storeID)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.StoreCreditBill{}).
+			Where("store_id = ? AND period_start = ?", storeID, start.Format("2006-01-02")).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		var transactions []models.StoreCreditTransaction
+		if err := tx.Where("account_id = ? AND bill_id IS NULL AND type IN ? AND created_at < ?", account.ID,
+			[]models.CreditTransactionType{models.CreditTransactionCharge, models.CreditTransactionReversal}, end).
+			Find(&transactions).Error; err != nil {
+			return err
+		}
+		if len(transactions) == 0 {
+			return nil
+		}
+
+		summary := summarizeCreditTransactions(transactions)
+		now := time.Now()
+		bill = &models.StoreCreditBill{
+			AccountID:      account.ID,
+			StoreID:        storeID,
+			PeriodStart:    start,
+			PeriodEnd:      end,
+			DueDate:        end.AddDate(0, 0, account.TermDays),
+			OrderCount:     summary.OrderCount,
+			ChargeAmount:   summary.ChargeAmount,
+			ReversalAmount: summary.ReversalAmount,
+			Status:         models.CreditBillStatusPending,
+		}
+		applyCreditBalance(account, bill, now)
+		if err := tx.Create(bill).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"used_amount":    account.UsedAmount,
+			"credit_balance": account.CreditBalance,
+		}).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uint64, 0, len(transactions))
+		for _, transaction := range transactions {
+			ids = append(ids, transaction.ID)
+		}
+		if err := tx.Model(&models.StoreCreditTransaction{}).Where("id IN ?", ids).Update("bill_id", bill.ID).Error; err != nil {
+			return err
+		}
+		if bill.Status != models.CreditBillStatusPaid {
+			return nil
+		}
+		return settleCreditBillOrdersTx(tx, bill.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bill, nil
+}
+
+// MarkOverdue 将超过还款期限的账单标记为逾期，并自动冻结对应门店的赊账下单
+func (s *CreditService) MarkOverdue(ctx context.Context) error {
+	today := time.Now().Format("2006-01-02")
+
+	var bills []models.StoreCreditBill
+	if err := s.db.Where("status = ? AND due_date < ?", models.CreditBillStatusPending, today).
+		Find(&bills).Error; err != nil {
+		return err
+	}
+
+	for _, bill := range bills {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.StoreCreditBill{}).
+				Where("id = ? AND status = ?", bill.ID, models.CreditBillStatusPending).
+				Update("status", models.CreditBillStatusOverdue)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			now := time.Now()
+			reason := fmt.Sprintf("账单 %s 逾期未还，未还金额%.2f元", bill.BillNo, bill.OutstandingAmount())
+			result = tx.Model(&models.StoreCreditAccount{}).
+				Where("id = ? AND status = ?", bill.AccountID, models.CreditAccountStatusActive).
+				Updates(map[string]interface{}{
+					"status":         models.CreditAccountStatusBlocked,
+					"auto_blocked":   true,
+					"blocked_reason": reason,
+					"blocked_at":     now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+
+			relatedType := "credit_bill"
+			return tx.Create(&models.AdminNotification{
+				Type:        models.AdminNotificationCreditOverdue,
+				Title:       "门店赊账账单逾期",
+				Content:     fmt.Sprintf("门店%d的%s，已自动暂停赊账下单", bill.StoreID, reason),
+				RelatedType: &relatedType,
+				RelatedID:   &bill.ID,
+				CreatedAt:   now,
+			}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Repay 登记账单还款，账单还清后其中的订单标记为已支付；
+// 因逾期自动冻结的账户在没有其他逾期账单时自动恢复
+func (s *CreditService) Repay(billID uint64, repayment *CreditRepayment) (*models.StoreCreditBill, error) {
+	var bill models.StoreCreditBill
+	if err := s.db.Select("id", "store_id").First(&bill, billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCreditBillNotFound
+		}
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		account, err := s.lockAccountTx(tx, bill.StoreID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bill, billID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		settled, err := applyCreditRepayment(account, &bill, decimal.NewFromFloat(repayment.Amount), now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&models.StoreCreditTransaction{
+			AccountID:    account.ID,
+			StoreID:      account.StoreID,
+			Type:         models.CreditTransactionRepayment,
+			BillID:       &bill.ID,
+			Amount:       repayment.Amount,
+			UsedAfter:    account.UsedAmount,
+			Reference:    repayment.Reference,
+			Remark:       repayment.Remark,
+			OperatorType: models.OperatorTypeAdmin,
+			OperatorID:   repayment.OperatorID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(account).Update("used_amount", account.UsedAmount).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&bill).Updates(map[string]interface{}{
+			"paid_amount": bill.PaidAmount,
+			"status":      bill.Status,
+			"paid_at":     bill.PaidAt,
+		}).Error; err != nil {
+			return err
+		}
+		if !settled {
+			return nil
+		}
+		if err := settleCreditBillOrdersTx(tx, bill.ID, now); err != nil {
+			return err
+		}
+
+		return resumeAutoBlockedAccountTx(tx, account, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// resumeAutoBlockedAccountTx 因逾期自动冻结的账户在没有其他逾期账单时恢复
+func resumeAutoBlockedAccountTx(tx *gorm.DB, account *models.StoreCreditAccount, now time.Time) error {
+	if !account.AutoBlocked {
+		return nil
+	}
+	overdue, err := hasOverdueBillTx(tx, account.StoreID, now)
+	if err != nil || overdue {
+		return err
+	}
+	return tx.Model(account).Updates(map[string]interface{}{
+		"status":         models.CreditAccountStatusActive,
+		"auto_blocked":   false,
+		"blocked_reason": "",
+		"blocked_at":     nil,
+	}).Error
+}
+
+// settleCreditBillOrdersTx 账单还清后，其中仍有效的赊账订单视为已支付
+func settleCreditBillOrdersTx(tx *gorm.DB, billID uint64, now time.Time) error {
+	return tx.Model(&models.Order{}).
+		Where("id IN (?)", tx.Model(&models.StoreCreditTransaction{}).
+			Select("order_id").
+			Where("bill_id = ? AND type = ?", billID, models.CreditTransactionCharge)).
+		Where("payment_status = ? AND status <> ?", models.PaymentStatusUnpaid, models.OrderStatusCancelled).
+		Updates(map[string]interface{}{
+			"payment_status": models.PaymentStatusPaid,
+			"payment_time":   now,
+		}).Error
+}
+
+// applyCreditBalance 出账时以账户余额抵扣本期金额，未抵扣完的余额留待下期；
+// 本期取消多于下单时差额计入余额，账单金额不为负，抵扣后无需还款的账单直接结清
+func applyCreditBalance(account *models.StoreCreditAccount, bill *models.StoreCreditBill, now time.Time) {
+	net := decimal.NewFromFloat(bill.ChargeAmount).Sub(decimal.NewFromFloat(bill.ReversalAmount))
+	carried := decimal.Min(decimal.NewFromFloat(account.CreditBalance), net)
+	bill.CarriedAmount = carried.Round(2).InexactFloat64()
+	bill.TotalAmount = net.Sub(carried).Round(2).InexactFloat64()
+	account.CreditBalance = decimal.NewFromFloat(account.CreditBalance).Sub(carried).Round(2).InexactFloat64()
+	account.UsedAmount = decimal.NewFromFloat(account.UsedAmount).Sub(carried).Round(2).InexactFloat64()
+	if bill.TotalAmount <= 0 {
+		bill.Status = models.CreditBillStatusPaid
+		bill.PaidAt = &now
+	}
+}
+
+// reverseBilledCharge 已出账订单取消：从所属账单中冲减，释放账单未还部分占用的额度，
+// 账单已还款超出冲减后金额的部分转为账户余额；返回账单是否因此还清
+func reverseBilledCharge(account *models.StoreCreditAccount, bill *models.StoreCreditBill, amount decimal.Decimal, now time.Time) bool {
+	released := decimal.Min(amount, decimal.NewFromFloat(bill.OutstandingAmount()))
+	account.UsedAmount = decimal.NewFromFloat(account.UsedAmount).Sub(released).Round(2).InexactFloat64()
+	account.CreditBalance = decimal.NewFromFloat(account.CreditBalance).Add(amount.Sub(released)).Round(2).InexactFloat64()
+	bill.ReversalAmount = decimal.NewFromFloat(bill.ReversalAmount).Add(amount).Round(2).InexactFloat64()
+	bill.TotalAmount = decimal.NewFromFloat(bill.TotalAmount).Sub(amount).Round(2).InexactFloat64()
+	if bill.Status == models.CreditBillStatusPaid || bill.OutstandingAmount() > 0 {
+		return false
+	}
+	bill.Status = models.CreditBillStatusPaid
+	bill.PaidAt = &now
+	return true
+}
+
+// applyCreditRepayment 登记还款金额，不能超过账单未还金额；返回账单是否还清
+func applyCreditRepayment(account *models.StoreCreditAccount, bill *models.StoreCreditBill, amount decimal.Decimal, now time.Time) (bool, error) {
+	if bill.Status == models.CreditBillStatusPaid {
+		return false, ErrCreditBillPaid
+	}
+	if !amount.IsPositive() || amount.GreaterThan(decimal.NewFromFloat(bill.OutstandingAmount())) {
+		return false, ErrRepaymentAmountInvalid
+	}
+	account.UsedAmount = decimal.NewFromFloat(account.UsedAmount).Sub(amount).Round(2).InexactFloat64()
+	bill.PaidAmount = decimal.NewFromFloat(bill.PaidAmount).Add(amount).Round(2).InexactFloat64()
+	if bill.OutstandingAmount() > 0 {
+		return false, nil
+	}
+	bill.Status = models.CreditBillStatusPaid
+	bill.PaidAt = &now
+	return true, nil
+}
+
+// SaveAccount 开通或修改门店赊账账户
+func (s *CreditService) SaveAccount(req *CreditAccountRequest) (*models.StoreCreditAccount, error) {
+	var account models.StoreCreditAccount
+	err := s.db.Where("store_id = ?", req.StoreID).First(&account).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		account = models.StoreCreditAccount{
+			StoreID: req.StoreID,
+			Status:  models.CreditAccountStatusActive,
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	account.CreditLimit = decimal.NewFromFloat(req.CreditLimit).Round(2).InexactFloat64()
+	account.BillCycle = normalizeSettlementCycle(req.BillCycle)
+	account.TermDays = req.TermDays
+	account.OperatorID = req.OperatorID
+	if err := s.db.Save(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// SetAccountStatus 管理员冻结、恢复或关闭赊账账户，手工操作会清除自动冻结标记
+func (s *CreditService) SetAccountStatus(storeID uint64, status models.CreditAccountStatus, reason string, adminID uint64) (*models.StoreCreditAccount, error) {
+	updates := map[string]interface{}{
+		"status":       status,
+		"auto_blocked": false,
+		"operator_id":  adminID,
+	}
+	if status == models.CreditAccountStatusActive {
+		updates["blocked_reason"] = ""
+		updates["blocked_at"] = nil
+	} else {
+		updates["blocked_reason"] = reason
+		updates["blocked_at"] = time.Now()
+	}
+
+	result := s.db.Model(&models.StoreCreditAccount{}).Where("store_id = ?", storeID).Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCreditAccountNotFound
+	}
+	return s.GetAccount(storeID)
+}
+
+// GetAccount 获取门店赊账账户
+func (s *CreditService) GetAccount(storeID uint64) (*models.StoreCreditAccount, error) {
+	var account models.StoreCreditAccount
+	if err := s.db.Preload("Store").Where("store_id = ?", storeID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCreditAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListAccounts 分页查询赊账账户
+func (s *CreditService) ListAccounts(page, pageSize int, status models.CreditAccountStatus) ([]models.StoreCreditAccount, int64, error) {
+	query := s.db.Model(&models.StoreCreditAccount{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var accounts []models.StoreCreditAccount
+	err := query.Preload("Store").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&accounts).Error
+	return accounts, total, err
+}
+
+// ListBills 分页查询赊账账单
+func (s *CreditService) ListBills(page, pageSize int, q *CreditBillQuery) ([]models.StoreCreditBill, int64, error) {
+	query := s.db.Model(&models.StoreCreditBill{})
+	if q.StoreID > 0 {
+		query = query.Where("store_id = ?", q.StoreID)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var bills []models.StoreCreditBill
+	err := query.Order("period_start DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&bills).Error
+	return bills, total, err
+}
+
+// GetBill 获取账单及其流水，storeID 为 0 时不限门店
+func (s *CreditService) GetBill(billID, storeID uint64) (*models.StoreCreditBill, error) {
+	query := s.db.Preload("Transactions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+	if storeID > 0 {
+		query = query.Where("store_id = ?", storeID)
+	}
+
+	var bill models.StoreCreditBill
+	if err := query.First(&bill, billID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCreditBillNotFound
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+// ListTransactions 分页查询门店赊账流水
+func (s *CreditService) ListTransactions(storeID uint64, page, pageSize int) ([]models.StoreCreditTransaction, int64, error) {
+	query := s.db.Model(&models.StoreCreditTransaction{}).Where("store_id = ?", storeID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []models.StoreCreditTransaction
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&transactions).Error
+	return transactions, total, err
+}
+
+// lockAccountTx 锁定门店赊账账户
+func (s *CreditService) lockAccountTx(tx *gorm.DB, storeID uint64) (*models.StoreCreditAccount, error) {
+	var account models.StoreCreditAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("store_id = ?", storeID).
+		First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCreditAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// hasOverdueBillTx 门店是否有逾期未还的账单，包括已过期限但定时任务尚未标记的
+func hasOverdueBillTx(tx *gorm.DB, storeID uint64, now time.Time) (bool, error) {
+	var count int64
+	err := tx.Model(&models.StoreCreditBill{}).
+		Where("store_id = ?", storeID).
+		Where("status = ? OR (status = ? AND due_date < ?)",
+			models.CreditBillStatusOverdue, models.CreditBillStatusPending, now.Format("2006-01-02")).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// checkCredit 校验赊账账户能否支付指定金额，可以时返回 nil
+func checkCredit(account *models.StoreCreditAccount, hasOverdue bool, amount float64) *CreditRejectedError {
+	rejected := &CreditRejectedError{
+		CreditLimit: account.CreditLimit,
+		Available:   account.AvailableCredit(),
+		Required:    amount,
+	}
+	switch {
+	case account.Status != models.CreditAccountStatusActive:
+		rejected.Reason = CreditReasonBlocked
+		rejected.Message = "赊账已暂停"
+		if account.BlockedReason != "" {
+			rejected.Message += ": " + account.BlockedReason
+		}
+	case hasOverdue:
+		rejected.Reason = CreditReasonOverdue
+		rejected.Message = "存在逾期未还的赊账账单，请先还款"
+	case amount > rejected.Available+0.005:
+		rejected.Reason = CreditReasonInsufficient
+		rejected.Message = fmt.Sprintf("赊账可用额度不足，可用%.2f元，需%.2f元", rejected.Available, amount)
+	default:
+		return nil
+	}
+	return rejected
+}
+
+// creditBillSummary 账单汇总
+type creditBillSummary struct {
+	OrderCount     int
+	ChargeAmount   float64
+	ReversalAmount float64
+	TotalAmount    float64
+}
+
+// summarizeCreditTransactions 汇总出账流水，同一订单下单和取消都在本期时不计入订单数
+func summarizeCreditTransactions(transactions []models.StoreCreditTransaction) creditBillSummary {
+	charge, reversal := decimal.Zero, decimal.Zero
+	net := make(map[uint64]decimal.Decimal)
+	for _, transaction := range transactions {
+		amount := decimal.NewFromFloat(transaction.Amount)
+		var orderID uint64
+		if transaction.OrderID != nil {
+			orderID = *transaction.OrderID
+		}
+		switch transaction.Type {
+		case models.CreditTransactionCharge:
+			charge = charge.Add(amount)
+			net[orderID] = net[orderID].Add(amount)
+		case models.CreditTransactionReversal:
+			reversal = reversal.Add(amount)
+			net[orderID] = net[orderID].Sub(amount)
+		}
+	}
+
+	summary := creditBillSummary{
+		ChargeAmount:   charge.Round(2).InexactFloat64(),
+		ReversalAmount: reversal.Round(2).InexactFloat64(),
+		TotalAmount:    charge.Sub(reversal).Round(2).InexactFloat64(),
+	}
+	for _, amount := range net {
+		if amount.IsPositive() {
+			summary.OrderCount++
+		}
+	}
+	return summary
+}
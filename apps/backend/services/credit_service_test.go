@@ -0,0 +1,154 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestCheckCredit(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         models.CreditAccountStatus
+		used           float64
+		hasOverdue     bool
+		amount         float64
+		expectedReason string
+	}{
+		{"within limit", models.CreditAccountStatusActive, 300, false, 700, ""},
+		{"rounding tolerance", models.CreditAccountStatusActive, 300.01, false, 699.99, ""},
+		{"insufficient", models.CreditAccountStatusActive, 300, false, 700.01, CreditReasonInsufficient},
+		{"blocked", models.CreditAccountStatusBlocked, 0, false, 10, CreditReasonBlocked},
+		{"closed", models.CreditAccountStatusClosed, 0, false, 10, CreditReasonBlocked},
+		{"overdue", models.CreditAccountStatusActive, 0, true, 10, CreditReasonOverdue},
+		{"blocked takes precedence", models.CreditAccountStatusBlocked, 1000, true, 10, CreditReasonBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &models.StoreCreditAccount{CreditLimit: 1000, UsedAmount: tt.used, Status: tt.status}
+			rejected := checkCredit(account, tt.hasOverdue, tt.amount)
+			reason := ""
+			if rejected != nil {
+				reason = rejected.Reason
+			}
+			if reason != tt.expectedReason {
+				t.Errorf("checkCredit() reason = %q, expected %q", reason, tt.expectedReason)
+			}
+		})
+	}
+}
+
+func TestSummarizeCreditTransactions(t *testing.T) {
+	orderID := func(id uint64) *uint64 { return &id }
+	billID := uint64(9)
+
+	transactions := []models.StoreCreditTransaction{
+		{Type: models.CreditTransactionCharge, OrderID: orderID(1), Amount: 100.1},
+		{Type: models.CreditTransactionCharge, OrderID: orderID(2), Amount: 200.2},
+		{Type: models.CreditTransactionCharge, OrderID: orderID(3), Amount: 50},
+		{Type: models.CreditTransactionReversal, OrderID: orderID(3), Amount: 50},
+		{Type: models.CreditTransactionReversal, OrderID: orderID(4), Amount: 30.05},
+		{Type: models.CreditTransactionRepayment, BillID: &billID, Amount: 500},
+	}
+
+	summary := summarizeCreditTransactions(transactions)
+	if summary.OrderCount != 2 {
+		t.Errorf("OrderCount = %d, expected 2", summary.OrderCount)
+	}
+	if summary.ChargeAmount != 350.3 {
+		t.Errorf("ChargeAmount = %v, expected 350.3", summary.ChargeAmount)
+	}
+	if summary.ReversalAmount != 80.05 {
+		t.Errorf("ReversalAmount = %v, expected 80.05", summary.ReversalAmount)
+	}
+	if summary.TotalAmount != 270.25 {
+		t.Errorf("TotalAmount = %v, expected 270.25", summary.TotalAmount)
+	}
+}
+
+func TestCreditCancelAfterBillingThenRepay(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	account := &models.StoreCreditAccount{CreditLimit: 1000, UsedAmount: 150}
+	bill := &models.StoreCreditBill{ChargeAmount: 150, Status: models.CreditBillStatusPending}
+	applyCreditBalance(account, bill, now)
+	if bill.TotalAmount != 150 || bill.Status != models.CreditBillStatusPending {
+		t.Fatalf("bill = %+v, expected 150 pending", bill)
+	}
+
+	// 出账后取消 50 元订单：从账单中冲减，额度只释放一次
+	if settled := reverseBilledCharge(account, bill, decimal.NewFromInt(50), now); settled {
+		t.Fatal("bill settled after partial cancellation")
+	}
+	if account.UsedAmount != 100 || bill.TotalAmount != 100 || bill.ReversalAmount != 50 {
+		t.Fatalf("used = %v bill total = %v reversal = %v, expected 100, 100, 50", account.UsedAmount, bill.TotalAmount, bill.ReversalAmount)
+	}
+
+	if _, err := applyCreditRepayment(account, bill, decimal.NewFromInt(150), now); err != ErrRepaymentAmountInvalid {
+		t.Errorf("repaying the pre-cancellation amount error = %v, expected ErrRepaymentAmountInvalid", err)
+	}
+	settled, err := applyCreditRepayment(account, bill, decimal.NewFromInt(100), now)
+	if err != nil || !settled {
+		t.Fatalf("applyCreditRepayment() = %v, %v, expected settled", settled, err)
+	}
+	if account.UsedAmount != 0 || account.AvailableCredit() != 1000 || account.CreditBalance != 0 {
+		t.Errorf("used = %v available = %v balance = %v, expected 0, 1000, 0", account.UsedAmount, account.AvailableCredit(), account.CreditBalance)
+	}
+	if _, err := applyCreditRepayment(account, bill, decimal.NewFromInt(1), now); err != ErrCreditBillPaid {
+		t.Errorf("repaying a paid bill error = %v, expected ErrCreditBillPaid", err)
+	}
+}
+
+func TestCreditCancelAfterRepaymentCarriesForward(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	account := &models.StoreCreditAccount{CreditLimit: 1000, UsedAmount: 150}
+	bill := &models.StoreCreditBill{ChargeAmount: 150, Status: models.CreditBillStatusPending}
+	applyCreditBalance(account, bill, now)
+
+	// 部分还款后取消的订单超出未还金额，超出部分转为余额，账单随之结清
+	if _, err := applyCreditRepayment(account, bill, decimal.NewFromInt(120), now); err != nil {
+		t.Fatalf("applyCreditRepayment() error = %v", err)
+	}
+	if settled := reverseBilledCharge(account, bill, decimal.NewFromInt(50), now); !settled {
+		t.Fatal("bill not settled after cancellation covered the outstanding amount")
+	}
+	if account.UsedAmount != 0 || account.CreditBalance != 20 || bill.OutstandingAmount() != 0 {
+		t.Fatalf("used = %v balance = %v outstanding = %v, expected 0, 20, 0", account.UsedAmount, account.CreditBalance, bill.OutstandingAmount())
+	}
+
+	// 已还清账单中的订单取消，全额转为余额
+	if settled := reverseBilledCharge(account, bill, decimal.NewFromInt(30), now); settled {
+		t.Error("already paid bill reported as newly settled")
+	}
+	if account.UsedAmount != 0 || account.CreditBalance != 50 {
+		t.Fatalf("used = %v balance = %v, expected 0, 50", account.UsedAmount, account.CreditBalance)
+	}
+
+	// 下期出账时余额抵扣
+	account.UsedAmount = 80
+	next := &models.StoreCreditBill{ChargeAmount: 80, Status: models.CreditBillStatusPending}
+	applyCreditBalance(account, next, now)
+	if next.CarriedAmount != 50 || next.TotalAmount != 30 || next.Status != models.CreditBillStatusPending {
+		t.Errorf("next bill = %+v, expected 50 carried and 30 due", next)
+	}
+	if account.UsedAmount != 30 || account.CreditBalance != 0 {
+		t.Errorf("used = %v balance = %v, expected 30, 0", account.UsedAmount, account.CreditBalance)
+	}
+}
+
+func TestApplyCreditBalanceNegativePeriod(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	account := &models.StoreCreditAccount{CreditLimit: 1000, UsedAmount: -40, CreditBalance: 10}
+	bill := &models.StoreCreditBill{ChargeAmount: 20, ReversalAmount: 60, Status: models.CreditBillStatusPending}
+	applyCreditBalance(account, bill, now)
+
+	// 取消多于下单的差额计入余额，不随账单结清丢弃
+	if bill.TotalAmount != 0 || bill.CarriedAmount != -40 || bill.Status != models.CreditBillStatusPaid {
+		t.Errorf("bill = %+v, expected zero total with -40 carried", bill)
+	}
+	if account.CreditBalance != 50 || account.UsedAmount != 0 {
+		t.Errorf("balance = %v used = %v, expected 50, 0", account.CreditBalance, account.UsedAmount)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// DeliveryRuleService 下单配送规则校验服务
+// 校验供应商起订金额、配送区域，并根据配送日和截单时间计算预计送达日期
+type DeliveryRuleService struct {
+	db *gorm.DB
+}
+
+// NewDeliveryRuleService 创建配送规则校验服务
+func NewDeliveryRuleService(db *gorm.DB) *DeliveryRuleService {
+	return &DeliveryRuleService{db: db}
+}
+
+// 配送规则不满足的原因
+const (
+	DeliveryReasonSupplierUnavailable = "supplier_unavailable"
+	DeliveryReasonBelowMinAmount      = "below_min_order_amount"
+	DeliveryReasonAddressRequired     = "delivery_address_required"
+	DeliveryReasonNoDeliveryArea      = "no_delivery_area"
+	DeliveryReasonOutOfDeliveryArea   = "out_of_delivery_area"
+)
+
+// DeliveryInfo 收货信息
+type DeliveryInfo struct {
+	Province string `json:"province"`
+	City     string `json:"city"`
+	District string `json:"district"`
+	Address  string `json:"address"`
+	Contact  string `json:"contact"`
+	Phone    string `json:"phone"`
+}
+
+// DeliveryViolation 不满足的配送规则
+type DeliveryViolation struct {
+	Reason         string  `json:"reason"`
+	Message        string  `json:"message"`
+	MinOrderAmount float64 `json:"minOrderAmount,omitempty"`
+	GoodsAmount    float64 `json:"goodsAmount,omitempty"`
+	Shortfall      float64 `json:"shortfall,omitempty"`
+}
+
+// DeliveryRejectedError 订单不满足供应商配送规则
+type DeliveryRejectedError struct {
+	SupplierID   uint64              `json:"supplierId"`
+	SupplierName string              `json:"supplierName"`
+	Violations   []DeliveryViolation `json:"violations"`
+}
+
+func (e *DeliveryRejectedError) Error() string {
+	if len(e.Violations) == 1 {
+		return e.Violations[0].Message
+	}
+	return fmt.Sprintf("订单不满足%d项配送规则", len(e.Violations))
+}
+
+// DeliveryCheckResult 配送规则校验结果
+type DeliveryCheckResult struct {
+	Delivery             DeliveryInfo `json:"delivery"`
+	MinOrderAmount       float64      `json:"minOrderAmount"`
+	DeliveryDays         []int        `json:"deliveryDays"`
+	ExpectedDeliveryDate time.Time    `json:"expectedDeliveryDate"`
+}
+
+// CheckOrder 校验门店向供应商下单是否满足配送规则
+// 收货信息为空时使用门店地址；不满足时返回 *DeliveryRejectedError
+func (s *DeliveryRuleService) CheckOrder(storeID, supplierID uint64, info DeliveryInfo, goodsAmount float64) (*DeliveryCheckResult, error) {
+	var store models.Store
+	if err := s.db.First(&store, storeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+
+	var supplier models.Supplier
+	if err := s.db.First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+
+	return s.Check(&supplier, ResolveDeliveryInfo(&store, info), goodsAmount, time.Now())
+}
+
+// Check 校验订单金额和收货地址，并计算预计送达日期
+func (s *DeliveryRuleService) Check(supplier *models.Supplier, delivery DeliveryInfo, goodsAmount float64, now time.Time) (*DeliveryCheckResult, error) {
+	setting := s.approvedSetting(supplier.ID)
+	result := &DeliveryCheckResult{
+		Delivery:       delivery,
+		MinOrderAmount: supplier.MinOrderAmount,
+		DeliveryDays:   []int(supplier.DeliveryDays),
+	}
+	if setting != nil {
+		result.MinOrderAmount = setting.MinOrderAmount
+		result.DeliveryDays = parseDeliveryDays(setting.DeliveryDays)
+	}
+
+	var violations []DeliveryViolation
+	if !supplier.IsActive() {
+		violations = append(violations, DeliveryViolation{
+			Reason:  DeliveryReasonSupplierUnavailable,
+			Message: "供应商已停用",
+		})
+	}
+	if goodsAmount < result.MinOrderAmount {
+		violations = append(violations, DeliveryViolation{
+			Reason:         DeliveryReasonBelowMinAmount,
+			Message:        fmt.Sprintf("未达到起订金额%.2f元", result.MinOrderAmount),
+			MinOrderAmount: result.MinOrderAmount,
+			GoodsAmount:    goodsAmount,
+			Shortfall:      decimal.NewFromFloat(result.MinOrderAmount).Sub(decimal.NewFromFloat(goodsAmount)).Round(2).InexactFloat64(),
+		})
+	}
+	if violation := s.checkArea(supplier.ID, delivery); violation != nil {
+		violations = append(violations, *violation)
+	}
+
+	if len(violations) > 0 {
+		return result, &DeliveryRejectedError{
+			SupplierID:   supplier.ID,
+			SupplierName: supplier.Name,
+			Violations:   violations,
+		}
+	}
+
+	result.ExpectedDeliveryDate = expectedDeliveryDate(now, result.DeliveryDays, s.cutoffTime())
+	return result, nil
+}
+
+// MinOrderAmount 供应商当前生效的起订金额
+func (s *DeliveryRuleService) MinOrderAmount(supplierID uint64) float64 {
+	if setting := s.approvedSetting(supplierID); setting != nil {
+		return setting.MinOrderAmount
+	}
+	var supplier models.Supplier
+	if err := s.db.Select("id", "min_order_amount").First(&supplier, supplierID).Error; err != nil {
+		return 0
+	}
+	return supplier.MinOrderAmount
+}
+
+// approvedSetting 审核通过的配送设置，优先于供应商档案中的设置
+func (s *DeliveryRuleService) approvedSetting(supplierID uint64) *DeliverySetting {
+	var setting DeliverySetting
+	if err := s.db.Where("supplier_id = ? AND audit_status = ?", supplierID, AuditStatusApproved).
+		First(&setting).Error; err != nil {
+		return nil
+	}
+	return &setting
+}
+
+// checkArea 收货地址是否在供应商已生效的配送区域内
+func (s *DeliveryRuleService) checkArea(supplierID uint64, delivery DeliveryInfo) *DeliveryViolation {
+	if delivery.Province == "" || delivery.City == "" {
+		return &DeliveryViolation{
+			Reason:  DeliveryReasonAddressRequired,
+			Message: "请填写收货地址",
+		}
+	}
+
+	var areas []models.DeliveryArea
+	s.db.Where("supplier_id = ? AND status = ?", supplierID, 1).Find(&areas)
+	if len(areas) == 0 {
+		return &DeliveryViolation{
+			Reason:  DeliveryReasonNoDeliveryArea,
+			Message: "供应商暂未开通配送区域",
+		}
+	}
+	for _, area := range areas {
+		if area.Covers(delivery.Province, delivery.City, delivery.District) {
+			return nil
+		}
+	}
+	return &DeliveryViolation{
+		Reason:  DeliveryReasonOutOfDeliveryArea,
+		Message: "收货地址不在供应商配送范围内",
+	}
+}
+
+// cutoffTime 每日截单时间，截单后下单顺延一天
+func (s *DeliveryRuleService) cutoffTime() time.Duration {
+	orderConfig, err := NewSystemConfigService(s.db).GetOrderConfig()
+	if err != nil {
+		return defaultOrderCutoff
+	}
+	return parseCutoffTime(orderConfig.CutoffTime)
+}
+
+// defaultOrderCutoff 默认截单时间 18:00
+const defaultOrderCutoff = 18 * time.Hour
+
+// parseCutoffTime 解析 HH:MM 格式的截单时间
+func parseCutoffTime(value string) time.Duration {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return defaultOrderCutoff
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// parseDeliveryDays 解析逗号分隔的配送日
+func parseDeliveryDays(value string) []int {
+	var days []int
+	for _, part := range strings.Split(value, ",") {
+		if day, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// expectedDeliveryDate 计算预计送达日期
+// 截单前下单最早次日送达，截单后最早后天送达，再顺延到最近的配送日；未设置配送日视为每天配送。
+// 配送日取值 0-6 对应周日至周六，7 也视为周日。
+func expectedDeliveryDate(now time.Time, deliveryDays []int, cutoff time.Duration) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	earliest := today.AddDate(0, 0, 1)
+	if now.Sub(today) >= cutoff {
+		earliest = earliest.AddDate(0, 0, 1)
+	}
+	if len(deliveryDays) == 0 {
+		return earliest
+	}
+
+	available := make(map[int]bool, len(deliveryDays))
+	for _, day := range deliveryDays {
+		available[day%7] = true
+	}
+	for i := 0; i < 7; i++ {
+		candidate := earliest.AddDate(0, 0, i)
+		if available[int(candidate.Weekday())] {
+			return candidate
+		}
+	}
+	return earliest
+}
+
+// ResolveDeliveryInfo 未填写收货信息时使用门店地址
+func ResolveDeliveryInfo(store *models.Store, info DeliveryInfo) DeliveryInfo {
+	if info.Province != "" {
+		return info
+	}
+	info = DeliveryInfo{
+		Contact: store.ContactName,
+		Phone:   store.ContactPhone,
+	}
+	if store.Province != nil {
+		info.Province = *store.Province
+	}
+	if store.City != nil {
+		info.City = *store.City
+	}
+	if store.District != nil {
+		info.District = *store.District
+	}
+	if store.Address != nil {
+		info.Address = *store.Address
+	}
+	return info
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestExpectedDeliveryDate(t *testing.T) {
+	// 2026-10-14 为周三
+	wednesday := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 14, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		days     []int
+		expected string
+	}{
+		{"before cutoff every day", wednesday(10, 0), nil, "2026-10-15"},
+		{"at cutoff every day", wednesday(18, 0), nil, "2026-10-16"},
+		{"after cutoff every day", wednesday(21, 30), nil, "2026-10-16"},
+		{"roll to next delivery day", wednesday(10, 0), []int{1, 5}, "2026-10-16"},
+		{"roll over weekend", wednesday(19, 0), []int{1}, "2026-10-19"},
+		{"seven means sunday", wednesday(10, 0), []int{7}, "2026-10-18"},
+		{"zero means sunday", wednesday(10, 0), []int{0}, "2026-10-18"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := expectedDeliveryDate(tt.now, tt.days, defaultOrderCutoff)
+			if got := result.Format("2006-01-02"); got != tt.expected {
+				t.Errorf("expectedDeliveryDate() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCutoffTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"18:00", 18 * time.Hour},
+		{" 09:30 ", 9*time.Hour + 30*time.Minute},
+		{"", defaultOrderCutoff},
+		{"25:00", defaultOrderCutoff},
+		{"abc", defaultOrderCutoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if result := parseCutoffTime(tt.value); result != tt.expected {
+				t.Errorf("parseCutoffTime(%q) = %v, expected %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDeliveryDays(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []int
+	}{
+		{"1,3,5", []int{1, 3, 5}},
+		{" 1, 2 ,x", []int{1, 2}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result := parseDeliveryDays(tt.value)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseDeliveryDays(%q) = %v, expected %v", tt.value, result, tt.expected)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("parseDeliveryDays(%q)[%d] = %d, expected %d", tt.value, i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveDeliveryInfo(t *testing.T) {
+	province, city, district, address := "浙江省", "杭州市", "西湖区", "文三路1号"
+	store := &models.Store{
+		ContactName:  "张三",
+		ContactPhone: "13800000000",
+		Province:     &province,
+		City:         &city,
+		District:     &district,
+		Address:      &address,
+	}
+
+	t.Run("use request info", func(t *testing.T) {
+		info := DeliveryInfo{Province: "江苏省", City: "南京市"}
+		result := ResolveDeliveryInfo(store, info)
+		if result.Province != "江苏省" || result.Contact != "" {
+			t.Errorf("ResolveDeliveryInfo() = %+v, expected request info", result)
+		}
+	})
+
+	t.Run("fallback to store", func(t *testing.T) {
+		result := ResolveDeliveryInfo(store, DeliveryInfo{})
+		if result.Province != province || result.District != district || result.Address != address {
+			t.Errorf("ResolveDeliveryInfo() = %+v, expected store address", result)
+		}
+		if result.Contact != "张三" || result.Phone != "13800000000" {
+			t.Errorf("ResolveDeliveryInfo() contact = %s/%s, expected store contact", result.Contact, result.Phone)
+		}
+	})
+}
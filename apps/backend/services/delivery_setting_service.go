@@ -98,13 +98,6 @@ type BatchAddDeliveryAreaRequest struct {
 	Areas []AddDeliveryAreaRequest `json:"areas" validate:"required,min=1"`
 }
 
-// CreateWaybillRequest 创建运单请求
-type CreateWaybillRequest struct {
-	OrderID   uint64 `json:"orderId" validate:"required"`
-	WaybillNo string `json:"waybillNo" validate:"required"`
-	Carrier   string `json:"carrier"`
-}
-
 // GetDeliverySetting 获取配送设置
 func (s *DeliverySettingService) GetDeliverySetting(supplierID uint64) (*DeliverySetting, error) {
 	var setting DeliverySetting
@@ -260,26 +253,6 @@ func (s *DeliverySettingService) GetWaybills(supplierID uint64, page, pageSize i
 	return waybills, total, err
 }
 
-// CreateWaybill 创建运单
-func (s *DeliverySettingService) CreateWaybill(supplierID uint64, req *CreateWaybillRequest) (*Waybill, error) {
-	waybill := Waybill{
-		SupplierID: supplierID,
-		OrderID:    req.OrderID,
-		WaybillNo:  req.WaybillNo,
-		Carrier:    req.Carrier,
-		Status:     "created",
-	}
-
-	if err := s.db.Create(&waybill).Error; err != nil {
-		return nil, err
-	}
-
-	// 更新订单状态为配送中
-	s.db.Table("orders").Where("id = ?", req.OrderID).Update("status", "delivering")
-
-	return &waybill, nil
-}
-
 // GetWaybillByOrder 根据订单获取运单
 func (s *DeliverySettingService) GetWaybillByOrder(orderID uint64) (*Waybill, error) {
 	var waybill Waybill
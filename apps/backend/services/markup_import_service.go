@@ -0,0 +1,618 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// ErrMarkupImportInvalid 导入数据存在错误，未写入任何规则
+var ErrMarkupImportInvalid = errors.New("导入数据存在错误")
+
+// markupImportMaxRows 单次导入的最大行数
+const markupImportMaxRows = 2000
+
+// MarkupImportColumn 导入导出列定义
+type MarkupImportColumn struct {
+	Key         string `json:"key"`
+	Title       string `json:"title"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// MarkupImportColumns 加价规则导入导出列，导出文件可修改后直接导入
+var MarkupImportColumns = []MarkupImportColumn{
+	{Key: "id", Title: "规则ID", Description: "填写时更新该规则，留空新增"},
+	{Key: "name", Title: "规则名称", Required: true},
+	{Key: "description", Title: "说明"},
+	{Key: "store", Title: "门店", Description: "门店编号或名称，留空不限"},
+	{Key: "supplier", Title: "供应商", Description: "供应商编号或名称，留空不限"},
+	{Key: "category", Title: "分类", Description: "分类ID或名称，留空不限"},
+	{Key: "material", Title: "物料", Description: "物料编号或名称，留空不限"},
+	{Key: "markupType", Title: "加价方式", Required: true, Description: "fixed 或 固定金额 / percent 或 百分比"},
+	{Key: "markupValue", Title: "加价值", Required: true, Description: "固定金额(元)或比例(0.05 表示 5%)"},
+	{Key: "tiers", Title: "阶梯", Description: "格式 最低价-最高价@起始数量=加价值，多档用分号分隔，最高价留空表示不封顶，如 0-50@1=0.08;0-50@10=0.05;50-@1=0.06"},
+	{Key: "minMarkup", Title: "最低加价"},
+	{Key: "maxMarkup", Title: "最高加价"},
+	{Key: "priority", Title: "优先级"},
+	{Key: "isActive", Title: "启用", Description: "是/否，留空为是"},
+	{Key: "startTime", Title: "开始时间", Description: "yyyy-mm-dd 或 yyyy-mm-dd hh:mm:ss"},
+	{Key: "endTime", Title: "结束时间", Description: "yyyy-mm-dd 或 yyyy-mm-dd hh:mm:ss"},
+}
+
+// MarkupImportRow 导入行(前端解析 Excel 后提交 JSON)
+type MarkupImportRow struct {
+	ID          uint64  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Store       string  `json:"store"`
+	Supplier    string  `json:"supplier"`
+	Category    string  `json:"category"`
+	Material    string  `json:"material"`
+	MarkupType  string  `json:"markupType"`
+	MarkupValue float64 `json:"markupValue"`
+	Tiers       string  `json:"tiers"`
+	MinMarkup   float64 `json:"minMarkup"`
+	MaxMarkup   float64 `json:"maxMarkup"`
+	Priority    int     `json:"priority"`
+	IsActive    string  `json:"isActive"`
+	StartTime   string  `json:"startTime"`
+	EndTime     string  `json:"endTime"`
+}
+
+// MarkupImportRowError 行级错误，Row 从 1 开始(不含表头)
+type MarkupImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// MarkupImportRequest 导入请求
+type MarkupImportRequest struct {
+	Rows   []MarkupImportRow
+	DryRun bool
+}
+
+// MarkupImportOperator 导入操作人，用于记录操作日志
+type MarkupImportOperator struct {
+	AdminID       uint64
+	IP            string
+	UserAgent     string
+	RequestURL    string
+	RequestMethod string
+}
+
+// MarkupImportResult 导入结果
+type MarkupImportResult struct {
+	DryRun       bool                   `json:"dryRun"`
+	Applied      bool                   `json:"applied"`
+	TotalCount   int                    `json:"totalCount"`
+	CreateCount  int                    `json:"createCount"`
+	UpdateCount  int                    `json:"updateCount"`
+	Errors       []MarkupImportRowError `json:"errors"`
+	CreatedIDs   []uint64               `json:"createdIds,omitempty"`
+	UpdatedIDs   []uint64               `json:"updatedIds,omitempty"`
+	OperationLog uint64                 `json:"operationLogId,omitempty"`
+}
+
+// markupImportRefs 导入引用的门店、供应商、分类、物料和已有规则
+type markupImportRefs struct {
+	stores     markupRefLookup
+	suppliers  markupRefLookup
+	categories markupRefLookup
+	materials  markupRefLookup
+	rules      map[uint64]bool
+}
+
+// markupRefLookup 编号或名称到ID的映射，名称可能重复
+type markupRefLookup struct {
+	byCode map[string]uint64
+	byName map[string][]uint64
+}
+
+// resolve 先按编号再按名称查找，名称重复时要求改填编号
+func (l markupRefLookup) resolve(ref string) (uint64, error) {
+	if id, ok := l.byCode[ref]; ok {
+		return id, nil
+	}
+	ids := l.byName[ref]
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("%s 不存在", ref)
+	case 1:
+		return ids[0], nil
+	}
+	return 0, fmt.Errorf("名称 %s 对应多条记录，请填写编号", ref)
+}
+
+// markupRefRecord 引用查询结果
+type markupRefRecord struct {
+	ID   uint64
+	Code string
+	Name string
+}
+
+// MarkupImportService 加价规则批量导入导出服务
+type MarkupImportService struct {
+	db *gorm.DB
+}
+
+// NewMarkupImportService 创建加价规则导入导出服务
+func NewMarkupImportService(db *gorm.DB) *MarkupImportService {
+	return &MarkupImportService{db: db}
+}
+
+// Import 校验并导入规则；DryRun 只校验，否则全部行在同一事务内写入，任一行有错则不写入
+func (s *MarkupImportService) Import(req *MarkupImportRequest, operator *MarkupImportOperator) (*MarkupImportResult, error) {
+	if len(req.Rows) > markupImportMaxRows {
+		return nil, fmt.Errorf("%w: 单次最多导入 %d 行", ErrMarkupImportInvalid, markupImportMaxRows)
+	}
+
+	refs, err := s.loadRefs(req.Rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MarkupImportResult{
+		DryRun:     req.DryRun,
+		TotalCount: len(req.Rows),
+		Errors:     make([]MarkupImportRowError, 0),
+	}
+	rules := make([]PriceMarkup, len(req.Rows))
+	for i := range req.Rows {
+		rule, rowErrors := buildImportRule(i+1, &req.Rows[i], refs)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		rules[i] = rule
+		if rule.ID > 0 {
+			result.UpdateCount++
+		} else {
+			result.CreateCount++
+		}
+	}
+	if req.DryRun || len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	now := time.Now()
+	err = markupIndexTransaction(s.db, func(tx *gorm.DB) error {
+		for i := range rules {
+			rule := &rules[i]
+			rule.CreatedBy = operator.AdminID
+			if rule.ID == 0 {
+				if err := tx.Create(rule).Error; err != nil {
+					return err
+				}
+				result.CreatedIDs = append(result.CreatedIDs, rule.ID)
+				continue
+			}
+
+			var existing PriceMarkup
+			if err := tx.First(&existing, rule.ID).Error; err != nil {
+				return err
+			}
+			rule.CreatedBy = existing.CreatedBy
+			rule.CreatedAt = existing.CreatedAt
+			if err := tx.Save(rule).Error; err != nil {
+				return err
+			}
+			result.UpdatedIDs = append(result.UpdatedIDs, rule.ID)
+		}
+
+		log, err := s.operationLog(tx, result, operator, now)
+		if err != nil {
+			return err
+		}
+		result.OperationLog = log.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// operationLog 记录导入操作日志
+func (s *MarkupImportService) operationLog(tx *gorm.DB, result *MarkupImportResult, operator *MarkupImportOperator, now time.Time) (*models.OperationLog, error) {
+	var admin models.Admin
+	tx.Select("id", "name").First(&admin, operator.AdminID)
+
+	log := models.NewOperationLog(operator.AdminID, models.LogUserTypeAdmin, admin.Name, "price_markup", "import").
+		SetDescription(fmt.Sprintf("导入加价规则 %d 行：新增 %d 条，更新 %d 条", result.TotalCount, result.CreateCount, result.UpdateCount)).
+		SetData(nil, models.JSONMap{
+			"createdIds": result.CreatedIDs,
+			"updatedIds": result.UpdatedIDs,
+		}, nil).
+		SetRequestInfo(operator.IP, operator.UserAgent, operator.RequestURL, operator.RequestMethod)
+	log.CreatedAt = now
+	if err := tx.Create(log).Error; err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// loadRefs 一次性加载导入行引用的对象
+func (s *MarkupImportService) loadRefs(rows []MarkupImportRow) (*markupImportRefs, error) {
+	var stores, suppliers, categories, materials []string
+	var ruleIDs []uint64
+	for _, row := range rows {
+		if v := strings.TrimSpace(row.Store); v != "" {
+			stores = append(stores, v)
+		}
+		if v := strings.TrimSpace(row.Supplier); v != "" {
+			suppliers = append(suppliers, v)
+		}
+		if v := strings.TrimSpace(row.Category); v != "" {
+			categories = append(categories, v)
+		}
+		if v := strings.TrimSpace(row.Material); v != "" {
+			materials = append(materials, v)
+		}
+		if row.ID > 0 {
+			ruleIDs = append(ruleIDs, row.ID)
+		}
+	}
+
+	refs := &markupImportRefs{rules: make(map[uint64]bool)}
+	var err error
+	if refs.stores, err = s.lookup("stores", "store_no", stores); err != nil {
+		return nil, err
+	}
+	if refs.suppliers, err = s.lookup("suppliers", "supplier_no", suppliers); err != nil {
+		return nil, err
+	}
+	if refs.categories, err = s.lookup("categories", "id", categories); err != nil {
+		return nil, err
+	}
+	if refs.materials, err = s.lookup("materials", "material_no", materials); err != nil {
+		return nil, err
+	}
+
+	if len(ruleIDs) > 0 {
+		var existing []uint64
+		if err := s.db.Model(&PriceMarkup{}).Where("id IN ?", uniqueUint64(ruleIDs)).Pluck("id", &existing).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range existing {
+			refs.rules[id] = true
+		}
+	}
+	return refs, nil
+}
+
+// lookup 按编号列或名称查询未删除的记录
+func (s *MarkupImportService) lookup(table, codeColumn string, refs []string) (markupRefLookup, error) {
+	lookup := markupRefLookup{byCode: make(map[string]uint64), byName: make(map[string][]uint64)}
+	if len(refs) == 0 {
+		return lookup, nil
+	}
+
+	var records []markupRefRecord
+	err := s.db.Table(table).
+		Select(fmt.Sprintf("id, CAST(%s AS CHAR) AS code, name", codeColumn)).
+		Where(fmt.Sprintf("(%s IN ? OR name IN ?) AND deleted_at IS NULL", codeColumn), refs, refs).
+		Scan(&records).Error
+	if err != nil {
+		return lookup, err
+	}
+	for _, record := range records {
+		if record.Code != "" {
+			lookup.byCode[record.Code] = record.ID
+		}
+		lookup.byName[record.Name] = append(lookup.byName[record.Name], record.ID)
+	}
+	return lookup, nil
+}
+
+// buildImportRule 校验导入行并转换为规则
+func buildImportRule(rowNo int, row *MarkupImportRow, refs *markupImportRefs) (PriceMarkup, []MarkupImportRowError) {
+	var errs []MarkupImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, MarkupImportRowError{Row: rowNo, Field: field, Message: message})
+	}
+
+	rule := PriceMarkup{
+		ID:          row.ID,
+		Name:        strings.TrimSpace(row.Name),
+		Description: strings.TrimSpace(row.Description),
+		MarkupType:  parseImportMarkupType(row.MarkupType),
+		MarkupValue: row.MarkupValue,
+		MinMarkup:   row.MinMarkup,
+		MaxMarkup:   row.MaxMarkup,
+		Priority:    row.Priority,
+	}
+
+	if rule.ID > 0 && !refs.rules[rule.ID] {
+		fail("id", fmt.Sprintf("规则 %d 不存在", rule.ID))
+	}
+	if rule.Name == "" {
+		fail("name", "规则名称不能为空")
+	} else if len([]rune(rule.Name)) > 100 {
+		fail("name", "规则名称不能超过100个字符")
+	}
+	if len([]rune(rule.Description)) > 500 {
+		fail("description", "说明不能超过500个字符")
+	}
+
+	resolve := func(field, ref string, lookup markupRefLookup) *uint64 {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return nil
+		}
+		id, err := lookup.resolve(ref)
+		if err != nil {
+			fail(field, err.Error())
+			return nil
+		}
+		return &id
+	}
+	rule.StoreID = resolve("store", row.Store, refs.stores)
+	rule.SupplierID = resolve("supplier", row.Supplier, refs.suppliers)
+	rule.CategoryID = resolve("category", row.Category, refs.categories)
+	rule.MaterialID = resolve("material", row.Material, refs.materials)
+
+	if rule.MarkupType != MarkupTypeFixed && rule.MarkupType != MarkupTypePercent {
+		fail("markupType", "加价方式只能是 fixed 或 percent")
+	}
+	if rule.MarkupValue <= 0 {
+		fail("markupValue", "加价值必须大于0")
+	}
+	if rule.MinMarkup < 0 || rule.MaxMarkup < 0 {
+		fail("minMarkup", "最低/最高加价不能为负数")
+	} else if rule.MaxMarkup > 0 && rule.MaxMarkup < rule.MinMarkup {
+		fail("maxMarkup", "最高加价不能低于最低加价")
+	}
+
+	tiers, err := parseMarkupTiers(row.Tiers)
+	if err == nil {
+		err = ValidateMarkupTiers(tiers)
+	}
+	if err != nil {
+		fail("tiers", err.Error())
+	}
+	rule.Tiers = tiers
+
+	isActive, err := parseImportBool(row.IsActive)
+	if err != nil {
+		fail("isActive", err.Error())
+	}
+	rule.IsActive = isActive
+
+	if rule.StartTime, err = parseImportTime(row.StartTime); err != nil {
+		fail("startTime", err.Error())
+	}
+	if rule.EndTime, err = parseImportTime(row.EndTime); err != nil {
+		fail("endTime", err.Error())
+	}
+	if rule.StartTime != nil && rule.EndTime != nil && !rule.EndTime.After(*rule.StartTime) {
+		fail("endTime", "结束时间必须晚于开始时间")
+	}
+
+	return rule, errs
+}
+
+// parseMarkupTiers 解析阶梯文本：最低价-最高价@起始数量=加价值，分号分隔
+func parseMarkupTiers(text string) (models.MarkupTiers, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	tiers := models.MarkupTiers{}
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == '；' }) {
+		part = strings.TrimSpace(part)
+		band, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("阶梯 %q 缺少加价值", part)
+		}
+		band, quantity, ok := strings.Cut(band, "@")
+		if !ok {
+			quantity = "1"
+		}
+		minText, maxText, ok := strings.Cut(band, "-")
+		if !ok {
+			return nil, fmt.Errorf("阶梯 %q 价格区间格式错误", part)
+		}
+
+		var tier models.MarkupTier
+		var err error
+		if tier.MinPrice, err = strconv.ParseFloat(strings.TrimSpace(minText), 64); err != nil {
+			return nil, fmt.Errorf("阶梯 %q 最低价格式错误", part)
+		}
+		if maxText = strings.TrimSpace(maxText); maxText != "" {
+			if tier.MaxPrice, err = strconv.ParseFloat(maxText, 64); err != nil {
+				return nil, fmt.Errorf("阶梯 %q 最高价格式错误", part)
+			}
+		}
+		if tier.MinQuantity, err = strconv.Atoi(strings.TrimSpace(quantity)); err != nil {
+			return nil, fmt.Errorf("阶梯 %q 起始数量格式错误", part)
+		}
+		if tier.MarkupValue, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return nil, fmt.Errorf("阶梯 %q 加价值格式错误", part)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// formatMarkupTiers 阶梯转为导入格式文本
+func formatMarkupTiers(tiers models.MarkupTiers) string {
+	parts := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		maxText := ""
+		if tier.MaxPrice > 0 {
+			maxText = strconv.FormatFloat(tier.MaxPrice, 'f', -1, 64)
+		}
+		parts = append(parts, fmt.Sprintf("%s-%s@%d=%s",
+			strconv.FormatFloat(tier.MinPrice, 'f', -1, 64), maxText, tier.MinQuantity,
+			strconv.FormatFloat(tier.MarkupValue, 'f', -1, 64)))
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseImportMarkupType 加价方式，兼容中文名称
+func parseImportMarkupType(text string) MarkupType {
+	switch text = strings.TrimSpace(text); text {
+	case "固定金额", "固定":
+		return MarkupTypeFixed
+	case "百分比", "比例":
+		return MarkupTypePercent
+	}
+	return MarkupType(strings.ToLower(text))
+}
+
+// parseImportBool 解析是/否，留空为是
+func parseImportBool(text string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "", "是", "启用", "true", "1", "y", "yes":
+		return true, nil
+	case "否", "停用", "false", "0", "n", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("无法识别 %q，请填写是或否", text)
+}
+
+// parseImportTime 解析本地时间，留空返回 nil
+func parseImportTime(text string) (*time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02 15:04:05", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("时间 %q 格式错误", text)
+}
+
+// Export 导出规则为导入格式，门店、供应商和物料导出编号(无编号时导出名称)，分类导出名称
+func (s *MarkupImportService) Export(params *PriceMarkupQueryParams) ([]map[string]interface{}, error) {
+	query := s.db.Model(&PriceMarkup{})
+	if params.StoreID != nil {
+		query = query.Where("store_id = ?", *params.StoreID)
+	}
+	if params.SupplierID != nil {
+		query = query.Where("supplier_id = ?", *params.SupplierID)
+	}
+	if params.IsActive != nil {
+		query = query.Where("is_active = ?", *params.IsActive)
+	}
+
+	var rules []PriceMarkup
+	if err := query.Order("priority DESC, created_at ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	var storeIDs, supplierIDs, categoryIDs, materialIDs []uint64
+	for _, rule := range rules {
+		if rule.StoreID != nil {
+			storeIDs = append(storeIDs, *rule.StoreID)
+		}
+		if rule.SupplierID != nil {
+			supplierIDs = append(supplierIDs, *rule.SupplierID)
+		}
+		if rule.CategoryID != nil {
+			categoryIDs = append(categoryIDs, *rule.CategoryID)
+		}
+		if rule.MaterialID != nil {
+			materialIDs = append(materialIDs, *rule.MaterialID)
+		}
+	}
+	stores, err := s.exportRefs("stores", "store_no", storeIDs)
+	if err != nil {
+		return nil, err
+	}
+	suppliers, err := s.exportRefs("suppliers", "supplier_no", supplierIDs)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.exportRefs("categories", "", categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	materials, err := s.exportRefs("materials", "material_no", materialIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := func(refs map[uint64]string, id *uint64) string {
+		if id == nil {
+			return ""
+		}
+		if v, ok := refs[*id]; ok {
+			return v
+		}
+		return strconv.FormatUint(*id, 10)
+	}
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+
+	data := make([]map[string]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		isActive := "否"
+		if rule.IsActive {
+			isActive = "是"
+		}
+		data = append(data, map[string]interface{}{
+			"id":          rule.ID,
+			"name":        rule.Name,
+			"description": rule.Description,
+			"store":       ref(stores, rule.StoreID),
+			"supplier":    ref(suppliers, rule.SupplierID),
+			"category":    ref(categories, rule.CategoryID),
+			"material":    ref(materials, rule.MaterialID),
+			"markupType":  rule.MarkupType,
+			"markupValue": rule.MarkupValue,
+			"tiers":       formatMarkupTiers(rule.Tiers),
+			"minMarkup":   rule.MinMarkup,
+			"maxMarkup":   rule.MaxMarkup,
+			"priority":    rule.Priority,
+			"isActive":    isActive,
+			"startTime":   formatTime(rule.StartTime),
+			"endTime":     formatTime(rule.EndTime),
+		})
+	}
+	return data, nil
+}
+
+// exportRefs 查询导出用的编号，无编号列或编号为空时用名称
+func (s *MarkupImportService) exportRefs(table, codeColumn string, ids []uint64) (map[uint64]string, error) {
+	refs := make(map[uint64]string)
+	if len(ids) == 0 {
+		return refs, nil
+	}
+
+	selectCode := "'' AS code"
+	if codeColumn != "" {
+		selectCode = codeColumn + " AS code"
+	}
+	var records []markupRefRecord
+	if err := s.db.Table(table).
+		Select("id, "+selectCode+", name").
+		Where("id IN ?", uniqueUint64(ids)).
+		Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if record.Code != "" {
+			refs[record.ID] = record.Code
+		} else {
+			refs[record.ID] = record.Name
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestParseMarkupTiers(t *testing.T) {
+	text := "0-50@1=0.08;0-50@10=0.05；50-@1=0.06"
+	tiers, err := parseMarkupTiers(text)
+	if err != nil {
+		t.Fatalf("parseMarkupTiers() error = %v", err)
+	}
+	expected := models.MarkupTiers{
+		{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.08},
+		{MinPrice: 0, MaxPrice: 50, MinQuantity: 10, MarkupValue: 0.05},
+		{MinPrice: 50, MinQuantity: 1, MarkupValue: 0.06},
+	}
+	if len(tiers) != len(expected) {
+		t.Fatalf("len(tiers) = %d, expected %d", len(tiers), len(expected))
+	}
+	for i := range expected {
+		if tiers[i] != expected[i] {
+			t.Errorf("tiers[%d] = %+v, expected %+v", i, tiers[i], expected[i])
+		}
+	}
+	if got := formatMarkupTiers(tiers); got != "0-50@1=0.08;0-50@10=0.05;50-@1=0.06" {
+		t.Errorf("formatMarkupTiers() = %q", got)
+	}
+
+	if tiers, err := parseMarkupTiers("0-@1"); err == nil {
+		t.Errorf("parseMarkupTiers() = %+v, expected error for missing value", tiers)
+	}
+	if tiers, err := parseMarkupTiers("  "); err != nil || tiers != nil {
+		t.Errorf("parseMarkupTiers(blank) = %+v, %v, expected nil", tiers, err)
+	}
+}
+
+func TestParseImportBool(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+		wantErr  bool
+	}{
+		{"", true, false},
+		{"是", true, false},
+		{"TRUE", true, false},
+		{"否", false, false},
+		{"0", false, false},
+		{"也许", false, true},
+	}
+	for _, tt := range tests {
+		got, err := parseImportBool(tt.text)
+		if got != tt.expected || (err != nil) != tt.wantErr {
+			t.Errorf("parseImportBool(%q) = %v, %v, expected %v, wantErr %v", tt.text, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestBuildImportRule(t *testing.T) {
+	refs := &markupImportRefs{
+		stores: markupRefLookup{
+			byCode: map[string]uint64{"S001": 1},
+			byName: map[string][]uint64{"一号店": {1}, "同名店": {2, 3}},
+		},
+		suppliers:  markupRefLookup{byCode: map[string]uint64{}, byName: map[string][]uint64{"鲜菜供应": {7}}},
+		categories: markupRefLookup{byCode: map[string]uint64{"10": 10}, byName: map[string][]uint64{"蔬菜": {10}}},
+		materials:  markupRefLookup{byCode: map[string]uint64{"M0001": 100}, byName: map[string][]uint64{}},
+		rules:      map[uint64]bool{5: true},
+	}
+
+	tests := []struct {
+		name   string
+		row    MarkupImportRow
+		fields []string
+	}{
+		{"valid by code and name", MarkupImportRow{
+			Name: "蔬菜加价", Store: "S001", Supplier: "鲜菜供应", Category: "蔬菜", Material: "M0001",
+			MarkupType: "percent", MarkupValue: 0.05, Tiers: "0-@1=0.05", StartTime: "2026-01-01", EndTime: "2026-12-31 23:59:59",
+		}, nil},
+		{"update existing", MarkupImportRow{ID: 5, Name: "全局", MarkupType: "固定金额", MarkupValue: 1, IsActive: "否"}, nil},
+		{"unknown rule", MarkupImportRow{ID: 6, Name: "全局", MarkupType: "fixed", MarkupValue: 1}, []string{"id"}},
+		{"ambiguous store name", MarkupImportRow{Name: "门店", Store: "同名店", MarkupType: "fixed", MarkupValue: 1}, []string{"store"}},
+		{"unknown material", MarkupImportRow{Name: "物料", Material: "M9999", MarkupType: "fixed", MarkupValue: 1}, []string{"material"}},
+		{"missing fields", MarkupImportRow{MarkupType: "ratio"}, []string{"name", "markupType", "markupValue"}},
+		{"max below min", MarkupImportRow{Name: "限额", MarkupType: "fixed", MarkupValue: 1, MinMarkup: 5, MaxMarkup: 2}, []string{"maxMarkup"}},
+		{"invalid tiers", MarkupImportRow{Name: "阶梯", MarkupType: "percent", MarkupValue: 0.05, Tiers: "10-@1=0.05"}, []string{"tiers"}},
+		{"end before start", MarkupImportRow{Name: "时间", MarkupType: "fixed", MarkupValue: 1, StartTime: "2026-02-01", EndTime: "2026-01-01"}, []string{"endTime"}},
+		{"bad time", MarkupImportRow{Name: "时间", MarkupType: "fixed", MarkupValue: 1, StartTime: "明天"}, []string{"startTime"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, errs := buildImportRule(3, &tt.row, refs)
+			if len(errs) != len(tt.fields) {
+				t.Fatalf("buildImportRule() errors = %+v, expected fields %v", errs, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if errs[i].Field != field || errs[i].Row != 3 {
+					t.Errorf("errors[%d] = %+v, expected row 3 field %s", i, errs[i], field)
+				}
+			}
+			if len(errs) > 0 {
+				return
+			}
+			if rule.ID != tt.row.ID || rule.Name != tt.row.Name {
+				t.Errorf("rule = %+v, expected id %d name %s", rule, tt.row.ID, tt.row.Name)
+			}
+		})
+	}
+
+	rule, _ := buildImportRule(1, &tests[0].row, refs)
+	if optionalID(rule.StoreID) != 1 || optionalID(rule.SupplierID) != 7 || optionalID(rule.CategoryID) != 10 || optionalID(rule.MaterialID) != 100 {
+		t.Errorf("scope = %d/%d/%d/%d, expected 1/7/10/100",
+			optionalID(rule.StoreID), optionalID(rule.SupplierID), optionalID(rule.CategoryID), optionalID(rule.MaterialID))
+	}
+	if !rule.IsActive || len(rule.Tiers) != 1 || rule.StartTime == nil || rule.EndTime == nil {
+		t.Errorf("rule = %+v, expected active with tier and time window", rule)
+	}
+}
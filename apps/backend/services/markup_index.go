@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// markupIndexChannel 加价规则或开关变更后广播的 Redis 频道
+const markupIndexChannel = "markup:index:invalidate"
+
+// markupIndexMaxAge 索引最长使用时间，兜底漏掉的变更通知
+const markupIndexMaxAge = 5 * time.Minute
+
+// markupIndexTables 变更后需要重建索引的表
+var markupIndexTables = map[string]bool{
+	"price_markups":  true,
+	"system_configs": true,
+	"stores":         true,
+	"suppliers":      true,
+	"categories":     true,
+}
+
+// markupScopeKey 规则适用范围，0 表示不限
+type markupScopeKey struct {
+	storeID    uint64
+	supplierID uint64
+	categoryID uint64
+	materialID uint64
+}
+
+// markupIndexEntry 索引中的规则，rank 为全部规则按 sortMarkupRules 排序后的位置
+type markupIndexEntry struct {
+	rule *PriceMarkup
+	rank int
+}
+
+// MarkupIndex 按适用范围编译的加价规则和加价开关，只读
+type MarkupIndex struct {
+	builtAt          time.Time
+	generation       uint64
+	ruleCount        int
+	scopes           map[markupScopeKey][]markupIndexEntry
+	globalEnabled    bool
+	storeDisabled    map[uint64]bool
+	supplierDisabled map[uint64]bool
+	categoryDisabled map[uint64]bool
+}
+
+// newMarkupIndex 编译规则，rules 为启用且未过期的规则(可包含未开始的)
+func newMarkupIndex(rules []PriceMarkup, builtAt time.Time) *MarkupIndex {
+	sortMarkupRules(rules)
+	index := &MarkupIndex{
+		builtAt:          builtAt,
+		ruleCount:        len(rules),
+		scopes:           make(map[markupScopeKey][]markupIndexEntry),
+		globalEnabled:    true,
+		storeDisabled:    make(map[uint64]bool),
+		supplierDisabled: make(map[uint64]bool),
+		categoryDisabled: make(map[uint64]bool),
+	}
+	for i := range rules {
+		rule := &rules[i]
+		key := markupScopeKey{
+			storeID:    optionalID(rule.StoreID),
+			supplierID: optionalID(rule.SupplierID),
+			categoryID: optionalID(rule.CategoryID),
+			materialID: optionalID(rule.MaterialID),
+		}
+		index.scopes[key] = append(index.scopes[key], markupIndexEntry{rule: rule, rank: i})
+	}
+	return index
+}
+
+// Match 查找请求命中的规则，结果与按优先级逐条 matchRule 一致
+func (idx *MarkupIndex) Match(req *CalculateMarkupRequest, now time.Time) *PriceMarkup {
+	stores := markupScopeCandidates(req.StoreID)
+	suppliers := markupScopeCandidates(req.SupplierID)
+	categories := markupScopeCandidates(req.CategoryID)
+	materials := markupScopeCandidates(req.MaterialID)
+
+	var best *markupIndexEntry
+	for _, storeID := range stores {
+		for _, supplierID := range suppliers {
+			for _, categoryID := range categories {
+				for _, materialID := range materials {
+					entries := idx.scopes[markupScopeKey{storeID, supplierID, categoryID, materialID}]
+					for i := range entries {
+						if best != nil && entries[i].rank > best.rank {
+							break
+						}
+						if markupRuleValidAt(entries[i].rule, now) {
+							best = &entries[i]
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.rule
+}
+
+// MarkupEnabled 全局、门店、供应商和分类加价开关是否均开启
+func (idx *MarkupIndex) MarkupEnabled(storeID, supplierID, categoryID uint64) bool {
+	return idx.globalEnabled && !idx.storeDisabled[storeID] &&
+		!idx.supplierDisabled[supplierID] && !idx.categoryDisabled[categoryID]
+}
+
+// markupScopeCandidates 请求的某一维度可命中的规则范围：不限，以及指定的ID
+func markupScopeCandidates(id uint64) []uint64 {
+	if id == 0 {
+		return []uint64{0}
+	}
+	return []uint64{0, id}
+}
+
+// optionalID 可空ID转为索引键，空表示不限
+func optionalID(id *uint64) uint64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// buildMarkupIndex 从数据库加载规则和开关并编译
+func buildMarkupIndex(db *gorm.DB, now time.Time) (*MarkupIndex, error) {
+	var rules []PriceMarkup
+	if err := db.Where("is_active = ?", true).
+		Where("(end_time IS NULL OR end_time >= ?)", now).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	index := newMarkupIndex(rules, now)
+
+	var globalValue string
+	if err := db.Table("system_configs").
+		Select("config_value").
+		Where("config_key = ?", "markup_global_enabled").
+		Scan(&globalValue).Error; err != nil {
+		return nil, err
+	}
+	index.globalEnabled = globalValue != "false" && globalValue != "0"
+
+	var storeIDs, supplierIDs, categoryIDs []uint64
+	if err := db.Model(&models.Store{}).Where("markup_enabled = ?", 0).Pluck("id", &storeIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Supplier{}).Where("markup_enabled = ?", 0).Pluck("id", &supplierIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.Category{}).Where("markup_enabled = ?", 0).Pluck("id", &categoryIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range storeIDs {
+		index.storeDisabled[id] = true
+	}
+	for _, id := range supplierIDs {
+		index.supplierDisabled[id] = true
+	}
+	for _, id := range categoryIDs {
+		index.categoryDisabled[id] = true
+	}
+	return index, nil
+}
+
+// markupIndexCache 进程内共享的加价规则索引，失效后在下次使用时重建
+type markupIndexCache struct {
+	db         *gorm.DB
+	redis      *redis.Client
+	logger     *zap.Logger
+	instanceID string
+	mu         sync.Mutex
+	index      atomic.Pointer[MarkupIndex]
+	generation atomic.Uint64
+}
+
+// sharedMarkupIndex InitMarkupIndex 之前为空，此时定价直接查库
+var sharedMarkupIndex atomic.Pointer[markupIndexCache]
+
+// InitMarkupIndex 启用加价规则索引：注册 GORM 回调在规则或开关变更提交后失效索引，
+// 并订阅 Redis 频道接收其他实例的变更通知，ctx 取消后停止订阅
+func InitMarkupIndex(ctx context.Context, db *gorm.DB, redisClient *redis.Client, logger *zap.Logger) error {
+	hostname, _ := os.Hostname()
+	cache := &markupIndexCache{
+		db:         db,
+		redis:      redisClient,
+		logger:     logger,
+		instanceID: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+	}
+
+	// 排在默认事务提交之后，避免在提交前用旧数据重建索引
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("markup_index:invalidate", cache.afterWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("markup_index:invalidate", cache.afterWrite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("markup_index:invalidate", cache.afterWrite); err != nil {
+		return err
+	}
+
+	if redisClient != nil {
+		go cache.subscribe(ctx)
+	}
+	sharedMarkupIndex.Store(cache)
+	return nil
+}
+
+// InvalidateMarkupIndex 失效本实例索引并通知其他实例，用于回调覆盖不到的写入
+func InvalidateMarkupIndex() {
+	if cache := sharedMarkupIndex.Load(); cache != nil {
+		cache.invalidate(true)
+	}
+}
+
+// markupIndexTransaction 在事务中写入加价规则或开关，提交后失效索引；
+// 显式事务内的写入回调不会失效索引，需通过此函数执行
+func markupIndexTransaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	if err := db.Transaction(fc); err != nil {
+		return err
+	}
+	InvalidateMarkupIndex()
+	return nil
+}
+
+// currentMarkupIndex 返回可用的索引，未启用或重建失败时返回 nil
+func currentMarkupIndex() *MarkupIndex {
+	cache := sharedMarkupIndex.Load()
+	if cache == nil {
+		return nil
+	}
+	index, err := cache.get()
+	if err != nil {
+		cache.logger.Warn("build markup index failed", zap.Error(err))
+		return nil
+	}
+	return index
+}
+
+// get 返回最新索引，失效或过期时重建
+func (c *markupIndexCache) get() (*MarkupIndex, error) {
+	if index := c.fresh(); index != nil {
+		return index, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index := c.fresh(); index != nil {
+		return index, nil
+	}
+
+	// 先取版本号再加载，加载期间的变更会让版本号变化，下次使用时再次重建
+	generation := c.generation.Load()
+	start := time.Now()
+	index, err := buildMarkupIndex(c.db, start)
+	if err != nil {
+		return nil, err
+	}
+	index.generation = generation
+	c.index.Store(index)
+	c.logger.Debug("markup index rebuilt",
+		zap.Int("rules", index.ruleCount),
+		zap.Uint64("generation", generation),
+		zap.Duration("duration", time.Since(start)))
+	return index, nil
+}
+
+// fresh 当前索引未失效且未过期时返回
+func (c *markupIndexCache) fresh() *MarkupIndex {
+	index := c.index.Load()
+	if index == nil || index.generation != c.generation.Load() || time.Since(index.builtAt) > markupIndexMaxAge {
+		return nil
+	}
+	return index
+}
+
+// invalidate 失效索引，broadcast 为 true 时通知其他实例
+func (c *markupIndexCache) invalidate(broadcast bool) {
+	c.generation.Add(1)
+	if !broadcast || c.redis == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.redis.Publish(ctx, markupIndexChannel, c.instanceID).Err(); err != nil {
+		c.logger.Warn("publish markup index invalidation failed", zap.Error(err))
+	}
+}
+
+// afterWrite GORM 回调：加价规则、加价开关或系统配置写入提交后失效索引，
+// 显式事务内的写入此时尚未提交，由 markupIndexTransaction 在提交后失效
+func (c *markupIndexCache) afterWrite(tx *gorm.DB) {
+	if tx.Error != nil || tx.RowsAffected == 0 || !markupIndexTables[tx.Statement.Table] {
+		return
+	}
+	if _, inTx := tx.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	// 门店、供应商和分类仅加价开关变更时需要重建
+	switch tx.Statement.Table {
+	case "stores", "suppliers", "categories":
+		if !strings.Contains(tx.Statement.SQL.String(), "markup_enabled") {
+			return
+		}
+	}
+	c.invalidate(true)
+}
+
+// subscribe 接收其他实例的失效通知，连接断开时 go-redis 会自动重连
+func (c *markupIndexCache) subscribe(ctx context.Context) {
+	pubsub := c.redis.Subscribe(ctx, markupIndexChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Payload != c.instanceID {
+				c.invalidate(false)
+			}
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// markupIndexFixture 生成规则和目录请求：以物料级和分类级规则为主，少量供应商、门店和全局规则，
+// 含同优先级和未开始的规则
+func markupIndexFixture(ruleCount, skuCount int, now time.Time) ([]PriceMarkup, []CalculateMarkupRequest) {
+	r := rand.New(rand.NewSource(42))
+	id := func(max int) *uint64 {
+		v := uint64(r.Intn(max) + 1)
+		return &v
+	}
+	past := now.AddDate(0, -1, 0)
+	future := now.AddDate(0, 0, 1)
+
+	rules := make([]PriceMarkup, ruleCount)
+	for i := range rules {
+		rule := PriceMarkup{
+			ID:          uint64(i + 1),
+			MarkupType:  MarkupTypePercent,
+			MarkupValue: 0.05,
+			IsActive:    true,
+			CreatedAt:   past.Add(time.Duration(r.Intn(1000)) * time.Minute),
+		}
+		switch n := r.Intn(100); {
+		case n < 70:
+			rule.MaterialID = id(2000)
+		case n < 90:
+			rule.CategoryID = id(50)
+		case n < 97:
+			rule.SupplierID = id(20)
+		case n < 99:
+			rule.StoreID = id(5)
+		}
+		if r.Intn(4) == 0 {
+			rule.SupplierID = id(20)
+		}
+		rule.Priority = markupSpecificityPriority(rule.StoreID, rule.SupplierID, rule.CategoryID, rule.MaterialID) + r.Intn(3)
+		if r.Intn(10) == 0 {
+			rule.StartTime = &future
+		}
+		rules[i] = rule
+	}
+
+	reqs := make([]CalculateMarkupRequest, skuCount)
+	for i := range reqs {
+		reqs[i] = CalculateMarkupRequest{
+			StoreID:       uint64(r.Intn(5) + 1),
+			SupplierID:    uint64(r.Intn(20) + 1),
+			CategoryID:    uint64(r.Intn(50) + 1),
+			MaterialID:    uint64(r.Intn(2000) + 1),
+			OriginalPrice: 10,
+		}
+	}
+	return rules, reqs
+}
+
+// scanMarkupRule 按优先级逐条匹配(未使用索引时的查找方式)
+func scanMarkupRule(s *PriceMarkupService, rules []PriceMarkup, req *CalculateMarkupRequest, now time.Time) *PriceMarkup {
+	for i := range rules {
+		if markupRuleValidAt(&rules[i], now) && s.matchRule(&rules[i], req) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func TestMarkupIndexMatch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	s := &PriceMarkupService{}
+	rules, reqs := markupIndexFixture(2000, 1000, now)
+	sortMarkupRules(rules)
+
+	compiled := make([]PriceMarkup, len(rules))
+	copy(compiled, rules)
+	index := newMarkupIndex(compiled, now)
+
+	for i := range reqs {
+		expected := scanMarkupRule(s, rules, &reqs[i], now)
+		got := index.Match(&reqs[i], now)
+		if (expected == nil) != (got == nil) || (expected != nil && expected.ID != got.ID) {
+			t.Fatalf("Match(%+v) = %v, expected %v", reqs[i], got, expected)
+		}
+	}
+
+	// 请求未指定的维度只能命中不限该维度的规则
+	category := uint64(3)
+	index = newMarkupIndex([]PriceMarkup{
+		{ID: 1, CategoryID: &category, Priority: 10, IsActive: true},
+		{ID: 2, Priority: 1, IsActive: true},
+	}, now)
+	if got := index.Match(&CalculateMarkupRequest{StoreID: 1, SupplierID: 1}, now); got == nil || got.ID != 2 {
+		t.Errorf("Match() without category = %v, expected rule 2", got)
+	}
+}
+
+func TestMarkupIndexMarkupEnabled(t *testing.T) {
+	index := newMarkupIndex(nil, time.Now())
+	index.storeDisabled[2] = true
+	index.categoryDisabled[7] = true
+
+	tests := []struct {
+		name       string
+		global     bool
+		storeID    uint64
+		supplierID uint64
+		categoryID uint64
+		expected   bool
+	}{
+		{"all enabled", true, 1, 1, 1, true},
+		{"store disabled", true, 2, 1, 1, false},
+		{"category disabled", true, 1, 1, 7, false},
+		{"global disabled", false, 1, 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index.globalEnabled = tt.global
+			if got := index.MarkupEnabled(tt.storeID, tt.supplierID, tt.categoryID); got != tt.expected {
+				t.Errorf("MarkupEnabled() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// markupRulesDB 只读的内存规则表：查询 price_markups 时返回 rules(按 start_time 条件过滤未开始的规则)，
+// 其余查询返回空结果，用于在测试中走真实的 GORM 查库路径
+func markupRulesDB(t testing.TB, rules []PriceMarkup) (*gorm.DB, *sql.DB) {
+	t.Helper()
+	sqlDB := sql.OpenDB(markupRulesConnector{rules: rules})
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open gorm: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db, sqlDB
+}
+
+type markupRulesConnector struct{ rules []PriceMarkup }
+
+func (c markupRulesConnector) Connect(context.Context) (driver.Conn, error) {
+	return markupRulesConn(c), nil
+}
+func (c markupRulesConnector) Driver() driver.Driver { return nil }
+
+type markupRulesConn markupRulesConnector
+
+func (c markupRulesConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c markupRulesConn) Close() error                        { return nil }
+func (c markupRulesConn) Begin() (driver.Tx, error)           { return markupRulesTx{}, nil }
+
+func (c markupRulesConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (c markupRulesConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "price_markups") {
+		return &markupRulesRows{}, nil
+	}
+	var now time.Time
+	for _, arg := range args {
+		if at, ok := arg.Value.(time.Time); ok {
+			now = at
+			break
+		}
+	}
+	rows := &markupRulesRows{columns: []string{"id", "store_id", "supplier_id", "category_id", "material_id",
+		"markup_type", "markup_value", "min_markup", "max_markup", "priority", "is_active", "start_time", "created_at"}}
+	for i := range c.rules {
+		rule := &c.rules[i]
+		if strings.Contains(query, "start_time <=") && rule.StartTime != nil && rule.StartTime.After(now) {
+			continue
+		}
+		rows.values = append(rows.values, []driver.Value{int64(rule.ID), markupRulesID(rule.StoreID),
+			markupRulesID(rule.SupplierID), markupRulesID(rule.CategoryID), markupRulesID(rule.MaterialID),
+			string(rule.MarkupType), rule.MarkupValue, rule.MinMarkup, rule.MaxMarkup, int64(rule.Priority),
+			rule.IsActive, markupRulesTime(rule.StartTime), rule.CreatedAt})
+	}
+	return rows, nil
+}
+
+func markupRulesID(id *uint64) driver.Value {
+	if id == nil {
+		return nil
+	}
+	return int64(*id)
+}
+
+func markupRulesTime(at *time.Time) driver.Value {
+	if at == nil {
+		return nil
+	}
+	return *at
+}
+
+type markupRulesTx struct{}
+
+func (markupRulesTx) Commit() error   { return nil }
+func (markupRulesTx) Rollback() error { return nil }
+
+type markupRulesRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *markupRulesRows) Columns() []string { return r.columns }
+func (r *markupRulesRows) Close() error      { return nil }
+
+func (r *markupRulesRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+func TestPriceCatalogMatchesCalculateMarkup(t *testing.T) {
+	rules, reqs := markupIndexFixture(300, 200, time.Now())
+	for i := range rules {
+		if i%3 == 0 {
+			rules[i].MarkupType = MarkupTypeFixed
+			rules[i].MarkupValue = 0.8
+		}
+	}
+	sortMarkupRules(rules)
+	db, _ := markupRulesDB(t, rules)
+	s := NewPriceMarkupService(db)
+
+	quotes := make([]CatalogQuote, len(reqs))
+	for i := range reqs {
+		reqs[i].StoreID = 1
+		reqs[i].OriginalPrice = 3.33 + float64(i%7)
+		quotes[i] = CatalogQuote{SupplierID: reqs[i].SupplierID, CategoryID: reqs[i].CategoryID,
+			MaterialID: reqs[i].MaterialID, Price: reqs[i].OriginalPrice}
+	}
+	prices, err := s.PriceCatalog(1, quotes)
+	if err != nil {
+		t.Fatalf("PriceCatalog() error = %v", err)
+	}
+
+	matched := 0
+	for i := range reqs {
+		expected, err := s.CalculateMarkup(&reqs[i])
+		if err != nil {
+			t.Fatalf("CalculateMarkup() error = %v", err)
+		}
+		got := prices[i]
+		if expected.AppliedRule == nil {
+			if got.MarkupRuleID != nil || got.FinalPrice != quotes[i].Price {
+				t.Errorf("quote %d = %+v, expected no markup", i, got)
+			}
+			continue
+		}
+		matched++
+		amount := priceOrderItem(PricingItemInput{Quantity: 1}, &models.SupplierMaterial{Price: quotes[i].Price}, expected)
+		if got.MarkupRuleID == nil || *got.MarkupRuleID != expected.AppliedRule.ID ||
+			got.MarkupAmount != amount.MarkupAmount || got.FinalPrice != amount.FinalPrice {
+			t.Errorf("quote %d = %+v, expected rule %d markup %v final %v",
+				i, got, expected.AppliedRule.ID, amount.MarkupAmount, amount.FinalPrice)
+		}
+	}
+	if matched == 0 {
+		t.Fatal("fixture matched no rules")
+	}
+}
+
+func TestPriceCatalogMarkupDisabled(t *testing.T) {
+	now := time.Now()
+	index := newMarkupIndex([]PriceMarkup{{ID: 1, MarkupType: MarkupTypeFixed, MarkupValue: 2, IsActive: true}}, now)
+	index.supplierDisabled[9] = true
+	prices := (&PriceMarkupService{}).priceCatalog(index, 1, []CatalogQuote{
+		{SupplierID: 8, Price: 10},
+		{SupplierID: 9, Price: 10},
+	}, now)
+	if prices[0].FinalPrice != 12 || prices[0].MarkupRuleID == nil {
+		t.Errorf("enabled supplier price = %+v, expected 12 with rule 1", prices[0])
+	}
+	if prices[1].FinalPrice != 10 || prices[1].MarkupAmount != 0 || prices[1].MarkupRuleID != nil {
+		t.Errorf("disabled supplier price = %+v, expected original price", prices[1])
+	}
+}
+
+func TestMarkupIndexAfterWrite(t *testing.T) {
+	_, sqlDB := markupRulesDB(t, nil)
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+
+	cache := &markupIndexCache{}
+	write := func(table string, pool gorm.ConnPool) *gorm.DB {
+		return &gorm.DB{Statement: &gorm.Statement{Table: table, ConnPool: pool}, RowsAffected: 1}
+	}
+
+	cache.afterWrite(write("price_markups", tx))
+	if got := cache.generation.Load(); got != 0 {
+		t.Errorf("generation after write inside transaction = %d, expected 0", got)
+	}
+	cache.afterWrite(write("orders", sqlDB))
+	if got := cache.generation.Load(); got != 0 {
+		t.Errorf("generation after unrelated write = %d, expected 0", got)
+	}
+	cache.afterWrite(write("price_markups", sqlDB))
+	if got := cache.generation.Load(); got != 1 {
+		t.Errorf("generation after committed write = %d, expected 1", got)
+	}
+}
+
+// BenchmarkCatalogMarkup 目录页一次为 500 个报价计算加价，规则 2000 条。
+// per-quote: 未启用索引前的路径，每个报价调用 CalculateMarkup 查询全部生效规则后逐条匹配；
+// catalog: PriceCatalog 未启用共享索引时整页加载一次规则；
+// shared-index: PriceCatalog 使用共享索引，不查库。
+// 查库走 GORM 和内存驱动，不含 MySQL 网络往返，实际差距大于结果所示
+func BenchmarkCatalogMarkup(b *testing.B) {
+	now := time.Now()
+	rules, reqs := markupIndexFixture(2000, 500, now)
+	sortMarkupRules(rules)
+	db, _ := markupRulesDB(b, rules)
+	s := NewPriceMarkupService(db)
+
+	quotes := make([]CatalogQuote, len(reqs))
+	for i := range reqs {
+		reqs[i].StoreID = 1
+		reqs[i].Quantity = 1
+		quotes[i] = CatalogQuote{SupplierID: reqs[i].SupplierID, CategoryID: reqs[i].CategoryID,
+			MaterialID: reqs[i].MaterialID, Price: reqs[i].OriginalPrice}
+	}
+
+	b.Run("per-quote", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for i := range reqs {
+				if _, err := s.CalculateMarkup(&reqs[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("catalog", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := s.PriceCatalog(1, quotes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("shared-index", func(b *testing.B) {
+		index, err := buildMarkupIndex(db, time.Now())
+		if err != nil {
+			b.Fatal(err)
+		}
+		cache := &markupIndexCache{db: db}
+		cache.index.Store(index)
+		sharedMarkupIndex.Store(cache)
+		defer sharedMarkupIndex.Store(nil)
+
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if _, err := s.PriceCatalog(1, quotes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
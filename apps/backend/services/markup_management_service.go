@@ -302,20 +302,7 @@ func (s *MarkupManagementService) CreateMarkupRule(name string, storeID, supplie
 
 // calculatePriority 计算规则优先级
 func (s *MarkupManagementService) calculatePriority(storeID, supplierID, categoryID, materialID *uint64) int {
-	priority := 0
-	if materialID != nil {
-		priority += 1000 // 物料级最高优先级
-	}
-	if categoryID != nil {
-		priority += 100
-	}
-	if supplierID != nil {
-		priority += 10
-	}
-	if storeID != nil {
-		priority += 1
-	}
-	return priority
+	return markupSpecificityPriority(storeID, supplierID, categoryID, materialID)
 }
 
 // UpdateMarkupRule 更新加价规则
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrMarkupRevenueQueryInvalid 加价收入统计参数无效
+var ErrMarkupRevenueQueryInvalid = errors.New("统计参数无效")
+
+// 加价收入统计维度
+const (
+	MarkupRevenueByRule     = "rule"
+	MarkupRevenueByCategory = "category"
+	MarkupRevenueBySupplier = "supplier"
+	MarkupRevenueByStore    = "store"
+)
+
+// 加价收入趋势粒度
+const (
+	MarkupTrendDay   = "day"
+	MarkupTrendWeek  = "week"
+	MarkupTrendMonth = "month"
+)
+
+// 加价收入统计参数
+const (
+	maxMarkupRevenueDays     = 366
+	maxMarkupTrendDays       = 92
+	defaultMarkupTrendSeries = 5
+	maxMarkupTrendSeries     = 20
+)
+
+// markupRevenueNoRule 按规则统计时未命中规则的明细分组名称
+const markupRevenueNoRule = "无规则"
+
+// markupRevenueDimensions 统计维度对应的分组字段，规则和分类为空时归入 0
+var markupRevenueDimensions = map[string]string{
+	MarkupRevenueByRule:     "COALESCE(oi.markup_rule_id, 0)",
+	MarkupRevenueByCategory: "COALESCE(m.category_id, 0)",
+	MarkupRevenueBySupplier: "o.supplier_id",
+	MarkupRevenueByStore:    "o.store_id",
+}
+
+// markupTrendPeriods 趋势粒度对应的周期字段，周以周一日期表示
+var markupTrendPeriods = map[string]string{
+	MarkupTrendDay:   "DATE_FORMAT(o.created_at, '%Y-%m-%d')",
+	MarkupTrendWeek:  "DATE_FORMAT(DATE_SUB(DATE(o.created_at), INTERVAL WEEKDAY(o.created_at) DAY), '%Y-%m-%d')",
+	MarkupTrendMonth: "DATE_FORMAT(o.created_at, '%Y-%m')",
+}
+
+// MarkupRevenueQuery 加价收入统计条件，EndDate 不含
+type MarkupRevenueQuery struct {
+	StartDate       time.Time
+	EndDate         time.Time
+	GroupBy         string
+	Interval        string
+	StoreID         uint64
+	SupplierID      uint64
+	CategoryID      uint64
+	RuleID          uint64
+	ExcludeRefunded bool
+	Limit           int
+}
+
+// MarkupRevenueTotals 加价收入汇总，BaseAmount 为供应商报价金额，MarkupRate 为加价金额占报价金额的百分比
+type MarkupRevenueTotals struct {
+	OrderCount   int64   `json:"orderCount"`
+	ItemCount    int64   `json:"itemCount"`
+	Quantity     int64   `json:"quantity"`
+	BaseAmount   float64 `json:"baseAmount"`
+	MarkupAmount float64 `json:"markupAmount"`
+	MarkupRate   float64 `json:"markupRate"`
+}
+
+// MarkupRevenueGroup 按维度汇总的加价收入，Share 为占加价收入的百分比
+// 按规则统计时 ID 为 0 表示未命中规则的明细(含记录规则之前的历史订单)
+type MarkupRevenueGroup struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+	MarkupRevenueTotals
+	Share float64 `json:"share"`
+}
+
+// MarkupRevenueReport 加价收入统计
+type MarkupRevenueReport struct {
+	StartDate       string               `json:"startDate"`
+	EndDate         string               `json:"endDate"`
+	GroupBy         string               `json:"groupBy"`
+	ExcludeRefunded bool                 `json:"excludeRefunded"`
+	Summary         MarkupRevenueTotals  `json:"summary"`
+	Groups          []MarkupRevenueGroup `json:"groups"`
+}
+
+// MarkupTrendPoint 趋势中一个周期的加价收入
+type MarkupTrendPoint struct {
+	Period       string  `json:"period"`
+	OrderCount   int64   `json:"orderCount"`
+	BaseAmount   float64 `json:"baseAmount"`
+	MarkupAmount float64 `json:"markupAmount"`
+}
+
+// MarkupTrendSeries 单个规则、分类、供应商或门店的趋势
+type MarkupTrendSeries struct {
+	ID           uint64             `json:"id"`
+	Name         string             `json:"name"`
+	MarkupAmount float64            `json:"markupAmount"`
+	Points       []MarkupTrendPoint `json:"points"`
+}
+
+// MarkupRevenueTrend 加价收入趋势，Series 为加价收入最高的若干个分组
+type MarkupRevenueTrend struct {
+	Interval        string              `json:"interval"`
+	GroupBy         string              `json:"groupBy,omitempty"`
+	ExcludeRefunded bool                `json:"excludeRefunded"`
+	Periods         []string            `json:"periods"`
+	Total           []MarkupTrendPoint  `json:"total"`
+	Series          []MarkupTrendSeries `json:"series,omitempty"`
+}
+
+// markupRevenueRow 统计查询结果
+type markupRevenueRow struct {
+	GroupID      uint64
+	Period       string
+	OrderCount   int64
+	ItemCount    int64
+	Quantity     int64
+	BaseAmount   float64
+	MarkupAmount float64
+}
+
+// MarkupRevenueService 加价收入统计服务，按订单明细的加价金额统计
+type MarkupRevenueService struct {
+	db *gorm.DB
+}
+
+// NewMarkupRevenueService 创建加价收入统计服务
+func NewMarkupRevenueService(db *gorm.DB) *MarkupRevenueService {
+	return &MarkupRevenueService{db: db}
+}
+
+// Report 按规则、分类、供应商或门店汇总时间范围内的加价收入
+func (s *MarkupRevenueService) Report(q *MarkupRevenueQuery) (*MarkupRevenueReport, error) {
+	dimension, ok := markupRevenueDimensions[q.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: 不支持的统计维度 %s", ErrMarkupRevenueQueryInvalid, q.GroupBy)
+	}
+	if err := validateMarkupRevenueRange(q, maxMarkupRevenueDays); err != nil {
+		return nil, err
+	}
+
+	var summary []markupRevenueRow
+	if err := s.query(q).Select(markupRevenueColumns).Scan(&summary).Error; err != nil {
+		return nil, err
+	}
+	var rows []markupRevenueRow
+	if err := s.query(q).
+		Select(dimension + " AS group_id, " + markupRevenueColumns).
+		Group("group_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	report := &MarkupRevenueReport{
+		StartDate:       q.StartDate.Format("2006-01-02"),
+		EndDate:         q.EndDate.AddDate(0, 0, -1).Format("2006-01-02"),
+		GroupBy:         q.GroupBy,
+		ExcludeRefunded: q.ExcludeRefunded,
+		Groups:          markupRevenueGroups(rows),
+	}
+	if len(summary) > 0 {
+		report.Summary = markupRevenueTotals(&summary[0])
+	}
+
+	ids := make([]uint64, 0, len(report.Groups))
+	for _, group := range report.Groups {
+		ids = append(ids, group.ID)
+	}
+	names, err := s.groupNames(q.GroupBy, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Groups {
+		report.Groups[i].Name = names[report.Groups[i].ID]
+	}
+	return report, nil
+}
+
+// Trend 按日、周或月统计加价收入趋势；指定维度时另返回加价收入最高的若干个分组的趋势
+func (s *MarkupRevenueService) Trend(q *MarkupRevenueQuery) (*MarkupRevenueTrend, error) {
+	period, ok := markupTrendPeriods[q.Interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: 不支持的趋势粒度 %s", ErrMarkupRevenueQueryInvalid, q.Interval)
+	}
+	dimension := ""
+	if q.GroupBy != "" {
+		if dimension, ok = markupRevenueDimensions[q.GroupBy]; !ok {
+			return nil, fmt.Errorf("%w: 不支持的统计维度 %s", ErrMarkupRevenueQueryInvalid, q.GroupBy)
+		}
+	}
+	maxDays := maxMarkupRevenueDays
+	if q.Interval == MarkupTrendDay {
+		maxDays = maxMarkupTrendDays
+	}
+	if err := validateMarkupRevenueRange(q, maxDays); err != nil {
+		return nil, err
+	}
+
+	var totals, rows []markupRevenueRow
+	if err := s.query(q).
+		Select(period + " AS period, " + markupRevenueColumns).
+		Group("period").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	if dimension != "" {
+		if err := s.query(q).
+			Select(dimension + " AS group_id, " + period + " AS period, " + markupRevenueColumns).
+			Group("group_id, period").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultMarkupTrendSeries
+	}
+	if limit > maxMarkupTrendSeries {
+		limit = maxMarkupTrendSeries
+	}
+	trend := buildMarkupTrend(totals, rows, markupTrendRange(q.StartDate, q.EndDate, q.Interval), limit)
+	trend.Interval = q.Interval
+	trend.GroupBy = q.GroupBy
+	trend.ExcludeRefunded = q.ExcludeRefunded
+
+	if len(trend.Series) > 0 {
+		ids := make([]uint64, 0, len(trend.Series))
+		for _, series := range trend.Series {
+			ids = append(ids, series.ID)
+		}
+		names, err := s.groupNames(q.GroupBy, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range trend.Series {
+			trend.Series[i].Name = names[trend.Series[i].ID]
+		}
+	}
+	return trend, nil
+}
+
+// markupRevenueColumns 明细汇总字段，加价金额和报价金额均为单件金额乘数量
+const markupRevenueColumns = `COUNT(DISTINCT oi.order_id) AS order_count, COUNT(*) AS item_count,
+	COALESCE(SUM(oi.quantity), 0) AS quantity,
+	COALESCE(SUM(oi.unit_price * oi.quantity), 0) AS base_amount,
+	COALESCE(SUM(oi.markup_amount * oi.quantity), 0) AS markup_amount`
+
+// query 时间范围内未取消订单的明细，分类按物料当前所属分类统计
+func (s *MarkupRevenueService) query(q *MarkupRevenueQuery) *gorm.DB {
+	query := s.db.Table("order_items oi").
+		Joins("JOIN orders o ON o.id = oi.order_id").
+		Joins("LEFT JOIN material_skus ms ON ms.id = oi.material_sku_id").
+		Joins("LEFT JOIN materials m ON m.id = ms.material_id").
+		Where("oi.deleted_at IS NULL AND o.deleted_at IS NULL").
+		Where("o.created_at >= ? AND o.created_at < ?", q.StartDate, q.EndDate).
+		Where("o.status <> ?", models.OrderStatusCancelled)
+	// 退款只记录到订单，部分退款无法对应到明细，因此整单排除
+	if q.ExcludeRefunded {
+		query = query.Where("o.payment_status NOT IN ?",
+			[]models.PaymentStatus{models.PaymentStatusRefunded, models.PaymentStatusPartialRefund})
+	}
+	if q.StoreID > 0 {
+		query = query.Where("o.store_id = ?", q.StoreID)
+	}
+	if q.SupplierID > 0 {
+		query = query.Where("o.supplier_id = ?", q.SupplierID)
+	}
+	if q.CategoryID > 0 {
+		query = query.Where("m.category_id = ?", q.CategoryID)
+	}
+	if q.RuleID > 0 {
+		query = query.Where("oi.markup_rule_id = ?", q.RuleID)
+	}
+	return query
+}
+
+// groupNames 查询分组名称，已删除的规则、门店、供应商和分类仍显示原名称
+func (s *MarkupRevenueService) groupNames(groupBy string, ids []uint64) (map[uint64]string, error) {
+	names := make(map[uint64]string)
+	if groupBy == MarkupRevenueByRule {
+		names[0] = markupRevenueNoRule
+	}
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	table := map[string]string{
+		MarkupRevenueByRule:     "price_markups",
+		MarkupRevenueByCategory: "categories",
+		MarkupRevenueBySupplier: "suppliers",
+		MarkupRevenueByStore:    "stores",
+	}[groupBy]
+	var records []markupRefRecord
+	if err := s.db.Table(table).Select("id, name").Where("id IN ?", uniqueUint64(ids)).Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		names[record.ID] = record.Name
+	}
+	return names, nil
+}
+
+// validateMarkupRevenueRange 校验统计时间范围
+func validateMarkupRevenueRange(q *MarkupRevenueQuery, maxDays int) error {
+	if !q.EndDate.After(q.StartDate) {
+		return fmt.Errorf("%w: 结束日期不能早于开始日期", ErrMarkupRevenueQueryInvalid)
+	}
+	if q.EndDate.Sub(q.StartDate) > time.Duration(maxDays)*24*time.Hour {
+		return fmt.Errorf("%w: 时间范围最长%d天", ErrMarkupRevenueQueryInvalid, maxDays)
+	}
+	return nil
+}
+
+// markupRevenueTotals 汇总行转为统计结果，金额保留两位小数
+func markupRevenueTotals(row *markupRevenueRow) MarkupRevenueTotals {
+	base := decimal.NewFromFloat(row.BaseAmount).Round(2)
+	markup := decimal.NewFromFloat(row.MarkupAmount).Round(2)
+	totals := MarkupRevenueTotals{
+		OrderCount:   row.OrderCount,
+		ItemCount:    row.ItemCount,
+		Quantity:     row.Quantity,
+		BaseAmount:   base.InexactFloat64(),
+		MarkupAmount: markup.InexactFloat64(),
+	}
+	if base.IsPositive() {
+		totals.MarkupRate = markup.Div(base).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+	}
+	return totals
+}
+
+// markupRevenueGroups 计算各分组占比，按加价收入从高到低排序
+func markupRevenueGroups(rows []markupRevenueRow) []MarkupRevenueGroup {
+	total := decimal.Zero
+	for _, row := range rows {
+		total = total.Add(decimal.NewFromFloat(row.MarkupAmount))
+	}
+
+	groups := make([]MarkupRevenueGroup, 0, len(rows))
+	for i := range rows {
+		group := MarkupRevenueGroup{ID: rows[i].GroupID, MarkupRevenueTotals: markupRevenueTotals(&rows[i])}
+		if total.IsPositive() {
+			group.Share = decimal.NewFromFloat(rows[i].MarkupAmount).Div(total).Mul(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].MarkupAmount != groups[j].MarkupAmount {
+			return groups[i].MarkupAmount > groups[j].MarkupAmount
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// markupTrendRange 列出时间范围内的全部周期，与 markupTrendPeriods 的格式一致
+func markupTrendRange(start, end time.Time, interval string) []string {
+	var periods []string
+	switch interval {
+	case MarkupTrendMonth:
+		for t := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location()); t.Before(end); t = t.AddDate(0, 1, 0) {
+			periods = append(periods, t.Format("2006-01"))
+		}
+	case MarkupTrendWeek:
+		t := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		t = t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		for ; t.Before(end); t = t.AddDate(0, 0, 7) {
+			periods = append(periods, t.Format("2006-01-02"))
+		}
+	default:
+		for t := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); t.Before(end); t = t.AddDate(0, 0, 1) {
+			periods = append(periods, t.Format("2006-01-02"))
+		}
+	}
+	return periods
+}
+
+// buildMarkupTrend 按周期生成总趋势和分组趋势，补齐无数据的周期；分组取加价收入最高的 limit 个
+func buildMarkupTrend(totals, groupRows []markupRevenueRow, periods []string, limit int) *MarkupRevenueTrend {
+	trend := &MarkupRevenueTrend{
+		Periods: periods,
+		Total:   markupTrendPoints(totals, periods),
+	}
+	if len(groupRows) == 0 {
+		return trend
+	}
+
+	byGroup := make(map[uint64][]markupRevenueRow)
+	groupMarkup := make(map[uint64]decimal.Decimal)
+	for _, row := range groupRows {
+		byGroup[row.GroupID] = append(byGroup[row.GroupID], row)
+		groupMarkup[row.GroupID] = groupMarkup[row.GroupID].Add(decimal.NewFromFloat(row.MarkupAmount))
+	}
+	ids := make([]uint64, 0, len(byGroup))
+	for id := range byGroup {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if cmp := groupMarkup[ids[i]].Cmp(groupMarkup[ids[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	trend.Series = make([]MarkupTrendSeries, 0, len(ids))
+	for _, id := range ids {
+		trend.Series = append(trend.Series, MarkupTrendSeries{
+			ID:           id,
+			MarkupAmount: groupMarkup[id].Round(2).InexactFloat64(),
+			Points:       markupTrendPoints(byGroup[id], periods),
+		})
+	}
+	return trend
+}
+
+// markupTrendPoints 按周期排列，无数据的周期为 0
+func markupTrendPoints(rows []markupRevenueRow, periods []string) []MarkupTrendPoint {
+	byPeriod := make(map[string]*markupRevenueRow, len(rows))
+	for i := range rows {
+		byPeriod[rows[i].Period] = &rows[i]
+	}
+	points := make([]MarkupTrendPoint, 0, len(periods))
+	for _, period := range periods {
+		point := MarkupTrendPoint{Period: period}
+		if row, ok := byPeriod[period]; ok {
+			point.OrderCount = row.OrderCount
+			point.BaseAmount = decimal.NewFromFloat(row.BaseAmount).Round(2).InexactFloat64()
+			point.MarkupAmount = decimal.NewFromFloat(row.MarkupAmount).Round(2).InexactFloat64()
+		}
+		points = append(points, point)
+	}
+	return points
+}
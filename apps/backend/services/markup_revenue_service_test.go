@@ -0,0 +1,78 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarkupTrendRange(t *testing.T) {
+	start := time.Date(2026, 9, 30, 0, 0, 0, 0, time.Local)
+	end := time.Date(2026, 10, 13, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		interval string
+		expected []string
+	}{
+		{MarkupTrendMonth, []string{"2026-09", "2026-10"}},
+		{MarkupTrendWeek, []string{"2026-09-28", "2026-10-05", "2026-10-12"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			if got := markupTrendRange(start, end, tt.interval); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("markupTrendRange() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	days := markupTrendRange(start, end, MarkupTrendDay)
+	if len(days) != 13 || days[0] != "2026-09-30" || days[12] != "2026-10-12" {
+		t.Errorf("markupTrendRange(day) = %v, expected 2026-09-30 .. 2026-10-12", days)
+	}
+}
+
+func TestMarkupRevenueGroups(t *testing.T) {
+	groups := markupRevenueGroups([]markupRevenueRow{
+		{GroupID: 0, OrderCount: 2, BaseAmount: 100, MarkupAmount: 0},
+		{GroupID: 3, OrderCount: 5, BaseAmount: 400, MarkupAmount: 30},
+		{GroupID: 7, OrderCount: 4, BaseAmount: 200, MarkupAmount: 10},
+	})
+
+	if len(groups) != 3 || groups[0].ID != 3 || groups[1].ID != 7 || groups[2].ID != 0 {
+		t.Fatalf("groups = %+v, expected order 3, 7, 0", groups)
+	}
+	if groups[0].Share != 75 || groups[0].MarkupRate != 7.5 {
+		t.Errorf("groups[0] share/rate = %v/%v, expected 75/7.5", groups[0].Share, groups[0].MarkupRate)
+	}
+	if groups[2].Share != 0 || groups[2].MarkupRate != 0 {
+		t.Errorf("groups[2] share/rate = %v/%v, expected 0/0", groups[2].Share, groups[2].MarkupRate)
+	}
+}
+
+func TestBuildMarkupTrend(t *testing.T) {
+	periods := []string{"2026-10-01", "2026-10-02", "2026-10-03"}
+	totals := []markupRevenueRow{
+		{Period: "2026-10-01", OrderCount: 3, BaseAmount: 300, MarkupAmount: 15},
+		{Period: "2026-10-03", OrderCount: 1, BaseAmount: 50, MarkupAmount: 2.5},
+	}
+	groupRows := []markupRevenueRow{
+		{GroupID: 1, Period: "2026-10-01", OrderCount: 2, MarkupAmount: 10},
+		{GroupID: 2, Period: "2026-10-01", OrderCount: 2, MarkupAmount: 5},
+		{GroupID: 2, Period: "2026-10-03", OrderCount: 1, MarkupAmount: 2.5},
+	}
+
+	trend := buildMarkupTrend(totals, groupRows, periods, 1)
+	if len(trend.Total) != 3 || trend.Total[1].MarkupAmount != 0 || trend.Total[0].OrderCount != 3 {
+		t.Errorf("Total = %+v, expected three periods with the gap filled", trend.Total)
+	}
+	if len(trend.Series) != 1 || trend.Series[0].ID != 1 || trend.Series[0].MarkupAmount != 10 {
+		t.Fatalf("Series = %+v, expected only group 1", trend.Series)
+	}
+	if points := trend.Series[0].Points; len(points) != 3 || points[2].MarkupAmount != 0 {
+		t.Errorf("Series[0].Points = %+v, expected three periods", points)
+	}
+
+	if trend := buildMarkupTrend(totals, nil, periods, 5); trend.Series != nil {
+		t.Errorf("Series = %+v, expected none without group rows", trend.Series)
+	}
+}
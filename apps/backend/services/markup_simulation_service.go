@@ -0,0 +1,553 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 加价模拟错误
+var (
+	ErrSimulationRangeInvalid = errors.New("模拟时间范围无效，最长92天")
+	ErrSimulationRuleNotFound = errors.New("草稿中修改的加价规则不存在")
+)
+
+// 加价模拟参数
+const (
+	maxSimulationDays     = 92
+	defaultSimulationTopN = 20
+	maxSimulationTopN     = 100
+	simulationBatchSize   = 1000
+)
+
+// MarkupSimulationService 加价模拟服务
+// 用草稿规则集重新计算历史订单明细的加价，与现行规则对比，不保存任何数据
+type MarkupSimulationService struct {
+	db            *gorm.DB
+	markupService *PriceMarkupService
+	pricing       *OrderPricingService
+}
+
+// NewMarkupSimulationService 创建加价模拟服务
+func NewMarkupSimulationService(db *gorm.DB) *MarkupSimulationService {
+	return &MarkupSimulationService{
+		db:            db,
+		markupService: NewPriceMarkupService(db),
+		pricing:       NewOrderPricingService(db),
+	}
+}
+
+// MarkupRuleDraftUpdate 草稿中修改的规则，按 ID 整体替换规则内容
+type MarkupRuleDraftUpdate struct {
+	ID uint64 `json:"id" validate:"required"`
+	CreatePriceMarkupRequest
+}
+
+// MarkupRuleDraft 草稿规则集：在现行生效规则的基础上新增、修改或停用
+type MarkupRuleDraft struct {
+	Add     []CreatePriceMarkupRequest `json:"add" validate:"dive"`
+	Update  []MarkupRuleDraftUpdate    `json:"update" validate:"dive"`
+	Disable []uint64                   `json:"disable"`
+}
+
+// MarkupSimulationRequest 加价模拟请求
+type MarkupSimulationRequest struct {
+	StartDate  time.Time
+	EndDate    time.Time // 不含当天
+	StoreID    uint64
+	SupplierID uint64
+	TopN       int
+	Draft      MarkupRuleDraft
+}
+
+// MarkupSimulationGroup 按分类或供应商汇总的模拟结果
+type MarkupSimulationGroup struct {
+	ID            uint64  `json:"id"`
+	Name          string  `json:"name"`
+	ItemCount     int     `json:"itemCount"`
+	CurrentSpend  float64 `json:"currentSpend"`
+	DraftSpend    float64 `json:"draftSpend"`
+	SpendDelta    float64 `json:"spendDelta"`
+	CurrentMarkup float64 `json:"currentMarkup"`
+	DraftMarkup   float64 `json:"draftMarkup"`
+	MarkupDelta   float64 `json:"markupDelta"`
+}
+
+// MarkupSimulationItem 价格变动的订单明细
+type MarkupSimulationItem struct {
+	OrderItemID   uint64  `json:"orderItemId"`
+	OrderID       uint64  `json:"orderId"`
+	OrderNo       string  `json:"orderNo"`
+	StoreID       uint64  `json:"storeId"`
+	SupplierID    uint64  `json:"supplierId"`
+	MaterialSkuID uint64  `json:"materialSkuId"`
+	MaterialName  string  `json:"materialName"`
+	Quantity      int     `json:"quantity"`
+	UnitPrice     float64 `json:"unitPrice"`
+	CurrentMarkup float64 `json:"currentMarkup"` // 单件加价
+	DraftMarkup   float64 `json:"draftMarkup"`
+	CurrentRuleID *uint64 `json:"currentRuleId,omitempty"`
+	DraftRuleID   *uint64 `json:"draftRuleId,omitempty"` // 草稿新增规则为 0
+	PriceDelta    float64 `json:"priceDelta"`            // 单价变动
+	SubtotalDelta float64 `json:"subtotalDelta"`         // 小计变动
+}
+
+// MarkupSimulationResult 加价模拟结果
+type MarkupSimulationResult struct {
+	OrderCount    int                     `json:"orderCount"`
+	ItemCount     int                     `json:"itemCount"`
+	ChangedCount  int                     `json:"changedCount"`
+	ActualMarkup  float64                 `json:"actualMarkup"`  // 下单时实际加价
+	CurrentMarkup float64                 `json:"currentMarkup"` // 按现行规则重算
+	DraftMarkup   float64                 `json:"draftMarkup"`   // 按草稿规则重算
+	MarkupDelta   float64                 `json:"markupDelta"`   // 草稿相对现行规则的加价收入变化
+	CurrentSpend  float64                 `json:"currentSpend"`
+	DraftSpend    float64                 `json:"draftSpend"`
+	SpendDelta    float64                 `json:"spendDelta"`
+	ByCategory    []MarkupSimulationGroup `json:"byCategory"`
+	BySupplier    []MarkupSimulationGroup `json:"bySupplier"`
+	TopChanges    []MarkupSimulationItem  `json:"topChanges"`
+}
+
+// markupSimItem 参与模拟的历史订单明细
+type markupSimItem struct {
+	OrderItemID   uint64
+	OrderID       uint64
+	OrderNo       string
+	StoreID       uint64
+	SupplierID    uint64
+	CategoryID    uint64
+	MaterialID    uint64
+	MaterialSkuID uint64
+	MaterialName  string
+	Quantity      int
+	UnitPrice     float64
+	MarkupAmount  float64
+	MarkupEnabled bool `gorm:"-"` // 门店、供应商、分类及全局加价开关均开启
+}
+
+// Simulate 执行加价模拟；规则有效期和加价开关均按当前时间判断
+func (s *MarkupSimulationService) Simulate(req *MarkupSimulationRequest) (*MarkupSimulationResult, error) {
+	if !req.EndDate.After(req.StartDate) || req.EndDate.Sub(req.StartDate) > maxSimulationDays*24*time.Hour {
+		return nil, ErrSimulationRangeInvalid
+	}
+
+	current, err := s.markupService.GetActiveRules()
+	if err != nil {
+		return nil, err
+	}
+	sortMarkupRules(current)
+	draft, err := s.buildDraftRules(current, &req.Draft)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.loadItems(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySwitches(items); err != nil {
+		return nil, err
+	}
+
+	result := simulateMarkup(items, current, draft, s.markupService, req.TopN)
+	if err := s.fillGroupNames(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// buildDraftRules 在现行规则上应用草稿，返回草稿生效时的规则集
+func (s *MarkupSimulationService) buildDraftRules(current []PriceMarkup, draft *MarkupRuleDraft) ([]PriceMarkup, error) {
+	rules := make(map[uint64]PriceMarkup, len(current))
+	for _, rule := range current {
+		rules[rule.ID] = rule
+	}
+
+	for _, update := range draft.Update {
+		if err := ValidateMarkupTiers(update.Tiers); err != nil {
+			return nil, err
+		}
+		rule, ok := rules[update.ID]
+		if !ok {
+			// 修改的可能是当前未生效的规则
+			if err := s.db.First(&rule, update.ID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, ErrSimulationRuleNotFound
+				}
+				return nil, err
+			}
+		}
+		rules[update.ID] = draftMarkupRule(rule.ID, rule.CreatedAt, &update.CreatePriceMarkupRequest)
+	}
+	for _, id := range draft.Disable {
+		delete(rules, id)
+	}
+
+	now := time.Now()
+	result := make([]PriceMarkup, 0, len(rules)+len(draft.Add))
+	for _, rule := range rules {
+		result = append(result, rule)
+	}
+	// 新增规则排在同优先级的已有规则之后
+	for i := range draft.Add {
+		if err := ValidateMarkupTiers(draft.Add[i].Tiers); err != nil {
+			return nil, err
+		}
+		rule := draftMarkupRule(0, now, &draft.Add[i])
+		rule.CreatedAt = now.Add(time.Duration(i) * time.Nanosecond)
+		result = append(result, rule)
+	}
+
+	active := result[:0]
+	for _, rule := range result {
+		if markupRuleValidAt(&rule, now) {
+			active = append(active, rule)
+		}
+	}
+	sortMarkupRules(active)
+	return active, nil
+}
+
+// loadItems 分批加载时间范围内未取消订单的明细
+func (s *MarkupSimulationService) loadItems(req *MarkupSimulationRequest) ([]markupSimItem, error) {
+	var items []markupSimItem
+	var lastID uint64
+	for {
+		query := s.db.Table("order_items oi").
+			Select(`oi.id AS order_item_id, oi.order_id, o.order_no, o.store_id, o.supplier_id,
+				m.category_id, m.id AS material_id, oi.material_sku_id, oi.material_name,
+				oi.quantity, oi.unit_price, oi.markup_amount`).
+			Joins("JOIN orders o ON o.id = oi.order_id").
+			Joins("JOIN material_skus ms ON ms.id = oi.material_sku_id").
+			Joins("JOIN materials m ON m.id = ms.material_id").
+			Where("oi.id > ? AND oi.deleted_at IS NULL AND o.deleted_at IS NULL", lastID).
+			Where("o.created_at >= ? AND o.created_at < ?", req.StartDate, req.EndDate).
+			Where("o.status <> ?", models.OrderStatusCancelled)
+		if req.StoreID > 0 {
+			query = query.Where("o.store_id = ?", req.StoreID)
+		}
+		if req.SupplierID > 0 {
+			query = query.Where("o.supplier_id = ?", req.SupplierID)
+		}
+
+		var batch []markupSimItem
+		if err := query.Order("oi.id ASC").Limit(simulationBatchSize).Scan(&batch).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if len(batch) < simulationBatchSize {
+			return items, nil
+		}
+		lastID = batch[len(batch)-1].OrderItemID
+	}
+}
+
+// applySwitches 按当前加价开关标记明细是否参与加价，与下单定价一致
+func (s *MarkupSimulationService) applySwitches(items []markupSimItem) error {
+	if !s.pricing.isGlobalMarkupEnabled() {
+		return nil
+	}
+
+	storeIDs := make([]uint64, 0)
+	supplierIDs := make([]uint64, 0)
+	for _, item := range items {
+		storeIDs = append(storeIDs, item.StoreID)
+		supplierIDs = append(supplierIDs, item.SupplierID)
+	}
+
+	var stores []models.Store
+	if err := s.db.Select("id", "markup_enabled").Where("id IN ?", uniqueUint64(storeIDs)).Find(&stores).Error; err != nil {
+		return err
+	}
+	var suppliers []models.Supplier
+	if err := s.db.Select("id", "markup_enabled").Where("id IN ?", uniqueUint64(supplierIDs)).Find(&suppliers).Error; err != nil {
+		return err
+	}
+	storeEnabled := make(map[uint64]bool, len(stores))
+	for _, store := range stores {
+		storeEnabled[store.ID] = store.MarkupEnabled == 1
+	}
+	supplierEnabled := make(map[uint64]bool, len(suppliers))
+	for _, supplier := range suppliers {
+		supplierEnabled[supplier.ID] = supplier.MarkupEnabled == 1
+	}
+
+	categorySwitches := make(map[uint64]bool)
+	for i := range items {
+		item := &items[i]
+		item.MarkupEnabled = storeEnabled[item.StoreID] && supplierEnabled[item.SupplierID] &&
+			s.pricing.isCategoryMarkupEnabled(item.CategoryID, categorySwitches)
+	}
+	return nil
+}
+
+// fillGroupNames 补充分类和供应商名称
+func (s *MarkupSimulationService) fillGroupNames(result *MarkupSimulationResult) error {
+	categoryIDs := make([]uint64, 0, len(result.ByCategory))
+	for _, group := range result.ByCategory {
+		categoryIDs = append(categoryIDs, group.ID)
+	}
+	supplierIDs := make([]uint64, 0, len(result.BySupplier))
+	for _, group := range result.BySupplier {
+		supplierIDs = append(supplierIDs, group.ID)
+	}
+
+	names := make(map[uint64]string)
+	if len(categoryIDs) > 0 {
+		var categories []models.Category
+		if err := s.db.Select("id", "name").Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
+		for _, category := range categories {
+			names[category.ID] = category.Name
+		}
+		for i := range result.ByCategory {
+			result.ByCategory[i].Name = names[result.ByCategory[i].ID]
+		}
+	}
+
+	names = make(map[uint64]string)
+	if len(supplierIDs) > 0 {
+		var suppliers []models.Supplier
+		if err := s.db.Select("id", "name").Where("id IN ?", supplierIDs).Find(&suppliers).Error; err != nil {
+			return err
+		}
+		for _, supplier := range suppliers {
+			names[supplier.ID] = supplier.Name
+		}
+		for i := range result.BySupplier {
+			result.BySupplier[i].Name = names[result.BySupplier[i].ID]
+		}
+	}
+	return nil
+}
+
+// draftMarkupRule 由草稿内容构造规则
+func draftMarkupRule(id uint64, createdAt time.Time, req *CreatePriceMarkupRequest) PriceMarkup {
+	return PriceMarkup{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		StoreID:     req.StoreID,
+		SupplierID:  req.SupplierID,
+		CategoryID:  req.CategoryID,
+		MaterialID:  req.MaterialID,
+		MarkupType:  req.MarkupType,
+		MarkupValue: req.MarkupValue,
+		Tiers:       req.Tiers,
+		MinMarkup:   req.MinMarkup,
+		MaxMarkup:   req.MaxMarkup,
+		Priority:    req.Priority,
+		IsActive:    req.IsActive,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		CreatedAt:   createdAt,
+	}
+}
+
+// markupRuleValidAt 规则在指定时间是否生效，与 GetActiveRules 的条件一致
+func markupRuleValidAt(rule *PriceMarkup, t time.Time) bool {
+	if !rule.IsActive {
+		return false
+	}
+	if rule.StartTime != nil && rule.StartTime.After(t) {
+		return false
+	}
+	if rule.EndTime != nil && rule.EndTime.Before(t) {
+		return false
+	}
+	return true
+}
+
+// sortMarkupRules 按优先级从高到低排序，同优先级先创建的在前
+func sortMarkupRules(rules []PriceMarkup) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+}
+
+// simulatedMarkup 单件加价及命中的规则
+func simulatedMarkup(markupService *PriceMarkupService, rules []PriceMarkup, item *markupSimItem) (decimal.Decimal, *PriceMarkup) {
+	if !item.MarkupEnabled {
+		return decimal.Zero, nil
+	}
+	req := &CalculateMarkupRequest{
+		StoreID:       item.StoreID,
+		SupplierID:    item.SupplierID,
+		CategoryID:    item.CategoryID,
+		MaterialID:    item.MaterialID,
+		OriginalPrice: item.UnitPrice,
+		Quantity:      item.Quantity,
+	}
+	for i := range rules {
+		if markupService.matchRule(&rules[i], req) {
+			return decimal.NewFromFloat(markupService.calculateMarkupAmount(&rules[i], item.UnitPrice, item.Quantity)).Round(2), &rules[i]
+		}
+	}
+	return decimal.Zero, nil
+}
+
+// simulationTotals 模拟金额累计
+type simulationTotals struct {
+	itemCount     int
+	currentSpend  decimal.Decimal
+	draftSpend    decimal.Decimal
+	currentMarkup decimal.Decimal
+	draftMarkup   decimal.Decimal
+}
+
+func (t *simulationTotals) add(currentSpend, draftSpend, currentMarkup, draftMarkup decimal.Decimal) {
+	t.itemCount++
+	t.currentSpend = t.currentSpend.Add(currentSpend)
+	t.draftSpend = t.draftSpend.Add(draftSpend)
+	t.currentMarkup = t.currentMarkup.Add(currentMarkup)
+	t.draftMarkup = t.draftMarkup.Add(draftMarkup)
+}
+
+func (t *simulationTotals) group(id uint64) MarkupSimulationGroup {
+	return MarkupSimulationGroup{
+		ID:            id,
+		ItemCount:     t.itemCount,
+		CurrentSpend:  t.currentSpend.Round(2).InexactFloat64(),
+		DraftSpend:    t.draftSpend.Round(2).InexactFloat64(),
+		SpendDelta:    t.draftSpend.Sub(t.currentSpend).Round(2).InexactFloat64(),
+		CurrentMarkup: t.currentMarkup.Round(2).InexactFloat64(),
+		DraftMarkup:   t.draftMarkup.Round(2).InexactFloat64(),
+		MarkupDelta:   t.draftMarkup.Sub(t.currentMarkup).Round(2).InexactFloat64(),
+	}
+}
+
+// simulateMarkup 用现行规则和草稿规则分别重算明细加价并汇总
+func simulateMarkup(items []markupSimItem, current, draft []PriceMarkup, markupService *PriceMarkupService, topN int) *MarkupSimulationResult {
+	if topN <= 0 {
+		topN = defaultSimulationTopN
+	}
+	topN = min(topN, maxSimulationTopN)
+
+	var total simulationTotals
+	actual := decimal.Zero
+	orders := make(map[uint64]struct{})
+	byCategory := make(map[uint64]*simulationTotals)
+	bySupplier := make(map[uint64]*simulationTotals)
+	var changed []MarkupSimulationItem
+
+	for i := range items {
+		item := &items[i]
+		qty := decimal.NewFromInt(int64(item.Quantity))
+		unitPrice := decimal.NewFromFloat(item.UnitPrice)
+		currentMarkup, currentRule := simulatedMarkup(markupService, current, item)
+		draftMarkup, draftRule := simulatedMarkup(markupService, draft, item)
+
+		currentSpend := unitPrice.Add(currentMarkup).Mul(qty)
+		draftSpend := unitPrice.Add(draftMarkup).Mul(qty)
+		currentTotal := currentMarkup.Mul(qty)
+		draftTotal := draftMarkup.Mul(qty)
+
+		orders[item.OrderID] = struct{}{}
+		actual = actual.Add(decimal.NewFromFloat(item.MarkupAmount).Mul(qty))
+		total.add(currentSpend, draftSpend, currentTotal, draftTotal)
+		if byCategory[item.CategoryID] == nil {
+			byCategory[item.CategoryID] = &simulationTotals{}
+		}
+		byCategory[item.CategoryID].add(currentSpend, draftSpend, currentTotal, draftTotal)
+		if bySupplier[item.SupplierID] == nil {
+			bySupplier[item.SupplierID] = &simulationTotals{}
+		}
+		bySupplier[item.SupplierID].add(currentSpend, draftSpend, currentTotal, draftTotal)
+
+		if draftMarkup.Equal(currentMarkup) {
+			continue
+		}
+		change := MarkupSimulationItem{
+			OrderItemID:   item.OrderItemID,
+			OrderID:       item.OrderID,
+			OrderNo:       item.OrderNo,
+			StoreID:       item.StoreID,
+			SupplierID:    item.SupplierID,
+			MaterialSkuID: item.MaterialSkuID,
+			MaterialName:  item.MaterialName,
+			Quantity:      item.Quantity,
+			UnitPrice:     item.UnitPrice,
+			CurrentMarkup: currentMarkup.InexactFloat64(),
+			DraftMarkup:   draftMarkup.InexactFloat64(),
+			PriceDelta:    draftMarkup.Sub(currentMarkup).InexactFloat64(),
+			SubtotalDelta: draftSpend.Sub(currentSpend).Round(2).InexactFloat64(),
+		}
+		if currentRule != nil {
+			id := currentRule.ID
+			change.CurrentRuleID = &id
+		}
+		if draftRule != nil {
+			id := draftRule.ID
+			change.DraftRuleID = &id
+		}
+		changed = append(changed, change)
+	}
+
+	sort.SliceStable(changed, func(i, j int) bool {
+		a, b := decimal.NewFromFloat(changed[i].SubtotalDelta).Abs(), decimal.NewFromFloat(changed[j].SubtotalDelta).Abs()
+		if !a.Equal(b) {
+			return a.GreaterThan(b)
+		}
+		return changed[i].OrderItemID < changed[j].OrderItemID
+	})
+
+	summary := total.group(0)
+	result := &MarkupSimulationResult{
+		OrderCount:    len(orders),
+		ItemCount:     len(items),
+		ChangedCount:  len(changed),
+		ActualMarkup:  actual.Round(2).InexactFloat64(),
+		CurrentMarkup: summary.CurrentMarkup,
+		DraftMarkup:   summary.DraftMarkup,
+		MarkupDelta:   summary.MarkupDelta,
+		CurrentSpend:  summary.CurrentSpend,
+		DraftSpend:    summary.DraftSpend,
+		SpendDelta:    summary.SpendDelta,
+		ByCategory:    simulationGroups(byCategory),
+		BySupplier:    simulationGroups(bySupplier),
+		TopChanges:    changed[:min(topN, len(changed))],
+	}
+	if result.TopChanges == nil {
+		result.TopChanges = []MarkupSimulationItem{}
+	}
+	return result
+}
+
+// simulationGroups 汇总结果按门店支出变化绝对值从大到小排序
+func simulationGroups(totals map[uint64]*simulationTotals) []MarkupSimulationGroup {
+	groups := make([]MarkupSimulationGroup, 0, len(totals))
+	for id, t := range totals {
+		groups = append(groups, t.group(id))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := decimal.NewFromFloat(groups[i].SpendDelta).Abs(), decimal.NewFromFloat(groups[j].SpendDelta).Abs()
+		if !a.Equal(b) {
+			return a.GreaterThan(b)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// uniqueUint64 去重
+func uniqueUint64(values []uint64) []uint64 {
+	seen := make(map[uint64]struct{}, len(values))
+	result := make([]uint64, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateMarkup(t *testing.T) {
+	id := func(v uint64) *uint64 { return &v }
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	markupService := &PriceMarkupService{}
+
+	current := []PriceMarkup{
+		{ID: 1, Name: "全局5%", MarkupType: MarkupTypePercent, MarkupValue: 0.05, IsActive: true, CreatedAt: created},
+	}
+	draft := []PriceMarkup{
+		{ID: 2, Name: "蔬菜固定1元", CategoryID: id(10), MarkupType: MarkupTypeFixed, MarkupValue: 1, Priority: 10, IsActive: true, CreatedAt: created},
+		{ID: 1, Name: "全局5%", MarkupType: MarkupTypePercent, MarkupValue: 0.05, IsActive: true, CreatedAt: created},
+	}
+
+	items := []markupSimItem{
+		{OrderItemID: 1, OrderID: 100, SupplierID: 7, CategoryID: 10, Quantity: 10, UnitPrice: 10, MarkupAmount: 0.5, MarkupEnabled: true},
+		{OrderItemID: 2, OrderID: 100, SupplierID: 7, CategoryID: 20, Quantity: 2, UnitPrice: 100, MarkupAmount: 5, MarkupEnabled: true},
+		{OrderItemID: 3, OrderID: 101, SupplierID: 8, CategoryID: 10, Quantity: 1, UnitPrice: 40, MarkupAmount: 2, MarkupEnabled: true},
+		{OrderItemID: 4, OrderID: 102, SupplierID: 8, CategoryID: 10, Quantity: 5, UnitPrice: 10, MarkupAmount: 0, MarkupEnabled: false},
+	}
+
+	result := simulateMarkup(items, current, draft, markupService, 2)
+
+	if result.OrderCount != 3 || result.ItemCount != 4 {
+		t.Errorf("counts = (%d, %d), expected (3, 4)", result.OrderCount, result.ItemCount)
+	}
+	if result.ActualMarkup != 17 {
+		t.Errorf("ActualMarkup = %v, expected 17", result.ActualMarkup)
+	}
+	if result.CurrentMarkup != 17 {
+		t.Errorf("CurrentMarkup = %v, expected 17", result.CurrentMarkup)
+	}
+	// 蔬菜明细改为每件1元：10*1 + 1*1 = 11，原为 5 + 2 = 7
+	if result.DraftMarkup != 21 || result.MarkupDelta != 4 {
+		t.Errorf("DraftMarkup = %v, MarkupDelta = %v, expected 21 and 4", result.DraftMarkup, result.MarkupDelta)
+	}
+	if result.SpendDelta != 4 {
+		t.Errorf("SpendDelta = %v, expected 4", result.SpendDelta)
+	}
+	if result.ChangedCount != 2 {
+		t.Errorf("ChangedCount = %d, expected 2", result.ChangedCount)
+	}
+	if len(result.TopChanges) != 2 || result.TopChanges[0].OrderItemID != 1 || result.TopChanges[0].SubtotalDelta != 5 {
+		t.Errorf("TopChanges = %+v, expected item 1 first with delta 5", result.TopChanges)
+	}
+	if result.TopChanges[1].SubtotalDelta != -1 || *result.TopChanges[1].DraftRuleID != 2 {
+		t.Errorf("TopChanges[1] = %+v, expected delta -1 by rule 2", result.TopChanges[1])
+	}
+
+	if len(result.ByCategory) != 2 || result.ByCategory[0].ID != 10 || result.ByCategory[0].SpendDelta != 4 {
+		t.Errorf("ByCategory = %+v, expected category 10 first with delta 4", result.ByCategory)
+	}
+	if len(result.BySupplier) != 2 || result.BySupplier[0].ID != 7 || result.BySupplier[0].MarkupDelta != 5 {
+		t.Errorf("BySupplier = %+v, expected supplier 7 first with delta 5", result.BySupplier)
+	}
+}
+
+func TestSortMarkupRules(t *testing.T) {
+	early := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	late := early.Add(time.Hour)
+	rules := []PriceMarkup{
+		{ID: 1, Priority: 1, CreatedAt: early},
+		{ID: 2, Priority: 5, CreatedAt: late},
+		{ID: 3, Priority: 5, CreatedAt: early},
+	}
+
+	sortMarkupRules(rules)
+	if rules[0].ID != 3 || rules[1].ID != 2 || rules[2].ID != 1 {
+		t.Errorf("sortMarkupRules() order = [%d %d %d], expected [3 2 1]", rules[0].ID, rules[1].ID, rules[2].ID)
+	}
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// 规则未命中/未生效原因
+const (
+	MarkupReasonInactive         = "inactive"
+	MarkupReasonNotStarted       = "not_started"
+	MarkupReasonExpired          = "expired"
+	MarkupReasonStoreMismatch    = "store_mismatch"
+	MarkupReasonSupplierMismatch = "supplier_mismatch"
+	MarkupReasonCategoryMismatch = "category_mismatch"
+	MarkupReasonMaterialMismatch = "material_mismatch"
+	MarkupReasonSwitchDisabled   = "switch_disabled"
+	MarkupReasonOutranked        = "outranked"
+	MarkupReasonScopeImpossible  = "scope_impossible"
+	MarkupReasonShadowed         = "shadowed"
+)
+
+// 加价开关类型
+const (
+	MarkupSwitchGlobal   = "global"
+	MarkupSwitchStore    = "store"
+	MarkupSwitchSupplier = "supplier"
+	MarkupSwitchCategory = "category"
+)
+
+// 最小/最大加价限制
+const (
+	MarkupClampMin = "min"
+	MarkupClampMax = "max"
+)
+
+// MarkupTraceReason 规则判定原因
+type MarkupTraceReason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MarkupSwitchState 加价开关状态
+type MarkupSwitchState struct {
+	Type     string `json:"type"`
+	TargetID uint64 `json:"targetId,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// MarkupRuleTrace 单条规则的判定结果
+type MarkupRuleTrace struct {
+	RuleID   uint64              `json:"ruleId"`
+	Name     string              `json:"name"`
+	Priority int                 `json:"priority"`
+	Matched  bool                `json:"matched"` // 范围和有效期均满足
+	Applied  bool                `json:"applied"`
+	Reasons  []MarkupTraceReason `json:"reasons"`
+}
+
+// MarkupClampTrace 最小/最大加价限制的应用情况
+type MarkupClampTrace struct {
+	RawMarkup float64 `json:"rawMarkup"`
+	MinMarkup float64 `json:"minMarkup"`
+	MaxMarkup float64 `json:"maxMarkup"`
+	Clamped   string  `json:"clamped,omitempty"`
+}
+
+// MarkupTrace 加价计算过程
+type MarkupTrace struct {
+	EvaluatedAt   time.Time           `json:"evaluatedAt"`
+	MarkupEnabled bool                `json:"markupEnabled"`
+	Switches      []MarkupSwitchState `json:"switches"`
+	Candidates    []MarkupRuleTrace   `json:"candidates"`
+	AppliedRuleID *uint64             `json:"appliedRuleId,omitempty"`
+	Tier          *models.MarkupTier  `json:"tier,omitempty"` // 命中的价格/数量阶梯
+	Clamp         *MarkupClampTrace   `json:"clamp,omitempty"`
+}
+
+// MarkupConflictRule 冲突报告中的规则摘要
+type MarkupConflictRule struct {
+	ID                  uint64 `json:"id"`
+	Name                string `json:"name"`
+	Priority            int    `json:"priority"`
+	SpecificityPriority int    `json:"specificityPriority"` // 按范围精确度建议的优先级
+}
+
+// MarkupRuleOverlap 范围和优先级相同且有效期重叠的规则，命中哪条只取决于创建时间
+type MarkupRuleOverlap struct {
+	Rules    []MarkupConflictRule `json:"rules"`
+	WinnerID uint64               `json:"winnerId"`
+}
+
+// MarkupRuleUnreachable 永远不会被命中的规则
+type MarkupRuleUnreachable struct {
+	Rule       MarkupConflictRule  `json:"rule"`
+	Reason     MarkupTraceReason   `json:"reason"`
+	ShadowedBy *MarkupConflictRule `json:"shadowedBy,omitempty"`
+}
+
+// MarkupConflictReport 加价规则冲突检查结果
+type MarkupConflictReport struct {
+	CheckedAt   time.Time               `json:"checkedAt"`
+	RuleCount   int                     `json:"ruleCount"`
+	Overlaps    []MarkupRuleOverlap     `json:"overlaps"`
+	Unreachable []MarkupRuleUnreachable `json:"unreachable"`
+}
+
+// explainMarkup 计算加价并返回完整判定过程，同时检查加价开关，结果与下单定价一致
+func (s *PriceMarkupService) explainMarkup(req *CalculateMarkupRequest) (*CalculateMarkupResponse, error) {
+	switches, err := s.markupSwitches(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 包含已停用和不在有效期内的规则，便于说明未命中原因
+	var rules []PriceMarkup
+	if err := s.db.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	sortMarkupRules(rules)
+
+	now := time.Now()
+	trace, applied := traceMarkup(rules, req, switches, now)
+
+	response := &CalculateMarkupResponse{
+		OriginalPrice: req.OriginalPrice,
+		FinalPrice:    req.OriginalPrice,
+		Trace:         trace,
+	}
+	if applied != nil {
+		response.MarkupAmount = s.calculateMarkupAmount(applied, req.OriginalPrice, req.Quantity)
+		response.FinalPrice = req.OriginalPrice + response.MarkupAmount
+		response.AppliedRule = applied
+	}
+	return response, nil
+}
+
+// markupSwitches 读取全局、门店、供应商和分类加价开关
+func (s *PriceMarkupService) markupSwitches(req *CalculateMarkupRequest) ([]MarkupSwitchState, error) {
+	pricing := &OrderPricingService{db: s.db, markupService: s}
+
+	var store models.Store
+	if err := s.db.Select("id", "markup_enabled").First(&store, req.StoreID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+	var supplier models.Supplier
+	if err := s.db.Select("id", "markup_enabled").First(&supplier, req.SupplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+
+	switches := []MarkupSwitchState{
+		{Type: MarkupSwitchGlobal, Enabled: pricing.isGlobalMarkupEnabled()},
+		{Type: MarkupSwitchStore, TargetID: req.StoreID, Enabled: store.MarkupEnabled == 1},
+		{Type: MarkupSwitchSupplier, TargetID: req.SupplierID, Enabled: supplier.MarkupEnabled == 1},
+	}
+	if req.CategoryID > 0 {
+		switches = append(switches, MarkupSwitchState{
+			Type:     MarkupSwitchCategory,
+			TargetID: req.CategoryID,
+			Enabled:  pricing.isCategoryMarkupEnabled(req.CategoryID, make(map[uint64]bool)),
+		})
+	}
+	return switches, nil
+}
+
+// traceMarkup 逐条说明规则是否命中，rules 需已按 sortMarkupRules 排序
+func traceMarkup(rules []PriceMarkup, req *CalculateMarkupRequest, switches []MarkupSwitchState, now time.Time) (*MarkupTrace, *PriceMarkup) {
+	trace := &MarkupTrace{
+		EvaluatedAt:   now,
+		MarkupEnabled: true,
+		Switches:      switches,
+		Candidates:    make([]MarkupRuleTrace, 0, len(rules)),
+	}
+	var disabled []string
+	for _, sw := range switches {
+		if !sw.Enabled {
+			trace.MarkupEnabled = false
+			disabled = append(disabled, sw.Type)
+		}
+	}
+
+	var applied *PriceMarkup
+	for i := range rules {
+		rule := &rules[i]
+		candidate := MarkupRuleTrace{RuleID: rule.ID, Name: rule.Name, Priority: rule.Priority, Reasons: make([]MarkupTraceReason, 0)}
+		candidate.Reasons = append(candidate.Reasons, markupWindowReasons(rule, now)...)
+		candidate.Reasons = append(candidate.Reasons, markupScopeReasons(rule, req)...)
+		candidate.Matched = len(candidate.Reasons) == 0
+
+		if candidate.Matched {
+			switch {
+			case !trace.MarkupEnabled:
+				candidate.Reasons = append(candidate.Reasons, MarkupTraceReason{
+					Code:    MarkupReasonSwitchDisabled,
+					Message: fmt.Sprintf("加价开关已关闭：%v", disabled),
+				})
+			case applied != nil:
+				candidate.Reasons = append(candidate.Reasons, MarkupTraceReason{
+					Code:    MarkupReasonOutranked,
+					Message: fmt.Sprintf("已由排序更靠前的规则 %d(%s) 命中", applied.ID, applied.Name),
+				})
+			default:
+				applied = rule
+				candidate.Applied = true
+			}
+		}
+		trace.Candidates = append(trace.Candidates, candidate)
+	}
+
+	if applied != nil {
+		ruleID := applied.ID
+		trace.AppliedRuleID = &ruleID
+		trace.Tier = applied.Tiers.Match(req.OriginalPrice, req.Quantity)
+		raw, clamped := clampMarkup(applied, req.OriginalPrice, req.Quantity)
+		trace.Clamp = &MarkupClampTrace{
+			RawMarkup: raw,
+			MinMarkup: applied.MinMarkup,
+			MaxMarkup: applied.MaxMarkup,
+			Clamped:   clamped,
+		}
+	}
+	return trace, applied
+}
+
+// markupWindowReasons 启用状态和有效期检查
+func markupWindowReasons(rule *PriceMarkup, now time.Time) []MarkupTraceReason {
+	var reasons []MarkupTraceReason
+	if !rule.IsActive {
+		reasons = append(reasons, MarkupTraceReason{Code: MarkupReasonInactive, Message: "规则已停用"})
+	}
+	if rule.StartTime != nil && rule.StartTime.After(now) {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonNotStarted,
+			Message: fmt.Sprintf("规则将于 %s 生效", rule.StartTime.Format("2006-01-02 15:04:05")),
+		})
+	}
+	if rule.EndTime != nil && rule.EndTime.Before(now) {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonExpired,
+			Message: fmt.Sprintf("规则已于 %s 过期", rule.EndTime.Format("2006-01-02 15:04:05")),
+		})
+	}
+	return reasons
+}
+
+// markupScopeReasons 适用范围检查，与 matchRule 的条件一致
+func markupScopeReasons(rule *PriceMarkup, req *CalculateMarkupRequest) []MarkupTraceReason {
+	var reasons []MarkupTraceReason
+	if rule.StoreID != nil && *rule.StoreID != req.StoreID {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonStoreMismatch,
+			Message: fmt.Sprintf("规则限定门店 %d，当前门店 %d", *rule.StoreID, req.StoreID),
+		})
+	}
+	if rule.SupplierID != nil && *rule.SupplierID != req.SupplierID {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonSupplierMismatch,
+			Message: fmt.Sprintf("规则限定供应商 %d，当前供应商 %d", *rule.SupplierID, req.SupplierID),
+		})
+	}
+	if rule.CategoryID != nil && *rule.CategoryID != req.CategoryID {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonCategoryMismatch,
+			Message: fmt.Sprintf("规则限定分类 %d，当前分类 %d", *rule.CategoryID, req.CategoryID),
+		})
+	}
+	if rule.MaterialID != nil && *rule.MaterialID != req.MaterialID {
+		reasons = append(reasons, MarkupTraceReason{
+			Code:    MarkupReasonMaterialMismatch,
+			Message: fmt.Sprintf("规则限定物料 %d，当前物料 %d", *rule.MaterialID, req.MaterialID),
+		})
+	}
+	return reasons
+}
+
+// CheckConflicts 检查启用中的加价规则：同范围同优先级的重叠规则，以及永远不会命中的规则
+func (s *PriceMarkupService) CheckConflicts() (*MarkupConflictReport, error) {
+	var rules []PriceMarkup
+	if err := s.db.Where("is_active = ?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	sortMarkupRules(rules)
+
+	materialIDs := make([]uint64, 0)
+	for _, rule := range rules {
+		if rule.MaterialID != nil {
+			materialIDs = append(materialIDs, *rule.MaterialID)
+		}
+	}
+	materialCategories := make(map[uint64]uint64)
+	if len(materialIDs) > 0 {
+		var materials []models.Material
+		if err := s.db.Select("id", "category_id").Where("id IN ?", uniqueUint64(materialIDs)).Find(&materials).Error; err != nil {
+			return nil, err
+		}
+		for _, material := range materials {
+			materialCategories[material.ID] = material.CategoryID
+		}
+	}
+
+	return detectMarkupConflicts(rules, materialCategories, time.Now()), nil
+}
+
+// detectMarkupConflicts 冲突检查，rules 需已按 sortMarkupRules 排序
+func detectMarkupConflicts(rules []PriceMarkup, materialCategories map[uint64]uint64, now time.Time) *MarkupConflictReport {
+	report := &MarkupConflictReport{
+		CheckedAt:   now,
+		RuleCount:   len(rules),
+		Overlaps:    make([]MarkupRuleOverlap, 0),
+		Unreachable: make([]MarkupRuleUnreachable, 0),
+	}
+
+	// 先剔除已过期和范围自相矛盾的规则，它们不参与遮蔽判断
+	live := make([]*PriceMarkup, 0, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.IsActive {
+			continue
+		}
+		if rule.EndTime != nil && rule.EndTime.Before(now) {
+			report.Unreachable = append(report.Unreachable, MarkupRuleUnreachable{
+				Rule: markupConflictRule(rule),
+				Reason: MarkupTraceReason{
+					Code:    MarkupReasonExpired,
+					Message: fmt.Sprintf("规则已于 %s 过期但仍为启用状态", rule.EndTime.Format("2006-01-02 15:04:05")),
+				},
+			})
+			continue
+		}
+		if rule.MaterialID != nil && rule.CategoryID != nil {
+			if categoryID, ok := materialCategories[*rule.MaterialID]; ok && categoryID != *rule.CategoryID {
+				report.Unreachable = append(report.Unreachable, MarkupRuleUnreachable{
+					Rule: markupConflictRule(rule),
+					Reason: MarkupTraceReason{
+						Code:    MarkupReasonScopeImpossible,
+						Message: fmt.Sprintf("物料 %d 属于分类 %d，与规则限定的分类 %d 不一致", *rule.MaterialID, categoryID, *rule.CategoryID),
+					},
+				})
+				continue
+			}
+		}
+		live = append(live, rule)
+	}
+
+	overlapped := make(map[uint64]bool)
+	for i, rule := range live {
+		// 同范围同优先级且有效期重叠：按创建时间先者命中
+		if !overlapped[rule.ID] {
+			group := []MarkupConflictRule{markupConflictRule(rule)}
+			for _, other := range live[i+1:] {
+				if other.Priority == rule.Priority && sameMarkupScope(rule, other) && markupWindowsOverlap(rule, other) {
+					group = append(group, markupConflictRule(other))
+					overlapped[other.ID] = true
+				}
+			}
+			if len(group) > 1 {
+				report.Overlaps = append(report.Overlaps, MarkupRuleOverlap{Rules: group, WinnerID: rule.ID})
+			}
+		}
+
+		// 排序更靠前、范围更宽且有效期完全覆盖的规则会截走全部请求
+		for _, prior := range live[:i] {
+			if markupScopeCovers(prior, rule, materialCategories) && markupWindowCovers(prior, rule) {
+				shadow := markupConflictRule(prior)
+				report.Unreachable = append(report.Unreachable, MarkupRuleUnreachable{
+					Rule: markupConflictRule(rule),
+					Reason: MarkupTraceReason{
+						Code: MarkupReasonShadowed,
+						Message: fmt.Sprintf("规则 %d(%s) 优先级 %d 不低于本规则的 %d，且适用范围和有效期完全覆盖本规则",
+							prior.ID, prior.Name, prior.Priority, rule.Priority),
+					},
+					ShadowedBy: &shadow,
+				})
+				break
+			}
+		}
+	}
+	return report
+}
+
+// markupConflictRule 规则摘要
+func markupConflictRule(rule *PriceMarkup) MarkupConflictRule {
+	return MarkupConflictRule{
+		ID:                  rule.ID,
+		Name:                rule.Name,
+		Priority:            rule.Priority,
+		SpecificityPriority: markupSpecificityPriority(rule.StoreID, rule.SupplierID, rule.CategoryID, rule.MaterialID),
+	}
+}
+
+// sameMarkupScope 两条规则适用范围完全相同
+func sameMarkupScope(a, b *PriceMarkup) bool {
+	return equalOptionalID(a.StoreID, b.StoreID) &&
+		equalOptionalID(a.SupplierID, b.SupplierID) &&
+		equalOptionalID(a.CategoryID, b.CategoryID) &&
+		equalOptionalID(a.MaterialID, b.MaterialID)
+}
+
+// markupScopeCovers outer 的适用范围包含 inner 的全部适用范围
+func markupScopeCovers(outer, inner *PriceMarkup, materialCategories map[uint64]uint64) bool {
+	if !coversOptionalID(outer.StoreID, inner.StoreID) ||
+		!coversOptionalID(outer.SupplierID, inner.SupplierID) ||
+		!coversOptionalID(outer.MaterialID, inner.MaterialID) {
+		return false
+	}
+	if coversOptionalID(outer.CategoryID, inner.CategoryID) {
+		return true
+	}
+	// 物料级规则的分类由物料决定
+	if inner.CategoryID == nil && inner.MaterialID != nil {
+		categoryID, ok := materialCategories[*inner.MaterialID]
+		return ok && categoryID == *outer.CategoryID
+	}
+	return false
+}
+
+// markupWindowCovers outer 的有效期包含 inner 的有效期
+func markupWindowCovers(outer, inner *PriceMarkup) bool {
+	if outer.StartTime != nil && (inner.StartTime == nil || outer.StartTime.After(*inner.StartTime)) {
+		return false
+	}
+	if outer.EndTime != nil && (inner.EndTime == nil || outer.EndTime.Before(*inner.EndTime)) {
+		return false
+	}
+	return true
+}
+
+// markupWindowsOverlap 两条规则有效期存在交集
+func markupWindowsOverlap(a, b *PriceMarkup) bool {
+	if a.StartTime != nil && b.EndTime != nil && a.StartTime.After(*b.EndTime) {
+		return false
+	}
+	if b.StartTime != nil && a.EndTime != nil && b.StartTime.After(*a.EndTime) {
+		return false
+	}
+	return true
+}
+
+// equalOptionalID 可空ID相等
+func equalOptionalID(a, b *uint64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// coversOptionalID outer 为空(不限)或与 inner 相同
+func coversOptionalID(outer, inner *uint64) bool {
+	return outer == nil || (inner != nil && *outer == *inner)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceMarkup(t *testing.T) {
+	id := func(v uint64) *uint64 { return &v }
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	created := now.AddDate(0, -1, 0)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	rules := []PriceMarkup{
+		{ID: 1, Name: "其他门店", StoreID: id(9), MarkupType: MarkupTypeFixed, MarkupValue: 3, Priority: 50, IsActive: true, CreatedAt: created},
+		{ID: 2, Name: "未开始", MarkupType: MarkupTypeFixed, MarkupValue: 2, Priority: 40, IsActive: true, StartTime: &tomorrow, CreatedAt: created},
+		{ID: 3, Name: "蔬菜5%", CategoryID: id(10), MarkupType: MarkupTypePercent, MarkupValue: 0.05, MinMarkup: 1, Priority: 30, IsActive: true, CreatedAt: created},
+		{ID: 4, Name: "全局2%", MarkupType: MarkupTypePercent, MarkupValue: 0.02, Priority: 10, IsActive: true, CreatedAt: created},
+		{ID: 5, Name: "已停用", MarkupType: MarkupTypeFixed, MarkupValue: 1, Priority: 5, IsActive: false, CreatedAt: created},
+	}
+	req := &CalculateMarkupRequest{StoreID: 1, SupplierID: 7, CategoryID: 10, MaterialID: 100, OriginalPrice: 10}
+	switches := []MarkupSwitchState{{Type: MarkupSwitchGlobal, Enabled: true}, {Type: MarkupSwitchStore, TargetID: 1, Enabled: true}}
+
+	trace, applied := traceMarkup(rules, req, switches, now)
+	if applied == nil || applied.ID != 3 || trace.AppliedRuleID == nil || *trace.AppliedRuleID != 3 {
+		t.Fatalf("applied = %+v, expected rule 3", applied)
+	}
+	if len(trace.Candidates) != 5 {
+		t.Fatalf("len(Candidates) = %d, expected 5", len(trace.Candidates))
+	}
+
+	expected := []struct {
+		matched bool
+		applied bool
+		code    string
+	}{
+		{false, false, MarkupReasonStoreMismatch},
+		{false, false, MarkupReasonNotStarted},
+		{true, true, ""},
+		{true, false, MarkupReasonOutranked},
+		{false, false, MarkupReasonInactive},
+	}
+	for i, exp := range expected {
+		c := trace.Candidates[i]
+		if c.Matched != exp.matched || c.Applied != exp.applied {
+			t.Errorf("Candidates[%d] matched/applied = %v/%v, expected %v/%v", i, c.Matched, c.Applied, exp.matched, exp.applied)
+		}
+		if exp.code == "" && len(c.Reasons) != 0 {
+			t.Errorf("Candidates[%d].Reasons = %+v, expected none", i, c.Reasons)
+		}
+		if exp.code != "" && (len(c.Reasons) == 0 || c.Reasons[0].Code != exp.code) {
+			t.Errorf("Candidates[%d].Reasons = %+v, expected %s", i, c.Reasons, exp.code)
+		}
+	}
+	if trace.Clamp == nil || trace.Clamp.RawMarkup != 0.5 || trace.Clamp.Clamped != MarkupClampMin {
+		t.Errorf("Clamp = %+v, expected raw 0.5 clamped to min", trace.Clamp)
+	}
+
+	switches = append(switches, MarkupSwitchState{Type: MarkupSwitchCategory, TargetID: 10, Enabled: false})
+	trace, applied = traceMarkup(rules, req, switches, now)
+	if applied != nil || trace.MarkupEnabled || trace.Clamp != nil {
+		t.Errorf("switch disabled: applied = %+v, MarkupEnabled = %v, expected no markup", applied, trace.MarkupEnabled)
+	}
+	if c := trace.Candidates[2]; !c.Matched || c.Applied || c.Reasons[0].Code != MarkupReasonSwitchDisabled {
+		t.Errorf("switch disabled: Candidates[2] = %+v, expected matched but switch_disabled", c)
+	}
+}
+
+func TestDetectMarkupConflicts(t *testing.T) {
+	id := func(v uint64) *uint64 { return &v }
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	early := now.AddDate(0, -2, 0)
+	late := now.AddDate(0, -1, 0)
+	yesterday := now.AddDate(0, 0, -1)
+	nextMonth := now.AddDate(0, 1, 0)
+
+	rules := []PriceMarkup{
+		{ID: 1, Name: "供应商7统一", SupplierID: id(7), Priority: 100, IsActive: true, CreatedAt: early},
+		{ID: 2, Name: "供应商7蔬菜", SupplierID: id(7), CategoryID: id(10), Priority: 50, IsActive: true, CreatedAt: early},
+		{ID: 3, Name: "蔬菜A", CategoryID: id(10), Priority: 20, IsActive: true, CreatedAt: early},
+		{ID: 4, Name: "蔬菜B", CategoryID: id(10), Priority: 20, IsActive: true, EndTime: &nextMonth, CreatedAt: late},
+		{ID: 5, Name: "已过期", Priority: 10, IsActive: true, EndTime: &yesterday, CreatedAt: early},
+		{ID: 6, Name: "物料分类不符", CategoryID: id(20), MaterialID: id(100), Priority: 5, IsActive: true, CreatedAt: early},
+		{ID: 7, Name: "蔬菜物料", MaterialID: id(101), Priority: 1, IsActive: true, CreatedAt: early},
+		{ID: 8, Name: "限时供应商8", SupplierID: id(8), Priority: 0, IsActive: true, StartTime: &yesterday, EndTime: &nextMonth, CreatedAt: early},
+	}
+	sortMarkupRules(rules)
+	materialCategories := map[uint64]uint64{100: 10, 101: 10}
+
+	report := detectMarkupConflicts(rules, materialCategories, now)
+
+	if len(report.Overlaps) != 1 || report.Overlaps[0].WinnerID != 3 || len(report.Overlaps[0].Rules) != 2 || report.Overlaps[0].Rules[1].ID != 4 {
+		t.Errorf("Overlaps = %+v, expected rules 3 and 4 with 3 winning", report.Overlaps)
+	}
+
+	unreachable := make(map[uint64]MarkupRuleUnreachable)
+	for _, u := range report.Unreachable {
+		unreachable[u.Rule.ID] = u
+	}
+	tests := []struct {
+		ruleID     uint64
+		code       string
+		shadowedBy uint64
+	}{
+		{2, MarkupReasonShadowed, 1},
+		{4, MarkupReasonShadowed, 3},
+		{5, MarkupReasonExpired, 0},
+		{6, MarkupReasonScopeImpossible, 0},
+		{7, MarkupReasonShadowed, 3},
+	}
+	for _, tt := range tests {
+		u, ok := unreachable[tt.ruleID]
+		if !ok {
+			t.Errorf("rule %d not reported unreachable", tt.ruleID)
+			continue
+		}
+		if u.Reason.Code != tt.code {
+			t.Errorf("rule %d reason = %s, expected %s", tt.ruleID, u.Reason.Code, tt.code)
+		}
+		if tt.shadowedBy != 0 && (u.ShadowedBy == nil || u.ShadowedBy.ID != tt.shadowedBy) {
+			t.Errorf("rule %d shadowedBy = %+v, expected %d", tt.ruleID, u.ShadowedBy, tt.shadowedBy)
+		}
+	}
+	if len(report.Unreachable) != len(tests) {
+		t.Errorf("len(Unreachable) = %d, expected %d", len(report.Unreachable), len(tests))
+	}
+	if u := unreachable[2]; u.Rule.SpecificityPriority != 110 {
+		t.Errorf("SpecificityPriority = %d, expected 110", u.Rule.SpecificityPriority)
+	}
+}
@@ -250,11 +250,7 @@ func (s *MobileStoreService) GetCart(storeID uint64) ([]CartGroup, error) {
 	groupMap := make(map[uint64]*CartGroup)
 	for _, item := range items {
 		if _, exists := groupMap[item.SupplierID]; !exists {
-			var minAmt float64
-			s.db.Table("delivery_settings").
-				Select("min_order_amount").
-				Where("supplier_id = ?", item.SupplierID).
-				Scan(&minAmt)
+			minAmt := NewDeliveryRuleService(s.db).MinOrderAmount(item.SupplierID)
 
 			groupMap[item.SupplierID] = &CartGroup{
 				SupplierID:     item.SupplierID,
@@ -282,12 +278,21 @@ func (s *MobileStoreService) GetCart(storeID uint64) ([]CartGroup, error) {
 // AddToCart 加入购物车
 func (s *MobileStoreService) AddToCart(storeID, materialID, materialSkuID, supplierID uint64, quantity int, price float64) error {
 	// 检查是否已存在
-	var existingID uint64
+	var existing struct {
+		ID       uint64
+		Quantity int
+	}
 	s.db.Table("cart_items").
-		Select("id").
+		Select("id, quantity").
 		Where("store_id = ? AND material_sku_id = ? AND supplier_id = ?", storeID, materialSkuID, supplierID).
-		Scan(&existingID)
+		Scan(&existing)
+
+	// 按加购后的总数量校验商品归属、上架库存状态及起订量
+	if _, err := NewOrderLineService(s.db).CheckLine(supplierID, materialSkuID, existing.Quantity+quantity); err != nil {
+		return err
+	}
 
+	existingID := existing.ID
 	if existingID > 0 {
 		// 更新数量
 		return s.db.Table("cart_items").
@@ -313,6 +318,18 @@ func (s *MobileStoreService) UpdateCartItemQuantity(cartItemID uint64, quantity
 	if quantity <= 0 {
 		return s.db.Table("cart_items").Where("id = ?", cartItemID).Delete(nil).Error
 	}
+
+	var item struct {
+		SupplierID    uint64
+		MaterialSkuID uint64
+	}
+	if err := s.db.Table("cart_items").Select("supplier_id, material_sku_id").
+		Where("id = ?", cartItemID).Scan(&item).Error; err != nil {
+		return err
+	}
+	if _, err := NewOrderLineService(s.db).CheckLine(item.SupplierID, item.MaterialSkuID, quantity); err != nil {
+		return err
+	}
 	return s.db.Table("cart_items").Where("id = ?", cartItemID).Update("quantity", quantity).Error
 }
 
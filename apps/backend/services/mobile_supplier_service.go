@@ -3,6 +3,7 @@ package services
 import (
 	"time"
 
+	"github.com/project/backend/models"
 	"gorm.io/gorm"
 )
 
@@ -214,33 +215,30 @@ func (s *MobileSupplierService) GetOrderDetail(supplierID, orderID uint64) (*Sup
 
 // ConfirmOrder 确认订单
 func (s *MobileSupplierService) ConfirmOrder(supplierID, orderID uint64) error {
-	return s.db.Table("orders").
-		Where("id = ? AND supplier_id = ? AND status = ?", orderID, supplierID, "pending").
-		Updates(map[string]interface{}{
-			"status":     "confirmed",
-			"updated_at": time.Now(),
-		}).Error
+	return s.transition(supplierID, orderID, models.OrderStatusPendingConfirm, models.OrderStatusConfirmed)
 }
 
 // StartDelivery 开始配送
 func (s *MobileSupplierService) StartDelivery(supplierID, orderID uint64) error {
-	return s.db.Table("orders").
-		Where("id = ? AND supplier_id = ? AND status = ?", orderID, supplierID, "confirmed").
-		Updates(map[string]interface{}{
-			"status":     "delivering",
-			"updated_at": time.Now(),
-		}).Error
+	return s.transition(supplierID, orderID, models.OrderStatusConfirmed, models.OrderStatusDelivering)
 }
 
 // CompleteOrder 完成订单
 func (s *MobileSupplierService) CompleteOrder(supplierID, orderID uint64) error {
-	return s.db.Table("orders").
-		Where("id = ? AND supplier_id = ? AND status = ?", orderID, supplierID, "delivering").
-		Updates(map[string]interface{}{
-			"status":       "completed",
-			"completed_at": time.Now(),
-			"updated_at":   time.Now(),
-		}).Error
+	return s.transition(supplierID, orderID, models.OrderStatusDelivering, models.OrderStatusCompleted)
+}
+
+// transition 通过订单状态机变更状态
+func (s *MobileSupplierService) transition(supplierID, orderID uint64, from, to models.OrderStatus) error {
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
+		OrderID:      orderID,
+		SupplierID:   supplierID,
+		From:         []models.OrderStatus{from},
+		To:           to,
+		OperatorType: models.OperatorTypeSupplier,
+		OperatorID:   supplierID,
+	})
+	return err
 }
 
 // GetProducts 获取产品列表
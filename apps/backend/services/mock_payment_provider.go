@@ -0,0 +1,308 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PaymentMethodMock 本地模拟支付，仅用于联调和离线测试
+const PaymentMethodMock PaymentMethod = "mock"
+
+// MockSignatureHeader 模拟支付回调的签名请求头
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockOperation 模拟支付渠道的操作
+type MockOperation string
+
+const (
+	MockOperationCreate MockOperation = "create"
+	MockOperationQuery  MockOperation = "query"
+	MockOperationClose  MockOperation = "close"
+	MockOperationRefund MockOperation = "refund"
+)
+
+// 模拟渠道的应用ID和商户号
+const (
+	mockAppID = "mock-app"
+	mockMchID = "mock-mch"
+)
+
+// ErrMockTradeNotFound 模拟交易不存在
+var ErrMockTradeNotFound = errors.New("模拟交易不存在")
+
+// MockPaymentProvider 本地模拟支付渠道
+// 交易保存在内存中，通过 SimulatePay 模拟用户扫码并生成签名回调，通过 FailNext 模拟渠道故障
+type MockPaymentProvider struct {
+	secret   []byte
+	mu       sync.Mutex
+	trades   map[string]*ProviderTrade
+	failures map[MockOperation]string
+	seq      int64
+}
+
+// NewMockPaymentProvider 创建模拟支付渠道，secret 为空时随机生成回调签名密钥
+func NewMockPaymentProvider(secret string) *MockPaymentProvider {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &MockPaymentProvider{
+		secret:   key,
+		trades:   make(map[string]*ProviderTrade),
+		failures: make(map[MockOperation]string),
+	}
+}
+
+// Method 支付方式
+func (p *MockPaymentProvider) Method() PaymentMethod {
+	return PaymentMethodMock
+}
+
+// Merchant 应用ID和商户号
+func (p *MockPaymentProvider) Merchant() (appID, mchID string) {
+	return mockAppID, mockMchID
+}
+
+// FailNext 令下一次指定操作返回失败
+func (p *MockPaymentProvider) FailNext(op MockOperation, message string) {
+	if message == "" {
+		message = "模拟渠道故障"
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[op] = message
+}
+
+// takeFailure 取出并清除预设的失败
+func (p *MockPaymentProvider) takeFailure(op MockOperation) error {
+	message, ok := p.failures[op]
+	if !ok {
+		return nil
+	}
+	delete(p.failures, op)
+	return errors.New(message)
+}
+
+// CreatePayment 创建模拟交易，二维码指向模拟扫码接口
+func (p *MockPaymentProvider) CreatePayment(ctx context.Context, req *ProviderPaymentRequest) (*ProviderPaymentResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.takeFailure(MockOperationCreate); err != nil {
+		return nil, err
+	}
+
+	trade, ok := p.trades[req.PaymentNo]
+	if !ok {
+		trade = &ProviderTrade{PaymentNo: req.PaymentNo, State: TradeStatePending}
+		p.trades[req.PaymentNo] = trade
+	}
+	switch trade.State {
+	case TradeStatePending:
+	case TradeStateClosed:
+		// 与微信、支付宝一致，已关闭的商户订单号不能再次下单
+		return nil, fmt.Errorf("交易%s已关闭", req.PaymentNo)
+	default:
+		return nil, fmt.Errorf("交易%s已支付", req.PaymentNo)
+	}
+	trade.Amount = req.Amount
+
+	return &ProviderPaymentResponse{
+		QRCodeURL: fmt.Sprintf("/api/payments/mock/%s/pay?amount=%.2f", url.PathEscape(req.PaymentNo), req.Amount),
+	}, nil
+}
+
+// QueryPayment 查询模拟交易
+func (p *MockPaymentProvider) QueryPayment(ctx context.Context, paymentNo string) (*ProviderTrade, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.takeFailure(MockOperationQuery); err != nil {
+		return nil, err
+	}
+
+	trade, ok := p.trades[paymentNo]
+	if !ok {
+		return nil, ErrMockTradeNotFound
+	}
+	result := *trade
+	return &result, nil
+}
+
+// ClosePayment 关闭模拟交易
+func (p *MockPaymentProvider) ClosePayment(ctx context.Context, paymentNo string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.takeFailure(MockOperationClose); err != nil {
+		return err
+	}
+
+	trade, ok := p.trades[paymentNo]
+	if !ok {
+		return ErrMockTradeNotFound
+	}
+	if trade.State == TradeStatePaid {
+		return fmt.Errorf("交易%s已支付，不能关闭", paymentNo)
+	}
+	trade.State = TradeStateClosed
+	return nil
+}
+
+// Refund 模拟退款
+func (p *MockPaymentProvider) Refund(ctx context.Context, req *ProviderRefundRequest) (*ProviderRefundResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.takeFailure(MockOperationRefund); err != nil {
+		return nil, err
+	}
+
+	trade, ok := p.trades[req.PaymentNo]
+	if !ok {
+		return nil, ErrMockTradeNotFound
+	}
+	if trade.State != TradeStatePaid && trade.State != TradeStateRefunded {
+		return nil, fmt.Errorf("交易%s未支付，不能退款", req.PaymentNo)
+	}
+	if req.Amount >= trade.Amount {
+		trade.State = TradeStateRefunded
+	}
+	p.seq++
+	return &ProviderRefundResponse{
+		RefundNo: req.RefundNo,
+		RefundID: fmt.Sprintf("MOCKRF%s%04d", time.Now().Format("20060102150405"), p.seq),
+		Status:   "SUCCESS",
+		State:    RefundStateSuccess,
+	}, nil
+}
+
+// SimulatePay 模拟用户扫码支付，succeed 为 false 时模拟支付失败并关闭交易
+// 返回签名后的回调报文及签名，可直接提交到回调接口
+func (p *MockPaymentProvider) SimulatePay(paymentNo string, succeed bool) (body []byte, signature string, err error) {
+	p.mu.Lock()
+	trade, ok := p.trades[paymentNo]
+	if !ok {
+		p.mu.Unlock()
+		return nil, "", ErrMockTradeNotFound
+	}
+	if trade.State != TradeStatePending {
+		p.mu.Unlock()
+		return nil, "", fmt.Errorf("交易%s不是待支付状态", paymentNo)
+	}
+	if succeed {
+		now := time.Now()
+		p.seq++
+		trade.State = TradeStatePaid
+		trade.TradeNo = fmt.Sprintf("MOCK%s%04d", now.Format("20060102150405"), p.seq)
+		trade.PayTime = &now
+	} else {
+		trade.State = TradeStateClosed
+	}
+	notification := ProviderNotification{ProviderTrade: *trade, AppID: mockAppID, MchID: mockMchID}
+	p.mu.Unlock()
+
+	body, err = json.Marshal(notification)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, p.sign(body), nil
+}
+
+// VerifyCallback 校验回调签名并解析报文
+func (p *MockPaymentProvider) VerifyCallback(ctx context.Context, req *http.Request) (*ProviderNotification, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body failed: %w", err)
+	}
+	if !hmac.Equal([]byte(p.sign(body)), []byte(req.Header.Get(MockSignatureHeader))) {
+		return nil, errors.New("signature verification failed")
+	}
+
+	notification := &ProviderNotification{}
+	if err := json.Unmarshal(body, notification); err != nil {
+		return nil, fmt.Errorf("invalid notification: %w", err)
+	}
+	notification.RawData = string(body)
+	return notification, nil
+}
+
+// DownloadBill 生成指定日期已支付交易的对账单，格式与微信交易账单一致
+func (p *MockPaymentProvider) DownloadBill(ctx context.Context, billDate time.Time) ([]byte, error) {
+	start := time.Date(billDate.Year(), billDate.Month(), billDate.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+
+	p.mu.Lock()
+	var trades []ProviderTrade
+	for _, trade := range p.trades {
+		if trade.PayTime == nil || trade.PayTime.Before(start) || !trade.PayTime.Before(end) {
+			continue
+		}
+		if trade.State == TradeStatePaid || trade.State == TradeStateRefunded {
+			trades = append(trades, *trade)
+		}
+	}
+	p.mu.Unlock()
+	sort.Slice(trades, func(i, j int) bool { return trades[i].PayTime.Before(*trades[j].PayTime) })
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"交易时间", "公众账号ID", "商户号", "微信订单号", "商户订单号", "交易类型", "交易状态", "应结订单总金额", "订单金额"})
+	total := 0.0
+	for _, trade := range trades {
+		amount := fmt.Sprintf("%.2f", trade.Amount)
+		writer.Write([]string{
+			"`" + trade.PayTime.Format("2006-01-02 15:04:05"),
+			"`" + mockAppID,
+			"`" + mockMchID,
+			"`" + trade.TradeNo,
+			"`" + trade.PaymentNo,
+			"`NATIVE",
+			"`SUCCESS",
+			"`" + amount,
+			"`" + amount,
+		})
+		total += trade.Amount
+	}
+	writer.Write([]string{"总交易单数", "应结订单总金额"})
+	writer.Write([]string{fmt.Sprintf("`%d", len(trades)), fmt.Sprintf("`%.2f", total)})
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// VerifyRefundCallback 校验退款通知签名并解析报文
+// 模拟渠道退款同步成功，不会主动发送退款通知，此接口用于联调时手工投递
+func (p *MockPaymentProvider) VerifyRefundCallback(ctx context.Context, req *http.Request) (*ProviderRefundNotification, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body failed: %w", err)
+	}
+	if !hmac.Equal([]byte(p.sign(body)), []byte(req.Header.Get(MockSignatureHeader))) {
+		return nil, errors.New("signature verification failed")
+	}
+
+	notification := &ProviderRefundNotification{}
+	if err := json.Unmarshal(body, notification); err != nil {
+		return nil, fmt.Errorf("invalid refund notification: %w", err)
+	}
+	notification.RawData = string(body)
+	return notification, nil
+}
+
+// sign 回调报文签名 HMAC-SHA256
+func (p *MockPaymentProvider) sign(body []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
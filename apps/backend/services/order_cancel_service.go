@@ -50,48 +50,17 @@ func (s *OrderCancelService) SelfCancelOrder(orderID uint64, storeID uint64, rea
 		return errors.New("order cannot be self-cancelled, please submit a cancellation request")
 	}
 
-	// Begin transaction
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Update order status
-	now := time.Now()
-	cancelledBy := models.CancelledByStore
-	updates := map[string]interface{}{
-		"status":        models.OrderStatusCancelled,
-		"cancel_reason": reason,
-		"cancelled_by":  cancelledBy,
-		"cancelled_at":  now,
-	}
-
-	if err := tx.Model(&order).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Create status log
-	fromStatus := string(models.OrderStatusPendingConfirm)
-	toStatus := string(models.OrderStatusCancelled)
-	operatorType := models.OperatorTypeStore
-	log := &models.OrderStatusLog{
+	// Update order status through the state machine
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
 		OrderID:      orderID,
-		FromStatus:   &fromStatus,
-		ToStatus:     toStatus,
-		OperatorType: &operatorType,
-		OperatorID:   &storeID,
-		Remark:       &reason,
-	}
-
-	if err := tx.Create(log).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	return tx.Commit().Error
+		StoreID:      storeID,
+		From:         []models.OrderStatus{models.OrderStatusPendingConfirm},
+		To:           models.OrderStatusCancelled,
+		OperatorType: models.OperatorTypeStore,
+		OperatorID:   storeID,
+		Remark:       reason,
+	})
+	return err
 }
 
 // SubmitCancelRequest submits a cancellation request (after 1 hour)
@@ -190,43 +159,16 @@ func (s *OrderCancelService) ApproveCancelRequest(requestID uint64, adminID uint
 		return err
 	}
 
-	// Update order status
-	var order models.Order
-	if err := tx.First(&order, request.OrderID).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	now := time.Now()
-	cancelledBy := models.CancelledByAdmin
-	updates := map[string]interface{}{
-		"status":          models.OrderStatusCancelled,
-		"cancel_reason":   request.Reason,
-		"cancelled_by":    cancelledBy,
-		"cancelled_by_id": adminID,
-		"cancelled_at":    now,
-	}
-
-	if err := tx.Model(&order).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Create status log
-	fromStatus := string(order.Status)
-	toStatus := string(models.OrderStatusCancelled)
-	operatorType := models.OperatorTypeAdmin
-	remarkText := "取消申请已批准: " + remark
-	log := &models.OrderStatusLog{
+	// Update order status through the state machine
+	_, err := NewOrderStateMachine(s.db).TransitionTx(tx, &OrderTransition{
 		OrderID:      request.OrderID,
-		FromStatus:   &fromStatus,
-		ToStatus:     toStatus,
-		OperatorType: &operatorType,
-		OperatorID:   &adminID,
-		Remark:       &remarkText,
-	}
-
-	if err := tx.Create(log).Error; err != nil {
+		To:           models.OrderStatusCancelled,
+		OperatorType: models.OperatorTypeAdmin,
+		OperatorID:   adminID,
+		Remark:       "取消申请已批准: " + remark,
+		Extra:        map[string]interface{}{"cancel_reason": request.Reason},
+	})
+	if err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -260,42 +202,14 @@ func (s *OrderCancelService) RestoreOrder(orderID uint64, adminID uint64, reason
 		return errors.New("order is not cancelled")
 	}
 
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
 	// Update order status to unpaid (needs re-payment)
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status":      models.OrderStatusUnpaid,
-		"restored_at": now,
-	}
-
-	if err := tx.Model(&order).Updates(updates).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Create status log
-	fromStatus := string(models.OrderStatusCancelled)
-	toStatus := string(models.OrderStatusUnpaid)
-	operatorType := models.OperatorTypeAdmin
-	log := &models.OrderStatusLog{
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
 		OrderID:      orderID,
-		FromStatus:   &fromStatus,
-		ToStatus:     toStatus,
-		OperatorType: &operatorType,
-		OperatorID:   &adminID,
-		Remark:       &reason,
-	}
-
-	if err := tx.Create(log).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	return tx.Commit().Error
+		From:         []models.OrderStatus{models.OrderStatusCancelled},
+		To:           models.OrderStatusUnpaid,
+		OperatorType: models.OperatorTypeAdmin,
+		OperatorID:   adminID,
+		Remark:       reason,
+	})
+	return err
 }
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// OrderLineService 订单明细下单条件校验服务
+// 校验商品是否由所选供应商供应、是否审核通过且在售有货，以及起订量和递增量
+type OrderLineService struct {
+	db *gorm.DB
+}
+
+// NewOrderLineService 创建订单明细校验服务
+func NewOrderLineService(db *gorm.DB) *OrderLineService {
+	return &OrderLineService{db: db}
+}
+
+// 订单明细不满足下单条件的原因
+const (
+	LineReasonSkuNotSupplied  = "sku_not_supplied"
+	LineReasonNotApproved     = "not_approved"
+	LineReasonOffShelf        = "off_shelf"
+	LineReasonOutOfStock      = "out_of_stock"
+	LineReasonInvalidQuantity = "invalid_quantity"
+)
+
+// OrderLine 待校验的订单明细
+type OrderLine struct {
+	MaterialSkuID uint64
+	Quantity      int
+}
+
+// LineIssue 单条明细不满足下单条件的原因
+type LineIssue struct {
+	Line          int    `json:"line"` // 明细序号，从 1 开始
+	MaterialSkuID uint64 `json:"materialSkuId"`
+	Quantity      int    `json:"quantity"`
+	Reason        string `json:"reason"`
+	Message       string `json:"message"`
+	MinQuantity   int    `json:"minQuantity,omitempty"`
+	StepQuantity  int    `json:"stepQuantity,omitempty"`
+}
+
+// OrderLineRejectedError 订单明细校验不通过
+type OrderLineRejectedError struct {
+	SupplierID uint64      `json:"supplierId"`
+	Issues     []LineIssue `json:"issues"`
+}
+
+func (e *OrderLineRejectedError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].Message
+	}
+	return fmt.Sprintf("%d个商品不满足下单条件", len(e.Issues))
+}
+
+// CheckLine 校验单个商品，通过时返回对应的供应商物料
+func (s *OrderLineService) CheckLine(supplierID, materialSkuID uint64, quantity int) (*models.SupplierMaterial, error) {
+	materials, err := s.CheckLines(supplierID, []OrderLine{{MaterialSkuID: materialSkuID, Quantity: quantity}})
+	if err != nil {
+		return nil, err
+	}
+	return materials[materialSkuID], nil
+}
+
+// CheckLines 校验订单全部明细，不通过时返回 *OrderLineRejectedError 并列出每条明细的原因
+func (s *OrderLineService) CheckLines(supplierID uint64, lines []OrderLine) (map[uint64]*models.SupplierMaterial, error) {
+	skuIDs := make([]uint64, 0, len(lines))
+	for _, line := range lines {
+		skuIDs = append(skuIDs, line.MaterialSkuID)
+	}
+
+	var found []models.SupplierMaterial
+	if len(skuIDs) > 0 {
+		if err := s.db.Where("supplier_id = ? AND material_sku_id IN ?", supplierID, skuIDs).
+			Find(&found).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	materials := make(map[uint64]*models.SupplierMaterial, len(found))
+	for i := range found {
+		materials[found[i].MaterialSkuID] = &found[i]
+	}
+
+	var issues []LineIssue
+	for i, line := range lines {
+		if issue := checkOrderLine(materials[line.MaterialSkuID], line); issue != nil {
+			issue.Line = i + 1
+			issues = append(issues, *issue)
+		}
+	}
+	if len(issues) > 0 {
+		return nil, &OrderLineRejectedError{SupplierID: supplierID, Issues: issues}
+	}
+	return materials, nil
+}
+
+// checkOrderLine 校验单条明细，sm 为 nil 表示供应商未供应该商品
+func checkOrderLine(sm *models.SupplierMaterial, line OrderLine) *LineIssue {
+	issue := &LineIssue{MaterialSkuID: line.MaterialSkuID, Quantity: line.Quantity}
+	switch {
+	case sm == nil:
+		issue.Reason = LineReasonSkuNotSupplied
+		issue.Message = fmt.Sprintf("供应商未供应该商品(SKU: %d)", line.MaterialSkuID)
+	case sm.AuditStatus != models.AuditStatusApproved:
+		issue.Reason = LineReasonNotApproved
+		issue.Message = "商品报价未审核通过"
+	case !sm.IsActive():
+		issue.Reason = LineReasonOffShelf
+		issue.Message = "商品已下架"
+	case !sm.IsAvailable():
+		issue.Reason = LineReasonOutOfStock
+		issue.Message = "商品缺货"
+	case !sm.ValidateQuantity(line.Quantity):
+		issue.Reason = LineReasonInvalidQuantity
+		issue.MinQuantity = sm.MinQuantity
+		issue.StepQuantity = sm.StepQuantity
+		if line.Quantity < sm.MinQuantity {
+			issue.Message = fmt.Sprintf("起订量为%d", sm.MinQuantity)
+		} else {
+			issue.Message = fmt.Sprintf("起订量为%d，需按%d递增", sm.MinQuantity, sm.StepQuantity)
+		}
+	default:
+		return nil
+	}
+	return issue
+}
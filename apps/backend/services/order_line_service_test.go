@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestCheckOrderLine(t *testing.T) {
+	material := func(modify func(sm *models.SupplierMaterial)) *models.SupplierMaterial {
+		sm := &models.SupplierMaterial{
+			MinQuantity:  2,
+			StepQuantity: 3,
+			StockStatus:  models.StockStatusInStock,
+			AuditStatus:  models.AuditStatusApproved,
+			Status:       1,
+		}
+		if modify != nil {
+			modify(sm)
+		}
+		return sm
+	}
+
+	tests := []struct {
+		name     string
+		sm       *models.SupplierMaterial
+		quantity int
+		expected string
+	}{
+		{"not supplied", nil, 2, LineReasonSkuNotSupplied},
+		{"pending audit", material(func(sm *models.SupplierMaterial) { sm.AuditStatus = models.AuditStatusPending }), 2, LineReasonNotApproved},
+		{"off shelf", material(func(sm *models.SupplierMaterial) { sm.Status = 0 }), 2, LineReasonOffShelf},
+		{"out of stock", material(func(sm *models.SupplierMaterial) { sm.StockStatus = models.StockStatusOutOfStock }), 2, LineReasonOutOfStock},
+		{"below min quantity", material(nil), 1, LineReasonInvalidQuantity},
+		{"off step", material(nil), 4, LineReasonInvalidQuantity},
+		{"min quantity", material(nil), 2, ""},
+		{"on step", material(nil), 8, ""},
+		{"zero step treated as one", material(func(sm *models.SupplierMaterial) { sm.StepQuantity = 0 }), 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := checkOrderLine(tt.sm, OrderLine{MaterialSkuID: 1, Quantity: tt.quantity})
+			reason := ""
+			if issue != nil {
+				reason = issue.Reason
+			}
+			if reason != tt.expected {
+				t.Errorf("checkOrderLine() reason = %q, expected %q", reason, tt.expected)
+			}
+		})
+	}
+}
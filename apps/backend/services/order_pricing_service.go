@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// OrderPricingService 订单服务端定价服务
+// 下单价格一律以供应商审核通过的报价 + 加价规则为准，客户端提交的价格仅用于比对
+type OrderPricingService struct {
+	db            *gorm.DB
+	markupService *PriceMarkupService
+}
+
+// NewOrderPricingService 创建订单定价服务
+func NewOrderPricingService(db *gorm.DB) *OrderPricingService {
+	return &OrderPricingService{
+		db:            db,
+		markupService: NewPriceMarkupService(db),
+	}
+}
+
+// priceTolerance 客户端价格与服务端价格允许的误差(元)
+const priceTolerance = 0.005
+
+// PricingItemInput 待定价的订单明细(客户端提交)
+type PricingItemInput struct {
+	MaterialSkuID uint64
+	Quantity      int
+	UnitPrice     float64
+	MarkupAmount  float64
+	FinalPrice    float64
+}
+
+// PricedItem 服务端定价后的订单明细
+type PricedItem struct {
+	MaterialSkuID      uint64              `json:"materialSkuId"`
+	SupplierMaterialID uint64              `json:"supplierMaterialId"`
+	MaterialID         uint64              `json:"materialId"`
+	CategoryID         uint64              `json:"categoryId"`
+	Quantity           int                 `json:"quantity"`
+	UnitPrice          float64             `json:"unitPrice"`
+	MarkupAmount       float64             `json:"markupAmount"`
+	FinalPrice         float64             `json:"finalPrice"`
+	Subtotal           float64             `json:"subtotal"`
+	MarkupRuleID       *uint64             `json:"markupRuleId,omitempty"`
+	Sku                *models.MaterialSku `json:"-"`
+}
+
+// OrderPricing 订单定价结果
+type OrderPricing struct {
+	Items       []PricedItem `json:"items"`
+	GoodsAmount float64      `json:"goodsAmount"`
+	MarkupTotal float64      `json:"markupTotal"`
+}
+
+// PriceChange 价格变动明细
+type PriceChange struct {
+	MaterialSkuID      uint64  `json:"materialSkuId"`
+	MaterialName       string  `json:"materialName"`
+	ClientUnitPrice    float64 `json:"clientUnitPrice"`
+	ClientMarkupAmount float64 `json:"clientMarkupAmount"`
+	ClientFinalPrice   float64 `json:"clientFinalPrice"`
+	UnitPrice          float64 `json:"unitPrice"`
+	MarkupAmount       float64 `json:"markupAmount"`
+	FinalPrice         float64 `json:"finalPrice"`
+}
+
+// PriceChangedError 商品价格已变动
+type PriceChangedError struct {
+	Changes []PriceChange `json:"changes"`
+}
+
+func (e *PriceChangedError) Error() string {
+	return fmt.Sprintf("%d个商品价格已变动，请确认后重新提交", len(e.Changes))
+}
+
+// ErrSupplierNotFound 供应商不存在
+var ErrSupplierNotFound = errors.New("供应商不存在")
+
+// ErrStoreNotFound 门店不存在
+var ErrStoreNotFound = errors.New("门店不存在")
+
+// SkuNotSuppliedError 供应商未供应该SKU
+type SkuNotSuppliedError struct {
+	MaterialSkuID uint64
+}
+
+func (e *SkuNotSuppliedError) Error() string {
+	return fmt.Sprintf("供应商未供应该商品(SKU: %d)", e.MaterialSkuID)
+}
+
+// PriceItems 按服务端规则计算订单明细价格
+func (s *OrderPricingService) PriceItems(storeID, supplierID uint64, items []PricingItemInput) (*OrderPricing, error) {
+	var store models.Store
+	if err := s.db.First(&store, storeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStoreNotFound
+		}
+		return nil, err
+	}
+
+	var supplier models.Supplier
+	if err := s.db.First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+
+	markupEnabled := s.isGlobalMarkupEnabled() && store.MarkupEnabled == 1 && supplier.MarkupEnabled == 1
+	categorySwitches := make(map[uint64]bool)
+
+	priced := make([]PricedItem, 0, len(items))
+	for _, item := range items {
+		var sm models.SupplierMaterial
+		err := s.db.Where("supplier_id = ? AND material_sku_id = ? AND status = ? AND audit_status = ?",
+			supplierID, item.MaterialSkuID, 1, models.AuditStatusApproved).
+			Preload("MaterialSku.Material").
+			First(&sm).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &SkuNotSuppliedError{MaterialSkuID: item.MaterialSkuID}
+			}
+			return nil, err
+		}
+		if sm.MaterialSku == nil || sm.MaterialSku.Material == nil {
+			return nil, &SkuNotSuppliedError{MaterialSkuID: item.MaterialSkuID}
+		}
+
+		material := sm.MaterialSku.Material
+		var markup *CalculateMarkupResponse
+		if markupEnabled && s.isCategoryMarkupEnabled(material.CategoryID, categorySwitches) {
+			result, err := s.markupService.CalculateMarkup(&CalculateMarkupRequest{
+				StoreID:       storeID,
+				SupplierID:    supplierID,
+				CategoryID:    material.CategoryID,
+				MaterialID:    material.ID,
+				OriginalPrice: sm.Price,
+				Quantity:      item.Quantity,
+			})
+			if err != nil {
+				return nil, err
+			}
+			markup = result
+		}
+		priced = append(priced, priceOrderItem(item, &sm, markup))
+	}
+
+	return summarizeOrderPricing(priced), nil
+}
+
+// priceOrderItem 按供应商报价和加价结果计算明细价格，markup 为 nil 表示不加价；加价金额保留两位小数
+func priceOrderItem(item PricingItemInput, sm *models.SupplierMaterial, markup *CalculateMarkupResponse) PricedItem {
+	priced := PricedItem{
+		MaterialSkuID:      item.MaterialSkuID,
+		SupplierMaterialID: sm.ID,
+		Quantity:           item.Quantity,
+		UnitPrice:          sm.Price,
+		FinalPrice:         sm.Price,
+		Sku:                sm.MaterialSku,
+	}
+	if sm.MaterialSku != nil && sm.MaterialSku.Material != nil {
+		priced.MaterialID = sm.MaterialSku.Material.ID
+		priced.CategoryID = sm.MaterialSku.Material.CategoryID
+	}
+
+	if markup != nil {
+		amount := decimal.NewFromFloat(markup.MarkupAmount).Round(2)
+		priced.MarkupAmount = amount.InexactFloat64()
+		priced.FinalPrice = decimal.NewFromFloat(sm.Price).Add(amount).InexactFloat64()
+		if markup.AppliedRule != nil {
+			ruleID := markup.AppliedRule.ID
+			priced.MarkupRuleID = &ruleID
+		}
+	}
+
+	priced.Subtotal = decimal.NewFromFloat(priced.FinalPrice).Mul(decimal.NewFromInt(int64(item.Quantity))).InexactFloat64()
+	return priced
+}
+
+// summarizeOrderPricing 汇总明细得到商品金额和加价总额
+func summarizeOrderPricing(items []PricedItem) *OrderPricing {
+	goodsAmount := decimal.Zero
+	markupTotal := decimal.Zero
+	for _, item := range items {
+		qty := decimal.NewFromInt(int64(item.Quantity))
+		goodsAmount = goodsAmount.Add(decimal.NewFromFloat(item.FinalPrice).Mul(qty))
+		markupTotal = markupTotal.Add(decimal.NewFromFloat(item.MarkupAmount).Mul(qty))
+	}
+	return &OrderPricing{
+		Items:       items,
+		GoodsAmount: goodsAmount.Round(2).InexactFloat64(),
+		MarkupTotal: markupTotal.Round(2).InexactFloat64(),
+	}
+}
+
+// PriceAndVerify 计算服务端价格并与客户端提交的价格比对，不一致时返回 *PriceChangedError
+func (s *OrderPricingService) PriceAndVerify(storeID, supplierID uint64, items []PricingItemInput) (*OrderPricing, error) {
+	pricing, err := s.PriceItems(storeID, supplierID, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return pricing, verifyClientPrices(items, pricing)
+}
+
+// verifyClientPrices 客户端价格与服务端价格不一致时返回 *PriceChangedError
+func verifyClientPrices(items []PricingItemInput, pricing *OrderPricing) error {
+	if changes := diffClientPrices(items, pricing.Items); len(changes) > 0 {
+		return &PriceChangedError{Changes: changes}
+	}
+	return nil
+}
+
+// diffClientPrices 比对客户端价格与服务端价格，返回有变动的明细
+func diffClientPrices(inputs []PricingItemInput, priced []PricedItem) []PriceChange {
+	var changes []PriceChange
+	for i, in := range inputs {
+		if i >= len(priced) {
+			break
+		}
+		p := priced[i]
+		if priceEquals(in.UnitPrice, p.UnitPrice) &&
+			priceEquals(in.MarkupAmount, p.MarkupAmount) &&
+			priceEquals(in.FinalPrice, p.FinalPrice) {
+			continue
+		}
+
+		change := PriceChange{
+			MaterialSkuID:      in.MaterialSkuID,
+			ClientUnitPrice:    in.UnitPrice,
+			ClientMarkupAmount: in.MarkupAmount,
+			ClientFinalPrice:   in.FinalPrice,
+			UnitPrice:          p.UnitPrice,
+			MarkupAmount:       p.MarkupAmount,
+			FinalPrice:         p.FinalPrice,
+		}
+		if p.Sku != nil && p.Sku.Material != nil {
+			change.MaterialName = p.Sku.Material.Name
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// priceEquals 金额比对(允许分以下误差)
+func priceEquals(a, b float64) bool {
+	return decimal.NewFromFloat(a).Sub(decimal.NewFromFloat(b)).Abs().LessThan(decimal.NewFromFloat(priceTolerance))
+}
+
+// isGlobalMarkupEnabled 全局加价开关，未配置时视为开启
+func (s *OrderPricingService) isGlobalMarkupEnabled() bool {
+	if index := currentMarkupIndex(); index != nil {
+		return index.globalEnabled
+	}
+	var value string
+	s.db.Table("system_configs").
+		Select("config_value").
+		Where("config_key = ?", "markup_global_enabled").
+		Scan(&value)
+	return value != "false" && value != "0"
+}
+
+// isCategoryMarkupEnabled 分类加价开关(带缓存)
+func (s *OrderPricingService) isCategoryMarkupEnabled(categoryID uint64, cache map[uint64]bool) bool {
+	if enabled, ok := cache[categoryID]; ok {
+		return enabled
+	}
+	if index := currentMarkupIndex(); index != nil {
+		return !index.categoryDisabled[categoryID]
+	}
+	var category models.Category
+	enabled := true
+	if err := s.db.Select("id", "markup_enabled").First(&category, categoryID).Error; err == nil {
+		enabled = category.MarkupEnabled == 1
+	}
+	cache[categoryID] = enabled
+	return enabled
+}
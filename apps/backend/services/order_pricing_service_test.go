@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestPriceOrderItem(t *testing.T) {
+	sm := &models.SupplierMaterial{
+		ID:    7,
+		Price: 12.5,
+		MaterialSku: &models.MaterialSku{
+			ID:       3,
+			Material: &models.Material{ID: 5, CategoryID: 9, Name: "土豆"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		markup     *CalculateMarkupResponse
+		quantity   int
+		markupAmt  float64
+		finalPrice float64
+		subtotal   float64
+		ruleID     uint64
+	}{
+		{"no markup", nil, 4, 0, 12.5, 50, 0},
+		{"markup without rule", &CalculateMarkupResponse{MarkupAmount: 0}, 2, 0, 12.5, 25, 0},
+		{"markup applied", &CalculateMarkupResponse{MarkupAmount: 1.25, AppliedRule: &PriceMarkup{ID: 11}}, 3, 1.25, 13.75, 41.25, 11},
+		{"markup rounded to cents", &CalculateMarkupResponse{MarkupAmount: 0.625, AppliedRule: &PriceMarkup{ID: 12}}, 10, 0.63, 13.13, 131.3, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priced := priceOrderItem(PricingItemInput{MaterialSkuID: 3, Quantity: tt.quantity}, sm, tt.markup)
+			if priced.SupplierMaterialID != 7 || priced.MaterialID != 5 || priced.CategoryID != 9 || priced.UnitPrice != 12.5 {
+				t.Errorf("priceOrderItem() ids/unit price = %+v", priced)
+			}
+			if priced.MarkupAmount != tt.markupAmt || priced.FinalPrice != tt.finalPrice || priced.Subtotal != tt.subtotal {
+				t.Errorf("priceOrderItem() = markup %v final %v subtotal %v, expected %v %v %v",
+					priced.MarkupAmount, priced.FinalPrice, priced.Subtotal, tt.markupAmt, tt.finalPrice, tt.subtotal)
+			}
+			switch {
+			case tt.ruleID == 0 && priced.MarkupRuleID != nil:
+				t.Errorf("MarkupRuleID = %v, expected nil", *priced.MarkupRuleID)
+			case tt.ruleID != 0 && (priced.MarkupRuleID == nil || *priced.MarkupRuleID != tt.ruleID):
+				t.Errorf("MarkupRuleID = %v, expected %d", priced.MarkupRuleID, tt.ruleID)
+			}
+		})
+	}
+}
+
+func TestSummarizeOrderPricing(t *testing.T) {
+	pricing := summarizeOrderPricing([]PricedItem{
+		{Quantity: 3, FinalPrice: 13.75, MarkupAmount: 1.25},
+		{Quantity: 10, FinalPrice: 0.33, MarkupAmount: 0.03},
+		{Quantity: 1, FinalPrice: 8, MarkupAmount: 0},
+	})
+	if pricing.GoodsAmount != 52.55 {
+		t.Errorf("GoodsAmount = %v, expected 52.55", pricing.GoodsAmount)
+	}
+	if pricing.MarkupTotal != 4.05 {
+		t.Errorf("MarkupTotal = %v, expected 4.05", pricing.MarkupTotal)
+	}
+	if len(pricing.Items) != 3 {
+		t.Errorf("len(Items) = %d, expected 3", len(pricing.Items))
+	}
+}
+
+func TestVerifyClientPrices(t *testing.T) {
+	pricing := &OrderPricing{Items: []PricedItem{{
+		MaterialSkuID: 3,
+		UnitPrice:     12.5,
+		MarkupAmount:  1.25,
+		FinalPrice:    13.75,
+		Sku:           &models.MaterialSku{Material: &models.Material{Name: "土豆"}},
+	}}}
+
+	tests := []struct {
+		name    string
+		input   PricingItemInput
+		changed bool
+	}{
+		{"exact", PricingItemInput{MaterialSkuID: 3, UnitPrice: 12.5, MarkupAmount: 1.25, FinalPrice: 13.75}, false},
+		{"within tolerance", PricingItemInput{MaterialSkuID: 3, UnitPrice: 12.504, MarkupAmount: 1.246, FinalPrice: 13.754}, false},
+		{"at tolerance", PricingItemInput{MaterialSkuID: 3, UnitPrice: 12.5, MarkupAmount: 1.25, FinalPrice: 13.755}, true},
+		{"stale unit price", PricingItemInput{MaterialSkuID: 3, UnitPrice: 12, MarkupAmount: 1.25, FinalPrice: 13.25}, true},
+		{"markup missing", PricingItemInput{MaterialSkuID: 3, UnitPrice: 12.5, MarkupAmount: 0, FinalPrice: 12.5}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyClientPrices([]PricingItemInput{tt.input}, pricing)
+			if !tt.changed {
+				if err != nil {
+					t.Errorf("verifyClientPrices() error = %v, expected nil", err)
+				}
+				return
+			}
+			var changed *PriceChangedError
+			if !errors.As(err, &changed) {
+				t.Fatalf("verifyClientPrices() error = %v, expected *PriceChangedError", err)
+			}
+			if len(changed.Changes) != 1 {
+				t.Fatalf("len(Changes) = %d, expected 1", len(changed.Changes))
+			}
+			change := changed.Changes[0]
+			if change.MaterialName != "土豆" || change.ClientFinalPrice != tt.input.FinalPrice || change.FinalPrice != 13.75 {
+				t.Errorf("change = %+v", change)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// OrderStateMachine 订单状态机
+// 所有订单状态变更都必须经过此处：校验流转合法性、写入对应时间戳、记录状态日志。
+// 更新时以当前状态作为条件(CAS)，并发修改同一订单时只有一方能成功。
+type OrderStateMachine struct {
+	db *gorm.DB
+}
+
+// NewOrderStateMachine 创建订单状态机
+func NewOrderStateMachine(db *gorm.DB) *OrderStateMachine {
+	return &OrderStateMachine{db: db}
+}
+
+// orderTransitions 合法的状态流转
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPendingPayment: {models.OrderStatusPendingConfirm, models.OrderStatusCancelled},
+	models.OrderStatusPendingConfirm: {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed:      {models.OrderStatusDelivering, models.OrderStatusCancelled},
+	models.OrderStatusDelivering:     {models.OrderStatusCompleted, models.OrderStatusCancelled},
+	models.OrderStatusCompleted:      {},
+	// 已取消订单可由管理员恢复为待支付
+	models.OrderStatusCancelled: {models.OrderStatusPendingPayment},
+}
+
+// 状态机错误
+var (
+	ErrOrderNotFound         = errors.New("订单不存在")
+	ErrOrderConcurrentUpdate = errors.New("订单状态已被修改，请刷新后重试")
+)
+
+// InvalidTransitionError 非法状态流转
+type InvalidTransitionError struct {
+	From models.OrderStatus
+	To   models.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("订单状态不允许从 %s 变更为 %s", e.From, e.To)
+}
+
+// IsOrderTransitionAllowed 判断状态流转是否合法
+func IsOrderTransitionAllowed(from, to models.OrderStatus) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderTransition 状态变更请求
+type OrderTransition struct {
+	OrderID      uint64
+	To           models.OrderStatus
+	OperatorType models.OperatorType
+	OperatorID   uint64
+	Remark       string
+	// 归属校验(可选)，非0时要求订单属于该门店/供应商
+	StoreID    uint64
+	SupplierID uint64
+	// 限定当前状态(可选)，为空时仅按流转表校验
+	From []models.OrderStatus
+	// 随状态一并更新的字段，如 payment_status、cancel_reason
+	Extra map[string]interface{}
+}
+
+// Transition 在独立事务中执行状态变更
+func (m *OrderStateMachine) Transition(t *OrderTransition) (*models.Order, error) {
+	var order *models.Order
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = m.TransitionTx(tx, t)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// TransitionTx 在调用方事务中执行状态变更
+func (m *OrderStateMachine) TransitionTx(tx *gorm.DB, t *OrderTransition) (*models.Order, error) {
+	query := tx.Where("id = ?", t.OrderID)
+	if t.StoreID > 0 {
+		query = query.Where("store_id = ?", t.StoreID)
+	}
+	if t.SupplierID > 0 {
+		query = query.Where("supplier_id = ?", t.SupplierID)
+	}
+
+	var order models.Order
+	if err := query.First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	from := order.Status
+	if len(t.From) > 0 && !containsOrderStatus(t.From, from) {
+		return nil, &InvalidTransitionError{From: from, To: t.To}
+	}
+	if !IsOrderTransitionAllowed(from, t.To) {
+		return nil, &InvalidTransitionError{From: from, To: t.To}
+	}
+
+	now := time.Now()
+	updates := transitionUpdates(t, now)
+
+	// 以当前状态为条件更新，防止并发覆盖
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOrderConcurrentUpdate
+	}
+
+	log := models.NewOrderStatusLog(order.ID, string(from), string(t.To), t.OperatorType, t.OperatorID, t.Remark)
+	if err := tx.Create(log).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.First(&order, order.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// 赊账订单取消时释放额度；已支付订单取消时在同一事务中创建退款单，由退款任务提交渠道
+	if t.To == models.OrderStatusCancelled {
+		switch {
+		case order.PaymentMethod != nil && *order.PaymentMethod == models.PaymentMethodCredit:
+			if err := releaseCreditOrderTx(tx, &order, t); err != nil {
+				return nil, err
+			}
+		case order.PaymentStatus == models.PaymentStatusPaid || order.PaymentStatus == models.PaymentStatusPartialRefund:
+			if err := createCancelRefundTx(tx, &order, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 与状态变更在同一事务中写入待推送的 Webhook，事务提交后由推送任务投递
+	if event, ok := webhookTransitionEvent(from, t.To); ok {
+		if err := enqueueOrderWebhooksTx(tx, &order, event); err != nil {
+			return nil, err
+		}
+	}
+	return &order, nil
+}
+
+// transitionUpdates 生成状态变更需要更新的字段
+func transitionUpdates(t *OrderTransition, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"status":     t.To,
+		"updated_at": now,
+	}
+
+	switch t.To {
+	case models.OrderStatusConfirmed:
+		updates["confirmed_at"] = now
+	case models.OrderStatusDelivering:
+		updates["delivering_at"] = now
+	case models.OrderStatusCompleted:
+		updates["completed_at"] = now
+	case models.OrderStatusCancelled:
+		updates["cancelled_at"] = now
+		updates["cancelled_by"] = cancelledByOperator(t.OperatorType)
+		if t.OperatorID > 0 {
+			updates["cancelled_by_id"] = t.OperatorID
+		}
+		if t.Remark != "" {
+			updates["cancel_reason"] = t.Remark
+		}
+	case models.OrderStatusPendingPayment:
+		updates["restored_at"] = now
+	}
+
+	for k, v := range t.Extra {
+		updates[k] = v
+	}
+	return updates
+}
+
+// cancelledByOperator 操作人类型转换为取消方
+func cancelledByOperator(operatorType models.OperatorType) models.CancelledByType {
+	switch operatorType {
+	case models.OperatorTypeStore:
+		return models.CancelledByStore
+	case models.OperatorTypeSupplier:
+		return models.CancelledBySupplier
+	case models.OperatorTypeAdmin:
+		return models.CancelledByAdmin
+	default:
+		return models.CancelledBySystem
+	}
+}
+
+func containsOrderStatus(list []models.OrderStatus, status models.OrderStatus) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestIsOrderTransitionAllowed(t *testing.T) {
+	tests := []struct {
+		from     models.OrderStatus
+		to       models.OrderStatus
+		expected bool
+	}{
+		{models.OrderStatusPendingPayment, models.OrderStatusPendingConfirm, true},
+		{models.OrderStatusPendingPayment, models.OrderStatusCancelled, true},
+		{models.OrderStatusPendingConfirm, models.OrderStatusConfirmed, true},
+		{models.OrderStatusConfirmed, models.OrderStatusDelivering, true},
+		{models.OrderStatusDelivering, models.OrderStatusCompleted, true},
+		{models.OrderStatusCancelled, models.OrderStatusPendingPayment, true},
+		{models.OrderStatusPendingPayment, models.OrderStatusConfirmed, false},
+		{models.OrderStatusPendingConfirm, models.OrderStatusDelivering, false},
+		{models.OrderStatusCompleted, models.OrderStatusCancelled, false},
+		{models.OrderStatusCancelled, models.OrderStatusConfirmed, false},
+		{models.OrderStatusConfirmed, models.OrderStatusConfirmed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			result := IsOrderTransitionAllowed(tt.from, tt.to)
+			if result != tt.expected {
+				t.Errorf("IsOrderTransitionAllowed(%s, %s) = %v, expected %v",
+					tt.from, tt.to, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTransitionUpdates(t *testing.T) {
+	tr := &OrderTransition{
+		To:           models.OrderStatusCancelled,
+		OperatorType: models.OperatorTypeStore,
+		OperatorID:   7,
+		Remark:       "不需要了",
+	}
+	now := time.Now()
+	updates := transitionUpdates(tr, now)
+
+	if updates["cancelled_by"] != models.CancelledByStore {
+		t.Errorf("cancelled_by = %v, expected %v", updates["cancelled_by"], models.CancelledByStore)
+	}
+	if updates["cancel_reason"] != "不需要了" {
+		t.Errorf("cancel_reason = %v", updates["cancel_reason"])
+	}
+	if _, ok := updates["cancelled_at"]; !ok {
+		t.Error("cancelled_at should be set")
+	}
+
+	tr = &OrderTransition{To: models.OrderStatusDelivering, OperatorType: models.OperatorTypeSupplier}
+	updates = transitionUpdates(tr, now)
+	if _, ok := updates["delivering_at"]; !ok {
+		t.Error("delivering_at should be set")
+	}
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// OrderTimeoutService 订单超时处理服务
+type OrderTimeoutService struct {
+	db             *gorm.DB
+	paymentService *PaymentService
+	refundService  *RefundService
+	logger         *zap.Logger
+}
+
+// NewOrderTimeoutService 创建订单超时处理服务
+func NewOrderTimeoutService(db *gorm.DB, providers *PaymentProviders, logger *zap.Logger) *OrderTimeoutService {
+	return &OrderTimeoutService{
+		db:             db,
+		paymentService: NewPaymentService(db, providers),
+		refundService:  NewRefundService(db, providers),
+		logger:         logger,
+	}
+}
+
+// timeoutBatchSize 每批读取的订单数
+const timeoutBatchSize = 100
+
+// scanOrders 按订单ID游标分批读取并逐个处理，直到读完；被跳过或处理失败的订单不会挡住后面的订单
+func scanOrders(ctx context.Context, fetch func(afterID uint64, limit int) ([]models.Order, error), handle func(order *models.Order)) error {
+	var afterID uint64
+	for {
+		orders, err := fetch(afterID, timeoutBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range orders {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			handle(&orders[i])
+		}
+		if len(orders) < timeoutBatchSize {
+			return nil
+		}
+		afterID = orders[len(orders)-1].ID
+	}
+}
+
+// CancelExpiredUnpaidOrders 取消超过支付时限的待支付订单
+func (s *OrderTimeoutService) CancelExpiredUnpaidOrders(ctx context.Context) error {
+	orderConfig, err := NewSystemConfigService(s.db).GetOrderConfig()
+	if err != nil {
+		return err
+	}
+	if orderConfig.PaymentTimeout <= 0 {
+		return nil
+	}
+
+	// 恢复的订单从恢复时间开始重新计时
+	deadline := time.Now().Add(-time.Duration(orderConfig.PaymentTimeout) * time.Minute)
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		var orders []models.Order
+		err := s.db.Where("status = ? AND created_at < ? AND id > ?", models.OrderStatusPendingPayment, deadline, afterID).
+			Where("restored_at IS NULL OR restored_at < ?", deadline).
+			Order("id ASC").
+			Limit(limit).
+			Find(&orders).Error
+		return orders, err
+	}
+	return scanOrders(ctx, fetch, func(order *models.Order) {
+		s.cancelExpiredOrder(ctx, order)
+	})
+}
+
+// cancelExpiredOrder 关闭第三方交易、取消订单并使支付记录过期
+func (s *OrderTimeoutService) cancelExpiredOrder(ctx context.Context, order *models.Order) {
+	var records []PaymentRecord
+	orderPaymentScope(s.db, order.ID).Where("status = ?", PaymentStatusPending).Find(&records)
+
+	for _, record := range records {
+		if s.isTradePaid(ctx, &record) {
+			// 用户已在超时前完成支付，等待回调处理
+			s.logger.Info("skip timeout cancel, trade already paid",
+				zap.Uint64("orderId", order.ID), zap.String("paymentNo", record.PaymentNo))
+			return
+		}
+		if err := s.closeTrade(ctx, &record); err != nil {
+			s.logger.Warn("close trade failed",
+				zap.Uint64("orderId", order.ID), zap.String("paymentNo", record.PaymentNo), zap.Error(err))
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		_, err := NewOrderStateMachine(s.db).TransitionTx(tx, &OrderTransition{
+			OrderID:      order.ID,
+			From:         []models.OrderStatus{models.OrderStatusPendingPayment},
+			To:           models.OrderStatusCancelled,
+			OperatorType: models.OperatorTypeSystem,
+			Remark:       "支付超时自动取消",
+		})
+		if err != nil {
+			return err
+		}
+
+		return orderPaymentScope(tx.Model(&PaymentRecord{}), order.ID).
+			Where("status = ?", PaymentStatusPending).
+			Updates(map[string]interface{}{
+				"status":    PaymentStatusExpired,
+				"error_msg": "支付超时",
+			}).Error
+	})
+	if err != nil {
+		s.logger.Warn("cancel expired order failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+		return
+	}
+	s.logger.Info("expired unpaid order cancelled", zap.Uint64("orderId", order.ID), zap.String("orderNo", order.OrderNo))
+}
+
+// orderPaymentScope 订单的支付记录，包括覆盖该订单的合并支付
+func orderPaymentScope(db *gorm.DB, orderID uint64) *gorm.DB {
+	allocated := db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.PaymentAllocation{}).
+		Select("payment_no").
+		Where("order_id = ?", orderID)
+	return db.Where("order_id = ? OR payment_no IN (?)", orderID, allocated)
+}
+
+// isTradePaid 查询第三方交易是否已支付
+func (s *OrderTimeoutService) isTradePaid(ctx context.Context, record *PaymentRecord) bool {
+	trade, err := s.paymentService.QueryTrade(ctx, record)
+	return err == nil && trade.State == TradeStatePaid
+}
+
+// closeTrade 关闭第三方交易，渠道未配置时跳过
+func (s *OrderTimeoutService) closeTrade(ctx context.Context, record *PaymentRecord) error {
+	err := s.paymentService.CloseTrade(ctx, record)
+	if errors.Is(err, ErrPaymentProviderUnavailable) {
+		return nil
+	}
+	return err
+}
+
+// AutoCompleteDeliveredOrders 配送超过设定天数的订单自动完成
+func (s *OrderTimeoutService) AutoCompleteDeliveredOrders(ctx context.Context) error {
+	orderConfig, err := NewSystemConfigService(s.db).GetOrderConfig()
+	if err != nil {
+		return err
+	}
+	if orderConfig.AutoCompleteDays <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().AddDate(0, 0, -orderConfig.AutoCompleteDays)
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		var orders []models.Order
+		err := s.db.Where("status = ? AND COALESCE(delivering_at, updated_at) < ? AND id > ?", models.OrderStatusDelivering, deadline, afterID).
+			Order("id ASC").
+			Limit(limit).
+			Find(&orders).Error
+		return orders, err
+	}
+
+	stateMachine := NewOrderStateMachine(s.db)
+	remark := fmt.Sprintf("配送超过%d天自动完成", orderConfig.AutoCompleteDays)
+	return scanOrders(ctx, fetch, func(order *models.Order) {
+		_, err := stateMachine.Transition(&OrderTransition{
+			OrderID:      order.ID,
+			From:         []models.OrderStatus{models.OrderStatusDelivering},
+			To:           models.OrderStatusCompleted,
+			OperatorType: models.OperatorTypeSystem,
+			Remark:       remark,
+		})
+		if err != nil {
+			s.logger.Warn("auto complete order failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+			return
+		}
+		s.logger.Info("delivered order auto completed", zap.Uint64("orderId", order.ID), zap.String("orderNo", order.OrderNo))
+	})
+}
+
+// EscalateUnconfirmedOrders 处理供应商确认超时的订单
+// 超过确认时限通知管理员，再超过取消时限则自动取消并退款
+func (s *OrderTimeoutService) EscalateUnconfirmedOrders(ctx context.Context) error {
+	orderConfig, err := NewSystemConfigService(s.db).GetOrderConfig()
+	if err != nil {
+		return err
+	}
+	if orderConfig.ConfirmTimeout <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	escalateDeadline := now.Add(-time.Duration(orderConfig.ConfirmTimeout) * time.Minute)
+	var cancelDeadline time.Time
+	if orderConfig.ConfirmCancelAfter > 0 {
+		cancelDeadline = escalateDeadline.Add(-time.Duration(orderConfig.ConfirmCancelAfter) * time.Minute)
+	}
+
+	// 已通知过的订单只在到达取消时限后再读取
+	const notEscalated = "NOT EXISTS (SELECT 1 FROM admin_notifications n WHERE n.type = ? AND n.related_type = 'order' AND n.related_id = orders.id)"
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		query := s.db.Where("status = ? AND COALESCE(payment_time, created_at) < ? AND id > ?", models.OrderStatusPendingConfirm, escalateDeadline, afterID)
+		if cancelDeadline.IsZero() {
+			query = query.Where(notEscalated, models.AdminNotificationOrderConfirmTimeout)
+		} else {
+			query = query.Where(notEscalated+" OR COALESCE(payment_time, created_at) < ?", models.AdminNotificationOrderConfirmTimeout, cancelDeadline)
+		}
+		var orders []models.Order
+		err := query.Order("id ASC").Limit(limit).Find(&orders).Error
+		return orders, err
+	}
+
+	return scanOrders(ctx, fetch, func(order *models.Order) {
+		confirmStart := order.CreatedAt
+		if order.PaymentTime != nil {
+			confirmStart = *order.PaymentTime
+		}
+
+		if !cancelDeadline.IsZero() && confirmStart.Before(cancelDeadline) {
+			s.cancelUnconfirmedOrder(ctx, order)
+			return
+		}
+		s.escalateUnconfirmedOrder(order, orderConfig.ConfirmTimeout)
+	})
+}
+
+// escalateUnconfirmedOrder 通知管理员订单确认超时，每个订单只通知一次
+func (s *OrderTimeoutService) escalateUnconfirmedOrder(order *models.Order, timeout int) {
+	notificationService := NewAdminNotificationService(s.db)
+	if notificationService.HasOrderNotification(models.AdminNotificationOrderConfirmTimeout, order.ID) {
+		return
+	}
+
+	remark := fmt.Sprintf("供应商超过%d分钟未确认，已通知管理员", timeout)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(models.NewOrderNotification(
+			models.AdminNotificationOrderConfirmTimeout,
+			order.ID,
+			"订单确认超时",
+			fmt.Sprintf("订单 %s 供应商超过%d分钟未确认，请及时跟进", order.OrderNo, timeout),
+		)).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewSystemStatusLog(order.ID, string(order.Status), string(order.Status), remark)).Error
+	})
+	if err != nil {
+		s.logger.Warn("escalate unconfirmed order failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+		return
+	}
+	s.logger.Info("unconfirmed order escalated", zap.Uint64("orderId", order.ID), zap.String("orderNo", order.OrderNo))
+}
+
+// cancelUnconfirmedOrder 取消确认超时的订单并原路退款
+func (s *OrderTimeoutService) cancelUnconfirmedOrder(ctx context.Context, order *models.Order) {
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
+		OrderID:      order.ID,
+		From:         []models.OrderStatus{models.OrderStatusPendingConfirm},
+		To:           models.OrderStatusCancelled,
+		OperatorType: models.OperatorTypeSystem,
+		Remark:       "供应商确认超时自动取消",
+	})
+	if err != nil {
+		s.logger.Warn("cancel unconfirmed order failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+		return
+	}
+
+	content := fmt.Sprintf("订单 %s 供应商确认超时，已自动取消", order.OrderNo)
+	if order.PaymentStatus == models.PaymentStatusPaid {
+		// 取消时已创建退款单，立即提交而不等待退款任务
+		if err := s.refundService.SubmitOrderRefunds(ctx, order.ID); err != nil {
+			s.logger.Warn("refund unconfirmed order failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+			content += "，退款提交失败，将由退款任务重试: " + err.Error()
+		} else {
+			content += "，已发起原路退款"
+		}
+	}
+
+	if err := NewAdminNotificationService(s.db).Notify(models.NewOrderNotification(
+		models.AdminNotificationOrderAutoCancelled, order.ID, "订单自动取消", content,
+	)); err != nil {
+		s.logger.Warn("notify admin failed", zap.Uint64("orderId", order.ID), zap.Error(err))
+	}
+	s.logger.Info("unconfirmed order auto cancelled", zap.Uint64("orderId", order.ID), zap.String("orderNo", order.OrderNo))
+}
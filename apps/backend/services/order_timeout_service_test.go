@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestScanOrders(t *testing.T) {
+	// 前一整批是已通知或已在渠道支付、本轮会被跳过的订单，后面是新超时的订单
+	handled := make(map[uint64]bool)
+	var due []models.Order
+	for id := uint64(1); id <= timeoutBatchSize+30; id++ {
+		due = append(due, models.Order{ID: id})
+		if id <= timeoutBatchSize {
+			handled[id] = true
+		}
+	}
+
+	fetches := 0
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		fetches++
+		var orders []models.Order
+		for _, order := range due {
+			if order.ID > afterID && len(orders) < limit {
+				orders = append(orders, order)
+			}
+		}
+		return orders, nil
+	}
+
+	var processed []uint64
+	err := scanOrders(context.Background(), fetch, func(order *models.Order) {
+		if handled[order.ID] {
+			return
+		}
+		processed = append(processed, order.ID)
+	})
+	if err != nil {
+		t.Fatalf("scanOrders() error = %v", err)
+	}
+	if len(processed) != 30 || processed[0] != timeoutBatchSize+1 || processed[29] != timeoutBatchSize+30 {
+		t.Errorf("processed = %v, expected orders %d-%d", processed, timeoutBatchSize+1, timeoutBatchSize+30)
+	}
+	if fetches != 2 {
+		t.Errorf("fetches = %d, expected 2", fetches)
+	}
+}
+
+func TestScanOrdersFullLastBatch(t *testing.T) {
+	fetches := 0
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		fetches++
+		if afterID > 0 {
+			return nil, nil
+		}
+		orders := make([]models.Order, limit)
+		for i := range orders {
+			orders[i].ID = uint64(i + 1)
+		}
+		return orders, nil
+	}
+
+	count := 0
+	if err := scanOrders(context.Background(), fetch, func(*models.Order) { count++ }); err != nil {
+		t.Fatalf("scanOrders() error = %v", err)
+	}
+	if count != timeoutBatchSize || fetches != 2 {
+		t.Errorf("count = %d fetches = %d, expected %d and 2", count, fetches, timeoutBatchSize)
+	}
+}
+
+func TestScanOrdersCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetch := func(afterID uint64, limit int) ([]models.Order, error) {
+		return []models.Order{{ID: afterID + 1}, {ID: afterID + 2}}, nil
+	}
+
+	count := 0
+	err := scanOrders(ctx, fetch, func(*models.Order) {
+		count++
+		cancel()
+	})
+	if err != context.Canceled || count != 1 {
+		t.Errorf("scanOrders() = %v after %d orders, expected context.Canceled after 1", err, count)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// BillDownloader 支持下载每日交易对账单的支付渠道
+// 返回与该渠道对账单文件相同格式的 CSV 内容
+type BillDownloader interface {
+	DownloadBill(ctx context.Context, billDate time.Time) ([]byte, error)
+}
+
+// ErrBillFormatUnsupported 不支持的对账单格式
+var ErrBillFormatUnsupported = errors.New("不支持该支付方式的对账单格式")
+
+// BillLine 对账单中的一笔支付成功交易
+type BillLine struct {
+	TradeNo   string     `json:"tradeNo"`
+	PaymentNo string     `json:"paymentNo"`
+	Amount    float64    `json:"amount"`
+	TradeTime *time.Time `json:"tradeTime,omitempty"`
+}
+
+// billFormat 对账单列名
+type billFormat struct {
+	tradeNo   string
+	paymentNo string
+	amount    []string // 按顺序取第一个存在的列
+	tradeTime string
+	state     string
+	paid      string // 支付成功交易的状态值，其余行(退款等)不参与支付对账
+	summary   string // 汇总区起始行的首列前缀
+}
+
+// wechatBillFormat 微信交易账单(ALL)
+var wechatBillFormat = billFormat{
+	tradeNo:   "微信订单号",
+	paymentNo: "商户订单号",
+	amount:    []string{"订单金额", "应结订单总金额"},
+	tradeTime: "交易时间",
+	state:     "交易状态",
+	paid:      "SUCCESS",
+	summary:   "总交易单数",
+}
+
+// alipayBillFormat 支付宝业务明细
+var alipayBillFormat = billFormat{
+	tradeNo:   "支付宝交易号",
+	paymentNo: "商户订单号",
+	amount:    []string{"订单金额（元）", "订单金额(元)"},
+	tradeTime: "完成时间",
+	state:     "业务类型",
+	paid:      "交易",
+	summary:   "#",
+}
+
+// billFormats 各支付方式的对账单格式，模拟渠道使用微信格式
+var billFormats = map[PaymentMethod]billFormat{
+	PaymentMethodWechat: wechatBillFormat,
+	PaymentMethodAlipay: alipayBillFormat,
+	PaymentMethodMock:   wechatBillFormat,
+}
+
+// ParseBill 解析渠道对账单，只返回支付成功的交易
+// 支持 UTF-8 与 GBK 编码，自动跳过说明行和汇总区；微信账单字段前的反引号会被去掉
+func ParseBill(method PaymentMethod, data []byte) ([]BillLine, error) {
+	format, ok := billFormats[method]
+	if !ok {
+		return nil, ErrBillFormatUnsupported
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("对账单编码无法识别: %w", err)
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var columns map[string]int
+	var lines []BillLine
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("对账单格式错误: %w", err)
+		}
+		for i := range row {
+			row[i] = cleanBillCell(row[i])
+		}
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+
+		if columns == nil {
+			// 表头之前的说明行跳过
+			if strings.HasPrefix(row[0], "#") || !containsString(row, format.tradeNo) {
+				continue
+			}
+			columns = make(map[string]int, len(row))
+			for i, name := range row {
+				columns[name] = i
+			}
+			continue
+		}
+		if strings.HasPrefix(row[0], format.summary) {
+			break
+		}
+
+		if billCell(row, columns, format.state) != format.paid {
+			continue
+		}
+		line := BillLine{
+			TradeNo:   billCell(row, columns, format.tradeNo),
+			PaymentNo: billCell(row, columns, format.paymentNo),
+		}
+		for _, name := range format.amount {
+			if value := billCell(row, columns, name); value != "" {
+				line.Amount = parseYuan(value)
+				break
+			}
+		}
+		if value := billCell(row, columns, format.tradeTime); value != "" {
+			if tradeTime, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+				line.TradeTime = &tradeTime
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("对账单缺少表头: 未找到列 %s", format.tradeNo)
+	}
+	return lines, nil
+}
+
+// cleanBillCell 去掉单元格首尾空白、制表符和微信账单的反引号
+func cleanBillCell(value string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "`"))
+}
+
+// billCell 按列名取值，列不存在时返回空
+func billCell(row []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPaymentRecordNotFound 通知对应的支付记录不存在
+	ErrPaymentRecordNotFound = errors.New("支付记录不存在")
+	// ErrPaymentCallbackNotFound 支付通知不存在
+	ErrPaymentCallbackNotFound = errors.New("支付通知不存在")
+	// ErrPaymentCallbackNotReplayable 支付通知不是可重放状态
+	ErrPaymentCallbackNotReplayable = errors.New("只能重放处理失败的通知")
+)
+
+// PaymentCallbackMismatchError 通知内容与支付记录或商户配置不一致
+type PaymentCallbackMismatchError struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (e *PaymentCallbackMismatchError) Error() string {
+	return fmt.Sprintf("回调%s不一致: 期望 %s, 实际 %s", e.Field, e.Expected, e.Actual)
+}
+
+// PaymentCallbackService 支付通知处理服务
+// 每条通知先写入收件箱再处理，支付记录与订单在同一事务中更新，重复通知不会产生任何变更
+type PaymentCallbackService struct {
+	db             *gorm.DB
+	paymentService *PaymentService
+	providers      *PaymentProviders
+}
+
+// NewPaymentCallbackService 创建支付通知处理服务
+func NewPaymentCallbackService(db *gorm.DB, providers *PaymentProviders) *PaymentCallbackService {
+	return &PaymentCallbackService{
+		db:             db,
+		paymentService: NewPaymentService(db, providers),
+		providers:      providers,
+	}
+}
+
+// RecordInvalid 记录验签失败的通知，仅用于排查，不会被处理
+func (s *PaymentCallbackService) RecordInvalid(method PaymentMethod, rawData string, verifyErr error) error {
+	return s.db.Create(&models.PaymentCallbackInbox{
+		PaymentMethod: models.PaymentMethod(method),
+		Source:        models.PaymentCallbackSourceCallback,
+		RawData:       rawData,
+		Status:        models.PaymentCallbackStatusInvalid,
+		ErrorMsg:      truncateErrorMsg(verifyErr.Error()),
+	}).Error
+}
+
+// Receive 保存验签通过的通知并处理
+// 同一笔交易此前处理失败的通知会被复用，避免渠道重试时堆积重复记录
+func (s *PaymentCallbackService) Receive(method PaymentMethod, source models.PaymentCallbackSource, notification *ProviderNotification) (*models.PaymentCallbackInbox, error) {
+	var inbox models.PaymentCallbackInbox
+	err := s.db.Where("payment_method = ? AND payment_no = ? AND trade_no = ? AND trade_state = ? AND status IN ?",
+		method, notification.PaymentNo, notification.TradeNo, notification.State,
+		[]models.PaymentCallbackStatus{models.PaymentCallbackStatusReceived, models.PaymentCallbackStatusFailed}).
+		First(&inbox).Error
+	switch {
+	case err == nil:
+		inbox.Source = source
+		inbox.RawData = notification.RawData
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		inbox = models.PaymentCallbackInbox{
+			PaymentMethod: models.PaymentMethod(method),
+			Source:        source,
+			PaymentNo:     notification.PaymentNo,
+			TradeNo:       notification.TradeNo,
+			TradeState:    string(notification.State),
+			Amount:        notification.Amount,
+			AppID:         notification.AppID,
+			MchID:         notification.MchID,
+			PayTime:       notification.PayTime,
+			RawData:       notification.RawData,
+			Status:        models.PaymentCallbackStatusReceived,
+		}
+		if err := s.db.Create(&inbox).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return &inbox, s.process(&inbox)
+}
+
+// Replay 重放处理失败的通知
+func (s *PaymentCallbackService) Replay(id uint64) (*models.PaymentCallbackInbox, error) {
+	var inbox models.PaymentCallbackInbox
+	if err := s.db.First(&inbox, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentCallbackNotFound
+		}
+		return nil, err
+	}
+	if !inbox.IsReplayable() {
+		return &inbox, ErrPaymentCallbackNotReplayable
+	}
+	return &inbox, s.process(&inbox)
+}
+
+// ReplayFailed 批量重放处理失败的通知，返回成功和失败的条数
+func (s *PaymentCallbackService) ReplayFailed(limit int) (succeeded int, failed int, err error) {
+	var inboxes []models.PaymentCallbackInbox
+	if err := s.db.Where("status IN ?", []models.PaymentCallbackStatus{
+		models.PaymentCallbackStatusReceived, models.PaymentCallbackStatusFailed,
+	}).Order("id ASC").Limit(limit).Find(&inboxes).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for i := range inboxes {
+		if err := s.process(&inboxes[i]); err != nil {
+			failed++
+			continue
+		}
+		succeeded++
+	}
+	return succeeded, failed, nil
+}
+
+// Reconcile 主动向渠道查询交易并按查询结果补单，用于回调丢失或处理失败的支付
+func (s *PaymentCallbackService) Reconcile(ctx context.Context, paymentNo string) (*models.PaymentCallbackInbox, error) {
+	var record PaymentRecord
+	if err := s.db.Where("payment_no = ?", paymentNo).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentRecordNotFound
+		}
+		return nil, err
+	}
+
+	trade, err := s.paymentService.QueryTrade(ctx, &record)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providers.Get(record.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	rawData, err := json.Marshal(trade)
+	if err != nil {
+		return nil, err
+	}
+
+	appID, mchID := provider.Merchant()
+	return s.Receive(record.PaymentMethod, models.PaymentCallbackSourceReconcile, &ProviderNotification{
+		ProviderTrade: *trade,
+		AppID:         appID,
+		MchID:         mchID,
+		RawData:       string(rawData),
+	})
+}
+
+// List 分页查询支付通知
+func (s *PaymentCallbackService) List(page, pageSize int, status models.PaymentCallbackStatus, paymentNo string) ([]models.PaymentCallbackInbox, int64, error) {
+	query := s.db.Model(&models.PaymentCallbackInbox{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if paymentNo != "" {
+		query = query.Where("payment_no = ?", paymentNo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var inboxes []models.PaymentCallbackInbox
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&inboxes).Error
+	return inboxes, total, err
+}
+
+// process 处理通知并记录处理结果
+func (s *PaymentCallbackService) process(inbox *models.PaymentCallbackInbox) error {
+	status, err := s.apply(inbox)
+	now := time.Now()
+	inbox.Status = status
+	inbox.Attempts++
+	inbox.ErrorMsg = ""
+	if err != nil {
+		inbox.ErrorMsg = truncateErrorMsg(err.Error())
+	}
+	inbox.ProcessedAt = &now
+
+	if saveErr := s.db.Model(inbox).Updates(map[string]interface{}{
+		"source":       inbox.Source,
+		"raw_data":     inbox.RawData,
+		"status":       inbox.Status,
+		"attempts":     inbox.Attempts,
+		"error_msg":    inbox.ErrorMsg,
+		"processed_at": inbox.ProcessedAt,
+	}).Error; saveErr != nil && err == nil {
+		return saveErr
+	}
+	return err
+}
+
+// apply 在一个事务中更新支付记录和订单
+// 支付记录加行锁，已支付成功的记录直接视为重复通知，保证并发和重复回调只生效一次
+func (s *PaymentCallbackService) apply(inbox *models.PaymentCallbackInbox) (models.PaymentCallbackStatus, error) {
+	if inbox.TradeState != string(TradeStatePaid) {
+		return models.PaymentCallbackStatusIgnored, nil
+	}
+
+	expectedAppID, expectedMchID := "", ""
+	if provider, err := s.providers.Get(PaymentMethod(inbox.PaymentMethod)); err == nil {
+		expectedAppID, expectedMchID = provider.Merchant()
+	}
+
+	status := models.PaymentCallbackStatusProcessed
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var record PaymentRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("payment_no = ?", inbox.PaymentNo).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentRecordNotFound
+			}
+			return err
+		}
+
+		if err := checkPaymentCallback(&record, inbox, expectedAppID, expectedMchID); err != nil {
+			return err
+		}
+		switch record.Status {
+		case PaymentStatusPending, PaymentStatusFailed, PaymentStatusExpired:
+		default:
+			// 已支付或已退款，说明通知此前已处理过
+			status = models.PaymentCallbackStatusDuplicate
+			return nil
+		}
+
+		paidAt := time.Now()
+		if inbox.PayTime != nil {
+			paidAt = *inbox.PayTime
+		}
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"status":        PaymentStatusSuccess,
+			"trade_no":      inbox.TradeNo,
+			"pay_time":      paidAt,
+			"callback_data": inbox.RawData,
+			"error_msg":     "",
+		}).Error; err != nil {
+			return err
+		}
+
+		if record.CheckoutID != nil {
+			return NewCheckoutService(s.db, nil).ApplyCombinedPaymentTx(tx, record.PaymentNo, paidAt)
+		}
+		return s.applyOrderPayment(tx, &record, paidAt)
+	})
+	if err != nil {
+		return models.PaymentCallbackStatusFailed, err
+	}
+	return status, nil
+}
+
+// applyOrderPayment 单笔支付到账后将订单流转为待确认
+// 订单已不是待支付状态时通知管理员，已取消的订单自动发起退款
+func (s *PaymentCallbackService) applyOrderPayment(tx *gorm.DB, record *PaymentRecord, paidAt time.Time) error {
+	paymentFields := map[string]interface{}{
+		"payment_status": models.PaymentStatusPaid,
+		"payment_time":   paidAt,
+		"payment_no":     record.PaymentNo,
+	}
+
+	_, err := NewOrderStateMachine(s.db).TransitionTx(tx, &OrderTransition{
+		OrderID:      record.OrderID,
+		From:         []models.OrderStatus{models.OrderStatusPendingPayment},
+		To:           models.OrderStatusPendingConfirm,
+		OperatorType: models.OperatorTypeSystem,
+		Remark:       "支付成功",
+		Extra:        paymentFields,
+	})
+	var invalid *InvalidTransitionError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	refunded, err := refundOrphanPaymentTx(tx, record.OrderID, record.PaymentNo, paidAt)
+	if err != nil {
+		return err
+	}
+	action := "需人工处理"
+	if refunded {
+		action = "已自动发起退款"
+	}
+	return tx.Create(models.NewOrderNotification(
+		models.AdminNotificationPaymentOrphan,
+		record.OrderID,
+		"支付到账时订单状态异常",
+		fmt.Sprintf("订单 %s 在支付 %s 到账时已是%s状态，支付金额%.2f元%s",
+			record.OrderNo, record.PaymentNo, invalid.From, record.Amount, action),
+	)).Error
+}
+
+// checkPaymentCallback 校验通知与支付记录的支付方式、金额、交易号以及商户配置是否一致
+// 渠道未返回或未配置的商户字段不参与比对
+func checkPaymentCallback(record *PaymentRecord, inbox *models.PaymentCallbackInbox, appID, mchID string) error {
+	if string(record.PaymentMethod) != string(inbox.PaymentMethod) {
+		return &PaymentCallbackMismatchError{Field: "支付方式", Expected: string(record.PaymentMethod), Actual: string(inbox.PaymentMethod)}
+	}
+	if !priceEquals(record.Amount, inbox.Amount) {
+		return &PaymentCallbackMismatchError{
+			Field:    "金额",
+			Expected: fmt.Sprintf("%.2f", record.Amount),
+			Actual:   fmt.Sprintf("%.2f", inbox.Amount),
+		}
+	}
+	if appID != "" && inbox.AppID != "" && appID != inbox.AppID {
+		return &PaymentCallbackMismatchError{Field: "应用ID", Expected: appID, Actual: inbox.AppID}
+	}
+	if mchID != "" && inbox.MchID != "" && mchID != inbox.MchID {
+		return &PaymentCallbackMismatchError{Field: "商户号", Expected: mchID, Actual: inbox.MchID}
+	}
+	if record.Status == PaymentStatusSuccess && record.TradeNo != "" && record.TradeNo != inbox.TradeNo {
+		return &PaymentCallbackMismatchError{Field: "交易号", Expected: record.TradeNo, Actual: inbox.TradeNo}
+	}
+	return nil
+}
+
+// truncateErrorMsg 截断错误信息以适配 error_msg 字段长度
+func truncateErrorMsg(msg string) string {
+	const maxLen = 500
+	runes := []rune(msg)
+	if len(runes) > maxLen {
+		return string(runes[:maxLen])
+	}
+	return msg
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestCheckPaymentCallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		record        PaymentRecord
+		inbox         models.PaymentCallbackInbox
+		appID         string
+		mchID         string
+		mismatchField string
+	}{
+		{
+			name:   "match",
+			record: PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 100.5, Status: PaymentStatusPending},
+			inbox:  models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 100.5, AppID: "app", MchID: "mch"},
+			appID:  "app",
+			mchID:  "mch",
+		},
+		{
+			name:          "amount mismatch",
+			record:        PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 100.5},
+			inbox:         models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 100.4},
+			mismatchField: "金额",
+		},
+		{
+			name:          "method mismatch",
+			record:        PaymentRecord{PaymentMethod: PaymentMethodAlipay, Amount: 10},
+			inbox:         models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 10},
+			mismatchField: "支付方式",
+		},
+		{
+			name:          "app id mismatch",
+			record:        PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 10},
+			inbox:         models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 10, AppID: "other"},
+			appID:         "app",
+			mismatchField: "应用ID",
+		},
+		{
+			name:          "mch id mismatch",
+			record:        PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 10},
+			inbox:         models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 10, MchID: "other"},
+			mchID:         "mch",
+			mismatchField: "商户号",
+		},
+		{
+			name:   "merchant not configured",
+			record: PaymentRecord{PaymentMethod: PaymentMethodAlipay, Amount: 10},
+			inbox:  models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodAlipay, Amount: 10, MchID: "seller"},
+			appID:  "app",
+		},
+		{
+			name:   "duplicate with same trade",
+			record: PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 10, Status: PaymentStatusSuccess, TradeNo: "T1"},
+			inbox:  models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 10, TradeNo: "T1"},
+		},
+		{
+			name:          "paid by another trade",
+			record:        PaymentRecord{PaymentMethod: PaymentMethodWechat, Amount: 10, Status: PaymentStatusSuccess, TradeNo: "T1"},
+			inbox:         models.PaymentCallbackInbox{PaymentMethod: models.PaymentMethodWechat, Amount: 10, TradeNo: "T2"},
+			mismatchField: "交易号",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPaymentCallback(&tt.record, &tt.inbox, tt.appID, tt.mchID)
+			if tt.mismatchField == "" {
+				if err != nil {
+					t.Errorf("checkPaymentCallback() error = %v, expected nil", err)
+				}
+				return
+			}
+			var mismatch *PaymentCallbackMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("checkPaymentCallback() error = %v, expected mismatch", err)
+			}
+			if mismatch.Field != tt.mismatchField {
+				t.Errorf("mismatch field = %s, expected %s", mismatch.Field, tt.mismatchField)
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/project/backend/config"
+	"github.com/shopspring/decimal"
+)
+
+// PaymentProvider 第三方支付渠道
+// 支付单号即渠道侧的商户订单号(out_trade_no)
+type PaymentProvider interface {
+	// Method 渠道对应的支付方式
+	Method() PaymentMethod
+	// CreatePayment 创建扫码支付
+	CreatePayment(ctx context.Context, req *ProviderPaymentRequest) (*ProviderPaymentResponse, error)
+	// QueryPayment 查询交易状态
+	QueryPayment(ctx context.Context, paymentNo string) (*ProviderTrade, error)
+	// ClosePayment 关闭未支付的交易
+	ClosePayment(ctx context.Context, paymentNo string) error
+	// Refund 申请退款
+	Refund(ctx context.Context, req *ProviderRefundRequest) (*ProviderRefundResponse, error)
+	// VerifyCallback 验签并解析支付回调
+	VerifyCallback(ctx context.Context, req *http.Request) (*ProviderNotification, error)
+	// VerifyRefundCallback 验签并解析退款结果通知
+	VerifyRefundCallback(ctx context.Context, req *http.Request) (*ProviderRefundNotification, error)
+	// Merchant 本渠道配置的应用ID和商户号，为空的字段不参与回调校验
+	Merchant() (appID, mchID string)
+}
+
+// TradeState 渠道交易状态
+type TradeState string
+
+const (
+	TradeStatePending  TradeState = "pending"
+	TradeStatePaid     TradeState = "paid"
+	TradeStateClosed   TradeState = "closed"
+	TradeStateRefunded TradeState = "refunded"
+)
+
+// RefundState 渠道退款状态
+type RefundState string
+
+const (
+	RefundStateProcessing RefundState = "processing"
+	RefundStateSuccess    RefundState = "success"
+	RefundStateFailed     RefundState = "failed"
+)
+
+// ProviderPaymentRequest 渠道下单请求
+type ProviderPaymentRequest struct {
+	PaymentNo string
+	Amount    float64 // 金额(元)
+	Subject   string
+}
+
+// ProviderPaymentResponse 渠道下单结果
+type ProviderPaymentResponse struct {
+	QRCodeURL string
+	PayURL    string
+}
+
+// ProviderTrade 渠道交易
+type ProviderTrade struct {
+	PaymentNo string     `json:"paymentNo"`
+	TradeNo   string     `json:"tradeNo"`
+	State     TradeState `json:"state"`
+	Amount    float64    `json:"amount"`
+	PayTime   *time.Time `json:"payTime,omitempty"`
+}
+
+// ProviderRefundRequest 渠道退款请求
+type ProviderRefundRequest struct {
+	PaymentNo   string
+	RefundNo    string
+	Amount      float64 // 退款金额(元)
+	TotalAmount float64 // 原交易金额(元)
+	Reason      string
+}
+
+// ProviderRefundResponse 渠道退款结果
+type ProviderRefundResponse struct {
+	RefundNo string      `json:"refundNo"`
+	RefundID string      `json:"refundId"` // 渠道退款单号
+	Status   string      `json:"status"`   // 渠道原始状态
+	State    RefundState `json:"state"`
+}
+
+// ProviderRefundNotification 验签后的退款结果通知
+type ProviderRefundNotification struct {
+	PaymentNo  string      `json:"paymentNo"`
+	RefundNo   string      `json:"refundNo"`
+	RefundID   string      `json:"refundId"`
+	State      RefundState `json:"state"`
+	Amount     float64     `json:"amount"`
+	RefundTime *time.Time  `json:"refundTime,omitempty"`
+	RawData    string      `json:"-"`
+}
+
+// ProviderNotification 验签后的支付回调
+type ProviderNotification struct {
+	ProviderTrade
+	AppID   string `json:"appId,omitempty"`
+	MchID   string `json:"mchId,omitempty"`
+	RawData string `json:"-"`
+}
+
+// ErrPaymentProviderUnavailable 支付渠道未配置
+var ErrPaymentProviderUnavailable = errors.New("支付渠道未配置")
+
+// ErrRefundNotifyUnsupported 渠道退款结果同步返回，不发送退款通知
+var ErrRefundNotifyUnsupported = errors.New("该支付渠道不发送退款通知")
+
+// PaymentGatewayError 调用支付渠道失败
+type PaymentGatewayError struct {
+	Method PaymentMethod
+	Err    error
+}
+
+func (e *PaymentGatewayError) Error() string {
+	return fmt.Sprintf("%s支付渠道调用失败: %v", e.Method, e.Err)
+}
+
+func (e *PaymentGatewayError) Unwrap() error {
+	return e.Err
+}
+
+// PaymentProviders 按支付方式选择支付渠道
+type PaymentProviders struct {
+	providers map[PaymentMethod]PaymentProvider
+}
+
+// NewPaymentProviders 创建支付渠道集合
+func NewPaymentProviders(providers ...PaymentProvider) *PaymentProviders {
+	p := &PaymentProviders{providers: make(map[PaymentMethod]PaymentProvider, len(providers))}
+	for _, provider := range providers {
+		p.providers[provider.Method()] = provider
+	}
+	return p
+}
+
+// InitPaymentProviders 根据配置初始化支付渠道，未配置的渠道不可用
+func InitPaymentProviders(cfg *config.Config) *PaymentProviders {
+	var providers []PaymentProvider
+	wechatService, alipayService := InitPaymentServices(cfg)
+	if wechatService != nil {
+		providers = append(providers, NewWeChatPaymentProvider(wechatService))
+	}
+	if alipayService != nil {
+		providers = append(providers, NewAlipayPaymentProvider(alipayService))
+	}
+	if cfg.Payment.MockEnabled {
+		providers = append(providers, NewMockPaymentProvider(cfg.Payment.MockSecret))
+	}
+	return NewPaymentProviders(providers...)
+}
+
+// Get 获取支付方式对应的渠道
+func (p *PaymentProviders) Get(method PaymentMethod) (PaymentProvider, error) {
+	if p != nil {
+		if provider, ok := p.providers[method]; ok {
+			return provider, nil
+		}
+	}
+	return nil, ErrPaymentProviderUnavailable
+}
+
+// Mock 获取模拟支付渠道，未启用时返回 nil
+func (p *PaymentProviders) Mock() *MockPaymentProvider {
+	provider, err := p.Get(PaymentMethodMock)
+	if err != nil {
+		return nil
+	}
+	mock, _ := provider.(*MockPaymentProvider)
+	return mock
+}
+
+// yuanToCents 元转分
+func yuanToCents(amount float64) int64 {
+	return decimal.NewFromFloat(amount).Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+}
+
+// centsToYuan 分转元
+func centsToYuan(cents int64) float64 {
+	return decimal.New(cents, -2).InexactFloat64()
+}
+
+// WeChatPaymentProvider 微信支付渠道
+type WeChatPaymentProvider struct {
+	service *WeChatPayService
+}
+
+// NewWeChatPaymentProvider 创建微信支付渠道
+func NewWeChatPaymentProvider(service *WeChatPayService) *WeChatPaymentProvider {
+	return &WeChatPaymentProvider{service: service}
+}
+
+// Method 支付方式
+func (p *WeChatPaymentProvider) Method() PaymentMethod {
+	return PaymentMethodWechat
+}
+
+// CreatePayment 创建Native扫码支付
+func (p *WeChatPaymentProvider) CreatePayment(ctx context.Context, req *ProviderPaymentRequest) (*ProviderPaymentResponse, error) {
+	resp, err := p.service.CreateNativePayment(ctx, &NativePaymentRequest{
+		OrderNo:       req.PaymentNo,
+		AmountInCents: yuanToCents(req.Amount),
+		Description:   req.Subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderPaymentResponse{QRCodeURL: resp.QRCodeURL}, nil
+}
+
+// QueryPayment 查询交易
+func (p *WeChatPaymentProvider) QueryPayment(ctx context.Context, paymentNo string) (*ProviderTrade, error) {
+	resp, err := p.service.QueryOrder(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderTrade{
+		PaymentNo: resp.OutTradeNo,
+		TradeNo:   resp.TransactionId,
+		State:     wechatTradeState(resp.TradeState),
+		Amount:    centsToYuan(resp.Amount),
+	}, nil
+}
+
+// Merchant 应用ID和商户号
+func (p *WeChatPaymentProvider) Merchant() (appID, mchID string) {
+	return p.service.Merchant()
+}
+
+// ClosePayment 关闭交易
+func (p *WeChatPaymentProvider) ClosePayment(ctx context.Context, paymentNo string) error {
+	return p.service.CloseOrder(ctx, paymentNo)
+}
+
+// Refund 申请退款
+func (p *WeChatPaymentProvider) Refund(ctx context.Context, req *ProviderRefundRequest) (*ProviderRefundResponse, error) {
+	resp, err := p.service.RefundOrder(ctx, &WeChatRefundRequest{
+		OrderNo:      req.PaymentNo,
+		RefundNo:     req.RefundNo,
+		RefundAmount: yuanToCents(req.Amount),
+		TotalAmount:  yuanToCents(req.TotalAmount),
+		RefundReason: req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderRefundResponse{
+		RefundNo: resp.OutRefundNo,
+		RefundID: resp.RefundId,
+		Status:   resp.RefundStatus,
+		State:    wechatRefundState(resp.RefundStatus),
+	}, nil
+}
+
+// VerifyRefundCallback 验签并解析退款结果通知
+func (p *WeChatPaymentProvider) VerifyRefundCallback(ctx context.Context, req *http.Request) (*ProviderRefundNotification, error) {
+	notification, err := p.service.VerifyRefundCallback(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := &ProviderRefundNotification{
+		PaymentNo: notification.OutTradeNo,
+		RefundNo:  notification.OutRefundNo,
+		RefundID:  notification.RefundId,
+		State:     wechatRefundState(notification.RefundStatus),
+		Amount:    centsToYuan(notification.RefundAmount),
+		RawData:   notification.RawData,
+	}
+	if !notification.SuccessTime.IsZero() {
+		refundTime := notification.SuccessTime
+		result.RefundTime = &refundTime
+	}
+	return result, nil
+}
+
+// VerifyCallback 验签并解析支付回调
+func (p *WeChatPaymentProvider) VerifyCallback(ctx context.Context, req *http.Request) (*ProviderNotification, error) {
+	notification, err := p.service.VerifyCallback(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := &ProviderNotification{
+		ProviderTrade: ProviderTrade{
+			PaymentNo: notification.OutTradeNo,
+			TradeNo:   notification.TransactionId,
+			State:     wechatTradeState(notification.TradeState),
+			Amount:    centsToYuan(notification.Amount),
+		},
+		AppID:   notification.AppID,
+		MchID:   notification.MchID,
+		RawData: notification.RawData,
+	}
+	if !notification.PayTime.IsZero() {
+		payTime := notification.PayTime
+		result.PayTime = &payTime
+	}
+	return result, nil
+}
+
+// wechatTradeState 微信交易状态转换
+func wechatTradeState(state string) TradeState {
+	switch state {
+	case "SUCCESS":
+		return TradeStatePaid
+	case "REFUND":
+		return TradeStateRefunded
+	case "CLOSED", "REVOKED", "PAYERROR":
+		return TradeStateClosed
+	}
+	return TradeStatePending
+}
+
+// wechatRefundState 微信退款状态转换
+func wechatRefundState(status string) RefundState {
+	switch status {
+	case "SUCCESS":
+		return RefundStateSuccess
+	case "CLOSED", "ABNORMAL":
+		return RefundStateFailed
+	}
+	return RefundStateProcessing
+}
+
+// AlipayPaymentProvider 支付宝渠道
+type AlipayPaymentProvider struct {
+	service *AlipayService
+}
+
+// NewAlipayPaymentProvider 创建支付宝渠道
+func NewAlipayPaymentProvider(service *AlipayService) *AlipayPaymentProvider {
+	return &AlipayPaymentProvider{service: service}
+}
+
+// Method 支付方式
+func (p *AlipayPaymentProvider) Method() PaymentMethod {
+	return PaymentMethodAlipay
+}
+
+// CreatePayment 创建当面付扫码支付
+func (p *AlipayPaymentProvider) CreatePayment(ctx context.Context, req *ProviderPaymentRequest) (*ProviderPaymentResponse, error) {
+	resp, err := p.service.CreateNativePayment(ctx, &AlipayPaymentRequest{
+		OrderNo: req.PaymentNo,
+		Amount:  req.Amount,
+		Subject: req.Subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderPaymentResponse{QRCodeURL: resp.QRCodeURL, PayURL: resp.PayURL}, nil
+}
+
+// QueryPayment 查询交易
+func (p *AlipayPaymentProvider) QueryPayment(ctx context.Context, paymentNo string) (*ProviderTrade, error) {
+	resp, err := p.service.QueryOrder(ctx, paymentNo)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderTrade{
+		PaymentNo: resp.OutTradeNo,
+		TradeNo:   resp.TradeNo,
+		State:     alipayTradeState(resp.TradeStatus),
+		Amount:    parseYuan(resp.TotalAmount),
+	}, nil
+}
+
+// Merchant 应用ID，支付宝通知不校验商户号
+func (p *AlipayPaymentProvider) Merchant() (appID, mchID string) {
+	return p.service.AppID(), ""
+}
+
+// ClosePayment 关闭交易
+func (p *AlipayPaymentProvider) ClosePayment(ctx context.Context, paymentNo string) error {
+	return p.service.CloseOrder(ctx, paymentNo)
+}
+
+// Refund 申请退款
+func (p *AlipayPaymentProvider) Refund(ctx context.Context, req *ProviderRefundRequest) (*ProviderRefundResponse, error) {
+	resp, err := p.service.RefundOrder(ctx, &AlipayRefundRequest{
+		OrderNo:      req.PaymentNo,
+		RefundNo:     req.RefundNo,
+		RefundAmount: req.Amount,
+		RefundReason: req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderRefundResponse{
+		RefundNo: resp.RefundNo,
+		RefundID: resp.TradeNo,
+		Status:   "SUCCESS",
+		State:    RefundStateSuccess,
+	}, nil
+}
+
+// VerifyRefundCallback 支付宝退款结果在退款接口同步返回，没有退款通知
+func (p *AlipayPaymentProvider) VerifyRefundCallback(ctx context.Context, req *http.Request) (*ProviderRefundNotification, error) {
+	return nil, ErrRefundNotifyUnsupported
+}
+
+// VerifyCallback 验签并解析异步通知
+func (p *AlipayPaymentProvider) VerifyCallback(ctx context.Context, req *http.Request) (*ProviderNotification, error) {
+	notification, err := p.service.VerifyCallback(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &ProviderNotification{
+		ProviderTrade: ProviderTrade{
+			PaymentNo: notification.OutTradeNo,
+			TradeNo:   notification.TradeNo,
+			State:     alipayTradeState(notification.TradeStatus),
+			Amount:    parseYuan(notification.TotalAmount),
+		},
+		AppID:   notification.AppID,
+		MchID:   notification.SellerID,
+		RawData: req.Form.Encode(),
+	}
+	if payTime, err := time.ParseInLocation("2006-01-02 15:04:05", notification.GmtPayment, time.Local); err == nil {
+		result.PayTime = &payTime
+	}
+	return result, nil
+}
+
+// alipayTradeState 支付宝交易状态转换
+func alipayTradeState(status string) TradeState {
+	switch status {
+	case "TRADE_SUCCESS", "TRADE_FINISHED":
+		return TradeStatePaid
+	case "TRADE_CLOSED":
+		return TradeStateClosed
+	}
+	return TradeStatePending
+}
+
+// parseYuan 解析以元为单位的金额字符串
+func parseYuan(value string) float64 {
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return 0
+	}
+	return amount.InexactFloat64()
+}
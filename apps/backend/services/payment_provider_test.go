@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestTradeStateMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    TradeState
+		expected TradeState
+	}{
+		{"wechat success", wechatTradeState("SUCCESS"), TradeStatePaid},
+		{"wechat notpay", wechatTradeState("NOTPAY"), TradeStatePending},
+		{"wechat closed", wechatTradeState("CLOSED"), TradeStateClosed},
+		{"wechat refund", wechatTradeState("REFUND"), TradeStateRefunded},
+		{"alipay success", alipayTradeState("TRADE_SUCCESS"), TradeStatePaid},
+		{"alipay finished", alipayTradeState("TRADE_FINISHED"), TradeStatePaid},
+		{"alipay waiting", alipayTradeState("WAIT_BUYER_PAY"), TradeStatePending},
+		{"alipay closed", alipayTradeState("TRADE_CLOSED"), TradeStateClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.state != tt.expected {
+				t.Errorf("state = %s, expected %s", tt.state, tt.expected)
+			}
+		})
+	}
+}
+
+func TestYuanCentsConversion(t *testing.T) {
+	tests := []struct {
+		yuan  float64
+		cents int64
+	}{
+		{0.01, 1},
+		{19.99, 1999},
+		{100.005, 10001},
+		{0.1 + 0.2, 30},
+	}
+
+	for _, tt := range tests {
+		if result := yuanToCents(tt.yuan); result != tt.cents {
+			t.Errorf("yuanToCents(%v) = %d, expected %d", tt.yuan, result, tt.cents)
+		}
+	}
+	if result := centsToYuan(1999); result != 19.99 {
+		t.Errorf("centsToYuan(1999) = %v, expected 19.99", result)
+	}
+}
+
+func TestMockPaymentProviderFlow(t *testing.T) {
+	ctx := context.Background()
+	provider := NewMockPaymentProvider("secret")
+	providers := NewPaymentProviders(provider)
+
+	got, err := providers.Get(PaymentMethodMock)
+	if err != nil || got != provider {
+		t.Fatalf("providers.Get(mock) = %v, %v", got, err)
+	}
+	if _, err := providers.Get(PaymentMethodWechat); err != ErrPaymentProviderUnavailable {
+		t.Errorf("providers.Get(wechat) error = %v, expected ErrPaymentProviderUnavailable", err)
+	}
+
+	if _, err := provider.CreatePayment(ctx, &ProviderPaymentRequest{PaymentNo: "MK1", Amount: 12.5}); err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+
+	body, signature, err := provider.SimulatePay("MK1", true)
+	if err != nil {
+		t.Fatalf("SimulatePay() error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(MockSignatureHeader, signature)
+	notification, err := provider.VerifyCallback(ctx, req)
+	if err != nil {
+		t.Fatalf("VerifyCallback() error = %v", err)
+	}
+	if notification.State != TradeStatePaid || notification.Amount != 12.5 || notification.TradeNo == "" {
+		t.Errorf("notification = %+v, expected paid trade", notification.ProviderTrade)
+	}
+
+	tampered, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	tampered.Header.Set(MockSignatureHeader, "invalid")
+	if _, err := provider.VerifyCallback(ctx, tampered); err == nil {
+		t.Error("VerifyCallback() with invalid signature should fail")
+	}
+
+	if err := provider.ClosePayment(ctx, "MK1"); err == nil {
+		t.Error("ClosePayment() on paid trade should fail")
+	}
+
+	if _, err := provider.Refund(ctx, &ProviderRefundRequest{PaymentNo: "MK1", RefundNo: "RF1", Amount: 12.5, TotalAmount: 12.5}); err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	trade, _ := provider.QueryPayment(ctx, "MK1")
+	if trade.State != TradeStateRefunded {
+		t.Errorf("QueryPayment().State = %s, expected refunded", trade.State)
+	}
+}
+
+func TestMockPaymentProviderFailures(t *testing.T) {
+	ctx := context.Background()
+	provider := NewMockPaymentProvider("")
+
+	provider.FailNext(MockOperationCreate, "")
+	if _, err := provider.CreatePayment(ctx, &ProviderPaymentRequest{PaymentNo: "MK2", Amount: 1}); err == nil {
+		t.Fatal("CreatePayment() should fail after FailNext")
+	}
+	if _, err := provider.CreatePayment(ctx, &ProviderPaymentRequest{PaymentNo: "MK2", Amount: 1}); err != nil {
+		t.Fatalf("CreatePayment() error = %v, failure should only apply once", err)
+	}
+
+	if _, _, err := provider.SimulatePay("MK2", false); err != nil {
+		t.Fatalf("SimulatePay(fail) error = %v", err)
+	}
+	trade, _ := provider.QueryPayment(ctx, "MK2")
+	if trade.State != TradeStateClosed {
+		t.Errorf("QueryPayment().State = %s, expected closed", trade.State)
+	}
+
+	if _, err := provider.QueryPayment(ctx, "unknown"); err != ErrMockTradeNotFound {
+		t.Errorf("QueryPayment(unknown) error = %v, expected ErrMockTradeNotFound", err)
+	}
+}
+
+// methodMockProvider 以指定支付方式注册的模拟渠道
+type methodMockProvider struct {
+	*MockPaymentProvider
+	method PaymentMethod
+}
+
+func (p *methodMockProvider) Method() PaymentMethod {
+	return p.method
+}
+
+func TestSwitchTradeBackAndForth(t *testing.T) {
+	ctx := context.Background()
+	wechat := &methodMockProvider{NewMockPaymentProvider("wx"), PaymentMethodWechat}
+	alipay := &methodMockProvider{NewMockPaymentProvider("ali"), PaymentMethodAlipay}
+	service := NewPaymentService(nil, NewPaymentProviders(wechat, alipay))
+
+	if _, err := wechat.CreatePayment(ctx, &ProviderPaymentRequest{PaymentNo: "WX1", Amount: 30}); err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	record := &PaymentRecord{OrderID: 1, OrderNo: "ORD1", PaymentNo: "WX1", PaymentMethod: PaymentMethodWechat, Amount: 30, Status: PaymentStatusPending}
+
+	seen := map[string]bool{record.PaymentNo: true}
+	for _, method := range []PaymentMethod{PaymentMethodAlipay, PaymentMethodWechat, PaymentMethodAlipay} {
+		previous := record
+		next, prepay, err := service.switchTrade(ctx, record, method)
+		if err != nil {
+			t.Fatalf("switchTrade(%s) error = %v", method, err)
+		}
+		if seen[next.PaymentNo] {
+			t.Fatalf("switchTrade(%s) reused payment no %s", method, next.PaymentNo)
+		}
+		seen[next.PaymentNo] = true
+		if next.PaymentMethod != method || next.Amount != 30 || next.OrderID != 1 || next.Status != PaymentStatusPending {
+			t.Errorf("switchTrade(%s) record = %+v", method, next)
+		}
+		if prepay.QRCodeURL == "" || next.QRCodeURL != prepay.QRCodeURL || next.QRCodeExpireTime == nil {
+			t.Errorf("switchTrade(%s) qrcode = %q, %v", method, next.QRCodeURL, next.QRCodeExpireTime)
+		}
+
+		trade, err := service.QueryTrade(ctx, previous)
+		if err != nil || trade.State != TradeStateClosed {
+			t.Errorf("previous trade %s = %v, %v, expected closed", previous.PaymentNo, trade, err)
+		}
+		record = next
+	}
+
+	// 已关闭的商户订单号不能再次下单
+	if _, err := service.Prepay(ctx, PaymentMethodWechat, "WX1", 30, ""); err == nil {
+		t.Error("Prepay() with a closed payment no should fail")
+	}
+}
@@ -1,25 +1,33 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/project/backend/config"
+	"github.com/project/backend/models"
 	"gorm.io/gorm"
 )
 
 // PaymentService 支付服务
 type PaymentService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	providers *PaymentProviders
 }
 
-// NewPaymentService 创建支付服务
-func NewPaymentService(db *gorm.DB) *PaymentService {
-	return &PaymentService{db: db}
+// NewPaymentService 创建支付服务，按支付方式选择 providers 中的渠道
+func NewPaymentService(db *gorm.DB, providers *PaymentProviders) *PaymentService {
+	return &PaymentService{db: db, providers: providers}
 }
 
+// paymentQRCodeTTL 支付二维码有效期
+const paymentQRCodeTTL = 15 * time.Minute
+
 // PaymentStatus 支付状态
 type PaymentStatus string
 
@@ -29,6 +37,7 @@ const (
 	PaymentStatusFailed        PaymentStatus = "failed"
 	PaymentStatusRefunded      PaymentStatus = "refunded"
 	PaymentStatusPartialRefund PaymentStatus = "partial_refund"
+	PaymentStatusExpired       PaymentStatus = "expired"
 )
 
 // PaymentMethod 支付方式
@@ -44,6 +53,7 @@ type PaymentRecord struct {
 	ID               uint64        `gorm:"primaryKey" json:"id"`
 	OrderID          uint64        `gorm:"not null;index" json:"orderId"`
 	OrderNo          string        `gorm:"type:varchar(30);not null" json:"orderNo"`
+	CheckoutID       *uint64       `gorm:"index" json:"checkoutId,omitempty"` // 合并支付时的结算单ID
 	PaymentNo        string        `gorm:"type:varchar(50);uniqueIndex;not null" json:"paymentNo"`
 	PaymentMethod    PaymentMethod `gorm:"type:varchar(20);not null" json:"paymentMethod"`
 	GoodsAmount      float64       `gorm:"type:decimal(10,2);not null" json:"goodsAmount"`
@@ -71,18 +81,22 @@ func (PaymentRecord) TableName() string {
 
 // CreatePaymentRequest 创建支付请求
 type CreatePaymentRequest struct {
+	PaymentNo     string        `json:"paymentNo"` // 商户支付单号，为空时自动生成
 	OrderID       uint64        `json:"orderId" validate:"required"`
 	OrderNo       string        `json:"orderNo" validate:"required"`
+	CheckoutID    *uint64       `json:"checkoutId"`
 	GoodsAmount   float64       `json:"goodsAmount" validate:"required,gt=0"`
 	ServiceFee    float64       `json:"serviceFee" validate:"gte=0"`
 	Amount        float64       `json:"amount" validate:"required,gt=0"`
-	PaymentMethod PaymentMethod `json:"paymentMethod" validate:"required,oneof=wechat alipay"`
+	PaymentMethod PaymentMethod `json:"paymentMethod" validate:"required,oneof=wechat alipay mock"`
+	Subject       string        `json:"subject"` // 渠道侧显示的商品标题
 }
 
 // PaymentQRCodeResponse 支付二维码响应
 type PaymentQRCodeResponse struct {
 	PaymentNo   string    `json:"paymentNo"`
 	QRCodeURL   string    `json:"qrcodeUrl"`
+	PayURL      string    `json:"payUrl,omitempty"`
 	ExpireTime  time.Time `json:"expireTime"`
 	ExpireIn    int       `json:"expireIn"` // 秒数
 	Amount      float64   `json:"amount"`
@@ -99,29 +113,36 @@ type PaymentStatusResponse struct {
 	TradeNo   string        `json:"tradeNo,omitempty"`
 }
 
-// CreatePayment 创建支付订单并生成二维码
-func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*PaymentQRCodeResponse, error) {
+// CreatePayment 调用支付渠道下单并创建支付记录
+func (s *PaymentService) CreatePayment(ctx context.Context, req *CreatePaymentRequest) (*PaymentQRCodeResponse, error) {
 	// 生成支付流水号
-	paymentNo := generatePaymentNo()
-
-	// 设置二维码过期时间（15分钟）
-	expireTime := time.Now().Add(15 * time.Minute)
+	paymentNo := req.PaymentNo
+	if paymentNo == "" {
+		paymentNo = generatePaymentNo()
+	}
 
-	// 生成模拟二维码URL（实际项目中应调用微信/支付宝接口）
-	qrcodeURL := fmt.Sprintf("https://pay.example.com/qrcode/%s?method=%s&amount=%.2f",
-		paymentNo, req.PaymentMethod, req.Amount)
+	subject := req.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("供应链订货-订单%s", req.OrderNo)
+	}
+	expireTime := time.Now().Add(paymentQRCodeTTL)
+	prepay, err := s.Prepay(ctx, req.PaymentMethod, paymentNo, req.Amount, subject)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建支付记录
 	record := &PaymentRecord{
 		OrderID:          req.OrderID,
 		OrderNo:          req.OrderNo,
+		CheckoutID:       req.CheckoutID,
 		PaymentNo:        paymentNo,
 		PaymentMethod:    req.PaymentMethod,
 		GoodsAmount:      req.GoodsAmount,
 		ServiceFee:       req.ServiceFee,
 		Amount:           req.Amount,
 		Status:           PaymentStatusPending,
-		QRCodeURL:        qrcodeURL,
+		QRCodeURL:        prepay.QRCodeURL,
 		QRCodeExpireTime: &expireTime,
 	}
 
@@ -129,18 +150,28 @@ func (s *PaymentService) CreatePayment(req *CreatePaymentRequest) (*PaymentQRCod
 		return nil, err
 	}
 
-	return &PaymentQRCodeResponse{
-		PaymentNo:  paymentNo,
-		QRCodeURL:  qrcodeURL,
-		ExpireTime: expireTime,
-		ExpireIn:   15 * 60,
-		Amount:     req.Amount,
-		OrderNo:    req.OrderNo,
-	}, nil
+	return newPaymentQRCodeResponse(record, prepay, expireTime), nil
+}
+
+// Prepay 调用支付方式对应的渠道下单
+func (s *PaymentService) Prepay(ctx context.Context, method PaymentMethod, paymentNo string, amount float64, subject string) (*ProviderPaymentResponse, error) {
+	provider, err := s.providers.Get(method)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.CreatePayment(ctx, &ProviderPaymentRequest{
+		PaymentNo: paymentNo,
+		Amount:    amount,
+		Subject:   subject,
+	})
+	if err != nil {
+		return nil, &PaymentGatewayError{Method: method, Err: err}
+	}
+	return resp, nil
 }
 
 // RefreshQRCode 刷新支付二维码
-func (s *PaymentService) RefreshQRCode(paymentNo string) (*PaymentQRCodeResponse, error) {
+func (s *PaymentService) RefreshQRCode(ctx context.Context, paymentNo string) (*PaymentQRCodeResponse, error) {
 	var record PaymentRecord
 	if err := s.db.Where("payment_no = ?", paymentNo).First(&record).Error; err != nil {
 		return nil, err
@@ -150,30 +181,26 @@ func (s *PaymentService) RefreshQRCode(paymentNo string) (*PaymentQRCodeResponse
 		return nil, errors.New("支付状态已变更，无法刷新二维码")
 	}
 
-	// 更新过期时间
-	expireTime := time.Now().Add(15 * time.Minute)
-	qrcodeURL := fmt.Sprintf("https://pay.example.com/qrcode/%s?method=%s&amount=%.2f&t=%d",
-		paymentNo, record.PaymentMethod, record.Amount, time.Now().Unix())
+	// 同一商户订单号重新下单，渠道返回新的二维码
+	expireTime := time.Now().Add(paymentQRCodeTTL)
+	prepay, err := s.Prepay(ctx, record.PaymentMethod, paymentNo, record.Amount, fmt.Sprintf("供应链订货-订单%s", record.OrderNo))
+	if err != nil {
+		return nil, err
+	}
 
 	if err := s.db.Model(&record).Updates(map[string]interface{}{
-		"qrcode_url":         qrcodeURL,
+		"qrcode_url":         prepay.QRCodeURL,
 		"qrcode_expire_time": expireTime,
 	}).Error; err != nil {
 		return nil, err
 	}
 
-	return &PaymentQRCodeResponse{
-		PaymentNo:  paymentNo,
-		QRCodeURL:  qrcodeURL,
-		ExpireTime: expireTime,
-		ExpireIn:   15 * 60,
-		Amount:     record.Amount,
-		OrderNo:    record.OrderNo,
-	}, nil
+	return newPaymentQRCodeResponse(&record, prepay, expireTime), nil
 }
 
-// SwitchPaymentMethod 切换支付方式
-func (s *PaymentService) SwitchPaymentMethod(paymentNo string, newMethod PaymentMethod) (*PaymentQRCodeResponse, error) {
+// SwitchPaymentMethod 切换支付方式，关闭原渠道交易后以新的支付单号在新渠道下单
+// 渠道不接受已关闭的商户订单号再次下单，原支付记录置为已过期，订单和结算单改为指向新支付单号
+func (s *PaymentService) SwitchPaymentMethod(ctx context.Context, paymentNo string, newMethod PaymentMethod) (*PaymentQRCodeResponse, error) {
 	var record PaymentRecord
 	if err := s.db.Where("payment_no = ?", paymentNo).First(&record).Error; err != nil {
 		return nil, err
@@ -182,37 +209,179 @@ func (s *PaymentService) SwitchPaymentMethod(paymentNo string, newMethod Payment
 	if record.Status != PaymentStatusPending {
 		return nil, errors.New("支付状态已变更，无法切换支付方式")
 	}
+	if record.PaymentMethod == newMethod {
+		return s.RefreshQRCode(ctx, paymentNo)
+	}
 
-	// 更新支付方式和二维码
-	expireTime := time.Now().Add(15 * time.Minute)
-	qrcodeURL := fmt.Sprintf("https://pay.example.com/qrcode/%s?method=%s&amount=%.2f&t=%d",
-		paymentNo, newMethod, record.Amount, time.Now().Unix())
+	next, prepay, err := s.switchTrade(ctx, &record, newMethod)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.db.Model(&record).Updates(map[string]interface{}{
-		"payment_method":     newMethod,
-		"qrcode_url":         qrcodeURL,
-		"qrcode_expire_time": expireTime,
-	}).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PaymentRecord{}).
+			Where("id = ? AND status = ?", record.ID, PaymentStatusPending).
+			Updates(map[string]interface{}{
+				"status":    PaymentStatusExpired,
+				"error_msg": fmt.Sprintf("已切换支付方式，新支付单号%s", next.PaymentNo),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("支付状态已变更，无法切换支付方式")
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		paymentFields := map[string]interface{}{
+			"payment_method": models.PaymentMethod(newMethod),
+			"payment_no":     next.PaymentNo,
+		}
+		if record.CheckoutID != nil {
+			// 原分摊明细保留，原交易关闭前已支付的迟到回调仍可按原单号分摊或退款
+			var allocations []models.PaymentAllocation
+			if err := tx.Where("payment_no = ?", paymentNo).Find(&allocations).Error; err != nil {
+				return err
+			}
+			for _, allocation := range allocations {
+				allocation.ID = 0
+				allocation.PaymentNo = next.PaymentNo
+				allocation.CreatedAt = time.Time{}
+				if err := tx.Create(&allocation).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&models.Checkout{}).Where("id = ? AND payment_no = ?", *record.CheckoutID, paymentNo).
+				Updates(paymentFields).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Order{}).Where("payment_no = ?", paymentNo).
+			Updates(paymentFields).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	return newPaymentQRCodeResponse(next, prepay, *next.QRCodeExpireTime), nil
+}
+
+// switchTrade 关闭原渠道交易，生成新的支付单号在新渠道下单，返回待保存的新支付记录
+func (s *PaymentService) switchTrade(ctx context.Context, record *PaymentRecord, newMethod PaymentMethod) (*PaymentRecord, *ProviderPaymentResponse, error) {
+	if err := s.CloseTrade(ctx, record); err != nil && !errors.Is(err, ErrPaymentProviderUnavailable) {
+		return nil, nil, err
+	}
+
+	paymentNo := generatePaymentNo()
+	expireTime := time.Now().Add(paymentQRCodeTTL)
+	prepay, err := s.Prepay(ctx, newMethod, paymentNo, record.Amount, fmt.Sprintf("供应链订货-订单%s", record.OrderNo))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &PaymentRecord{
+		OrderID:          record.OrderID,
+		OrderNo:          record.OrderNo,
+		CheckoutID:       record.CheckoutID,
+		PaymentNo:        paymentNo,
+		PaymentMethod:    newMethod,
+		GoodsAmount:      record.GoodsAmount,
+		ServiceFee:       record.ServiceFee,
+		Amount:           record.Amount,
+		Status:           PaymentStatusPending,
+		QRCodeURL:        prepay.QRCodeURL,
+		QRCodeExpireTime: &expireTime,
+	}, prepay, nil
+}
+
+// newPaymentQRCodeResponse 组装支付二维码响应
+func newPaymentQRCodeResponse(record *PaymentRecord, prepay *ProviderPaymentResponse, expireTime time.Time) *PaymentQRCodeResponse {
 	return &PaymentQRCodeResponse{
-		PaymentNo:  paymentNo,
-		QRCodeURL:  qrcodeURL,
+		PaymentNo:  record.PaymentNo,
+		QRCodeURL:  prepay.QRCodeURL,
+		PayURL:     prepay.PayURL,
 		ExpireTime: expireTime,
-		ExpireIn:   15 * 60,
+		ExpireIn:   int(paymentQRCodeTTL.Seconds()),
 		Amount:     record.Amount,
 		OrderNo:    record.OrderNo,
-	}, nil
+	}
+}
+
+// QueryTrade 查询支付记录在渠道侧的交易状态
+func (s *PaymentService) QueryTrade(ctx context.Context, record *PaymentRecord) (*ProviderTrade, error) {
+	provider, err := s.providers.Get(record.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	trade, err := provider.QueryPayment(ctx, record.PaymentNo)
+	if err != nil {
+		return nil, &PaymentGatewayError{Method: record.PaymentMethod, Err: err}
+	}
+	return trade, nil
+}
+
+// CloseTrade 关闭支付记录在渠道侧的交易
+func (s *PaymentService) CloseTrade(ctx context.Context, record *PaymentRecord) error {
+	provider, err := s.providers.Get(record.PaymentMethod)
+	if err != nil {
+		return err
+	}
+	if err := provider.ClosePayment(ctx, record.PaymentNo); err != nil {
+		return &PaymentGatewayError{Method: record.PaymentMethod, Err: err}
+	}
+	return nil
+}
+
+// RefundTrade 对支付记录发起渠道退款
+func (s *PaymentService) RefundTrade(ctx context.Context, record *PaymentRecord, refundNo string, amount float64, reason string) (*ProviderRefundResponse, error) {
+	provider, err := s.providers.Get(record.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := provider.Refund(ctx, &ProviderRefundRequest{
+		PaymentNo:   record.PaymentNo,
+		RefundNo:    refundNo,
+		Amount:      amount,
+		TotalAmount: record.Amount,
+		Reason:      reason,
+	})
+	if err != nil {
+		return nil, &PaymentGatewayError{Method: record.PaymentMethod, Err: err}
+	}
+	return resp, nil
 }
 
-// GetPaymentStatus 查询支付状态
-func (s *PaymentService) GetPaymentStatus(paymentNo string) (*PaymentStatusResponse, error) {
+// VerifyCallback 使用对应渠道验签并解析支付回调
+func (s *PaymentService) VerifyCallback(ctx context.Context, method PaymentMethod, req *http.Request) (*ProviderNotification, error) {
+	provider, err := s.providers.Get(method)
+	if err != nil {
+		return nil, err
+	}
+	return provider.VerifyCallback(ctx, req)
+}
+
+// GetPaymentStatus 查询支付状态，待支付时以渠道侧交易状态为准
+func (s *PaymentService) GetPaymentStatus(ctx context.Context, paymentNo string) (*PaymentStatusResponse, error) {
 	var record PaymentRecord
 	if err := s.db.Where("payment_no = ?", paymentNo).First(&record).Error; err != nil {
 		return nil, err
 	}
 
+	if record.Status == PaymentStatusPending {
+		if trade, err := s.QueryTrade(ctx, &record); err == nil && trade.State == TradeStatePaid {
+			return &PaymentStatusResponse{
+				PaymentNo: record.PaymentNo,
+				OrderNo:   record.OrderNo,
+				Status:    PaymentStatusSuccess,
+				Amount:    record.Amount,
+				PayTime:   trade.PayTime,
+				TradeNo:   trade.TradeNo,
+			}, nil
+		}
+	}
+
 	// 检查是否已过期
 	if record.Status == PaymentStatusPending && record.QRCodeExpireTime != nil {
 		if time.Now().After(*record.QRCodeExpireTime) {
@@ -242,24 +411,22 @@ func (s *PaymentService) GetPaymentByOrderID(orderID uint64) (*PaymentRecord, er
 	return &record, nil
 }
 
-// HandlePaymentCallback 处理支付回调
-func (s *PaymentService) HandlePaymentCallback(paymentNo string, tradeNo string, callbackData string) error {
-	var record PaymentRecord
-	if err := s.db.Where("payment_no = ?", paymentNo).First(&record).Error; err != nil {
-		return err
-	}
+// InitPaymentServices 根据配置初始化微信支付/支付宝服务，未配置或初始化失败时返回 nil
+func InitPaymentServices(cfg *config.Config) (*WeChatPayService, *AlipayService) {
+	var wechatService *WeChatPayService
+	var alipayService *AlipayService
 
-	if record.Status != PaymentStatusPending {
-		return errors.New("支付状态已变更")
+	if cfg.WeChatPay.AppID != "" && cfg.WeChatPay.MchID != "" {
+		if svc, err := NewWeChatPayService(&cfg.WeChatPay); err == nil {
+			wechatService = svc
+		}
 	}
-
-	now := time.Now()
-	return s.db.Model(&record).Updates(map[string]interface{}{
-		"status":        PaymentStatusSuccess,
-		"trade_no":      tradeNo,
-		"pay_time":      now,
-		"callback_data": callbackData,
-	}).Error
+	if cfg.Alipay.AppID != "" && cfg.Alipay.PrivateKey != "" {
+		if svc, err := NewAlipayService(&cfg.Alipay); err == nil {
+			alipayService = svc
+		}
+	}
+	return wechatService, alipayService
 }
 
 // generatePaymentNo 生成支付流水号
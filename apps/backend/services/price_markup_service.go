@@ -2,11 +2,19 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+// ErrMarkupTiersInvalid 加价阶梯配置不合法
+var ErrMarkupTiersInvalid = errors.New("加价阶梯配置不合法")
+
 // PriceMarkupService 加价规则服务
 type PriceMarkupService struct {
 	db *gorm.DB
@@ -27,24 +35,27 @@ const (
 
 // PriceMarkup 加价规则模型
 type PriceMarkup struct {
-	ID          uint64     `gorm:"primaryKey" json:"id"`
-	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
-	Description string     `gorm:"type:varchar(500)" json:"description"`
-	StoreID     *uint64    `gorm:"index" json:"storeId"`
-	SupplierID  *uint64    `gorm:"index" json:"supplierId"`
-	CategoryID  *uint64    `json:"categoryId"`
-	MaterialID  *uint64    `json:"materialId"`
-	MarkupType  MarkupType `gorm:"type:varchar(20);not null" json:"markupType"`
-	MarkupValue float64    `gorm:"type:decimal(10,4);not null" json:"markupValue"`
-	MinMarkup   float64    `gorm:"type:decimal(10,2)" json:"minMarkup"`
-	MaxMarkup   float64    `gorm:"type:decimal(10,2)" json:"maxMarkup"`
-	Priority    int        `gorm:"default:0" json:"priority"`
-	IsActive    bool       `gorm:"default:true" json:"isActive"`
-	StartTime   *time.Time `json:"startTime"`
-	EndTime     *time.Time `json:"endTime"`
-	CreatedBy   uint64     `json:"createdBy"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
+	ID          uint64             `gorm:"primaryKey" json:"id"`
+	Name        string             `gorm:"type:varchar(100);not null" json:"name"`
+	Description string             `gorm:"type:varchar(500)" json:"description"`
+	StoreID     *uint64            `gorm:"index" json:"storeId"`
+	SupplierID  *uint64            `gorm:"index" json:"supplierId"`
+	CategoryID  *uint64            `json:"categoryId"`
+	MaterialID  *uint64            `json:"materialId"`
+	MarkupType  MarkupType         `gorm:"type:varchar(20);not null" json:"markupType"`
+	MarkupValue float64            `gorm:"type:decimal(10,4);not null" json:"markupValue"`
+	Tiers       models.MarkupTiers `gorm:"type:json" json:"tiers,omitempty"`
+	MinMarkup   float64            `gorm:"type:decimal(10,2)" json:"minMarkup"`
+	MaxMarkup   float64            `gorm:"type:decimal(10,2)" json:"maxMarkup"`
+	Priority    int                `gorm:"default:0" json:"priority"`
+	IsActive    bool               `gorm:"default:true" json:"isActive"`
+	StartTime   *time.Time         `json:"startTime"`
+	EndTime     *time.Time         `json:"endTime"`
+	CreatedBy   uint64             `json:"createdBy"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 表名
@@ -54,38 +65,40 @@ func (PriceMarkup) TableName() string {
 
 // CreatePriceMarkupRequest 创建加价规则请求
 type CreatePriceMarkupRequest struct {
-	Name        string     `json:"name" validate:"required,max=100"`
-	Description string     `json:"description" validate:"max=500"`
-	StoreID     *uint64    `json:"storeId"`
-	SupplierID  *uint64    `json:"supplierId"`
-	CategoryID  *uint64    `json:"categoryId"`
-	MaterialID  *uint64    `json:"materialId"`
-	MarkupType  MarkupType `json:"markupType" validate:"required,oneof=fixed percent"`
-	MarkupValue float64    `json:"markupValue" validate:"required,gt=0"`
-	MinMarkup   float64    `json:"minMarkup" validate:"gte=0"`
-	MaxMarkup   float64    `json:"maxMarkup" validate:"gte=0"`
-	Priority    int        `json:"priority"`
-	IsActive    bool       `json:"isActive"`
-	StartTime   *time.Time `json:"startTime"`
-	EndTime     *time.Time `json:"endTime"`
+	Name        string             `json:"name" validate:"required,max=100"`
+	Description string             `json:"description" validate:"max=500"`
+	StoreID     *uint64            `json:"storeId"`
+	SupplierID  *uint64            `json:"supplierId"`
+	CategoryID  *uint64            `json:"categoryId"`
+	MaterialID  *uint64            `json:"materialId"`
+	MarkupType  MarkupType         `json:"markupType" validate:"required,oneof=fixed percent"`
+	MarkupValue float64            `json:"markupValue" validate:"required,gt=0"`
+	Tiers       models.MarkupTiers `json:"tiers"`
+	MinMarkup   float64            `json:"minMarkup" validate:"gte=0"`
+	MaxMarkup   float64            `json:"maxMarkup" validate:"gte=0"`
+	Priority    int                `json:"priority"`
+	IsActive    bool               `json:"isActive"`
+	StartTime   *time.Time         `json:"startTime"`
+	EndTime     *time.Time         `json:"endTime"`
 }
 
 // UpdatePriceMarkupRequest 更新加价规则请求
 type UpdatePriceMarkupRequest struct {
-	Name        string     `json:"name" validate:"max=100"`
-	Description string     `json:"description" validate:"max=500"`
-	StoreID     *uint64    `json:"storeId"`
-	SupplierID  *uint64    `json:"supplierId"`
-	CategoryID  *uint64    `json:"categoryId"`
-	MaterialID  *uint64    `json:"materialId"`
-	MarkupType  MarkupType `json:"markupType" validate:"oneof=fixed percent"`
-	MarkupValue float64    `json:"markupValue" validate:"gt=0"`
-	MinMarkup   float64    `json:"minMarkup" validate:"gte=0"`
-	MaxMarkup   float64    `json:"maxMarkup" validate:"gte=0"`
-	Priority    int        `json:"priority"`
-	IsActive    *bool      `json:"isActive"`
-	StartTime   *time.Time `json:"startTime"`
-	EndTime     *time.Time `json:"endTime"`
+	Name        string             `json:"name" validate:"max=100"`
+	Description string             `json:"description" validate:"max=500"`
+	StoreID     *uint64            `json:"storeId"`
+	SupplierID  *uint64            `json:"supplierId"`
+	CategoryID  *uint64            `json:"categoryId"`
+	MaterialID  *uint64            `json:"materialId"`
+	MarkupType  MarkupType         `json:"markupType" validate:"oneof=fixed percent"`
+	MarkupValue float64            `json:"markupValue" validate:"gt=0"`
+	Tiers       models.MarkupTiers `json:"tiers"` // 传空数组清除阶梯
+	MinMarkup   float64            `json:"minMarkup" validate:"gte=0"`
+	MaxMarkup   float64            `json:"maxMarkup" validate:"gte=0"`
+	Priority    int                `json:"priority"`
+	IsActive    *bool              `json:"isActive"`
+	StartTime   *time.Time         `json:"startTime"`
+	EndTime     *time.Time         `json:"endTime"`
 }
 
 // PriceMarkupQueryParams 加价规则查询参数
@@ -107,6 +120,8 @@ type CalculateMarkupRequest struct {
 	CategoryID    uint64  `json:"categoryId"`
 	MaterialID    uint64  `json:"materialId"`
 	OriginalPrice float64 `json:"originalPrice" validate:"required,gt=0"`
+	Quantity      int     `json:"quantity"` // 用于匹配数量阶梯，未传按 1 件计
+	Explain       bool    `json:"explain"`  // 返回规则判定过程
 }
 
 // CalculateMarkupResponse 计算加价响应
@@ -115,10 +130,15 @@ type CalculateMarkupResponse struct {
 	MarkupAmount  float64      `json:"markupAmount"`
 	FinalPrice    float64      `json:"finalPrice"`
 	AppliedRule   *PriceMarkup `json:"appliedRule,omitempty"`
+	Trace         *MarkupTrace `json:"trace,omitempty"`
 }
 
 // Create 创建加价规则
 func (s *PriceMarkupService) Create(req *CreatePriceMarkupRequest, createdBy uint64) (*PriceMarkup, error) {
+	if err := ValidateMarkupTiers(req.Tiers); err != nil {
+		return nil, err
+	}
+
 	markup := &PriceMarkup{
 		Name:        req.Name,
 		Description: req.Description,
@@ -128,6 +148,7 @@ func (s *PriceMarkupService) Create(req *CreatePriceMarkupRequest, createdBy uin
 		MaterialID:  req.MaterialID,
 		MarkupType:  req.MarkupType,
 		MarkupValue: req.MarkupValue,
+		Tiers:       req.Tiers,
 		MinMarkup:   req.MinMarkup,
 		MaxMarkup:   req.MaxMarkup,
 		Priority:    req.Priority,
@@ -145,6 +166,10 @@ func (s *PriceMarkupService) Create(req *CreatePriceMarkupRequest, createdBy uin
 
 // Update 更新加价规则
 func (s *PriceMarkupService) Update(id uint64, req *UpdatePriceMarkupRequest) (*PriceMarkup, error) {
+	if err := ValidateMarkupTiers(req.Tiers); err != nil {
+		return nil, err
+	}
+
 	var markup PriceMarkup
 	if err := s.db.First(&markup, id).Error; err != nil {
 		return nil, err
@@ -175,6 +200,9 @@ func (s *PriceMarkupService) Update(id uint64, req *UpdatePriceMarkupRequest) (*
 	if req.MarkupValue > 0 {
 		updates["markup_value"] = req.MarkupValue
 	}
+	if req.Tiers != nil {
+		updates["tiers"] = req.Tiers
+	}
 	if req.MinMarkup >= 0 {
 		updates["min_markup"] = req.MinMarkup
 	}
@@ -275,7 +303,7 @@ func (s *PriceMarkupService) GetActiveRules() ([]PriceMarkup, error) {
 	err := s.db.Where("is_active = ?", true).
 		Where("(start_time IS NULL OR start_time <= ?)", now).
 		Where("(end_time IS NULL OR end_time >= ?)", now).
-		Order("priority DESC").
+		Order("priority DESC, created_at ASC").
 		Find(&markups).Error
 
 	return markups, err
@@ -288,20 +316,13 @@ func (s *PriceMarkupService) UpdateStatus(id uint64, isActive bool) error {
 
 // CalculateMarkup 计算加价
 func (s *PriceMarkupService) CalculateMarkup(req *CalculateMarkupRequest) (*CalculateMarkupResponse, error) {
-	// 获取所有生效中的规则
-	rules, err := s.GetActiveRules()
-	if err != nil {
-		return nil, err
+	if req.Explain {
+		return s.explainMarkup(req)
 	}
 
-	// 按优先级从高到低匹配规则
-	var matchedRule *PriceMarkup
-	for i := range rules {
-		rule := &rules[i]
-		if s.matchRule(rule, req) {
-			matchedRule = rule
-			break
-		}
+	matchedRule, err := s.findRule(req)
+	if err != nil {
+		return nil, err
 	}
 
 	response := &CalculateMarkupResponse{
@@ -311,7 +332,7 @@ func (s *PriceMarkupService) CalculateMarkup(req *CalculateMarkupRequest) (*Calc
 	}
 
 	if matchedRule != nil {
-		markupAmount := s.calculateMarkupAmount(matchedRule, req.OriginalPrice)
+		markupAmount := s.calculateMarkupAmount(matchedRule, req.OriginalPrice, req.Quantity)
 		response.MarkupAmount = markupAmount
 		response.FinalPrice = req.OriginalPrice + markupAmount
 		response.AppliedRule = matchedRule
@@ -320,6 +341,90 @@ func (s *PriceMarkupService) CalculateMarkup(req *CalculateMarkupRequest) (*Calc
 	return response, nil
 }
 
+// findRule 查找请求命中的规则，已启用规则索引时不查库
+func (s *PriceMarkupService) findRule(req *CalculateMarkupRequest) (*PriceMarkup, error) {
+	if index := currentMarkupIndex(); index != nil {
+		if rule := index.Match(req, time.Now()); rule != nil {
+			// 索引内的规则共享，返回副本
+			matched := *rule
+			return &matched, nil
+		}
+		return nil, nil
+	}
+
+	// 获取所有生效中的规则
+	rules, err := s.GetActiveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按优先级从高到低匹配规则
+	for i := range rules {
+		rule := &rules[i]
+		if s.matchRule(rule, req) {
+			return rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// CatalogQuote 目录页待加价的供应商报价
+type CatalogQuote struct {
+	SupplierID uint64
+	CategoryID uint64
+	MaterialID uint64
+	Price      float64
+}
+
+// CatalogPrice 目录页报价的单件加价结果
+type CatalogPrice struct {
+	MarkupAmount float64 `json:"markupAmount"`
+	FinalPrice   float64 `json:"finalPrice"`
+	MarkupRuleID *uint64 `json:"markupRuleId,omitempty"`
+}
+
+// PriceCatalog 按单件计算门店目录页报价的加价，结果与 quotes 一一对应；
+// 整页共用一份规则索引和加价开关，未启用共享索引时为本页加载一次，不逐个报价查库
+func (s *PriceMarkupService) PriceCatalog(storeID uint64, quotes []CatalogQuote) ([]CatalogPrice, error) {
+	now := time.Now()
+	index := currentMarkupIndex()
+	if index == nil {
+		var err error
+		if index, err = buildMarkupIndex(s.db, now); err != nil {
+			return nil, err
+		}
+	}
+	return s.priceCatalog(index, storeID, quotes, now), nil
+}
+
+// priceCatalog 按索引计算报价加价，加价开关关闭或未命中规则时按原价；加价金额保留两位小数
+func (s *PriceMarkupService) priceCatalog(index *MarkupIndex, storeID uint64, quotes []CatalogQuote, now time.Time) []CatalogPrice {
+	prices := make([]CatalogPrice, len(quotes))
+	for i, quote := range quotes {
+		prices[i].FinalPrice = quote.Price
+		if !index.MarkupEnabled(storeID, quote.SupplierID, quote.CategoryID) {
+			continue
+		}
+		rule := index.Match(&CalculateMarkupRequest{
+			StoreID:       storeID,
+			SupplierID:    quote.SupplierID,
+			CategoryID:    quote.CategoryID,
+			MaterialID:    quote.MaterialID,
+			OriginalPrice: quote.Price,
+			Quantity:      1,
+		}, now)
+		if rule == nil {
+			continue
+		}
+		amount := decimal.NewFromFloat(s.calculateMarkupAmount(rule, quote.Price, 1)).Round(2)
+		ruleID := rule.ID
+		prices[i].MarkupAmount = amount.InexactFloat64()
+		prices[i].FinalPrice = decimal.NewFromFloat(quote.Price).Add(amount).InexactFloat64()
+		prices[i].MarkupRuleID = &ruleID
+	}
+	return prices
+}
+
 // matchRule 检查规则是否匹配
 func (s *PriceMarkupService) matchRule(rule *PriceMarkup, req *CalculateMarkupRequest) bool {
 	// 检查门店匹配
@@ -341,23 +446,145 @@ func (s *PriceMarkupService) matchRule(rule *PriceMarkup, req *CalculateMarkupRe
 	return true
 }
 
-// calculateMarkupAmount 计算加价金额
-func (s *PriceMarkupService) calculateMarkupAmount(rule *PriceMarkup, originalPrice float64) float64 {
+// calculateMarkupAmount 计算单件加价金额
+func (s *PriceMarkupService) calculateMarkupAmount(rule *PriceMarkup, originalPrice float64, quantity int) float64 {
+	markup, clamped := clampMarkup(rule, originalPrice, quantity)
+	switch clamped {
+	case MarkupClampMin:
+		return rule.MinMarkup
+	case MarkupClampMax:
+		return rule.MaxMarkup
+	}
+	return markup
+}
+
+// clampMarkup 返回限制前的加价金额以及触发的最小/最大限制
+func clampMarkup(rule *PriceMarkup, originalPrice float64, quantity int) (float64, string) {
 	var markup float64
 
+	value := rule.MarkupValue
+	if tier := rule.Tiers.Match(originalPrice, quantity); tier != nil {
+		value = tier.MarkupValue
+	}
 	if rule.MarkupType == MarkupTypeFixed {
-		markup = rule.MarkupValue
+		markup = value
 	} else {
-		markup = originalPrice * rule.MarkupValue
+		markup = originalPrice * value
 	}
 
 	// 应用最小/最大限制
 	if rule.MinMarkup > 0 && markup < rule.MinMarkup {
-		markup = rule.MinMarkup
+		return markup, MarkupClampMin
 	}
 	if rule.MaxMarkup > 0 && markup > rule.MaxMarkup {
-		markup = rule.MaxMarkup
+		return markup, MarkupClampMax
 	}
+	return markup, ""
+}
 
-	return markup
+// markupSpecificityPriority 按适用范围精确度计算的优先级：物料 > 分类 > 供应商 > 门店
+func markupSpecificityPriority(storeID, supplierID, categoryID, materialID *uint64) int {
+	priority := 0
+	if materialID != nil {
+		priority += 1000 // 物料级最高优先级
+	}
+	if categoryID != nil {
+		priority += 100
+	}
+	if supplierID != nil {
+		priority += 10
+	}
+	if storeID != nil {
+		priority += 1
+	}
+	return priority
+}
+
+// markupPriceBand 加价阶梯的价格区间
+type markupPriceBand struct {
+	min, max   float64
+	quantities []int
+}
+
+// ValidateMarkupTiers 校验并排序加价阶梯：价格区间从 0 起连续覆盖到不封顶，
+// 不允许空档或重叠；每个区间必须有从 1 件起的数量档，数量档不重复
+func ValidateMarkupTiers(tiers models.MarkupTiers) error {
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	for _, tier := range tiers {
+		if tier.MinPrice < 0 || (tier.MaxPrice != 0 && tier.MaxPrice <= tier.MinPrice) {
+			return fmt.Errorf("%w: 价格区间 %v-%v 无效", ErrMarkupTiersInvalid, tier.MinPrice, tier.MaxPrice)
+		}
+		if tier.MinQuantity < 1 {
+			return fmt.Errorf("%w: 起始数量必须大于0", ErrMarkupTiersInvalid)
+		}
+		if tier.MarkupValue < 0 {
+			return fmt.Errorf("%w: 加价值不能为负数", ErrMarkupTiersInvalid)
+		}
+	}
+
+	sort.SliceStable(tiers, func(i, j int) bool {
+		if tiers[i].MinPrice != tiers[j].MinPrice {
+			return tiers[i].MinPrice < tiers[j].MinPrice
+		}
+		if tiers[i].MaxPrice != tiers[j].MaxPrice {
+			return markupBandEnd(tiers[i].MaxPrice) < markupBandEnd(tiers[j].MaxPrice)
+		}
+		return tiers[i].MinQuantity < tiers[j].MinQuantity
+	})
+
+	var bands []*markupPriceBand
+	for _, tier := range tiers {
+		if n := len(bands); n > 0 && bands[n-1].min == tier.MinPrice && bands[n-1].max == tier.MaxPrice {
+			bands[n-1].quantities = append(bands[n-1].quantities, tier.MinQuantity)
+			continue
+		}
+		bands = append(bands, &markupPriceBand{min: tier.MinPrice, max: tier.MaxPrice, quantities: []int{tier.MinQuantity}})
+	}
+
+	if bands[0].min != 0 {
+		return fmt.Errorf("%w: 价格区间必须从 0 开始", ErrMarkupTiersInvalid)
+	}
+	for i, band := range bands {
+		if band.quantities[0] != 1 {
+			return fmt.Errorf("%w: 价格区间 %s 缺少从 1 件起的数量档", ErrMarkupTiersInvalid, band)
+		}
+		for j := 1; j < len(band.quantities); j++ {
+			if band.quantities[j] == band.quantities[j-1] {
+				return fmt.Errorf("%w: 价格区间 %s 的数量档 %d 重复", ErrMarkupTiersInvalid, band, band.quantities[j])
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := bands[i-1]
+		switch {
+		case prev.max == 0 || band.min < prev.max:
+			return fmt.Errorf("%w: 价格区间 %s 与 %s 重叠", ErrMarkupTiersInvalid, prev, band)
+		case band.min > prev.max:
+			return fmt.Errorf("%w: 价格区间 %s 与 %s 之间存在空档", ErrMarkupTiersInvalid, prev, band)
+		}
+	}
+	if last := bands[len(bands)-1]; last.max != 0 {
+		return fmt.Errorf("%w: 价格 %v 以上未设置阶梯", ErrMarkupTiersInvalid, last.max)
+	}
+	return nil
+}
+
+// String 区间描述
+func (b *markupPriceBand) String() string {
+	if b.max == 0 {
+		return fmt.Sprintf("%v 以上", b.min)
+	}
+	return fmt.Sprintf("%v-%v", b.min, b.max)
+}
+
+// markupBandEnd 区间上限，不封顶视为无穷大
+func markupBandEnd(max float64) float64 {
+	if max == 0 {
+		return math.Inf(1)
+	}
+	return max
 }
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestValidateMarkupTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		tiers   models.MarkupTiers
+		wantErr bool
+	}{
+		{"no tiers", nil, false},
+		{"single open band", models.MarkupTiers{{MinPrice: 0, MinQuantity: 1, MarkupValue: 0.05}}, false},
+		{"bands and quantities unordered", models.MarkupTiers{
+			{MinPrice: 50, MinQuantity: 10, MarkupValue: 0.02},
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.08},
+			{MinPrice: 50, MinQuantity: 1, MarkupValue: 0.03},
+		}, false},
+		{"not from zero", models.MarkupTiers{{MinPrice: 10, MinQuantity: 1, MarkupValue: 0.05}}, true},
+		{"gap between bands", models.MarkupTiers{
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.08},
+			{MinPrice: 60, MinQuantity: 1, MarkupValue: 0.03},
+		}, true},
+		{"overlapping bands", models.MarkupTiers{
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.08},
+			{MinPrice: 40, MinQuantity: 1, MarkupValue: 0.03},
+		}, true},
+		{"two open bands", models.MarkupTiers{
+			{MinPrice: 0, MinQuantity: 1, MarkupValue: 0.08},
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.03},
+		}, true},
+		{"top not open", models.MarkupTiers{{MinPrice: 0, MaxPrice: 100, MinQuantity: 1, MarkupValue: 0.05}}, true},
+		{"band without quantity 1", models.MarkupTiers{{MinPrice: 0, MinQuantity: 5, MarkupValue: 0.05}}, true},
+		{"duplicate quantity", models.MarkupTiers{
+			{MinPrice: 0, MinQuantity: 1, MarkupValue: 0.05},
+			{MinPrice: 0, MinQuantity: 1, MarkupValue: 0.04},
+		}, true},
+		{"max below min", models.MarkupTiers{{MinPrice: 10, MaxPrice: 5, MinQuantity: 1, MarkupValue: 0.05}}, true},
+		{"negative value", models.MarkupTiers{{MinPrice: 0, MinQuantity: 1, MarkupValue: -1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMarkupTiers(tt.tiers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMarkupTiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrMarkupTiersInvalid) {
+				t.Errorf("ValidateMarkupTiers() error = %v, expected ErrMarkupTiersInvalid", err)
+			}
+		})
+	}
+}
+
+func TestCalculateMarkupAmountWithTiers(t *testing.T) {
+	s := &PriceMarkupService{}
+	rule := &PriceMarkup{
+		MarkupType:  MarkupTypePercent,
+		MarkupValue: 0.1,
+		MaxMarkup:   4,
+		Tiers: models.MarkupTiers{
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 1, MarkupValue: 0.08},
+			{MinPrice: 0, MaxPrice: 50, MinQuantity: 10, MarkupValue: 0.05},
+			{MinPrice: 50, MinQuantity: 1, MarkupValue: 0.06},
+			{MinPrice: 50, MinQuantity: 20, MarkupValue: 0.02},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		price    float64
+		quantity int
+		expected float64
+	}{
+		{"low band single", 10, 1, 0.8},
+		{"quantity unset counts as one", 10, 0, 0.8},
+		{"low band bulk", 10, 12, 0.5},
+		{"band boundary belongs to upper band", 50, 1, 3},
+		{"high band capped by max", 100, 5, 4},
+		{"high band bulk", 100, 20, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.calculateMarkupAmount(rule, tt.price, tt.quantity)
+			if diff := got - tt.expected; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("calculateMarkupAmount(%v, %d) = %v, expected %v", tt.price, tt.quantity, got, tt.expected)
+			}
+		})
+	}
+}
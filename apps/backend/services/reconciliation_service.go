@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 对账错误
+var (
+	ErrBillUnavailable        = errors.New("未找到对账单")
+	ErrBillPathInvalid        = errors.New("对账单文件必须位于对账单目录下")
+	ErrReconciliationNotFound = errors.New("对账批次不存在")
+	ErrDiscrepancyNotFound    = errors.New("对账差异不存在")
+)
+
+// reconcileMethods 定时对账的支付方式，未配置的渠道跳过
+var reconcileMethods = []PaymentMethod{PaymentMethodWechat, PaymentMethodAlipay, PaymentMethodMock}
+
+// settledPaymentStatuses 渠道已结算的支付记录状态
+var settledPaymentStatuses = []PaymentStatus{PaymentStatusSuccess, PaymentStatusPartialRefund, PaymentStatusRefunded}
+
+// ReconciliationService 支付对账服务
+// 按渠道每日对账单核对支付记录：先对待支付记录查单补正，再按交易号逐笔匹配，
+// 标记本地缺失、对账单缺失和金额不一致的交易
+type ReconciliationService struct {
+	db              *gorm.DB
+	providers       *PaymentProviders
+	callbackService *PaymentCallbackService
+	billDir         string
+}
+
+// NewReconciliationService 创建对账服务，billDir 为本地对账单目录
+func NewReconciliationService(db *gorm.DB, providers *PaymentProviders, billDir string) *ReconciliationService {
+	return &ReconciliationService{
+		db:              db,
+		providers:       providers,
+		callbackService: NewPaymentCallbackService(db, providers),
+		billDir:         billDir,
+	}
+}
+
+// ReconcileRequest 对账请求
+type ReconcileRequest struct {
+	PaymentMethod PaymentMethod
+	BillDate      time.Time
+	FileName      string // 对账单目录下的文件名，为空时先找默认文件名再从渠道下载
+	OperatorID    uint64
+}
+
+// DiscrepancyQuery 对账差异查询条件
+type DiscrepancyQuery struct {
+	Type     models.DiscrepancyType
+	Resolved *bool
+}
+
+// Run 执行一次对账，同一渠道同一天重复对账时覆盖上一次的结果
+// 对账单读取或解析失败时也会保存失败的批次，便于管理员查看原因
+func (s *ReconciliationService) Run(ctx context.Context, req *ReconcileRequest) (*models.ReconciliationBatch, error) {
+	start := time.Date(req.BillDate.Year(), req.BillDate.Month(), req.BillDate.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+
+	batch := &models.ReconciliationBatch{
+		PaymentMethod: models.PaymentMethod(req.PaymentMethod),
+		BillDate:      start,
+		OperatorID:    req.OperatorID,
+	}
+
+	data, err := s.loadBill(ctx, req, batch)
+	if err != nil {
+		if errors.Is(err, ErrBillUnavailable) || errors.Is(err, ErrBillPathInvalid) {
+			return nil, err
+		}
+		return s.saveFailed(batch, err)
+	}
+	lines, err := ParseBill(req.PaymentMethod, data)
+	if err != nil {
+		return s.saveFailed(batch, err)
+	}
+
+	batch.FixedCount = s.fixPendingRecords(ctx, req.PaymentMethod, lines, start, end)
+
+	records, err := s.loadRecords(req.PaymentMethod, lines, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	result := matchBill(lines, records, start, end)
+	batch.Status = models.ReconciliationStatusCompleted
+	batch.BillCount = len(lines)
+	batch.BillAmount = result.BillAmount
+	batch.RecordCount = result.RecordCount
+	batch.RecordAmount = result.RecordAmount
+	batch.MatchedCount = result.Matched
+	batch.DiscrepancyCount = len(result.Discrepancies)
+	for _, d := range result.Discrepancies {
+		switch d.Type {
+		case models.DiscrepancyTypeMissing:
+			batch.MissingCount++
+		case models.DiscrepancyTypeExtra:
+			batch.ExtraCount++
+		case models.DiscrepancyTypeAmountMismatch:
+			batch.MismatchCount++
+		}
+	}
+
+	if err := s.save(batch, result.Discrepancies); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// RunDaily 对前一天的账单执行对账，供定时任务调用
+// 已成功对账或对账单尚未送达的渠道跳过，下一轮再试
+func (s *ReconciliationService) RunDaily(ctx context.Context) error {
+	billDate := time.Now().AddDate(0, 0, -1)
+	for _, method := range reconcileMethods {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.providers.Get(method); err != nil {
+			continue
+		}
+
+		var count int64
+		s.db.Model(&models.ReconciliationBatch{}).
+			Where("payment_method = ? AND bill_date = ? AND status = ?",
+				method, billDate.Format("2006-01-02"), models.ReconciliationStatusCompleted).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		if _, err := s.Run(ctx, &ReconcileRequest{PaymentMethod: method, BillDate: billDate}); err != nil &&
+			!errors.Is(err, ErrBillUnavailable) {
+			return fmt.Errorf("%s对账失败: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// List 分页查询对账批次
+func (s *ReconciliationService) List(page, pageSize int, method PaymentMethod) ([]models.ReconciliationBatch, int64, error) {
+	query := s.db.Model(&models.ReconciliationBatch{})
+	if method != "" {
+		query = query.Where("payment_method = ?", method)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var batches []models.ReconciliationBatch
+	err := query.Order("bill_date DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&batches).Error
+	return batches, total, err
+}
+
+// Report 获取对账报告：批次汇总及差异明细
+func (s *ReconciliationService) Report(batchID uint64, query *DiscrepancyQuery) (*models.ReconciliationBatch, error) {
+	var batch models.ReconciliationBatch
+	if err := s.db.First(&batch, batchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationNotFound
+		}
+		return nil, err
+	}
+
+	db := s.db.Where("batch_id = ?", batch.ID)
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.Resolved != nil {
+		db = db.Where("resolved = ?", *query.Resolved)
+	}
+	if err := db.Order("id ASC").Find(&batch.Discrepancies).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ResolveDiscrepancy 标记对账差异已处理
+func (s *ReconciliationService) ResolveDiscrepancy(id uint64, adminID uint64, remark string) error {
+	now := time.Now()
+	result := s.db.Model(&models.ReconciliationDiscrepancy{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"resolved":    true,
+			"resolved_by": adminID,
+			"resolved_at": now,
+			"remark":      remark,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDiscrepancyNotFound
+	}
+	return nil
+}
+
+// loadBill 读取对账单：指定文件、对账单目录下的默认文件、渠道下载，依次尝试
+func (s *ReconciliationService) loadBill(ctx context.Context, req *ReconcileRequest, batch *models.ReconciliationBatch) ([]byte, error) {
+	if req.FileName != "" || s.billDir != "" {
+		fileName := req.FileName
+		if fileName == "" {
+			fileName = fmt.Sprintf("%s_%s.csv", req.PaymentMethod, batch.BillDate.Format("20060102"))
+		}
+		path, err := s.billPath(fileName)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			batch.Source = models.ReconciliationBillSourceFile
+			batch.FilePath = path
+			return data, nil
+		case req.FileName != "" || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("读取对账单失败: %w", err)
+		}
+	}
+
+	provider, err := s.providers.Get(req.PaymentMethod)
+	if err != nil {
+		return nil, ErrBillUnavailable
+	}
+	downloader, ok := provider.(BillDownloader)
+	if !ok {
+		return nil, ErrBillUnavailable
+	}
+	data, err := downloader.DownloadBill(ctx, batch.BillDate)
+	if err != nil {
+		return nil, &PaymentGatewayError{Method: req.PaymentMethod, Err: err}
+	}
+	batch.Source = models.ReconciliationBillSourceProvider
+	return data, nil
+}
+
+// billPath 对账单文件路径，只允许读取对账单目录下的文件
+func (s *ReconciliationService) billPath(fileName string) (string, error) {
+	if s.billDir == "" {
+		return "", ErrBillPathInvalid
+	}
+	dir, err := filepath.Abs(s.billDir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fileName)
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", ErrBillPathInvalid
+	}
+	return path, nil
+}
+
+// fixPendingRecords 对当天创建以及对账单中出现的未支付记录向渠道查单补正，返回补正成功的条数
+func (s *ReconciliationService) fixPendingRecords(ctx context.Context, method PaymentMethod, lines []BillLine, start, end time.Time) int {
+	paymentNos := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line.PaymentNo != "" {
+			paymentNos = append(paymentNos, line.PaymentNo)
+		}
+	}
+
+	query := s.db.Where("payment_method = ?", method)
+	if len(paymentNos) > 0 {
+		query = query.Where(
+			s.db.Where("status = ? AND created_at >= ? AND created_at < ?", PaymentStatusPending, start, end).
+				Or("status IN ? AND payment_no IN ?", []PaymentStatus{PaymentStatusPending, PaymentStatusFailed, PaymentStatusExpired}, paymentNos),
+		)
+	} else {
+		query = query.Where("status = ? AND created_at >= ? AND created_at < ?", PaymentStatusPending, start, end)
+	}
+
+	var records []PaymentRecord
+	if err := query.Find(&records).Error; err != nil {
+		return 0
+	}
+
+	fixed := 0
+	for _, record := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		inbox, err := s.callbackService.Reconcile(ctx, record.PaymentNo)
+		if err == nil && inbox.Status == models.PaymentCallbackStatusProcessed {
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// loadRecords 加载当天已结算的支付记录以及对账单中出现的支付记录
+func (s *ReconciliationService) loadRecords(method PaymentMethod, lines []BillLine, start, end time.Time) ([]PaymentRecord, error) {
+	var records []PaymentRecord
+	if err := s.db.Where("payment_method = ? AND status IN ? AND pay_time >= ? AND pay_time < ?",
+		method, settledPaymentStatuses, start, end).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[uint64]bool, len(records))
+	for _, record := range records {
+		loaded[record.ID] = true
+	}
+
+	const chunkSize = 500
+	for i := 0; i < len(lines); i += chunkSize {
+		chunk := lines[i:min(i+chunkSize, len(lines))]
+		tradeNos := make([]string, 0, len(chunk))
+		paymentNos := make([]string, 0, len(chunk))
+		for _, line := range chunk {
+			tradeNos = append(tradeNos, line.TradeNo)
+			paymentNos = append(paymentNos, line.PaymentNo)
+		}
+
+		var matched []PaymentRecord
+		if err := s.db.Where("trade_no IN ? OR payment_no IN ?", tradeNos, paymentNos).Find(&matched).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range matched {
+			if !loaded[record.ID] {
+				loaded[record.ID] = true
+				records = append(records, record)
+			}
+		}
+	}
+	return records, nil
+}
+
+// saveFailed 保存失败的对账批次
+func (s *ReconciliationService) saveFailed(batch *models.ReconciliationBatch, cause error) (*models.ReconciliationBatch, error) {
+	batch.Status = models.ReconciliationStatusFailed
+	batch.ErrorMsg = truncateErrorMsg(cause.Error())
+	if batch.Source == "" {
+		batch.Source = models.ReconciliationBillSourceFile
+	}
+	if err := s.save(batch, nil); err != nil {
+		return nil, err
+	}
+	return batch, cause
+}
+
+// save 保存对账批次及差异，覆盖同一渠道同一天的上一次结果
+func (s *ReconciliationService) save(batch *models.ReconciliationBatch, discrepancies []models.ReconciliationDiscrepancy) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.ReconciliationBatch
+		err := tx.Where("payment_method = ? AND bill_date = ?", batch.PaymentMethod, batch.BillDate.Format("2006-01-02")).
+			First(&existing).Error
+		switch {
+		case err == nil:
+			if err := tx.Where("batch_id = ?", existing.ID).Delete(&models.ReconciliationDiscrepancy{}).Error; err != nil {
+				return err
+			}
+			batch.ID = existing.ID
+			batch.CreatedAt = existing.CreatedAt
+			if err := tx.Save(batch).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(batch).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		for i := range discrepancies {
+			discrepancies[i].BatchID = batch.ID
+		}
+		if len(discrepancies) > 0 {
+			return tx.CreateInBatches(discrepancies, 200).Error
+		}
+		return nil
+	})
+}
+
+// billMatchResult 对账单与支付记录的匹配结果
+type billMatchResult struct {
+	Matched       int
+	BillAmount    float64
+	RecordCount   int
+	RecordAmount  float64
+	Discrepancies []models.ReconciliationDiscrepancy
+}
+
+// matchBill 按交易号逐笔匹配对账单与支付记录，交易号匹配不到时按支付单号匹配
+// 对账单中有而本地未结算的为 extra，本地当天已结算而对账单中没有的为 missing
+func matchBill(lines []BillLine, records []PaymentRecord, start, end time.Time) *billMatchResult {
+	byTradeNo := make(map[string]*PaymentRecord, len(records))
+	byPaymentNo := make(map[string]*PaymentRecord, len(records))
+	for i := range records {
+		if records[i].TradeNo != "" {
+			byTradeNo[records[i].TradeNo] = &records[i]
+		}
+		byPaymentNo[records[i].PaymentNo] = &records[i]
+	}
+
+	result := &billMatchResult{}
+	billAmount := decimal.Zero
+	matched := make(map[uint64]bool, len(lines))
+	for _, line := range lines {
+		billAmount = billAmount.Add(decimal.NewFromFloat(line.Amount))
+
+		record := byTradeNo[line.TradeNo]
+		if record == nil {
+			record = byPaymentNo[line.PaymentNo]
+		}
+
+		discrepancy := models.ReconciliationDiscrepancy{
+			PaymentNo:  line.PaymentNo,
+			TradeNo:    line.TradeNo,
+			BillAmount: line.Amount,
+			TradeTime:  line.TradeTime,
+		}
+		if record != nil {
+			discrepancy.RecordAmount = record.Amount
+			discrepancy.RecordStatus = string(record.Status)
+		}
+
+		switch {
+		case record == nil || !isSettledPaymentStatus(record.Status):
+			discrepancy.Type = models.DiscrepancyTypeExtra
+		case !priceEquals(record.Amount, line.Amount):
+			matched[record.ID] = true
+			discrepancy.Type = models.DiscrepancyTypeAmountMismatch
+		default:
+			matched[record.ID] = true
+			result.Matched++
+			continue
+		}
+		result.Discrepancies = append(result.Discrepancies, discrepancy)
+	}
+	result.BillAmount = billAmount.InexactFloat64()
+
+	recordAmount := decimal.Zero
+	for _, record := range records {
+		if !isSettledPaymentStatus(record.Status) || record.PayTime == nil ||
+			record.PayTime.Before(start) || !record.PayTime.Before(end) {
+			continue
+		}
+		result.RecordCount++
+		recordAmount = recordAmount.Add(decimal.NewFromFloat(record.Amount))
+		if matched[record.ID] {
+			continue
+		}
+		result.Discrepancies = append(result.Discrepancies, models.ReconciliationDiscrepancy{
+			Type:         models.DiscrepancyTypeMissing,
+			PaymentNo:    record.PaymentNo,
+			TradeNo:      record.TradeNo,
+			RecordAmount: record.Amount,
+			RecordStatus: string(record.Status),
+			TradeTime:    record.PayTime,
+		})
+	}
+	result.RecordAmount = recordAmount.InexactFloat64()
+	return result
+}
+
+// isSettledPaymentStatus 支付记录是否已在渠道结算
+func isSettledPaymentStatus(status PaymentStatus) bool {
+	for _, s := range settledPaymentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestParseBill(t *testing.T) {
+	wechatBill := "交易时间,公众账号ID,商户号,微信订单号,商户订单号,交易类型,交易状态,订单金额,退款金额\n" +
+		"`2026-10-16 09:30:00,`wx123,`1900000001,`4200001,`PAY001,`NATIVE,`SUCCESS,`128.50,`0.00\n" +
+		"`2026-10-16 10:00:00,`wx123,`1900000001,`4200002,`PAY002,`NATIVE,`REFUND,`50.00,`50.00\n" +
+		"`2026-10-16 11:15:00,`wx123,`1900000001,`4200003,`PAY003,`NATIVE,`SUCCESS,`20.00,`0.00\n" +
+		"总交易单数,应结订单总金额,退款总金额\n" +
+		"`3,`148.50,`50.00\n"
+
+	alipayBill := "#支付宝业务明细查询\n" +
+		"#账号：[20880000000000]\n" +
+		"支付宝交易号,商户订单号,业务类型,商品名称,创建时间,完成时间,订单金额（元）\n" +
+		"2026101622001,PAY101\t,交易,食材,2026-10-16 08:00:00,2026-10-16 08:00:05,99.90\n" +
+		"2026101622002,PAY102\t,退款,食材,2026-10-16 09:00:00,2026-10-16 09:00:05,-10.00\n" +
+		"#-----------------------------------------业务明细列表结束------------------------------------\n" +
+		"#交易合计：1笔，退款合计：1笔\n"
+
+	gbkBill, err := simplifiedchinese.GBK.NewEncoder().String(alipayBill)
+	if err != nil {
+		t.Fatalf("encode GBK: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		method      PaymentMethod
+		data        string
+		expected    []BillLine
+		expectedErr bool
+	}{
+		{
+			name:   "wechat bill skips refunds and summary",
+			method: PaymentMethodWechat,
+			data:   "\xef\xbb\xbf" + wechatBill,
+			expected: []BillLine{
+				{TradeNo: "4200001", PaymentNo: "PAY001", Amount: 128.5},
+				{TradeNo: "4200003", PaymentNo: "PAY003", Amount: 20},
+			},
+		},
+		{
+			name:     "alipay bill skips comments",
+			method:   PaymentMethodAlipay,
+			data:     alipayBill,
+			expected: []BillLine{{TradeNo: "2026101622001", PaymentNo: "PAY101", Amount: 99.9}},
+		},
+		{
+			name:     "alipay bill in GBK",
+			method:   PaymentMethodAlipay,
+			data:     gbkBill,
+			expected: []BillLine{{TradeNo: "2026101622001", PaymentNo: "PAY101", Amount: 99.9}},
+		},
+		{"missing header", PaymentMethodWechat, "a,b,c\n1,2,3\n", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := ParseBill(tt.method, []byte(tt.data))
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("ParseBill() error = %v, expectedErr %v", err, tt.expectedErr)
+			}
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("ParseBill() returned %d lines, expected %d", len(lines), len(tt.expected))
+			}
+			for i, line := range lines {
+				expected := tt.expected[i]
+				if line.TradeNo != expected.TradeNo || line.PaymentNo != expected.PaymentNo || line.Amount != expected.Amount {
+					t.Errorf("ParseBill() line %d = %+v, expected %+v", i, line, expected)
+				}
+				if line.TradeTime == nil {
+					t.Errorf("ParseBill() line %d has no trade time", i)
+				}
+			}
+		})
+	}
+
+	if _, err := ParseBill("cash", []byte(wechatBill)); !errors.Is(err, ErrBillFormatUnsupported) {
+		t.Errorf("ParseBill() unsupported method error = %v", err)
+	}
+}
+
+func TestMatchBill(t *testing.T) {
+	start := time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, 1)
+	inDay := start.Add(10 * time.Hour)
+	prevDay := start.Add(-time.Hour)
+
+	records := []PaymentRecord{
+		{ID: 1, PaymentNo: "PAY001", TradeNo: "T001", Amount: 100, Status: PaymentStatusSuccess, PayTime: &inDay},
+		{ID: 2, PaymentNo: "PAY002", TradeNo: "T002", Amount: 50, Status: PaymentStatusSuccess, PayTime: &inDay},
+		{ID: 3, PaymentNo: "PAY003", TradeNo: "T003", Amount: 30, Status: PaymentStatusPartialRefund, PayTime: &inDay},
+		{ID: 4, PaymentNo: "PAY004", TradeNo: "T004", Amount: 80, Status: PaymentStatusSuccess, PayTime: &inDay},
+		{ID: 5, PaymentNo: "PAY005", Amount: 60, Status: PaymentStatusPending},
+		{ID: 6, PaymentNo: "PAY006", TradeNo: "T006", Amount: 40, Status: PaymentStatusSuccess, PayTime: &prevDay},
+	}
+	lines := []BillLine{
+		{TradeNo: "T001", PaymentNo: "PAY001", Amount: 100},
+		{TradeNo: "T002", PaymentNo: "PAY002", Amount: 55},
+		{TradeNo: "T003", PaymentNo: "PAY003", Amount: 30},
+		{TradeNo: "T005", PaymentNo: "PAY005", Amount: 60},
+		{TradeNo: "T006", PaymentNo: "PAY006", Amount: 40},
+		{TradeNo: "T999", PaymentNo: "PAY999", Amount: 10},
+	}
+
+	result := matchBill(lines, records, start, end)
+
+	if result.Matched != 3 {
+		t.Errorf("matchBill() matched = %d, expected 3", result.Matched)
+	}
+	if result.BillAmount != 295 {
+		t.Errorf("matchBill() bill amount = %v, expected 295", result.BillAmount)
+	}
+	if result.RecordCount != 4 || result.RecordAmount != 260 {
+		t.Errorf("matchBill() records = %d/%v, expected 4/260", result.RecordCount, result.RecordAmount)
+	}
+
+	expected := map[string]models.DiscrepancyType{
+		"PAY002": models.DiscrepancyTypeAmountMismatch,
+		"PAY005": models.DiscrepancyTypeExtra,
+		"PAY999": models.DiscrepancyTypeExtra,
+		"PAY004": models.DiscrepancyTypeMissing,
+	}
+	if len(result.Discrepancies) != len(expected) {
+		t.Fatalf("matchBill() returned %d discrepancies, expected %d: %+v", len(result.Discrepancies), len(expected), result.Discrepancies)
+	}
+	for _, d := range result.Discrepancies {
+		if expected[d.PaymentNo] != d.Type {
+			t.Errorf("matchBill() %s type = %s, expected %s", d.PaymentNo, d.Type, expected[d.PaymentNo])
+		}
+	}
+}
@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 退款错误
+var (
+	ErrOrderNotPaid         = errors.New("订单未支付，无法退款")
+	ErrNothingToRefund      = errors.New("订单已无可退金额")
+	ErrRefundAmountExceeded = errors.New("退款金额超过可退金额")
+	ErrRefundNotFound       = errors.New("退款单不存在")
+	ErrRefundNotRetryable   = errors.New("只能重试失败的退款")
+)
+
+// maxRefundAttempts 渠道调用失败时的最大提交次数，超过后标记失败并通知管理员
+const maxRefundAttempts = 5
+
+// refundBatchSize 每轮最多提交的退款单数
+const refundBatchSize = 50
+
+// activeRefundStatuses 占用可退金额的退款状态
+var activeRefundStatuses = []models.RefundStatus{
+	models.RefundStatusPending,
+	models.RefundStatusProcessing,
+	models.RefundStatusSuccess,
+}
+
+// RefundService 退款服务
+// 退款单先落库再提交渠道：取消订单时在同一事务中创建退款单，由后台任务或管理员操作提交，
+// 渠道结果以同步返回或退款通知为准，成功后同步支付记录和订单的支付状态
+type RefundService struct {
+	db             *gorm.DB
+	paymentService *PaymentService
+}
+
+// NewRefundService 创建退款服务
+func NewRefundService(db *gorm.DB, providers *PaymentProviders) *RefundService {
+	return &RefundService{
+		db:             db,
+		paymentService: NewPaymentService(db, providers),
+	}
+}
+
+// RefundRequest 退款申请
+type RefundRequest struct {
+	OrderID      uint64
+	Amount       float64 // 为0时退还订单剩余可退金额
+	Reason       string
+	Source       models.RefundSource
+	OperatorType models.OperatorType
+	OperatorID   uint64
+}
+
+// RefundQuery 退款单查询条件
+type RefundQuery struct {
+	Status    models.RefundStatus
+	OrderID   uint64
+	PaymentNo string
+}
+
+// CreateRefund 创建退款单并立即提交渠道
+// 渠道调用失败时退款单保留待提交状态，返回退款单和错误
+func (s *RefundService) CreateRefund(ctx context.Context, req *RefundRequest) (*models.Refund, error) {
+	var refund *models.Refund
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, req.OrderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOrderNotFound
+			}
+			return err
+		}
+
+		var err error
+		refund, err = createRefundTx(tx, &order, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, s.Submit(ctx, refund)
+}
+
+// Submit 向渠道提交退款
+// 以待提交状态为条件认领退款单，后台任务与手工操作不会重复提交；渠道故障时退回待提交等待重试
+func (s *RefundService) Submit(ctx context.Context, refund *models.Refund) error {
+	result := s.db.Model(&models.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, models.RefundStatusPending).
+		Updates(map[string]interface{}{
+			"status":   models.RefundStatusProcessing,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	refund.Status = models.RefundStatusProcessing
+	refund.Attempts++
+
+	var record PaymentRecord
+	if err := s.db.Where("payment_no = ?", refund.PaymentNo).First(&record).Error; err != nil {
+		return s.fail(refund, fmt.Errorf("未找到支付记录: %w", err))
+	}
+
+	resp, err := s.paymentService.RefundTrade(ctx, &record, refund.RefundNo, refund.Amount, refund.Reason)
+	if err != nil {
+		if refund.Attempts >= maxRefundAttempts {
+			return s.fail(refund, err)
+		}
+		// 退款单号在渠道侧幂等，可以安全重试
+		refund.Status = models.RefundStatusPending
+		if saveErr := s.db.Model(refund).Updates(map[string]interface{}{
+			"status":    models.RefundStatusPending,
+			"error_msg": truncateErrorMsg(err.Error()),
+		}).Error; saveErr != nil {
+			return saveErr
+		}
+		return err
+	}
+
+	switch resp.State {
+	case RefundStateSuccess:
+		return s.complete(refund, resp.RefundID, time.Now())
+	case RefundStateFailed:
+		return s.fail(refund, fmt.Errorf("渠道拒绝退款: %s", resp.Status))
+	}
+
+	// 渠道处理中，等待退款通知
+	refund.GatewayRefundID = resp.RefundID
+	return s.db.Model(refund).Updates(map[string]interface{}{
+		"gateway_refund_id": resp.RefundID,
+		"error_msg":         "",
+	}).Error
+}
+
+// SubmitPending 提交待提交的退款单，供后台任务调用
+func (s *RefundService) SubmitPending(ctx context.Context) error {
+	var refunds []models.Refund
+	if err := s.db.Where("status = ?", models.RefundStatusPending).
+		Order("id ASC").
+		Limit(refundBatchSize).
+		Find(&refunds).Error; err != nil {
+		return err
+	}
+
+	for i := range refunds {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// 失败原因已记录在退款单上，继续处理下一笔
+		s.Submit(ctx, &refunds[i])
+	}
+	return nil
+}
+
+// SubmitOrderRefunds 立即提交订单下待提交的退款单
+func (s *RefundService) SubmitOrderRefunds(ctx context.Context, orderID uint64) error {
+	var refunds []models.Refund
+	if err := s.db.Where("order_id = ? AND status = ?", orderID, models.RefundStatusPending).
+		Order("id ASC").
+		Find(&refunds).Error; err != nil {
+		return err
+	}
+
+	for i := range refunds {
+		if err := s.Submit(ctx, &refunds[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retry 重新提交失败的退款单
+func (s *RefundService) Retry(ctx context.Context, refundID uint64) (*models.Refund, error) {
+	var refund models.Refund
+	if err := s.db.First(&refund, refundID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	if refund.Status != models.RefundStatusFailed {
+		return &refund, ErrRefundNotRetryable
+	}
+
+	result := s.db.Model(&models.Refund{}).
+		Where("id = ? AND status = ?", refund.ID, models.RefundStatusFailed).
+		Updates(map[string]interface{}{
+			"status":    models.RefundStatusPending,
+			"attempts":  0,
+			"error_msg": "",
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &refund, ErrRefundNotRetryable
+	}
+	refund.Status = models.RefundStatusPending
+	refund.Attempts = 0
+
+	err := s.Submit(ctx, &refund)
+	s.db.First(&refund, refund.ID)
+	return &refund, err
+}
+
+// HandleNotification 处理渠道退款结果通知，重复通知不会重复入账
+func (s *RefundService) HandleNotification(notification *ProviderRefundNotification) error {
+	var refund models.Refund
+	if err := s.db.Where("refund_no = ?", notification.RefundNo).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefundNotFound
+		}
+		return err
+	}
+
+	if notification.PaymentNo != "" && notification.PaymentNo != refund.PaymentNo {
+		return &PaymentCallbackMismatchError{Field: "支付单号", Expected: refund.PaymentNo, Actual: notification.PaymentNo}
+	}
+	if notification.Amount > 0 && !priceEquals(notification.Amount, refund.Amount) {
+		return &PaymentCallbackMismatchError{
+			Field:    "退款金额",
+			Expected: fmt.Sprintf("%.2f", refund.Amount),
+			Actual:   fmt.Sprintf("%.2f", notification.Amount),
+		}
+	}
+
+	switch notification.State {
+	case RefundStateSuccess:
+		refundedAt := time.Now()
+		if notification.RefundTime != nil {
+			refundedAt = *notification.RefundTime
+		}
+		return s.complete(&refund, notification.RefundID, refundedAt)
+	case RefundStateFailed:
+		return s.markFailed(&refund, "渠道退款失败")
+	}
+	return nil
+}
+
+// List 分页查询退款单
+func (s *RefundService) List(page, pageSize int, query *RefundQuery) ([]models.Refund, int64, error) {
+	db := s.db.Model(&models.Refund{})
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.OrderID > 0 {
+		db = db.Where("order_id = ?", query.OrderID)
+	}
+	if query.PaymentNo != "" {
+		db = db.Where("payment_no = ?", query.PaymentNo)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var refunds []models.Refund
+	err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&refunds).Error
+	return refunds, total, err
+}
+
+// complete 退款成功：更新退款单、支付记录和订单支付状态
+// 渠道确认成功的结果优先，已标记失败的退款单也会被更正为成功
+func (s *RefundService) complete(refund *models.Refund, gatewayRefundID string, refundedAt time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":      models.RefundStatusSuccess,
+			"refunded_at": refundedAt,
+			"error_msg":   "",
+		}
+		if gatewayRefundID != "" {
+			updates["gateway_refund_id"] = gatewayRefundID
+		}
+		result := tx.Model(&models.Refund{}).
+			Where("id = ? AND status <> ?", refund.ID, models.RefundStatusSuccess).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		refund.Status = models.RefundStatusSuccess
+		refund.RefundedAt = &refundedAt
+
+		return applyRefundTx(tx, refund, refundedAt)
+	})
+}
+
+// fail 将退款单标记为失败并返回原因
+func (s *RefundService) fail(refund *models.Refund, cause error) error {
+	if err := s.markFailed(refund, cause.Error()); err != nil {
+		return err
+	}
+	return cause
+}
+
+// markFailed 将未成功的退款单标记为失败并通知管理员
+func (s *RefundService) markFailed(refund *models.Refund, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Refund{}).
+			Where("id = ? AND status IN ?", refund.ID, []models.RefundStatus{
+				models.RefundStatusPending, models.RefundStatusProcessing,
+			}).
+			Updates(map[string]interface{}{
+				"status":    models.RefundStatusFailed,
+				"error_msg": truncateErrorMsg(reason),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		refund.Status = models.RefundStatusFailed
+
+		return tx.Create(models.NewOrderNotification(
+			models.AdminNotificationRefundFailed,
+			refund.OrderID,
+			"退款失败",
+			fmt.Sprintf("订单 %s 退款单 %s（%.2f元）退款失败: %s，请处理后重试",
+				refund.OrderNo, refund.RefundNo, refund.Amount, reason),
+		)).Error
+	})
+}
+
+// createRefundTx 在调用方事务中创建待提交的退款单，调用方需已锁定订单
+func createRefundTx(tx *gorm.DB, order *models.Order, req *RefundRequest) (*models.Refund, error) {
+	if order.PaymentMethod != nil && *order.PaymentMethod == models.PaymentMethodCredit {
+		return nil, ErrCreditOrderNotRefundable
+	}
+	if order.PaymentStatus != models.PaymentStatusPaid && order.PaymentStatus != models.PaymentStatusPartialRefund {
+		return nil, ErrOrderNotPaid
+	}
+
+	var record PaymentRecord
+	if err := orderPaymentScope(tx, order.ID).
+		Where("status IN ?", []PaymentStatus{PaymentStatusSuccess, PaymentStatusPartialRefund}).
+		Order("id DESC").
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentRecordNotFound
+		}
+		return nil, err
+	}
+
+	orderRefunded, err := sumRefunds(tx, activeRefundStatuses, "order_id = ?", order.ID)
+	if err != nil {
+		return nil, err
+	}
+	paymentRefunded, err := sumRefunds(tx, activeRefundStatuses, "payment_no = ?", record.PaymentNo)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, refundType, err := resolveRefundAmount(req.Amount, order.TotalAmount, orderRefunded, record.Amount, paymentRefunded)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &models.Refund{
+		OrderID:       order.ID,
+		OrderNo:       order.OrderNo,
+		PaymentNo:     record.PaymentNo,
+		PaymentMethod: models.PaymentMethod(record.PaymentMethod),
+		Amount:        amount,
+		Type:          refundType,
+		Source:        req.Source,
+		Reason:        req.Reason,
+		Status:        models.RefundStatusPending,
+		OperatorType:  req.OperatorType,
+		OperatorID:    req.OperatorID,
+	}
+	if err := tx.Create(refund).Error; err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// createCancelRefundTx 已支付订单取消时创建全额退款单
+// 找不到支付记录时不阻止取消，改为通知管理员人工退款
+func createCancelRefundTx(tx *gorm.DB, order *models.Order, t *OrderTransition) error {
+	reason := "订单取消"
+	if t.Remark != "" {
+		reason = t.Remark
+	}
+
+	_, err := createRefundTx(tx, order, &RefundRequest{
+		OrderID:      order.ID,
+		Reason:       reason,
+		Source:       models.RefundSourceCancel,
+		OperatorType: t.OperatorType,
+		OperatorID:   t.OperatorID,
+	})
+	switch {
+	case errors.Is(err, ErrNothingToRefund):
+		return nil
+	case errors.Is(err, ErrPaymentRecordNotFound):
+		return tx.Create(models.NewOrderNotification(
+			models.AdminNotificationRefundFailed,
+			order.ID,
+			"取消订单未能自动退款",
+			fmt.Sprintf("订单 %s 已支付但未找到支付记录，无法自动退款，请人工处理", order.OrderNo),
+		)).Error
+	}
+	return err
+}
+
+// refundOrphanPaymentTx 支付到账时订单已取消，记录到账信息并自动发起全额退款
+// 订单不是已取消未支付状态时不做处理，返回 false
+func refundOrphanPaymentTx(tx *gorm.DB, orderID uint64, paymentNo string, paidAt time.Time) (bool, error) {
+	var order models.Order
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return false, err
+	}
+	if order.Status != models.OrderStatusCancelled || order.PaymentStatus != models.PaymentStatusUnpaid {
+		return false, nil
+	}
+
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"payment_status": models.PaymentStatusPaid,
+		"payment_time":   paidAt,
+		"payment_no":     paymentNo,
+	}).Error; err != nil {
+		return false, err
+	}
+	order.PaymentStatus = models.PaymentStatusPaid
+
+	if _, err := createRefundTx(tx, &order, &RefundRequest{
+		OrderID:      order.ID,
+		Reason:       "订单已取消，支付到账后自动退款",
+		Source:       models.RefundSourceCancel,
+		OperatorType: models.OperatorTypeSystem,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applyRefundTx 退款成功后累计支付记录退款金额，并按订单已退金额更新订单支付状态
+func applyRefundTx(tx *gorm.DB, refund *models.Refund, refundedAt time.Time) error {
+	var record PaymentRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_no = ?", refund.PaymentNo).First(&record).Error; err != nil {
+		return err
+	}
+	recordRefunded := decimal.NewFromFloat(record.RefundAmount).Add(decimal.NewFromFloat(refund.Amount))
+	recordStatus := PaymentStatusPartialRefund
+	if recordRefunded.GreaterThanOrEqual(decimal.NewFromFloat(record.Amount)) {
+		recordStatus = PaymentStatusRefunded
+	}
+	if err := tx.Model(&record).Updates(map[string]interface{}{
+		"status":        recordStatus,
+		"refund_no":     refund.RefundNo,
+		"refund_amount": recordRefunded.InexactFloat64(),
+		"refund_time":   refundedAt,
+		"refund_reason": refund.Reason,
+	}).Error; err != nil {
+		return err
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, refund.OrderID).Error; err != nil {
+		return err
+	}
+	orderRefunded, err := sumRefunds(tx, []models.RefundStatus{models.RefundStatusSuccess}, "order_id = ?", order.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&order).Update("payment_status", orderRefundPaymentStatus(order.TotalAmount, orderRefunded)).Error
+}
+
+// sumRefunds 汇总指定状态的退款金额
+func sumRefunds(tx *gorm.DB, statuses []models.RefundStatus, query string, args ...interface{}) (float64, error) {
+	var total float64
+	err := tx.Model(&models.Refund{}).
+		Where(query, args...).
+		Where("status IN ?", statuses).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// resolveRefundAmount 计算本次退款金额和类型
+// 可退金额取订单剩余未退金额与支付单剩余未退金额中的较小值，requested 为0时退还全部可退金额
+func resolveRefundAmount(requested, orderTotal, orderRefunded, paymentTotal, paymentRefunded float64) (float64, models.RefundType, error) {
+	refundable := decimal.NewFromFloat(orderTotal).Sub(decimal.NewFromFloat(orderRefunded))
+	paymentRefundable := decimal.NewFromFloat(paymentTotal).Sub(decimal.NewFromFloat(paymentRefunded))
+	if paymentRefundable.LessThan(refundable) {
+		refundable = paymentRefundable
+	}
+	refundable = refundable.Round(2)
+	if !refundable.IsPositive() {
+		return 0, "", ErrNothingToRefund
+	}
+
+	amount := refundable
+	if requested > 0 {
+		amount = decimal.NewFromFloat(requested).Round(2)
+		if amount.GreaterThan(refundable) {
+			return 0, "", ErrRefundAmountExceeded
+		}
+	}
+	if !amount.IsPositive() {
+		return 0, "", ErrNothingToRefund
+	}
+
+	refundType := models.RefundTypePartial
+	if amount.Add(decimal.NewFromFloat(orderRefunded)).GreaterThanOrEqual(decimal.NewFromFloat(orderTotal)) {
+		refundType = models.RefundTypeFull
+	}
+	return amount.InexactFloat64(), refundType, nil
+}
+
+// orderRefundPaymentStatus 按已退金额计算订单支付状态
+func orderRefundPaymentStatus(orderTotal, refunded float64) models.PaymentStatus {
+	switch {
+	case refunded <= 0:
+		return models.PaymentStatusPaid
+	case decimal.NewFromFloat(refunded).GreaterThanOrEqual(decimal.NewFromFloat(orderTotal)):
+		return models.PaymentStatusRefunded
+	}
+	return models.PaymentStatusPartialRefund
+}
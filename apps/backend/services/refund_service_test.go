@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/project/backend/models"
+)
+
+func TestResolveRefundAmount(t *testing.T) {
+	tests := []struct {
+		name            string
+		requested       float64
+		orderTotal      float64
+		orderRefunded   float64
+		paymentTotal    float64
+		paymentRefunded float64
+		expected        float64
+		expectedType    models.RefundType
+		expectedErr     error
+	}{
+		{"full refund", 0, 100, 0, 100, 0, 100, models.RefundTypeFull, nil},
+		{"partial refund", 30, 100, 0, 100, 0, 30, models.RefundTypePartial, nil},
+		{"remaining after partial", 0, 100, 30, 100, 30, 70, models.RefundTypeFull, nil},
+		{"last partial completes order", 70, 100, 30, 100, 30, 70, models.RefundTypeFull, nil},
+		{"combined payment order share", 0, 40, 0, 100, 60, 40, models.RefundTypeFull, nil},
+		{"capped by payment remaining", 0, 40, 0, 100, 80, 20, models.RefundTypePartial, nil},
+		{"rounds to cents", 10.004, 100, 0, 100, 0, 10, models.RefundTypePartial, nil},
+		{"exceeds refundable", 80, 100, 30, 100, 30, 0, "", ErrRefundAmountExceeded},
+		{"nothing left", 0, 100, 100, 100, 100, 0, "", ErrNothingToRefund},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, refundType, err := resolveRefundAmount(tt.requested, tt.orderTotal, tt.orderRefunded, tt.paymentTotal, tt.paymentRefunded)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("resolveRefundAmount() error = %v, expected %v", err, tt.expectedErr)
+			}
+			if amount != tt.expected {
+				t.Errorf("resolveRefundAmount() amount = %v, expected %v", amount, tt.expected)
+			}
+			if refundType != tt.expectedType {
+				t.Errorf("resolveRefundAmount() type = %s, expected %s", refundType, tt.expectedType)
+			}
+		})
+	}
+}
+
+func TestOrderRefundPaymentStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    float64
+		refunded float64
+		expected models.PaymentStatus
+	}{
+		{"nothing refunded", 100, 0, models.PaymentStatusPaid},
+		{"partial", 100, 30.5, models.PaymentStatusPartialRefund},
+		{"full", 100, 100, models.PaymentStatusRefunded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderRefundPaymentStatus(tt.total, tt.refunded); got != tt.expected {
+				t.Errorf("orderRefundPaymentStatus() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWechatRefundState(t *testing.T) {
+	tests := []struct {
+		status   string
+		expected RefundState
+	}{
+		{"SUCCESS", RefundStateSuccess},
+		{"PROCESSING", RefundStateProcessing},
+		{"CLOSED", RefundStateFailed},
+		{"ABNORMAL", RefundStateFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := wechatRefundState(tt.status); got != tt.expected {
+				t.Errorf("wechatRefundState(%s) = %s, expected %s", tt.status, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// JobFunc 定时任务函数
+type JobFunc func(ctx context.Context) error
+
+// scheduledJob 定时任务
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	fn       JobFunc
+}
+
+// Scheduler 后台定时任务调度器
+// 多实例部署时通过 Redis 锁保证同一任务同一时刻只有一个实例执行
+type Scheduler struct {
+	redis      *redis.Client
+	logger     *zap.Logger
+	instanceID string
+	jobs       []scheduledJob
+	wg         sync.WaitGroup
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(redisClient *redis.Client, logger *zap.Logger) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		redis:      redisClient,
+		logger:     logger,
+		instanceID: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+	}
+}
+
+// Register 注册定时任务
+func (s *Scheduler) Register(name string, interval time.Duration, fn JobFunc) {
+	s.jobs = append(s.jobs, scheduledJob{name: name, interval: interval, fn: fn})
+}
+
+// Start 启动所有任务，ctx 取消后停止
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Wait 等待所有任务退出
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, job)
+		}
+	}
+}
+
+// runOnce 获取锁后执行一次任务
+func (s *Scheduler) runOnce(ctx context.Context, job scheduledJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("scheduled job panic", zap.String("job", job.name), zap.Any("panic", r))
+		}
+	}()
+
+	lockKey := "lock:job:" + job.name
+	if s.redis != nil {
+		ok, err := s.redis.SetNX(ctx, lockKey, s.instanceID, job.interval).Result()
+		if err != nil {
+			s.logger.Warn("acquire job lock failed", zap.String("job", job.name), zap.Error(err))
+			return
+		}
+		if !ok {
+			return
+		}
+		defer s.releaseLock(lockKey)
+	}
+
+	start := time.Now()
+	if err := job.fn(ctx); err != nil {
+		s.logger.Error("scheduled job failed", zap.String("job", job.name), zap.Error(err))
+		return
+	}
+	s.logger.Debug("scheduled job finished", zap.String("job", job.name), zap.Duration("duration", time.Since(start)))
+}
+
+// releaseLockScript 仅释放自己持有的锁
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (s *Scheduler) releaseLock(key string) {
+	releaseLockScript.Run(context.Background(), s.redis, []string{key}, s.instanceID)
+}
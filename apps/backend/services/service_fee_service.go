@@ -0,0 +1,466 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 服务费规则错误
+var (
+	ErrServiceFeeRuleNotFound = errors.New("服务费规则不存在")
+	ErrServiceFeeRuleInvalid  = errors.New("服务费规则无效")
+)
+
+// defaultServiceFeeRuleName 没有匹配的服务费规则时使用订单配置中的默认费率
+const defaultServiceFeeRuleName = "默认服务费"
+
+// ServiceFeeService 服务费规则服务
+// 免收规则优先；其余规则按优先级、适用范围(门店+供应商 > 门店 > 供应商 > 全局)取第一条
+type ServiceFeeService struct {
+	db *gorm.DB
+}
+
+// NewServiceFeeService 创建服务费规则服务
+func NewServiceFeeService(db *gorm.DB) *ServiceFeeService {
+	return &ServiceFeeService{db: db}
+}
+
+// ServiceFeeRuleRequest 创建或修改服务费规则
+type ServiceFeeRuleRequest struct {
+	Name        string                 `json:"name" validate:"required,max=100"`
+	Description string                 `json:"description" validate:"max=500"`
+	StoreID     *uint64                `json:"storeId"`
+	SupplierID  *uint64                `json:"supplierId"`
+	CategoryID  *uint64                `json:"categoryId"`
+	FeeType     models.ServiceFeeType  `json:"feeType" validate:"required,oneof=percent fixed exempt"`
+	Tiers       models.ServiceFeeTiers `json:"tiers"`
+	FixedFee    float64                `json:"fixedFee" validate:"gte=0"`
+	MinFee      float64                `json:"minFee" validate:"gte=0"`
+	MaxFee      float64                `json:"maxFee" validate:"gte=0"`
+	Priority    int                    `json:"priority"`
+	IsActive    bool                   `json:"isActive"`
+	StartTime   *time.Time             `json:"startTime"`
+	EndTime     *time.Time             `json:"endTime"`
+}
+
+// ServiceFeeRuleQuery 服务费规则查询条件
+type ServiceFeeRuleQuery struct {
+	StoreID    uint64
+	SupplierID uint64
+	FeeType    models.ServiceFeeType
+	IsActive   *bool
+}
+
+// ServiceFeeQuote 订单服务费计算结果及适用的规则
+type ServiceFeeQuote struct {
+	Fee           float64               `json:"fee"`
+	BaseAmount    float64               `json:"baseAmount"`   // 计费金额，已扣除免收分类商品
+	ExemptAmount  float64               `json:"exemptAmount"` // 免收金额
+	Rate          float64               `json:"rate,omitempty"`
+	RuleID        *uint64               `json:"ruleId,omitempty"` // 为空表示使用默认费率
+	RuleName      string                `json:"ruleName"`
+	FeeType       models.ServiceFeeType `json:"feeType"`
+	ExemptRuleIDs []uint64              `json:"exemptRuleIds,omitempty"` // 生效的分类免收规则
+}
+
+// Create 创建服务费规则
+func (s *ServiceFeeService) Create(req *ServiceFeeRuleRequest, createdBy uint64) (*models.ServiceFeeRule, error) {
+	rule := &models.ServiceFeeRule{CreatedBy: createdBy}
+	applyServiceFeeRuleRequest(rule, req)
+	if err := validateServiceFeeRule(rule); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// Update 修改服务费规则
+func (s *ServiceFeeService) Update(id uint64, req *ServiceFeeRuleRequest) (*models.ServiceFeeRule, error) {
+	var rule models.ServiceFeeRule
+	if err := s.db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceFeeRuleNotFound
+		}
+		return nil, err
+	}
+
+	applyServiceFeeRuleRequest(&rule, req)
+	if err := validateServiceFeeRule(&rule); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Delete 删除服务费规则
+func (s *ServiceFeeService) Delete(id uint64) error {
+	result := s.db.Delete(&models.ServiceFeeRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrServiceFeeRuleNotFound
+	}
+	return nil
+}
+
+// Get 获取服务费规则
+func (s *ServiceFeeService) Get(id uint64) (*models.ServiceFeeRule, error) {
+	var rule models.ServiceFeeRule
+	if err := s.db.Preload("Store").Preload("Supplier").Preload("Category").First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceFeeRuleNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// List 分页查询服务费规则
+func (s *ServiceFeeService) List(page, pageSize int, q *ServiceFeeRuleQuery) ([]models.ServiceFeeRule, int64, error) {
+	query := s.db.Model(&models.ServiceFeeRule{})
+	if q.StoreID > 0 {
+		query = query.Where("store_id = ?", q.StoreID)
+	}
+	if q.SupplierID > 0 {
+		query = query.Where("supplier_id = ?", q.SupplierID)
+	}
+	if q.FeeType != "" {
+		query = query.Where("fee_type = ?", q.FeeType)
+	}
+	if q.IsActive != nil {
+		query = query.Where("is_active = ?", *q.IsActive)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rules []models.ServiceFeeRule
+	err := query.Preload("Store").Preload("Supplier").Preload("Category").
+		Order("priority DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&rules).Error
+	return rules, total, err
+}
+
+// Calculate 计算门店在供应商处一笔订单的服务费
+func (s *ServiceFeeService) Calculate(storeID, supplierID uint64, items []PricedItem, now time.Time) (*ServiceFeeQuote, error) {
+	var rules []models.ServiceFeeRule
+	if err := s.db.Where("is_active = ?", true).
+		Where("(start_time IS NULL OR start_time <= ?)", now).
+		Where("(end_time IS NULL OR end_time >= ?)", now).
+		Where("(store_id IS NULL OR store_id = ?)", storeID).
+		Where("(supplier_id IS NULL OR supplier_id = ?)", supplierID).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	orderConfig, err := NewSystemConfigService(s.db).GetOrderConfig()
+	if err != nil {
+		return nil, err
+	}
+	fallback := &models.ServiceFeeRule{
+		Name:     defaultServiceFeeRuleName,
+		FeeType:  models.ServiceFeeTypePercent,
+		Tiers:    models.ServiceFeeTiers{{MinAmount: 0, Rate: orderConfig.ServiceFeeRate}},
+		MinFee:   orderConfig.MinServiceFee,
+		IsActive: true,
+	}
+
+	lines, err := s.feeLines(items, rules)
+	if err != nil {
+		return nil, err
+	}
+	return quoteServiceFee(rules, fallback, storeID, supplierID, lines, now), nil
+}
+
+// feeLines 将订单明细转换为计费明细，有分类免收规则时补齐上级分类
+func (s *ServiceFeeService) feeLines(items []PricedItem, rules []models.ServiceFeeRule) ([]serviceFeeLine, error) {
+	needCategories := false
+	for _, rule := range rules {
+		if rule.CategoryID != nil {
+			needCategories = true
+			break
+		}
+	}
+
+	ancestors := make(map[uint64][]uint64)
+	if needCategories {
+		ids := make([]uint64, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.CategoryID)
+		}
+		var categories []models.Category
+		if err := s.db.Select("id", "path").Where("id IN ?", ids).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			ancestors[category.ID] = parseCategoryPath(category.Path)
+		}
+	}
+
+	lines := make([]serviceFeeLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, serviceFeeLine{
+			Categories: append([]uint64{item.CategoryID}, ancestors[item.CategoryID]...),
+			Amount:     item.Subtotal,
+		})
+	}
+	return lines, nil
+}
+
+// applyServiceFeeRuleRequest 将请求内容写入规则
+func applyServiceFeeRuleRequest(rule *models.ServiceFeeRule, req *ServiceFeeRuleRequest) {
+	rule.Name = req.Name
+	rule.Description = req.Description
+	rule.StoreID = req.StoreID
+	rule.SupplierID = req.SupplierID
+	rule.CategoryID = req.CategoryID
+	rule.FeeType = req.FeeType
+	rule.Tiers = req.Tiers
+	rule.FixedFee = req.FixedFee
+	rule.MinFee = req.MinFee
+	rule.MaxFee = req.MaxFee
+	rule.Priority = req.Priority
+	rule.IsActive = req.IsActive
+	rule.StartTime = req.StartTime
+	rule.EndTime = req.EndTime
+}
+
+// validateServiceFeeRule 校验规则配置，阶梯按起始金额排序
+func validateServiceFeeRule(rule *models.ServiceFeeRule) error {
+	invalid := func(msg string) error {
+		return fmt.Errorf("%w: %s", ErrServiceFeeRuleInvalid, msg)
+	}
+
+	switch rule.FeeType {
+	case models.ServiceFeeTypePercent:
+		if len(rule.Tiers) == 0 {
+			return invalid("按比例计费至少设置一档费率")
+		}
+		sort.Slice(rule.Tiers, func(i, j int) bool { return rule.Tiers[i].MinAmount < rule.Tiers[j].MinAmount })
+		if rule.Tiers[0].MinAmount != 0 {
+			return invalid("第一档起始金额必须为0")
+		}
+		for i, tier := range rule.Tiers {
+			if tier.Rate < 0 || tier.Rate > 1 {
+				return invalid("费率必须在0到1之间")
+			}
+			if i > 0 && tier.MinAmount == rule.Tiers[i-1].MinAmount {
+				return invalid("阶梯起始金额不能重复")
+			}
+		}
+		rule.FixedFee = 0
+	case models.ServiceFeeTypeFixed:
+		if rule.FixedFee <= 0 {
+			return invalid("固定服务费必须大于0")
+		}
+		rule.Tiers = nil
+	case models.ServiceFeeTypeExempt:
+		if rule.StoreID == nil && rule.SupplierID == nil && rule.CategoryID == nil {
+			return invalid("免收规则必须指定门店、供应商或分类")
+		}
+		rule.Tiers = nil
+		rule.FixedFee = 0
+		rule.MinFee = 0
+		rule.MaxFee = 0
+	default:
+		return invalid("不支持的计费方式")
+	}
+
+	if rule.FeeType != models.ServiceFeeTypeExempt && rule.CategoryID != nil {
+		return invalid("仅免收规则可以指定分类")
+	}
+	if rule.MinFee < 0 || rule.MaxFee < 0 {
+		return invalid("最低和最高服务费不能为负数")
+	}
+	if rule.MaxFee > 0 && rule.MaxFee < rule.MinFee {
+		return invalid("最高服务费不能低于最低服务费")
+	}
+	if rule.StartTime != nil && rule.EndTime != nil && !rule.EndTime.After(*rule.StartTime) {
+		return invalid("结束时间必须晚于开始时间")
+	}
+	return nil
+}
+
+// serviceFeeLine 计费明细，Categories 为商品分类及其所有上级分类
+type serviceFeeLine struct {
+	Categories []uint64
+	Amount     float64
+}
+
+// quoteServiceFee 按规则计算服务费
+// 整单免收规则命中时不收费；分类免收规则命中的商品不计入计费金额；
+// 其余按优先级最高的计费规则计算，没有时使用 fallback
+func quoteServiceFee(rules []models.ServiceFeeRule, fallback *models.ServiceFeeRule, storeID, supplierID uint64, lines []serviceFeeLine, now time.Time) *ServiceFeeQuote {
+	candidates := make([]*models.ServiceFeeRule, 0, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.IsValidAt(now) {
+			continue
+		}
+		if rule.StoreID != nil && *rule.StoreID != storeID {
+			continue
+		}
+		if rule.SupplierID != nil && *rule.SupplierID != supplierID {
+			continue
+		}
+		candidates = append(candidates, rule)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if sa, sb := serviceFeeRuleSpecificity(a), serviceFeeRuleSpecificity(b); sa != sb {
+			return sa > sb
+		}
+		return a.ID > b.ID
+	})
+
+	var orderExempt, feeRule *models.ServiceFeeRule
+	exemptCategories := make(map[uint64]uint64)
+	for _, rule := range candidates {
+		switch {
+		case rule.FeeType == models.ServiceFeeTypeExempt && rule.CategoryID == nil:
+			if orderExempt == nil {
+				orderExempt = rule
+			}
+		case rule.FeeType == models.ServiceFeeTypeExempt:
+			if _, ok := exemptCategories[*rule.CategoryID]; !ok {
+				exemptCategories[*rule.CategoryID] = rule.ID
+			}
+		case feeRule == nil:
+			feeRule = rule
+		}
+	}
+
+	goods := decimal.Zero
+	exempt := decimal.Zero
+	var exemptRuleIDs []uint64
+	for _, line := range lines {
+		amount := decimal.NewFromFloat(line.Amount)
+		goods = goods.Add(amount)
+		for _, categoryID := range line.Categories {
+			if ruleID, ok := exemptCategories[categoryID]; ok {
+				exempt = exempt.Add(amount)
+				if !containsUint64(exemptRuleIDs, ruleID) {
+					exemptRuleIDs = append(exemptRuleIDs, ruleID)
+				}
+				break
+			}
+		}
+	}
+
+	if orderExempt != nil {
+		ruleID := orderExempt.ID
+		return &ServiceFeeQuote{
+			ExemptAmount: goods.Round(2).InexactFloat64(),
+			RuleID:       &ruleID,
+			RuleName:     orderExempt.Name,
+			FeeType:      models.ServiceFeeTypeExempt,
+		}
+	}
+
+	if feeRule == nil {
+		feeRule = fallback
+	}
+	base := goods.Sub(exempt).Round(2)
+	fee, rate := serviceFeeAmount(feeRule, base)
+	quote := &ServiceFeeQuote{
+		Fee:           fee,
+		BaseAmount:    base.InexactFloat64(),
+		ExemptAmount:  exempt.Round(2).InexactFloat64(),
+		Rate:          rate,
+		RuleName:      feeRule.Name,
+		FeeType:       feeRule.FeeType,
+		ExemptRuleIDs: exemptRuleIDs,
+	}
+	if feeRule.ID > 0 {
+		ruleID := feeRule.ID
+		quote.RuleID = &ruleID
+	}
+	return quote
+}
+
+// serviceFeeAmount 按规则计算计费金额的服务费，返回服务费和适用的费率
+func serviceFeeAmount(rule *models.ServiceFeeRule, base decimal.Decimal) (float64, float64) {
+	if !base.IsPositive() {
+		return 0, 0
+	}
+
+	var fee decimal.Decimal
+	var rate float64
+	switch rule.FeeType {
+	case models.ServiceFeeTypePercent:
+		for _, tier := range rule.Tiers {
+			if base.GreaterThanOrEqual(decimal.NewFromFloat(tier.MinAmount)) {
+				rate = tier.Rate
+			}
+		}
+		fee = base.Mul(decimal.NewFromFloat(rate))
+	case models.ServiceFeeTypeFixed:
+		fee = decimal.NewFromFloat(rule.FixedFee)
+	default:
+		return 0, 0
+	}
+
+	if rule.MinFee > 0 && fee.LessThan(decimal.NewFromFloat(rule.MinFee)) {
+		fee = decimal.NewFromFloat(rule.MinFee)
+	}
+	if rule.MaxFee > 0 && fee.GreaterThan(decimal.NewFromFloat(rule.MaxFee)) {
+		fee = decimal.NewFromFloat(rule.MaxFee)
+	}
+	return fee.Round(2).InexactFloat64(), rate
+}
+
+// serviceFeeRuleSpecificity 规则适用范围越小越优先
+func serviceFeeRuleSpecificity(rule *models.ServiceFeeRule) int {
+	specificity := 0
+	if rule.StoreID != nil {
+		specificity += 2
+	}
+	if rule.SupplierID != nil {
+		specificity++
+	}
+	return specificity
+}
+
+// parseCategoryPath 解析分类路径(如 "1/5")为上级分类ID
+func parseCategoryPath(path string) []uint64 {
+	if path == "" {
+		return nil
+	}
+	parts := strings.Split(path, "/")
+	ids := make([]uint64, 0, len(parts))
+	for i := len(parts) - 1; i >= 0; i-- {
+		if id, err := strconv.ParseUint(parts[i], 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// containsUint64 切片中是否包含指定值
+func containsUint64(values []uint64, target uint64) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
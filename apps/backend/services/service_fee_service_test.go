@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestQuoteServiceFee(t *testing.T) {
+	id := func(v uint64) *uint64 { return &v }
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	yesterday := now.AddDate(0, 0, -1)
+
+	fallback := &models.ServiceFeeRule{
+		Name:     defaultServiceFeeRuleName,
+		FeeType:  models.ServiceFeeTypePercent,
+		Tiers:    models.ServiceFeeTiers{{MinAmount: 0, Rate: 0.003}},
+		IsActive: true,
+	}
+	tiered := models.ServiceFeeRule{
+		ID: 1, Name: "阶梯费率", FeeType: models.ServiceFeeTypePercent, IsActive: true,
+		Tiers:  models.ServiceFeeTiers{{MinAmount: 0, Rate: 0.01}, {MinAmount: 1000, Rate: 0.005}},
+		MinFee: 2, MaxFee: 20,
+	}
+	supplierFixed := models.ServiceFeeRule{
+		ID: 2, Name: "供应商固定", SupplierID: id(7), FeeType: models.ServiceFeeTypeFixed, FixedFee: 5, IsActive: true,
+	}
+	storeExempt := models.ServiceFeeRule{
+		ID: 3, Name: "门店免收", StoreID: id(3), FeeType: models.ServiceFeeTypeExempt, IsActive: true,
+	}
+	categoryExempt := models.ServiceFeeRule{
+		ID: 4, Name: "蔬菜免收", CategoryID: id(10), FeeType: models.ServiceFeeTypeExempt, IsActive: true,
+	}
+	expired := models.ServiceFeeRule{
+		ID: 5, Name: "已过期", FeeType: models.ServiceFeeTypeFixed, FixedFee: 1, Priority: 100, IsActive: true, EndTime: &yesterday,
+	}
+
+	lines := []serviceFeeLine{
+		{Categories: []uint64{11, 10}, Amount: 300},
+		{Categories: []uint64{20}, Amount: 500},
+	}
+
+	tests := []struct {
+		name         string
+		rules        []models.ServiceFeeRule
+		storeID      uint64
+		supplierID   uint64
+		lines        []serviceFeeLine
+		expectedFee  float64
+		expectedRule string
+		expectedBase float64
+	}{
+		{"no rules uses fallback", nil, 1, 1, lines, 2.4, defaultServiceFeeRuleName, 800},
+		{"lower tier", []models.ServiceFeeRule{tiered}, 1, 1, lines, 8, "阶梯费率", 800},
+		{"upper tier", []models.ServiceFeeRule{tiered}, 1, 1, []serviceFeeLine{{Categories: []uint64{20}, Amount: 2000}}, 10, "阶梯费率", 2000},
+		{"max fee", []models.ServiceFeeRule{tiered}, 1, 1, []serviceFeeLine{{Categories: []uint64{20}, Amount: 5000}}, 20, "阶梯费率", 5000},
+		{"min fee", []models.ServiceFeeRule{tiered}, 1, 1, []serviceFeeLine{{Categories: []uint64{20}, Amount: 50}}, 2, "阶梯费率", 50},
+		{"more specific wins on same priority", []models.ServiceFeeRule{tiered, supplierFixed}, 1, 7, lines, 5, "供应商固定", 800},
+		{"scope mismatch ignored", []models.ServiceFeeRule{tiered, supplierFixed}, 1, 8, lines, 8, "阶梯费率", 800},
+		{"store exempt", []models.ServiceFeeRule{tiered, storeExempt}, 3, 1, lines, 0, "门店免收", 0},
+		{"category exempt via parent", []models.ServiceFeeRule{tiered, categoryExempt}, 1, 1, lines, 5, "阶梯费率", 500},
+		{"all exempt by category", []models.ServiceFeeRule{tiered, categoryExempt}, 1, 1, lines[:1], 0, "阶梯费率", 0},
+		{"expired ignored", []models.ServiceFeeRule{tiered, expired}, 1, 1, lines, 8, "阶梯费率", 800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := quoteServiceFee(tt.rules, fallback, tt.storeID, tt.supplierID, tt.lines, now)
+			if quote.Fee != tt.expectedFee {
+				t.Errorf("Fee = %v, expected %v", quote.Fee, tt.expectedFee)
+			}
+			if quote.RuleName != tt.expectedRule {
+				t.Errorf("RuleName = %q, expected %q", quote.RuleName, tt.expectedRule)
+			}
+			if quote.BaseAmount != tt.expectedBase {
+				t.Errorf("BaseAmount = %v, expected %v", quote.BaseAmount, tt.expectedBase)
+			}
+		})
+	}
+}
+
+func TestValidateServiceFeeRule(t *testing.T) {
+	id := uint64(1)
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name    string
+		rule    models.ServiceFeeRule
+		wantErr bool
+	}{
+		{"valid tiers", models.ServiceFeeRule{FeeType: models.ServiceFeeTypePercent, Tiers: models.ServiceFeeTiers{{MinAmount: 500, Rate: 0.002}, {MinAmount: 0, Rate: 0.003}}}, false},
+		{"no tiers", models.ServiceFeeRule{FeeType: models.ServiceFeeTypePercent}, true},
+		{"tiers not from zero", models.ServiceFeeRule{FeeType: models.ServiceFeeTypePercent, Tiers: models.ServiceFeeTiers{{MinAmount: 100, Rate: 0.003}}}, true},
+		{"duplicate tier", models.ServiceFeeRule{FeeType: models.ServiceFeeTypePercent, Tiers: models.ServiceFeeTiers{{MinAmount: 0, Rate: 0.003}, {MinAmount: 0, Rate: 0.002}}}, true},
+		{"rate out of range", models.ServiceFeeRule{FeeType: models.ServiceFeeTypePercent, Tiers: models.ServiceFeeTiers{{MinAmount: 0, Rate: 3}}}, true},
+		{"fixed zero", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeFixed}, true},
+		{"fixed with category", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeFixed, FixedFee: 5, CategoryID: &id}, true},
+		{"max below min", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeFixed, FixedFee: 5, MinFee: 10, MaxFee: 8}, true},
+		{"global exempt", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeExempt}, true},
+		{"category exempt", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeExempt, CategoryID: &id}, false},
+		{"end before start", models.ServiceFeeRule{FeeType: models.ServiceFeeTypeFixed, FixedFee: 5, StartTime: &start, EndTime: &end}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServiceFeeRule(&tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateServiceFeeRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrServiceFeeRuleInvalid) {
+				t.Errorf("validateServiceFeeRule() error = %v, expected ErrServiceFeeRuleInvalid", err)
+			}
+		})
+	}
+}
+
+func TestParseCategoryPath(t *testing.T) {
+	ids := parseCategoryPath("1/5/12")
+	if len(ids) != 3 || ids[0] != 12 || ids[1] != 5 || ids[2] != 1 {
+		t.Errorf("parseCategoryPath() = %v, expected [12 5 1]", ids)
+	}
+	if ids := parseCategoryPath(""); len(ids) != 0 {
+		t.Errorf("parseCategoryPath(\"\") = %v, expected empty", ids)
+	}
+}
@@ -0,0 +1,555 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 结算错误
+var (
+	ErrStatementNotFound       = errors.New("结算单不存在")
+	ErrStatementNotConfirmable = errors.New("结算单不是待确认状态")
+	ErrStatementNotPayable     = errors.New("结算单需供应商确认后才能打款")
+	ErrStatementAlreadyPaid    = errors.New("结算单已打款完成")
+	ErrNothingToSettle         = errors.New("结算周期内没有需要结算的订单、退款或调整")
+	ErrStatementExists         = errors.New("该结算周期的结算单已生成")
+	ErrNothingToPay            = errors.New("结算单没有可打款金额")
+	ErrHoldAmountInvalid       = errors.New("暂扣金额超出可用范围")
+	ErrReleaseAmountInvalid    = errors.New("释放金额超出暂扣金额")
+)
+
+// SettlementService 供应商结算服务
+// 按供应商的结算周期汇总已完成订单，扣减退款和调整生成结算单，
+// 结算单生成、暂扣、释放和打款都会追加一条供应商台账记录
+type SettlementService struct {
+	db *gorm.DB
+}
+
+// NewSettlementService 创建结算服务
+func NewSettlementService(db *gorm.DB) *SettlementService {
+	return &SettlementService{db: db}
+}
+
+// StatementQuery 结算单查询条件
+type StatementQuery struct {
+	SupplierID uint64
+	Status     models.SettlementStatementStatus
+}
+
+// LedgerOperation 台账操作
+type LedgerOperation struct {
+	Amount       float64
+	Reference    string
+	Remark       string
+	OperatorType models.OperatorType
+	OperatorID   uint64
+}
+
+// GenerateDue 为所有启用的供应商生成上一个结算周期的结算单，供定时任务调用
+func (s *SettlementService) GenerateDue(ctx context.Context) error {
+	var suppliers []models.Supplier
+	if err := s.db.Select("id", "settlement_cycle").Where("status = ?", 1).Find(&suppliers).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, supplier := range suppliers {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		cycle := normalizeSettlementCycle(supplier.SettlementCycle)
+		start, end := settlementPeriod(cycle, now)
+
+		var count int64
+		s.db.Model(&models.SettlementStatement{}).
+			Where("supplier_id = ? AND period_start = ?", supplier.ID, start.Format("2006-01-02")).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		if _, err := s.Generate(supplier.ID, cycle, start, end); err != nil && !errors.Is(err, ErrNothingToSettle) {
+			return fmt.Errorf("供应商%d生成结算单失败: %w", supplier.ID, err)
+		}
+	}
+	return nil
+}
+
+// GenerateForSupplier 按供应商当前结算周期生成上一个周期的结算单
+func (s *SettlementService) GenerateForSupplier(supplierID uint64) (*models.SettlementStatement, error) {
+	var supplier models.Supplier
+	if err := s.db.Select("id", "settlement_cycle").First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+	cycle := normalizeSettlementCycle(supplier.SettlementCycle)
+	start, end := settlementPeriod(cycle, time.Now())
+	return s.Generate(supplierID, cycle, start, end)
+}
+
+// Generate 生成结算单
+// 计入周期结束前完成且尚未结算的订单、已结算订单上尚未扣减的成功退款，以及未计入的调整；
+// 之前周期遗漏的订单(如补完成的订单)会在下一张结算单中补结
+func (s *SettlementService) Generate(supplierID uint64, cycle models.SettlementCycle, start, end time.Time) (*models.SettlementStatement, error) {
+	var statement *models.SettlementStatement
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSupplierTx(tx, supplierID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.SettlementStatement{}).
+			Where("supplier_id = ? AND period_start = ?", supplierID, start.Format("2006-01-02")).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrStatementExists
+		}
+
+		items, adjustmentIDs, err := s.collectItemsTx(tx, supplierID, end)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrNothingToSettle
+		}
+
+		summary := summarizeStatementItems(items)
+		statement = &models.SettlementStatement{
+			SupplierID:       supplierID,
+			Cycle:            cycle,
+			PeriodStart:      start,
+			PeriodEnd:        end,
+			OrderCount:       summary.OrderCount,
+			OrderAmount:      summary.OrderAmount,
+			RefundAmount:     summary.RefundAmount,
+			AdjustmentAmount: summary.AdjustmentAmount,
+			PayableAmount:    summary.PayableAmount,
+			Status:           models.SettlementStatusPendingConfirm,
+		}
+		if err := tx.Create(statement).Error; err != nil {
+			return err
+		}
+
+		for i := range items {
+			items[i].StatementID = statement.ID
+		}
+		if err := tx.CreateInBatches(items, 200).Error; err != nil {
+			return err
+		}
+		if len(adjustmentIDs) > 0 {
+			if err := tx.Model(&models.SettlementAdjustment{}).
+				Where("id IN ?", adjustmentIDs).
+				Update("statement_id", statement.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		_, err = appendLedgerTx(tx, statement, models.LedgerEntryPayable, &LedgerOperation{
+			Amount:       statement.PayableAmount,
+			Remark:       fmt.Sprintf("结算单 %s", statement.StatementNo),
+			OperatorType: models.OperatorTypeSystem,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// collectItemsTx 收集待结算的订单、退款和调整明细
+func (s *SettlementService) collectItemsTx(tx *gorm.DB, supplierID uint64, end time.Time) ([]models.SettlementStatementItem, []uint64, error) {
+	var orders []models.Order
+	if err := tx.Where("supplier_id = ? AND status = ? AND completed_at < ?", supplierID, models.OrderStatusCompleted, end).
+		Where("NOT EXISTS (SELECT 1 FROM settlement_statement_items i WHERE i.type = ? AND i.ref_id = orders.id)", models.SettlementItemOrder).
+		Order("completed_at ASC").
+		Find(&orders).Error; err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]models.SettlementStatementItem, 0, len(orders))
+	orderIDs := make([]uint64, 0, len(orders))
+	for _, order := range orders {
+		orderIDs = append(orderIDs, order.ID)
+		items = append(items, models.SettlementStatementItem{
+			Type:        models.SettlementItemOrder,
+			RefID:       order.ID,
+			OrderID:     order.ID,
+			OrderNo:     order.OrderNo,
+			GoodsAmount: order.GoodsAmount,
+			MarkupTotal: order.MarkupTotal,
+			ServiceFee:  order.ServiceFee,
+			Amount:      order.SupplierAmount,
+			OccurredAt:  *order.CompletedAt,
+		})
+	}
+
+	// 只扣减已结算(或本次结算)订单上的退款，未完成订单的退款不影响供应商应付
+	type refundRow struct {
+		models.Refund
+		SupplierAmount float64
+		TotalAmount    float64
+	}
+	var refunds []refundRow
+	settled := tx.Where("EXISTS (SELECT 1 FROM settlement_statement_items i WHERE i.type = ? AND i.ref_id = o.id)", models.SettlementItemOrder)
+	if len(orderIDs) > 0 {
+		settled = settled.Or("o.id IN ?", orderIDs)
+	}
+	if err := tx.Table("refunds r").
+		Select("r.*, o.supplier_amount, o.total_amount").
+		Joins("JOIN orders o ON o.id = r.order_id").
+		Where("o.supplier_id = ? AND r.status = ? AND r.refunded_at < ?", supplierID, models.RefundStatusSuccess, end).
+		Where(settled).
+		Where("NOT EXISTS (SELECT 1 FROM settlement_statement_items i WHERE i.type = ? AND i.ref_id = r.id)", models.SettlementItemRefund).
+		Order("r.refunded_at ASC").
+		Scan(&refunds).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, refund := range refunds {
+		items = append(items, models.SettlementStatementItem{
+			Type:       models.SettlementItemRefund,
+			RefID:      refund.ID,
+			OrderID:    refund.OrderID,
+			OrderNo:    refund.OrderNo,
+			Amount:     -supplierRefundShare(refund.Amount, refund.SupplierAmount, refund.TotalAmount),
+			Remark:     fmt.Sprintf("退款 %s %.2f 元", refund.RefundNo, refund.Amount),
+			OccurredAt: *refund.RefundedAt,
+		})
+	}
+
+	var adjustments []models.SettlementAdjustment
+	if err := tx.Where("supplier_id = ? AND statement_id IS NULL AND created_at < ?", supplierID, end).
+		Order("id ASC").
+		Find(&adjustments).Error; err != nil {
+		return nil, nil, err
+	}
+	adjustmentIDs := make([]uint64, 0, len(adjustments))
+	for _, adjustment := range adjustments {
+		adjustmentIDs = append(adjustmentIDs, adjustment.ID)
+		items = append(items, models.SettlementStatementItem{
+			Type:       models.SettlementItemAdjustment,
+			RefID:      adjustment.ID,
+			Amount:     adjustment.Amount,
+			Remark:     adjustment.Reason,
+			OccurredAt: adjustment.CreatedAt,
+		})
+	}
+
+	return items, adjustmentIDs, nil
+}
+
+// Confirm 供应商确认结算单
+func (s *SettlementService) Confirm(statementID, supplierID, userID uint64) (*models.SettlementStatement, error) {
+	now := time.Now()
+	result := s.db.Model(&models.SettlementStatement{}).
+		Where("id = ? AND supplier_id = ? AND status = ?", statementID, supplierID, models.SettlementStatusPendingConfirm).
+		Updates(map[string]interface{}{
+			"status":       models.SettlementStatusConfirmed,
+			"confirmed_by": userID,
+			"confirmed_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(statementID, supplierID); err != nil {
+			return nil, err
+		}
+		return nil, ErrStatementNotConfirmable
+	}
+	return s.Get(statementID, supplierID)
+}
+
+// Hold 暂扣结算单部分应付金额
+func (s *SettlementService) Hold(statementID uint64, op *LedgerOperation) (*models.SettlementStatement, error) {
+	return s.updateStatementLedger(statementID, func(tx *gorm.DB, statement *models.SettlementStatement) error {
+		if statement.Status == models.SettlementStatusPaid {
+			return ErrStatementAlreadyPaid
+		}
+		if op.Amount <= 0 || op.Amount > statement.AvailableAmount() {
+			return ErrHoldAmountInvalid
+		}
+		statement.HeldAmount = decimal.NewFromFloat(statement.HeldAmount).Add(decimal.NewFromFloat(op.Amount)).Round(2).InexactFloat64()
+		if _, err := appendLedgerTx(tx, statement, models.LedgerEntryHold, op); err != nil {
+			return err
+		}
+		return tx.Model(statement).Update("held_amount", statement.HeldAmount).Error
+	})
+}
+
+// Release 释放暂扣金额
+func (s *SettlementService) Release(statementID uint64, op *LedgerOperation) (*models.SettlementStatement, error) {
+	return s.updateStatementLedger(statementID, func(tx *gorm.DB, statement *models.SettlementStatement) error {
+		if op.Amount <= 0 || op.Amount > statement.HeldAmount {
+			return ErrReleaseAmountInvalid
+		}
+		statement.HeldAmount = decimal.NewFromFloat(statement.HeldAmount).Sub(decimal.NewFromFloat(op.Amount)).Round(2).InexactFloat64()
+		if _, err := appendLedgerTx(tx, statement, models.LedgerEntryRelease, op); err != nil {
+			return err
+		}
+		return tx.Model(statement).Update("held_amount", statement.HeldAmount).Error
+	})
+}
+
+// Payout 标记打款，打出全部可打款金额；没有暂扣时结算单变为已打款
+func (s *SettlementService) Payout(statementID uint64, op *LedgerOperation) (*models.SettlementStatement, error) {
+	return s.updateStatementLedger(statementID, func(tx *gorm.DB, statement *models.SettlementStatement) error {
+		switch statement.Status {
+		case models.SettlementStatusPendingConfirm:
+			return ErrStatementNotPayable
+		case models.SettlementStatusPaid:
+			return ErrStatementAlreadyPaid
+		}
+		amount := statement.AvailableAmount()
+		if amount <= 0 {
+			return ErrNothingToPay
+		}
+
+		op.Amount = amount
+		if _, err := appendLedgerTx(tx, statement, models.LedgerEntryPayout, op); err != nil {
+			return err
+		}
+
+		statement.PaidAmount = decimal.NewFromFloat(statement.PaidAmount).Add(decimal.NewFromFloat(amount)).Round(2).InexactFloat64()
+		updates := map[string]interface{}{"paid_amount": statement.PaidAmount}
+		if statement.HeldAmount == 0 {
+			now := time.Now()
+			statement.Status = models.SettlementStatusPaid
+			statement.PaidAt = &now
+			updates["status"] = statement.Status
+			updates["paid_at"] = now
+		}
+		return tx.Model(statement).Updates(updates).Error
+	})
+}
+
+// updateStatementLedger 锁定供应商和结算单后执行台账变更
+// 先锁供应商再锁结算单，与生成结算单的加锁顺序一致
+func (s *SettlementService) updateStatementLedger(statementID uint64, fn func(tx *gorm.DB, statement *models.SettlementStatement) error) (*models.SettlementStatement, error) {
+	var statement models.SettlementStatement
+	if err := s.db.Select("id", "supplier_id").First(&statement, statementID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStatementNotFound
+		}
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSupplierTx(tx, statement.SupplierID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&statement, statementID).Error; err != nil {
+			return err
+		}
+		return fn(tx, &statement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// CreateAdjustment 录入结算调整，计入该供应商下一张结算单
+func (s *SettlementService) CreateAdjustment(supplierID uint64, amount float64, reason string, adminID uint64) (*models.SettlementAdjustment, error) {
+	adjustment := &models.SettlementAdjustment{
+		SupplierID: supplierID,
+		Amount:     decimal.NewFromFloat(amount).Round(2).InexactFloat64(),
+		Reason:     reason,
+		OperatorID: adminID,
+	}
+	if err := s.db.Create(adjustment).Error; err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// Get 获取结算单及明细，supplierID 为 0 时不限供应商
+func (s *SettlementService) Get(statementID, supplierID uint64) (*models.SettlementStatement, error) {
+	query := s.db.Preload("Supplier").Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("type ASC, occurred_at ASC, id ASC")
+	})
+	if supplierID > 0 {
+		query = query.Where("supplier_id = ?", supplierID)
+	}
+
+	var statement models.SettlementStatement
+	if err := query.First(&statement, statementID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStatementNotFound
+		}
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// List 分页查询结算单
+func (s *SettlementService) List(page, pageSize int, q *StatementQuery) ([]models.SettlementStatement, int64, error) {
+	query := s.db.Model(&models.SettlementStatement{})
+	if q.SupplierID > 0 {
+		query = query.Where("supplier_id = ?", q.SupplierID)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var statements []models.SettlementStatement
+	err := query.Preload("Supplier").
+		Order("period_start DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&statements).Error
+	return statements, total, err
+}
+
+// Ledger 分页查询供应商台账
+func (s *SettlementService) Ledger(supplierID uint64, page, pageSize int) ([]models.SupplierLedgerEntry, int64, error) {
+	query := s.db.Model(&models.SupplierLedgerEntry{}).Where("supplier_id = ?", supplierID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.SupplierLedgerEntry
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error
+	return entries, total, err
+}
+
+// lockSupplierTx 锁定供应商行，串行化同一供应商的结算和台账写入
+func lockSupplierTx(tx *gorm.DB, supplierID uint64) error {
+	var supplier models.Supplier
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&supplier, supplierID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSupplierNotFound
+	}
+	return err
+}
+
+// appendLedgerTx 追加一条台账记录，调用方需已锁定供应商
+func appendLedgerTx(tx *gorm.DB, statement *models.SettlementStatement, entryType models.SupplierLedgerEntryType, op *LedgerOperation) (*models.SupplierLedgerEntry, error) {
+	var last models.SupplierLedgerEntry
+	if err := tx.Where("supplier_id = ?", statement.SupplierID).Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, err
+	}
+
+	entry := &models.SupplierLedgerEntry{
+		SupplierID:   statement.SupplierID,
+		StatementID:  statement.ID,
+		Type:         entryType,
+		Amount:       op.Amount,
+		Reference:    op.Reference,
+		Remark:       op.Remark,
+		OperatorType: op.OperatorType,
+		OperatorID:   op.OperatorID,
+	}
+	entry.PayableBalance, entry.HeldBalance, entry.PaidTotal = applyLedgerEntry(
+		last.PayableBalance, last.HeldBalance, last.PaidTotal, entryType, op.Amount,
+	)
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// applyLedgerEntry 计算台账变动后的应付、暂扣和累计已付余额
+func applyLedgerEntry(payable, held, paid float64, entryType models.SupplierLedgerEntryType, amount float64) (float64, float64, float64) {
+	p := decimal.NewFromFloat(payable)
+	h := decimal.NewFromFloat(held)
+	d := decimal.NewFromFloat(paid)
+	a := decimal.NewFromFloat(amount)
+
+	switch entryType {
+	case models.LedgerEntryPayable:
+		p = p.Add(a)
+	case models.LedgerEntryHold:
+		p = p.Sub(a)
+		h = h.Add(a)
+	case models.LedgerEntryRelease:
+		h = h.Sub(a)
+		p = p.Add(a)
+	case models.LedgerEntryPayout:
+		p = p.Sub(a)
+		d = d.Add(a)
+	}
+	return p.Round(2).InexactFloat64(), h.Round(2).InexactFloat64(), d.Round(2).InexactFloat64()
+}
+
+// statementSummary 结算单汇总
+type statementSummary struct {
+	OrderCount       int
+	OrderAmount      float64
+	RefundAmount     float64
+	AdjustmentAmount float64
+	PayableAmount    float64
+}
+
+// summarizeStatementItems 汇总结算明细，退款金额以正数表示
+func summarizeStatementItems(items []models.SettlementStatementItem) statementSummary {
+	orders, refunds, adjustments := decimal.Zero, decimal.Zero, decimal.Zero
+	summary := statementSummary{}
+	for _, item := range items {
+		amount := decimal.NewFromFloat(item.Amount)
+		switch item.Type {
+		case models.SettlementItemOrder:
+			summary.OrderCount++
+			orders = orders.Add(amount)
+		case models.SettlementItemRefund:
+			refunds = refunds.Sub(amount)
+		case models.SettlementItemAdjustment:
+			adjustments = adjustments.Add(amount)
+		}
+	}
+	summary.OrderAmount = orders.Round(2).InexactFloat64()
+	summary.RefundAmount = refunds.Round(2).InexactFloat64()
+	summary.AdjustmentAmount = adjustments.Round(2).InexactFloat64()
+	summary.PayableAmount = orders.Sub(refunds).Add(adjustments).Round(2).InexactFloat64()
+	return summary
+}
+
+// supplierRefundShare 退款中由供应商承担的金额，按订单供应商应得占实付总额的比例分摊
+func supplierRefundShare(refundAmount, supplierAmount, totalAmount float64) float64 {
+	if refundAmount <= 0 || supplierAmount <= 0 || totalAmount <= 0 {
+		return 0
+	}
+	supplier := decimal.NewFromFloat(supplierAmount)
+	share := decimal.NewFromFloat(refundAmount).Mul(supplier).Div(decimal.NewFromFloat(totalAmount)).Round(2)
+	if share.GreaterThan(supplier) {
+		share = supplier
+	}
+	return share.InexactFloat64()
+}
+
+// settlementPeriod 返回 now 之前最近一个已结束的结算周期 [start, end)
+// 周结以周一为起点，月结以自然月为周期
+func settlementPeriod(cycle models.SettlementCycle, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if cycle == models.SettlementCycleMonthly {
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return end.AddDate(0, -1, 0), end
+	}
+	end := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return end.AddDate(0, 0, -7), end
+}
+
+// normalizeSettlementCycle 未设置结算周期的供应商按周结算
+func normalizeSettlementCycle(cycle models.SettlementCycle) models.SettlementCycle {
+	if cycle == models.SettlementCycleMonthly {
+		return cycle
+	}
+	return models.SettlementCycleWeekly
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestSettlementPeriod(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name          string
+		cycle         models.SettlementCycle
+		now           time.Time
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{"weekly on monday", models.SettlementCycleWeekly, date(2026, 10, 12).Add(9 * time.Hour), date(2026, 10, 5), date(2026, 10, 12)},
+		{"weekly midweek", models.SettlementCycleWeekly, date(2026, 10, 16).Add(15 * time.Hour), date(2026, 10, 5), date(2026, 10, 12)},
+		{"weekly on sunday", models.SettlementCycleWeekly, date(2026, 10, 18), date(2026, 10, 5), date(2026, 10, 12)},
+		{"monthly", models.SettlementCycleMonthly, date(2026, 10, 16), date(2026, 9, 1), date(2026, 10, 1)},
+		{"monthly across year", models.SettlementCycleMonthly, date(2026, 1, 1).Add(time.Hour), date(2025, 12, 1), date(2026, 1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := settlementPeriod(tt.cycle, tt.now)
+			if !start.Equal(tt.expectedStart) || !end.Equal(tt.expectedEnd) {
+				t.Errorf("settlementPeriod() = [%v, %v), expected [%v, %v)", start, end, tt.expectedStart, tt.expectedEnd)
+			}
+		})
+	}
+}
+
+func TestSupplierRefundShare(t *testing.T) {
+	tests := []struct {
+		name           string
+		refundAmount   float64
+		supplierAmount float64
+		totalAmount    float64
+		expected       float64
+	}{
+		{"full refund", 103, 90, 103, 90},
+		{"partial refund proportional", 51.5, 90, 103, 45},
+		{"rounds to cents", 10, 90, 103, 8.74},
+		{"capped by supplier amount", 200, 90, 103, 90},
+		{"zero total", 10, 90, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := supplierRefundShare(tt.refundAmount, tt.supplierAmount, tt.totalAmount)
+			if result != tt.expected {
+				t.Errorf("supplierRefundShare() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSummarizeStatementItems(t *testing.T) {
+	items := []models.SettlementStatementItem{
+		{Type: models.SettlementItemOrder, Amount: 100.1},
+		{Type: models.SettlementItemOrder, Amount: 200.2},
+		{Type: models.SettlementItemRefund, Amount: -50.05},
+		{Type: models.SettlementItemAdjustment, Amount: -10},
+		{Type: models.SettlementItemAdjustment, Amount: 5.5},
+	}
+
+	summary := summarizeStatementItems(items)
+	if summary.OrderCount != 2 {
+		t.Errorf("OrderCount = %d, expected 2", summary.OrderCount)
+	}
+	if summary.OrderAmount != 300.3 {
+		t.Errorf("OrderAmount = %v, expected 300.3", summary.OrderAmount)
+	}
+	if summary.RefundAmount != 50.05 {
+		t.Errorf("RefundAmount = %v, expected 50.05", summary.RefundAmount)
+	}
+	if summary.AdjustmentAmount != -4.5 {
+		t.Errorf("AdjustmentAmount = %v, expected -4.5", summary.AdjustmentAmount)
+	}
+	if summary.PayableAmount != 245.75 {
+		t.Errorf("PayableAmount = %v, expected 245.75", summary.PayableAmount)
+	}
+}
+
+func TestApplyLedgerEntry(t *testing.T) {
+	tests := []struct {
+		name            string
+		entryType       models.SupplierLedgerEntryType
+		amount          float64
+		expectedPayable float64
+		expectedHeld    float64
+		expectedPaid    float64
+	}{
+		{"payable", models.LedgerEntryPayable, 100, 200, 20, 50},
+		{"negative payable", models.LedgerEntryPayable, -30, 70, 20, 50},
+		{"hold", models.LedgerEntryHold, 40, 60, 60, 50},
+		{"release", models.LedgerEntryRelease, 20, 120, 0, 50},
+		{"payout", models.LedgerEntryPayout, 100, 0, 20, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payable, held, paid := applyLedgerEntry(100, 20, 50, tt.entryType, tt.amount)
+			if payable != tt.expectedPayable || held != tt.expectedHeld || paid != tt.expectedPaid {
+				t.Errorf("applyLedgerEntry() = (%v, %v, %v), expected (%v, %v, %v)",
+					payable, held, paid, tt.expectedPayable, tt.expectedHeld, tt.expectedPaid)
+			}
+		})
+	}
+}
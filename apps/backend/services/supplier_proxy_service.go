@@ -3,6 +3,7 @@ package services
 import (
 	"time"
 
+	"github.com/project/backend/models"
 	"gorm.io/gorm"
 )
 
@@ -168,29 +169,23 @@ func (s *SupplierProxyService) ProxyAddDeliveryArea(supplierID uint64, province,
 
 // ProxyConfirmOrder 代管确认订单
 func (s *SupplierProxyService) ProxyConfirmOrder(supplierID uint64, orderID uint64, operatorID uint64, operatorName string) error {
-	// 更新订单状态
-	result := s.db.Table("orders").
-		Where("id = ? AND supplier_id = ? AND status = ?", orderID, supplierID, "pending_confirm").
-		Update("status", "confirmed")
-
-	if result.Error != nil {
-		return result.Error
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
+		OrderID:      orderID,
+		SupplierID:   supplierID,
+		From:         []models.OrderStatus{models.OrderStatusPendingConfirm},
+		To:           models.OrderStatusConfirmed,
+		OperatorType: models.OperatorTypeAdmin,
+		OperatorID:   operatorID,
+		Remark:       "平台代管确认",
+	})
+	if err != nil {
+		return err
 	}
 
 	// 记录操作日志
 	s.logOperation(supplierID, operatorID, operatorName, "confirm_order", "order", orderID,
 		"pending_confirm", "confirmed", "代管确认订单")
 
-	// 记录订单状态日志
-	s.db.Table("order_status_logs").Create(map[string]interface{}{
-		"order_id":    orderID,
-		"from_status": "pending_confirm",
-		"to_status":   "confirmed",
-		"operator_id": operatorID,
-		"remark":      "平台代管确认",
-		"created_at":  time.Now(),
-	})
-
 	return nil
 }
 
@@ -200,29 +195,22 @@ func (s *SupplierProxyService) ProxyUpdateOrderStatus(supplierID uint64, orderID
 	var currentStatus string
 	s.db.Table("orders").Select("status").Where("id = ?", orderID).Scan(&currentStatus)
 
-	// 更新订单状态
-	result := s.db.Table("orders").
-		Where("id = ? AND supplier_id = ?", orderID, supplierID).
-		Update("status", newStatus)
-
-	if result.Error != nil {
-		return result.Error
+	_, err := NewOrderStateMachine(s.db).Transition(&OrderTransition{
+		OrderID:      orderID,
+		SupplierID:   supplierID,
+		To:           models.OrderStatus(newStatus),
+		OperatorType: models.OperatorTypeAdmin,
+		OperatorID:   operatorID,
+		Remark:       "平台代管操作",
+	})
+	if err != nil {
+		return err
 	}
 
 	// 记录操作日志
 	s.logOperation(supplierID, operatorID, operatorName, "update_order_status", "order", orderID,
 		currentStatus, newStatus, "代管更新订单状态")
 
-	// 记录订单状态日志
-	s.db.Table("order_status_logs").Create(map[string]interface{}{
-		"order_id":    orderID,
-		"from_status": currentStatus,
-		"to_status":   newStatus,
-		"operator_id": operatorID,
-		"remark":      "平台代管操作",
-		"created_at":  time.Now(),
-	})
-
 	return nil
 }
 
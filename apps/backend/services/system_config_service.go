@@ -53,8 +53,12 @@ type APIConfig struct {
 type OrderConfig struct {
 	CancelTimeThreshold int     `json:"cancelTimeThreshold"` // 分钟
 	PaymentTimeout      int     `json:"paymentTimeout"`      // 分钟
-	ServiceFeeRate      float64 `json:"serviceFeeRate"`      // 服务费率
-	MinServiceFee       float64 `json:"minServiceFee"`       // 最低服务费
+	ServiceFeeRate      float64 `json:"serviceFeeRate"`      // 默认服务费率，没有匹配的服务费规则时使用
+	MinServiceFee       float64 `json:"minServiceFee"`       // 默认最低服务费
+	AutoCompleteDays    int     `json:"autoCompleteDays"`    // 配送后自动完成天数
+	ConfirmTimeout      int     `json:"confirmTimeout"`      // 供应商确认时限(分钟)，超时通知管理员
+	ConfirmCancelAfter  int     `json:"confirmCancelAfter"`  // 确认超时后再过多久自动取消并退款(分钟)
+	CutoffTime          string  `json:"cutoffTime"`          // 每日截单时间(HH:MM)，截单后下单顺延一天配送
 }
 
 // DeliveryNoteTemplate 送货单模板
@@ -160,6 +164,10 @@ func (s *SystemConfigService) GetOrderConfig() (*OrderConfig, error) {
 		PaymentTimeout:      30,
 		ServiceFeeRate:      0.03,
 		MinServiceFee:       1.0,
+		AutoCompleteDays:    7,
+		ConfirmTimeout:      120,
+		ConfirmCancelAfter:  360,
+		CutoffTime:          "18:00",
 	}
 
 	// 从数据库读取配置
@@ -183,6 +191,24 @@ func (s *SystemConfigService) GetOrderConfig() (*OrderConfig, error) {
 			config.MinServiceFee = v
 		}
 	}
+	if val, err := s.GetConfig("order_auto_complete_days"); err == nil && val != "" {
+		if v, e := strconv.Atoi(val); e == nil {
+			config.AutoCompleteDays = v
+		}
+	}
+	if val, err := s.GetConfig("order_confirm_timeout"); err == nil && val != "" {
+		if v, e := strconv.Atoi(val); e == nil {
+			config.ConfirmTimeout = v
+		}
+	}
+	if val, err := s.GetConfig("order_confirm_cancel_after"); err == nil && val != "" {
+		if v, e := strconv.Atoi(val); e == nil {
+			config.ConfirmCancelAfter = v
+		}
+	}
+	if val, err := s.GetConfig("order_cutoff_time"); err == nil && val != "" {
+		config.CutoffTime = val
+	}
 
 	return config, nil
 }
@@ -195,6 +221,10 @@ func (s *SystemConfigService) SaveOrderConfig(config *OrderConfig) error {
 		"order_payment_timeout":       strconv.Itoa(config.PaymentTimeout),
 		"order_service_fee_rate":      strconv.FormatFloat(config.ServiceFeeRate, 'f', 4, 64),
 		"order_min_service_fee":       strconv.FormatFloat(config.MinServiceFee, 'f', 2, 64),
+		"order_auto_complete_days":    strconv.Itoa(config.AutoCompleteDays),
+		"order_confirm_timeout":       strconv.Itoa(config.ConfirmTimeout),
+		"order_confirm_cancel_after":  strconv.Itoa(config.ConfirmCancelAfter),
+		"order_cutoff_time":           config.CutoffTime,
 	}
 	for key, value := range configs {
 		if err := s.SetConfig(key, value, "string", "", "order"); err != nil {
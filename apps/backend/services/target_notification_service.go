@@ -0,0 +1,81 @@
+package services
+
+import (
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// TargetNotificationService 门店和供应商站内通知服务
+type TargetNotificationService struct {
+	db *gorm.DB
+}
+
+// NewTargetNotificationService 创建门店和供应商通知服务
+func NewTargetNotificationService(db *gorm.DB) *TargetNotificationService {
+	return &TargetNotificationService{db: db}
+}
+
+// Notify 发送通知
+func (s *TargetNotificationService) Notify(notification *models.TargetNotification) error {
+	return s.db.Create(notification).Error
+}
+
+// scope 限定为指定门店或供应商的通知
+func (s *TargetNotificationService) scope(targetType models.WebhookTargetType, targetID uint64) *gorm.DB {
+	return s.db.Model(&models.TargetNotification{}).Where("target_type = ? AND target_id = ?", targetType, targetID)
+}
+
+// List 获取通知列表
+func (s *TargetNotificationService) List(targetType models.WebhookTargetType, targetID uint64, page, pageSize int, unreadOnly bool) ([]models.TargetNotification, int64, error) {
+	var notifications []models.TargetNotification
+	var total int64
+
+	query := s.scope(targetType, targetID)
+	if unreadOnly {
+		query = query.Where("is_read = ?", 0)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// UnreadCount 未读数量
+func (s *TargetNotificationService) UnreadCount(targetType models.WebhookTargetType, targetID uint64) int64 {
+	var count int64
+	s.scope(targetType, targetID).Where("is_read = ?", 0).Count(&count)
+	return count
+}
+
+// MarkRead 标记已读
+func (s *TargetNotificationService) MarkRead(targetType models.WebhookTargetType, targetID, id uint64) error {
+	return s.scope(targetType, targetID).
+		Where("id = ? AND is_read = ?", id, 0).
+		Updates(map[string]interface{}{
+			"is_read": 1,
+			"read_at": time.Now(),
+		}).Error
+}
+
+// MarkAllRead 全部标记已读
+func (s *TargetNotificationService) MarkAllRead(targetType models.WebhookTargetType, targetID uint64) error {
+	return s.scope(targetType, targetID).
+		Where("is_read = ?", 0).
+		Updates(map[string]interface{}{
+			"is_read": 1,
+			"read_at": time.Now(),
+		}).Error
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 推送地址熔断参数
+const (
+	webhookCircuitThreshold   = 5               // 连续失败次数达到后熔断
+	webhookCircuitCooldown    = 5 * time.Minute // 首次熔断后的试探间隔，之后每次翻倍
+	webhookCircuitMaxCooldown = 2 * time.Hour
+	webhookProbeBatchSize     = 50
+	webhookDeadLetterMsg      = "推送地址已熔断，暂停推送"
+)
+
+// webhookCircuitCooldownFor 第 trips 次熔断后的试探间隔
+func webhookCircuitCooldownFor(trips int) time.Duration {
+	cooldown := webhookCircuitCooldown
+	for i := 1; i < trips && cooldown < webhookCircuitMaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > webhookCircuitMaxCooldown {
+		cooldown = webhookCircuitMaxCooldown
+	}
+	return cooldown
+}
+
+// admitWebhookDelivery 判断推送地址当前能否推送：正常时放行；熔断到期或试探租约过期时放行一次作为试探，
+// 并进入试探状态，租约到 leaseUntil；其余情况转入死信
+func admitWebhookDelivery(endpoint *models.WebhookEndpoint, now, leaseUntil time.Time) (admit, probe bool) {
+	switch endpoint.State {
+	case models.WebhookCircuitOpen, models.WebhookCircuitHalfOpen:
+		if endpoint.NextProbeAt != nil && endpoint.NextProbeAt.After(now) {
+			return false, false
+		}
+		endpoint.State = models.WebhookCircuitHalfOpen
+		endpoint.NextProbeAt = &leaseUntil
+		return true, true
+	}
+	return true, false
+}
+
+// applyWebhookOutcome 记录推送结果：成功时恢复正常；试探失败或正常状态下连续失败达到阈值时熔断
+func applyWebhookOutcome(endpoint *models.WebhookEndpoint, success bool, errMsg string, now time.Time) (opened, recovered bool) {
+	if success {
+		recovered = endpoint.State != models.WebhookCircuitClosed
+		endpoint.State = models.WebhookCircuitClosed
+		endpoint.ConsecutiveFailures = 0
+		endpoint.TripCount = 0
+		endpoint.OpenedAt = nil
+		endpoint.NextProbeAt = nil
+		endpoint.LastSuccessAt = &now
+		return false, recovered
+	}
+
+	endpoint.ConsecutiveFailures++
+	endpoint.LastError = truncateRunes(errMsg, 500)
+	endpoint.LastFailureAt = &now
+	switch {
+	case endpoint.State == models.WebhookCircuitHalfOpen:
+		endpoint.State = models.WebhookCircuitOpen
+	case endpoint.State == models.WebhookCircuitClosed && endpoint.ConsecutiveFailures >= webhookCircuitThreshold:
+		endpoint.State = models.WebhookCircuitOpen
+		endpoint.OpenedAt = &now
+		opened = true
+	default:
+		return false, false
+	}
+	endpoint.TripCount++
+	next := now.Add(webhookCircuitCooldownFor(endpoint.TripCount))
+	endpoint.NextProbeAt = &next
+	return opened, false
+}
+
+// lockWebhookEndpoint 加锁读取推送地址的熔断状态，不存在时返回未保存的正常状态
+func lockWebhookEndpoint(tx *gorm.DB, log *models.WebhookLog) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("target_type = ? AND target_id = ? AND webhook_url = ?", log.TargetType, log.TargetID, log.WebhookURL).
+		First(&endpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.WebhookEndpoint{
+			TargetType: log.TargetType,
+			TargetID:   log.TargetID,
+			WebhookURL: log.WebhookURL,
+			State:      models.WebhookCircuitClosed,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// admit 推送前检查熔断状态，返回 false 时推送应转入死信
+func (s *WebhookService) admit(log *models.WebhookLog, leaseUntil time.Time) (bool, error) {
+	admitted := true
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		endpoint, err := lockWebhookEndpoint(tx, log)
+		if err != nil || endpoint.ID == 0 {
+			return err
+		}
+		var probe bool
+		admitted, probe = admitWebhookDelivery(endpoint, time.Now(), leaseUntil)
+		if !probe {
+			return nil
+		}
+		return tx.Model(endpoint).Updates(map[string]interface{}{
+			"state":         endpoint.State,
+			"next_probe_at": endpoint.NextProbeAt,
+		}).Error
+	})
+	return admitted, err
+}
+
+// recordOutcome 记录推送结果并更新熔断状态，熔断或恢复时通知管理员和门店/供应商；返回推送地址是否处于熔断状态
+func (s *WebhookService) recordOutcome(log *models.WebhookLog, result *webhookAttemptResult) bool {
+	var endpoint *models.WebhookEndpoint
+	var opened, recovered bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if endpoint, err = lockWebhookEndpoint(tx, log); err != nil {
+			return err
+		}
+		// 正常的地址推送成功时无需记录
+		if endpoint.ID == 0 && result.Success {
+			return nil
+		}
+		opened, recovered = applyWebhookOutcome(endpoint, result.Success, result.ErrorMsg, time.Now())
+		if err := tx.Save(endpoint).Error; err != nil {
+			return err
+		}
+		if !opened {
+			return nil
+		}
+		// 熔断时已排队的推送一并转入死信，不再占用推送任务
+		return tx.Model(&models.WebhookLog{}).
+			Where("target_type = ? AND target_id = ? AND webhook_url = ? AND id <> ?", log.TargetType, log.TargetID, log.WebhookURL, log.ID).
+			Where("status IN ? AND next_retry_at IS NOT NULL", []models.WebhookStatus{models.WebhookStatusPending, models.WebhookStatusFailed}).
+			Updates(map[string]interface{}{
+				"status":        models.WebhookStatusDead,
+				"next_retry_at": nil,
+				"error_msg":     webhookDeadLetterMsg,
+			}).Error
+	})
+	if err != nil {
+		s.logger.Error("update webhook circuit failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+		return false
+	}
+
+	if opened || recovered {
+		s.notifyCircuit(endpoint, opened)
+	}
+	return endpoint.State != models.WebhookCircuitClosed
+}
+
+// notifyCircuit 推送地址熔断或恢复时通知管理员，同时给门店或供应商发送站内通知
+func (s *WebhookService) notifyCircuit(endpoint *models.WebhookEndpoint, opened bool) {
+	notificationType := models.AdminNotificationWebhookRecovered
+	if opened {
+		notificationType = models.AdminNotificationWebhookCircuitOpen
+	}
+	title, adminContent, targetContent := webhookCircuitMessages(endpoint, s.targetLabel(endpoint), opened)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(models.NewWebhookNotification(notificationType, endpoint.TargetType, endpoint.TargetID,
+			title, truncateRunes(adminContent, 1000))).Error; err != nil {
+			return err
+		}
+		return tx.Create(models.NewTargetNotification(notificationType, endpoint.TargetType, endpoint.TargetID,
+			title, truncateRunes(targetContent, 1000))).Error
+	})
+	if err != nil {
+		s.logger.Warn("notify webhook circuit failed", zap.Uint64("endpointId", endpoint.ID), zap.Error(err))
+	}
+	s.logger.Warn(title,
+		zap.String("targetType", string(endpoint.TargetType)),
+		zap.Uint64("targetId", endpoint.TargetID),
+		zap.String("lastError", endpoint.LastError))
+}
+
+// webhookCircuitMessages 熔断或恢复通知的标题、管理员通知内容和门店/供应商通知内容
+// 死信只能由管理员重新推送，门店和供应商的通知提示其修复接收服务并联系平台
+func webhookCircuitMessages(endpoint *models.WebhookEndpoint, label string, opened bool) (title, adminContent, targetContent string) {
+	if !opened {
+		return "Webhook推送地址已恢复",
+			fmt.Sprintf("%s 的推送地址试探成功，已恢复推送，熔断期间的死信需在推送日志中处理", label),
+			fmt.Sprintf("您的推送地址 %s 已恢复推送。熔断期间未送达的消息不会自动补发，如需补发请联系平台", endpoint.WebhookURL)
+	}
+
+	probeAt := ""
+	if endpoint.NextProbeAt != nil {
+		probeAt = endpoint.NextProbeAt.Format("2006-01-02 15:04:05")
+	}
+	return "Webhook推送地址已熔断",
+		fmt.Sprintf("%s 的推送地址连续 %d 次推送失败，已暂停推送，后续推送转入死信，将在 %s 自动试探。最近错误：%s",
+			label, endpoint.ConsecutiveFailures, probeAt, endpoint.LastError),
+		fmt.Sprintf("您的推送地址 %s 连续 %d 次推送失败，已暂停推送，期间的推送消息将不会送达。请尽快检查接收服务，系统将在 %s 自动重试。最近错误：%s",
+			endpoint.WebhookURL, endpoint.ConsecutiveFailures, probeAt, endpoint.LastError)
+}
+
+// targetLabel 通知中的目标名称
+func (s *WebhookService) targetLabel(endpoint *models.WebhookEndpoint) string {
+	var name string
+	if endpoint.TargetType == models.WebhookTargetStore {
+		s.db.Model(&models.Store{}).Select("name").Where("id = ?", endpoint.TargetID).Scan(&name)
+		return "门店「" + name + "」"
+	}
+	s.db.Model(&models.Supplier{}).Select("name").Where("id = ?", endpoint.TargetID).Scan(&name)
+	return "供应商「" + name + "」"
+}
+
+// deadLetter 推送转入死信，不记录推送尝试
+func (s *WebhookService) deadLetter(log *models.WebhookLog) {
+	if err := s.db.Model(&models.WebhookLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+		"status":        models.WebhookStatusDead,
+		"next_retry_at": nil,
+		"error_msg":     webhookDeadLetterMsg,
+	}).Error; err != nil {
+		s.logger.Error("dead-letter webhook failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+	}
+}
+
+// scheduleProbes 熔断到期的推送地址取最早的一条死信重新排队，投递时作为试探；
+// 没有死信的地址在下一次推送时试探
+func (s *WebhookService) scheduleProbes() error {
+	var endpoints []models.WebhookEndpoint
+	if err := s.db.Where("state = ? AND next_probe_at <= ?", models.WebhookCircuitOpen, time.Now()).
+		Limit(webhookProbeBatchSize).
+		Find(&endpoints).Error; err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		var log models.WebhookLog
+		err := s.db.Select("id").
+			Where("target_type = ? AND target_id = ? AND webhook_url = ? AND status = ?",
+				endpoint.TargetType, endpoint.TargetID, endpoint.WebhookURL, models.WebhookStatusDead).
+			Order("id ASC").
+			First(&log).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.db.Model(&models.WebhookLog{}).
+			Where("id = ? AND status = ?", log.ID, models.WebhookStatusDead).
+			Updates(map[string]interface{}{
+				"status":        models.WebhookStatusPending,
+				"retry_count":   0,
+				"next_retry_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
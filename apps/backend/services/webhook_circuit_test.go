@@ -0,0 +1,159 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestWebhookCircuitCooldownFor(t *testing.T) {
+	tests := []struct {
+		trips    int
+		expected time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{6, 2 * time.Hour},
+		{20, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookCircuitCooldownFor(tt.trips); got != tt.expected {
+			t.Errorf("webhookCircuitCooldownFor(%d) = %v, expected %v", tt.trips, got, tt.expected)
+		}
+	}
+}
+
+func TestAdmitWebhookDelivery(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	lease := now.Add(time.Minute)
+	future := now.Add(time.Minute)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		state     models.WebhookCircuitState
+		probeAt   *time.Time
+		admit     bool
+		probe     bool
+		nextState models.WebhookCircuitState
+	}{
+		{"closed", models.WebhookCircuitClosed, nil, true, false, models.WebhookCircuitClosed},
+		{"open cooling down", models.WebhookCircuitOpen, &future, false, false, models.WebhookCircuitOpen},
+		{"open due", models.WebhookCircuitOpen, &past, true, true, models.WebhookCircuitHalfOpen},
+		{"probe in flight", models.WebhookCircuitHalfOpen, &future, false, false, models.WebhookCircuitHalfOpen},
+		{"probe lease expired", models.WebhookCircuitHalfOpen, &past, true, true, models.WebhookCircuitHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &models.WebhookEndpoint{State: tt.state, NextProbeAt: tt.probeAt}
+			admit, probe := admitWebhookDelivery(endpoint, now, lease)
+			if admit != tt.admit || probe != tt.probe {
+				t.Errorf("admitWebhookDelivery() = %v, %v, expected %v, %v", admit, probe, tt.admit, tt.probe)
+			}
+			if endpoint.State != tt.nextState {
+				t.Errorf("state = %v, expected %v", endpoint.State, tt.nextState)
+			}
+			if probe && !endpoint.NextProbeAt.Equal(lease) {
+				t.Errorf("nextProbeAt = %v, expected lease %v", endpoint.NextProbeAt, lease)
+			}
+		})
+	}
+}
+
+func TestApplyWebhookOutcome(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+
+	t.Run("opens after threshold", func(t *testing.T) {
+		endpoint := &models.WebhookEndpoint{State: models.WebhookCircuitClosed}
+		for i := 1; i < webhookCircuitThreshold; i++ {
+			if opened, _ := applyWebhookOutcome(endpoint, false, "timeout", now); opened {
+				t.Fatalf("opened after %d failures", i)
+			}
+		}
+		opened, recovered := applyWebhookOutcome(endpoint, false, "timeout", now)
+		if !opened || recovered {
+			t.Fatalf("applyWebhookOutcome() = %v, %v, expected open", opened, recovered)
+		}
+		if endpoint.State != models.WebhookCircuitOpen || endpoint.TripCount != 1 {
+			t.Errorf("state = %v trips = %d, expected open with 1 trip", endpoint.State, endpoint.TripCount)
+		}
+		if !endpoint.NextProbeAt.Equal(now.Add(5 * time.Minute)) {
+			t.Errorf("nextProbeAt = %v, expected %v", endpoint.NextProbeAt, now.Add(5*time.Minute))
+		}
+	})
+
+	t.Run("failed probe reopens with longer cooldown", func(t *testing.T) {
+		endpoint := &models.WebhookEndpoint{State: models.WebhookCircuitHalfOpen, TripCount: 1, ConsecutiveFailures: 5}
+		opened, recovered := applyWebhookOutcome(endpoint, false, "404", now)
+		if opened || recovered {
+			t.Errorf("applyWebhookOutcome() = %v, %v, expected no notification", opened, recovered)
+		}
+		if endpoint.State != models.WebhookCircuitOpen || endpoint.TripCount != 2 {
+			t.Errorf("state = %v trips = %d, expected open with 2 trips", endpoint.State, endpoint.TripCount)
+		}
+		if !endpoint.NextProbeAt.Equal(now.Add(10 * time.Minute)) {
+			t.Errorf("nextProbeAt = %v, expected %v", endpoint.NextProbeAt, now.Add(10*time.Minute))
+		}
+	})
+
+	t.Run("successful probe recovers", func(t *testing.T) {
+		probeAt := now
+		endpoint := &models.WebhookEndpoint{State: models.WebhookCircuitHalfOpen, TripCount: 3, ConsecutiveFailures: 7, NextProbeAt: &probeAt}
+		opened, recovered := applyWebhookOutcome(endpoint, true, "", now)
+		if opened || !recovered {
+			t.Errorf("applyWebhookOutcome() = %v, %v, expected recovered", opened, recovered)
+		}
+		if endpoint.State != models.WebhookCircuitClosed || endpoint.TripCount != 0 || endpoint.ConsecutiveFailures != 0 || endpoint.NextProbeAt != nil {
+			t.Errorf("endpoint not reset: %+v", endpoint)
+		}
+	})
+
+	t.Run("success on closed endpoint", func(t *testing.T) {
+		endpoint := &models.WebhookEndpoint{State: models.WebhookCircuitClosed, ConsecutiveFailures: 2}
+		if opened, recovered := applyWebhookOutcome(endpoint, true, "", now); opened || recovered {
+			t.Errorf("applyWebhookOutcome() = %v, %v, expected no change", opened, recovered)
+		}
+		if endpoint.ConsecutiveFailures != 0 {
+			t.Errorf("consecutiveFailures = %d, expected 0", endpoint.ConsecutiveFailures)
+		}
+	})
+}
+
+func TestWebhookCircuitMessages(t *testing.T) {
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.Local)
+	endpoint := &models.WebhookEndpoint{
+		TargetType:          models.WebhookTargetStore,
+		TargetID:            3,
+		WebhookURL:          "https://example.com/hook",
+		State:               models.WebhookCircuitClosed,
+		ConsecutiveFailures: webhookCircuitThreshold - 1,
+	}
+	if opened, _ := applyWebhookOutcome(endpoint, false, "timeout", now); !opened {
+		t.Fatal("applyWebhookOutcome() did not open the circuit")
+	}
+
+	title, adminContent, targetContent := webhookCircuitMessages(endpoint, "门店「测试店」", true)
+	if title != "Webhook推送地址已熔断" {
+		t.Errorf("title = %q", title)
+	}
+	if !strings.Contains(adminContent, "门店「测试店」") || !strings.Contains(adminContent, "2026-10-17 10:05:00") {
+		t.Errorf("admin content = %q, expected target label and probe time", adminContent)
+	}
+	// 门店和供应商的通知不含其他目标信息，提示自己的推送地址和重试时间
+	for _, want := range []string{"https://example.com/hook", "2026-10-17 10:05:00", "timeout"} {
+		if !strings.Contains(targetContent, want) {
+			t.Errorf("target content = %q, expected to contain %q", targetContent, want)
+		}
+	}
+	if strings.Contains(targetContent, "死信") {
+		t.Errorf("target content = %q, should not mention dead letters", targetContent)
+	}
+
+	title, _, targetContent = webhookCircuitMessages(endpoint, "门店「测试店」", false)
+	if title != "Webhook推送地址已恢复" || !strings.Contains(targetContent, "已恢复推送") {
+		t.Errorf("recovered message = %q, %q", title, targetContent)
+	}
+}
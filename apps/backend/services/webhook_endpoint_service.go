@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// 推送地址错误
+var (
+	ErrWebhookEndpointNotFound = errors.New("推送地址不存在")
+	ErrWebhookCircuitOpen      = errors.New("推送地址仍处于熔断状态，请先恢复后再重发死信")
+	ErrWebhookDrainInvalid     = errors.New("死信处理方式错误")
+)
+
+// 死信处理方式
+const (
+	WebhookDrainResend  = "resend"  // 重新排队推送
+	WebhookDrainDiscard = "discard" // 标记为最终失败
+)
+
+const webhookDiscardedMsg = "死信已丢弃"
+
+// WebhookEndpointItem 推送地址熔断状态及死信数量
+type WebhookEndpointItem struct {
+	models.WebhookEndpoint
+	TargetName string `json:"targetName"`
+	DeadCount  int64  `json:"deadCount"`
+}
+
+// WebhookDrainResult 死信处理结果
+type WebhookDrainResult struct {
+	Action string `json:"action"`
+	Count  int64  `json:"count"`
+}
+
+// ListEndpoints 分页查询推送地址的熔断状态，state 和目标为空时不过滤
+func (s *WebhookLogService) ListEndpoints(state models.WebhookCircuitState, targetType models.WebhookTargetType, targetID uint64, page, pageSize int) ([]WebhookEndpointItem, int64, error) {
+	query := s.db.Model(&models.WebhookEndpoint{})
+	switch state {
+	case "":
+	case models.WebhookCircuitClosed, models.WebhookCircuitOpen, models.WebhookCircuitHalfOpen:
+		query = query.Where("state = ?", state)
+	default:
+		return nil, 0, fmt.Errorf("%w: 熔断状态错误", ErrWebhookLogQueryInvalid)
+	}
+	switch targetType {
+	case "":
+	case models.WebhookTargetStore, models.WebhookTargetSupplier:
+		query = query.Where("target_type = ?", targetType)
+	default:
+		return nil, 0, fmt.Errorf("%w: 目标类型错误", ErrWebhookLogQueryInvalid)
+	}
+	if targetID > 0 {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := query.Order("FIELD(state, 'open', 'half_open', 'closed')").
+		Order("updated_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&endpoints).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items, err := s.endpointItems(endpoints)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// TargetEndpoints 门店或供应商查看自己推送地址的熔断状态、最近错误和死信数量
+func (s *WebhookLogService) TargetEndpoints(targetType models.WebhookTargetType, targetID uint64) ([]WebhookEndpointItem, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := s.db.Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("updated_at DESC").
+		Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return s.endpointItems(endpoints)
+}
+
+// ResetEndpoint 手动恢复推送地址，清除熔断状态和失败计数，死信需另行处理
+func (s *WebhookLogService) ResetEndpoint(id uint64) (*WebhookEndpointItem, error) {
+	endpoint, err := s.endpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(endpoint).Updates(map[string]interface{}{
+		"state":                models.WebhookCircuitClosed,
+		"consecutive_failures": 0,
+		"trip_count":           0,
+		"opened_at":            nil,
+		"next_probe_at":        nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	endpoint, err = s.endpoint(id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.endpointItems([]models.WebhookEndpoint{*endpoint})
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// DrainEndpoint 处理推送地址的死信：resend 重新排队由推送任务投递，熔断中不允许；discard 标记为最终失败
+func (s *WebhookLogService) DrainEndpoint(id uint64, action string) (*WebhookDrainResult, error) {
+	endpoint, err := s.endpoint(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates map[string]interface{}
+	switch action {
+	case WebhookDrainResend:
+		if endpoint.State != models.WebhookCircuitClosed {
+			return nil, ErrWebhookCircuitOpen
+		}
+		updates = map[string]interface{}{
+			"status":        models.WebhookStatusPending,
+			"retry_count":   0,
+			"next_retry_at": time.Now(),
+		}
+	case WebhookDrainDiscard:
+		updates = map[string]interface{}{
+			"status":    models.WebhookStatusFailed,
+			"error_msg": webhookDiscardedMsg,
+		}
+	default:
+		return nil, ErrWebhookDrainInvalid
+	}
+
+	result := s.db.Model(&models.WebhookLog{}).
+		Where("target_type = ? AND target_id = ? AND webhook_url = ? AND status = ?",
+			endpoint.TargetType, endpoint.TargetID, endpoint.WebhookURL, models.WebhookStatusDead).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &WebhookDrainResult{Action: action, Count: result.RowsAffected}, nil
+}
+
+// endpoint 查询推送地址
+func (s *WebhookLogService) endpoint(id uint64) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := s.db.First(&endpoint, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookEndpointNotFound
+		}
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// endpointItems 补充目标名称和死信数量
+func (s *WebhookLogService) endpointItems(endpoints []models.WebhookEndpoint) ([]WebhookEndpointItem, error) {
+	items := make([]WebhookEndpointItem, 0, len(endpoints))
+	if len(endpoints) == 0 {
+		return items, nil
+	}
+
+	var storeIDs, supplierIDs []uint64
+	for _, endpoint := range endpoints {
+		if endpoint.TargetType == models.WebhookTargetStore {
+			storeIDs = append(storeIDs, endpoint.TargetID)
+		} else {
+			supplierIDs = append(supplierIDs, endpoint.TargetID)
+		}
+	}
+	names, err := s.targetNames(storeIDs, supplierIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		TargetType models.WebhookTargetType
+		TargetID   uint64
+		WebhookURL string
+		Total      int64
+	}
+	if err := s.db.Model(&models.WebhookLog{}).
+		Select("target_type, target_id, webhook_url, COUNT(*) AS total").
+		Where("status = ? AND target_id IN ?", models.WebhookStatusDead, append(storeIDs, supplierIDs...)).
+		Group("target_type, target_id, webhook_url").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	dead := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		dead[fmt.Sprintf("%s:%d:%s", c.TargetType, c.TargetID, c.WebhookURL)] = c.Total
+	}
+
+	for _, endpoint := range endpoints {
+		items = append(items, WebhookEndpointItem{
+			WebhookEndpoint: endpoint,
+			TargetName:      names[webhookTargetKey{endpoint.TargetType, endpoint.TargetID}],
+			DeadCount:       dead[fmt.Sprintf("%s:%d:%s", endpoint.TargetType, endpoint.TargetID, endpoint.WebhookURL)],
+		})
+	}
+	return items, nil
+}
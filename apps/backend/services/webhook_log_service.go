@@ -0,0 +1,582 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/project/backend/types"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 推送日志错误
+var (
+	ErrWebhookLogNotFound     = errors.New("推送记录不存在")
+	ErrWebhookLogQueryInvalid = errors.New("查询参数无效")
+	ErrWebhookLogPending      = errors.New("推送记录正在等待投递，无需重发")
+)
+
+// 推送日志查询参数
+const (
+	maxWebhookResendBatch    = 1000
+	maxWebhookHealthDays     = 92
+	defaultWebhookHealthDays = 7
+)
+
+// WebhookLogService 推送日志查询、重发和推送地址健康统计
+type WebhookLogService struct {
+	db       *gorm.DB
+	delivery *WebhookService
+}
+
+// NewWebhookLogService 创建推送日志服务
+func NewWebhookLogService(db *gorm.DB, delivery *WebhookService) *WebhookLogService {
+	return &WebhookLogService{db: db, delivery: delivery}
+}
+
+// WebhookLogFilter 推送日志筛选条件，EndDate 不包含
+type WebhookLogFilter struct {
+	TargetType models.WebhookTargetType
+	TargetID   uint64
+	EventType  models.WebhookEventType
+	Status     models.WebhookStatus
+	OrderNo    string
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+// NewWebhookLogFilter 由查询参数生成筛选条件，日期为 yyyy-mm-dd，事件支持 order.created 和 new_order 两种写法
+func NewWebhookLogFilter(params *types.WebhookQueryParams) (*WebhookLogFilter, error) {
+	filter := &WebhookLogFilter{
+		TargetType: models.WebhookTargetType(params.TargetType),
+		TargetID:   params.TargetID,
+		Status:     models.WebhookStatus(params.Status),
+		OrderNo:    params.OrderNo,
+	}
+	switch filter.TargetType {
+	case "", models.WebhookTargetStore, models.WebhookTargetSupplier:
+	default:
+		return nil, fmt.Errorf("%w: 目标类型错误", ErrWebhookLogQueryInvalid)
+	}
+	switch filter.Status {
+	case "", models.WebhookStatusPending, models.WebhookStatusSuccess, models.WebhookStatusFailed, models.WebhookStatusDead:
+	default:
+		return nil, fmt.Errorf("%w: 推送状态错误", ErrWebhookLogQueryInvalid)
+	}
+	if params.Event != "" {
+		event, ok := parseWebhookLogEvent(string(params.Event))
+		if !ok {
+			return nil, fmt.Errorf("%w: 事件类型错误", ErrWebhookLogQueryInvalid)
+		}
+		filter.EventType = event
+	}
+	if params.StartDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 开始日期格式错误", ErrWebhookLogQueryInvalid)
+		}
+		filter.StartDate = &t
+	}
+	if params.EndDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 结束日期格式错误", ErrWebhookLogQueryInvalid)
+		}
+		t = t.AddDate(0, 0, 1)
+		filter.EndDate = &t
+	}
+	if filter.StartDate != nil && filter.EndDate != nil && !filter.StartDate.Before(*filter.EndDate) {
+		return nil, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrWebhookLogQueryInvalid)
+	}
+	return filter, nil
+}
+
+// parseWebhookLogEvent 解析事件类型，兼容通用推送中的事件名
+func parseWebhookLogEvent(name string) (models.WebhookEventType, bool) {
+	for _, event := range webhookTemplateEvents {
+		if string(event) == name || string(genericWebhookEvents[event]) == name {
+			return event, true
+		}
+	}
+	return "", false
+}
+
+// apply 按筛选条件过滤，prefix 为 webhook_logs 的列名前缀，createdColumn 为日期筛选的列
+func (f *WebhookLogFilter) apply(query *gorm.DB, prefix, createdColumn string) *gorm.DB {
+	if f.TargetType != "" {
+		query = query.Where(prefix+"target_type = ?", f.TargetType)
+	}
+	if f.TargetID > 0 {
+		query = query.Where(prefix+"target_id = ?", f.TargetID)
+	}
+	if f.EventType != "" {
+		query = query.Where(prefix+"event_type = ?", f.EventType)
+	}
+	if f.Status != "" {
+		query = query.Where(prefix+"status = ?", f.Status)
+	}
+	if f.OrderNo != "" {
+		orders := query.Session(&gorm.Session{NewDB: true}).Model(&models.Order{}).Select("id").Where("order_no = ?", f.OrderNo)
+		query = query.Where(prefix+"order_id IN (?)", orders)
+	}
+	if f.StartDate != nil {
+		query = query.Where(createdColumn+" >= ?", *f.StartDate)
+	}
+	if f.EndDate != nil {
+		query = query.Where(createdColumn+" < ?", *f.EndDate)
+	}
+	return query
+}
+
+// WebhookLogItem 推送日志列表项
+type WebhookLogItem struct {
+	models.WebhookLog
+	TargetName string `json:"targetName"`
+	OrderNo    string `json:"orderNo"`
+}
+
+// WebhookLogDetail 推送日志详情，包含每次尝试的请求结果
+type WebhookLogDetail struct {
+	WebhookLogItem
+	Attempts []models.WebhookAttempt `json:"attempts"`
+}
+
+// List 分页查询推送日志，列表不返回请求和响应内容
+func (s *WebhookLogService) List(filter *WebhookLogFilter, page, pageSize int) ([]WebhookLogItem, int64, error) {
+	query := filter.apply(s.db.Model(&models.WebhookLog{}), "", "created_at")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []models.WebhookLog
+	if err := query.Omit("request_headers", "request_body", "response_body").
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items, err := s.items(logs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Get 推送日志详情
+func (s *WebhookLogService) Get(id uint64) (*WebhookLogDetail, error) {
+	var log models.WebhookLog
+	if err := s.db.First(&log, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookLogNotFound
+		}
+		return nil, err
+	}
+
+	items, err := s.items([]models.WebhookLog{log})
+	if err != nil {
+		return nil, err
+	}
+	detail := &WebhookLogDetail{WebhookLogItem: items[0], Attempts: []models.WebhookAttempt{}}
+	if err := s.db.Where("webhook_log_id = ?", id).Order("id ASC").Find(&detail.Attempts).Error; err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// Resend 立即重发一条推送记录并返回结果，重发后按目标设置重新计算重试次数
+func (s *WebhookLogService) Resend(ctx context.Context, id uint64) (*WebhookLogDetail, error) {
+	var log models.WebhookLog
+	if err := s.db.First(&log, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookLogNotFound
+		}
+		return nil, err
+	}
+	if log.Status == models.WebhookStatusPending {
+		return nil, ErrWebhookLogPending
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.WebhookLog{}).
+		Where("id = ? AND status <> ?", id, models.WebhookStatusPending).
+		Updates(map[string]interface{}{
+			"status":        models.WebhookStatusPending,
+			"retry_count":   0,
+			"next_retry_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookLogPending
+	}
+	if err := s.db.First(&log, id).Error; err != nil {
+		return nil, err
+	}
+
+	// 未抢占到时由推送任务投递
+	s.delivery.Deliver(ctx, &log)
+	return s.Get(id)
+}
+
+// WebhookResendRequest 批量重发，指定 IDs 时只重发其中已失败的记录，否则按筛选条件重发
+type WebhookResendRequest struct {
+	IDs    []uint64
+	Filter *WebhookLogFilter
+}
+
+// WebhookResendResult 批量重发结果
+type WebhookResendResult struct {
+	Count   int64 `json:"count"`
+	HasMore bool  `json:"hasMore"` // 超出单次上限，可再次调用
+}
+
+// ResendFailed 将已失败且不再重试的记录重新加入推送队列，由推送任务投递
+func (s *WebhookLogService) ResendFailed(req *WebhookResendRequest) (*WebhookResendResult, error) {
+	query := s.db.Model(&models.WebhookLog{}).
+		Where("status = ? AND next_retry_at IS NULL", models.WebhookStatusFailed)
+	if len(req.IDs) > 0 {
+		if len(req.IDs) > maxWebhookResendBatch {
+			return nil, fmt.Errorf("%w: 单次最多重发 %d 条", ErrWebhookLogQueryInvalid, maxWebhookResendBatch)
+		}
+		query = query.Where("id IN ?", req.IDs)
+	} else if req.Filter != nil {
+		filter := *req.Filter
+		filter.Status = ""
+		query = filter.apply(query, "", "created_at")
+	}
+
+	var ids []uint64
+	if err := query.Order("id ASC").Limit(maxWebhookResendBatch+1).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	result := &WebhookResendResult{}
+	if len(ids) > maxWebhookResendBatch {
+		ids = ids[:maxWebhookResendBatch]
+		result.HasMore = true
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	updated := s.db.Model(&models.WebhookLog{}).
+		Where("id IN ? AND status = ? AND next_retry_at IS NULL", ids, models.WebhookStatusFailed).
+		Updates(map[string]interface{}{
+			"status":        models.WebhookStatusPending,
+			"retry_count":   0,
+			"next_retry_at": time.Now(),
+		})
+	if updated.Error != nil {
+		return nil, updated.Error
+	}
+	result.Count = updated.RowsAffected
+	return result, nil
+}
+
+// items 补充目标名称和订单号
+func (s *WebhookLogService) items(logs []models.WebhookLog) ([]WebhookLogItem, error) {
+	var storeIDs, supplierIDs, orderIDs []uint64
+	for _, log := range logs {
+		if log.TargetType == models.WebhookTargetStore {
+			storeIDs = append(storeIDs, log.TargetID)
+		} else {
+			supplierIDs = append(supplierIDs, log.TargetID)
+		}
+		orderIDs = append(orderIDs, log.OrderID)
+	}
+	names, err := s.targetNames(storeIDs, supplierIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	orderNos := make(map[uint64]string)
+	if len(orderIDs) > 0 {
+		var orders []models.Order
+		if err := s.db.Select("id", "order_no").Where("id IN ?", orderIDs).Find(&orders).Error; err != nil {
+			return nil, err
+		}
+		for _, order := range orders {
+			orderNos[order.ID] = order.OrderNo
+		}
+	}
+
+	items := make([]WebhookLogItem, 0, len(logs))
+	for _, log := range logs {
+		items = append(items, WebhookLogItem{
+			WebhookLog: log,
+			TargetName: names[webhookTargetKey{log.TargetType, log.TargetID}],
+			OrderNo:    orderNos[log.OrderID],
+		})
+	}
+	return items, nil
+}
+
+// webhookTargetKey 推送目标
+type webhookTargetKey struct {
+	Type models.WebhookTargetType
+	ID   uint64
+}
+
+// targetNames 门店和供应商名称
+func (s *WebhookLogService) targetNames(storeIDs, supplierIDs []uint64) (map[webhookTargetKey]string, error) {
+	names := make(map[webhookTargetKey]string)
+	if len(storeIDs) > 0 {
+		var stores []models.Store
+		if err := s.db.Select("id", "name").Where("id IN ?", storeIDs).Find(&stores).Error; err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			names[webhookTargetKey{models.WebhookTargetStore, store.ID}] = store.Name
+		}
+	}
+	if len(supplierIDs) > 0 {
+		var suppliers []models.Supplier
+		if err := s.db.Select("id", "name").Where("id IN ?", supplierIDs).Find(&suppliers).Error; err != nil {
+			return nil, err
+		}
+		for _, supplier := range suppliers {
+			names[webhookTargetKey{models.WebhookTargetSupplier, supplier.ID}] = supplier.Name
+		}
+	}
+	return names, nil
+}
+
+// WebhookEndpointHealth 推送地址健康统计
+// 推送成功率 = 成功 / (成功 + 最终失败 + 死信)，不含等待投递和重试中的记录；请求成功率按每次尝试统计
+type WebhookEndpointHealth struct {
+	TargetType         models.WebhookTargetType   `json:"targetType"`
+	TargetID           uint64                     `json:"targetId"`
+	TargetName         string                     `json:"targetName"`
+	WebhookURL         string                     `json:"webhookUrl"`
+	CircuitState       models.WebhookCircuitState `json:"circuitState"`
+	Deliveries         int64                      `json:"deliveries"`
+	Succeeded          int64                      `json:"succeeded"`
+	Failed             int64                      `json:"failed"`
+	Retrying           int64                      `json:"retrying"`
+	Pending            int64                      `json:"pending"`
+	Dead               int64                      `json:"dead"`
+	SuccessRate        float64                    `json:"successRate"`
+	Attempts           int64                      `json:"attempts"`
+	AttemptSuccessRate float64                    `json:"attemptSuccessRate"`
+	AvgDurationMs      float64                    `json:"avgDurationMs"`
+	MaxDurationMs      int                        `json:"maxDurationMs"`
+	LastSuccessAt      *time.Time                 `json:"lastSuccessAt,omitempty"`
+	LastFailureAt      *time.Time                 `json:"lastFailureAt,omitempty"`
+}
+
+// WebhookHealthReport 推送地址健康统计，按推送成功率从低到高排列
+type WebhookHealthReport struct {
+	StartDate time.Time               `json:"startDate"`
+	EndDate   time.Time               `json:"endDate"`
+	Summary   WebhookEndpointHealth   `json:"summary"`
+	Endpoints []WebhookEndpointHealth `json:"endpoints"`
+}
+
+// webhookDeliveryRow 按推送地址汇总的推送记录
+type webhookDeliveryRow struct {
+	TargetType models.WebhookTargetType
+	TargetID   uint64
+	WebhookURL string
+	Deliveries int64
+	Succeeded  int64
+	Failed     int64
+	Retrying   int64
+	Pending    int64
+	Dead       int64
+}
+
+// webhookAttemptRow 按推送地址汇总的推送尝试
+type webhookAttemptRow struct {
+	TargetType      models.WebhookTargetType
+	TargetID        uint64
+	WebhookURL      string
+	Attempts        int64
+	SuccessAttempts int64
+	TotalDurationMs int64
+	MaxDurationMs   int
+	LastSuccessAt   *time.Time
+	LastFailureAt   *time.Time
+}
+
+// Health 统计各推送地址的成功率和耗时，未指定日期时统计近7天
+// 推送记录按创建时间、尝试按尝试时间落入统计区间
+func (s *WebhookLogService) Health(filter *WebhookLogFilter) (*WebhookHealthReport, error) {
+	f := *filter
+	today := time.Now()
+	if f.EndDate == nil {
+		end := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+		f.EndDate = &end
+	}
+	if f.StartDate == nil {
+		start := f.EndDate.AddDate(0, 0, -defaultWebhookHealthDays)
+		f.StartDate = &start
+	}
+	if f.EndDate.Sub(*f.StartDate) > maxWebhookHealthDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: 统计区间不能超过 %d 天", ErrWebhookLogQueryInvalid, maxWebhookHealthDays)
+	}
+	f.Status = ""
+
+	var deliveries []webhookDeliveryRow
+	if err := f.apply(s.db.Model(&models.WebhookLog{}), "", "created_at").
+		Select(`target_type, target_id, webhook_url,
+			COUNT(*) AS deliveries,
+			SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS succeeded,
+			SUM(CASE WHEN status = 'failed' AND next_retry_at IS NULL THEN 1 ELSE 0 END) AS failed,
+			SUM(CASE WHEN status = 'failed' AND next_retry_at IS NOT NULL THEN 1 ELSE 0 END) AS retrying,
+			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) AS pending,
+			SUM(CASE WHEN status = 'dead' THEN 1 ELSE 0 END) AS dead`).
+		Group("target_type, target_id, webhook_url").
+		Scan(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	var attempts []webhookAttemptRow
+	if err := f.apply(s.db.Table("webhook_attempts AS a").
+		Joins("JOIN webhook_logs AS l ON l.id = a.webhook_log_id AND l.deleted_at IS NULL"), "l.", "a.created_at").
+		Select(`l.target_type, l.target_id, l.webhook_url,
+			COUNT(*) AS attempts,
+			SUM(CASE WHEN a.success THEN 1 ELSE 0 END) AS success_attempts,
+			SUM(a.duration_ms) AS total_duration_ms,
+			MAX(a.duration_ms) AS max_duration_ms,
+			MAX(CASE WHEN a.success THEN a.created_at END) AS last_success_at,
+			MAX(CASE WHEN a.success THEN NULL ELSE a.created_at END) AS last_failure_at`).
+		Group("l.target_type, l.target_id, l.webhook_url").
+		Scan(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	report := mergeWebhookHealth(deliveries, attempts)
+	report.StartDate = *f.StartDate
+	report.EndDate = f.EndDate.AddDate(0, 0, -1)
+
+	var storeIDs, supplierIDs []uint64
+	for _, e := range report.Endpoints {
+		if e.TargetType == models.WebhookTargetStore {
+			storeIDs = append(storeIDs, e.TargetID)
+		} else {
+			supplierIDs = append(supplierIDs, e.TargetID)
+		}
+	}
+	names, err := s.targetNames(storeIDs, supplierIDs)
+	if err != nil {
+		return nil, err
+	}
+	var circuits []models.WebhookEndpoint
+	if err := s.db.Select("target_type", "target_id", "webhook_url", "state").
+		Where("state <> ?", models.WebhookCircuitClosed).
+		Find(&circuits).Error; err != nil {
+		return nil, err
+	}
+	states := make(map[string]models.WebhookCircuitState, len(circuits))
+	for _, c := range circuits {
+		states[fmt.Sprintf("%s:%d:%s", c.TargetType, c.TargetID, c.WebhookURL)] = c.State
+	}
+	for i := range report.Endpoints {
+		e := &report.Endpoints[i]
+		e.TargetName = names[webhookTargetKey{e.TargetType, e.TargetID}]
+		e.CircuitState = models.WebhookCircuitClosed
+		if state, ok := states[fmt.Sprintf("%s:%d:%s", e.TargetType, e.TargetID, e.WebhookURL)]; ok {
+			e.CircuitState = state
+		}
+	}
+	return report, nil
+}
+
+// mergeWebhookHealth 合并推送记录和尝试的汇总，计算成功率和平均耗时
+func mergeWebhookHealth(deliveries []webhookDeliveryRow, attempts []webhookAttemptRow) *WebhookHealthReport {
+	type endpointKey struct {
+		Type models.WebhookTargetType
+		ID   uint64
+		URL  string
+	}
+	index := make(map[endpointKey]int)
+	report := &WebhookHealthReport{Endpoints: []WebhookEndpointHealth{}}
+	endpoint := func(key endpointKey) *WebhookEndpointHealth {
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Endpoints)
+			index[key] = i
+			report.Endpoints = append(report.Endpoints, WebhookEndpointHealth{TargetType: key.Type, TargetID: key.ID, WebhookURL: key.URL})
+		}
+		return &report.Endpoints[i]
+	}
+
+	var successAttempts, totalDuration int64
+	summary := &report.Summary
+	for _, row := range deliveries {
+		e := endpoint(endpointKey{row.TargetType, row.TargetID, row.WebhookURL})
+		e.Deliveries, e.Succeeded, e.Failed, e.Retrying, e.Pending, e.Dead = row.Deliveries, row.Succeeded, row.Failed, row.Retrying, row.Pending, row.Dead
+		summary.Deliveries += row.Deliveries
+		summary.Succeeded += row.Succeeded
+		summary.Failed += row.Failed
+		summary.Retrying += row.Retrying
+		summary.Pending += row.Pending
+		summary.Dead += row.Dead
+	}
+	for _, row := range attempts {
+		e := endpoint(endpointKey{row.TargetType, row.TargetID, row.WebhookURL})
+		e.Attempts = row.Attempts
+		e.MaxDurationMs = row.MaxDurationMs
+		e.LastSuccessAt, e.LastFailureAt = row.LastSuccessAt, row.LastFailureAt
+		e.AttemptSuccessRate = webhookRate(row.SuccessAttempts, row.Attempts)
+		e.AvgDurationMs = webhookAverage(row.TotalDurationMs, row.Attempts)
+
+		summary.Attempts += row.Attempts
+		successAttempts += row.SuccessAttempts
+		totalDuration += row.TotalDurationMs
+		if row.MaxDurationMs > summary.MaxDurationMs {
+			summary.MaxDurationMs = row.MaxDurationMs
+		}
+		summary.LastSuccessAt = laterTime(summary.LastSuccessAt, row.LastSuccessAt)
+		summary.LastFailureAt = laterTime(summary.LastFailureAt, row.LastFailureAt)
+	}
+
+	for i := range report.Endpoints {
+		e := &report.Endpoints[i]
+		e.SuccessRate = webhookRate(e.Succeeded, e.Succeeded+e.Failed+e.Dead)
+	}
+	summary.SuccessRate = webhookRate(summary.Succeeded, summary.Succeeded+summary.Failed+summary.Dead)
+	summary.AttemptSuccessRate = webhookRate(successAttempts, summary.Attempts)
+	summary.AvgDurationMs = webhookAverage(totalDuration, summary.Attempts)
+
+	sort.SliceStable(report.Endpoints, func(i, j int) bool {
+		a, b := report.Endpoints[i], report.Endpoints[j]
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate < b.SuccessRate
+		}
+		return a.Deliveries > b.Deliveries
+	})
+	return report
+}
+
+// webhookRate 百分比，保留两位小数，分母为 0 时返回 0
+func webhookRate(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return decimal.NewFromInt(part).Mul(decimal.NewFromInt(100)).Div(decimal.NewFromInt(total)).Round(2).InexactFloat64()
+}
+
+// webhookAverage 平均值，保留两位小数
+func webhookAverage(sum, count int64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return decimal.NewFromInt(sum).Div(decimal.NewFromInt(count)).Round(2).InexactFloat64()
+}
+
+// laterTime 取较晚的时间
+func laterTime(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/project/backend/types"
+)
+
+func TestNewWebhookLogFilter(t *testing.T) {
+	filter, err := NewWebhookLogFilter(&types.WebhookQueryParams{
+		Event:     types.WebhookNewOrder,
+		Status:    types.WebhookFailed,
+		StartDate: "2026-10-01",
+		EndDate:   "2026-10-07",
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookLogFilter() error = %v", err)
+	}
+	if filter.EventType != models.WebhookEventOrderCreated {
+		t.Errorf("EventType = %v, expected new_order to map to order.created", filter.EventType)
+	}
+	if end := time.Date(2026, 10, 8, 0, 0, 0, 0, time.Local); !filter.EndDate.Equal(end) {
+		t.Errorf("EndDate = %v, expected the day after the end date", filter.EndDate)
+	}
+	if filter, err := NewWebhookLogFilter(&types.WebhookQueryParams{Event: types.WebhookPriceUpdated}); err != nil || filter.EventType != models.WebhookEventPriceUpdated {
+		t.Errorf("NewWebhookLogFilter(price_updated) = %v, %v, expected price.updated", filter, err)
+	}
+
+	tests := []struct {
+		name   string
+		params types.WebhookQueryParams
+	}{
+		{"event", types.WebhookQueryParams{Event: "stock_changed"}},
+		{"status", types.WebhookQueryParams{Status: "done"}},
+		{"target", types.WebhookQueryParams{TargetType: "admin"}},
+		{"date", types.WebhookQueryParams{StartDate: "2026/10/01"}},
+		{"range", types.WebhookQueryParams{StartDate: "2026-10-08", EndDate: "2026-10-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebhookLogFilter(&tt.params); !errors.Is(err, ErrWebhookLogQueryInvalid) {
+				t.Errorf("NewWebhookLogFilter() error = %v, expected ErrWebhookLogQueryInvalid", err)
+			}
+		})
+	}
+}
+
+func TestMergeWebhookHealth(t *testing.T) {
+	early := time.Date(2026, 10, 16, 8, 0, 0, 0, time.Local)
+	late := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	deliveries := []webhookDeliveryRow{
+		{TargetType: models.WebhookTargetSupplier, TargetID: 1, WebhookURL: "https://a", Deliveries: 10, Succeeded: 9, Failed: 1},
+		{TargetType: models.WebhookTargetStore, TargetID: 2, WebhookURL: "https://b", Deliveries: 5, Succeeded: 2, Failed: 2, Pending: 1},
+	}
+	attempts := []webhookAttemptRow{
+		{TargetType: models.WebhookTargetSupplier, TargetID: 1, WebhookURL: "https://a", Attempts: 12, SuccessAttempts: 9, TotalDurationMs: 1200, MaxDurationMs: 300, LastSuccessAt: &late},
+		{TargetType: models.WebhookTargetStore, TargetID: 2, WebhookURL: "https://b", Attempts: 8, SuccessAttempts: 2, TotalDurationMs: 4000, MaxDurationMs: 2000, LastSuccessAt: &early, LastFailureAt: &late},
+	}
+
+	report := mergeWebhookHealth(deliveries, attempts)
+	if len(report.Endpoints) != 2 || report.Endpoints[0].TargetID != 2 {
+		t.Fatalf("Endpoints = %+v, expected the unhealthy store endpoint first", report.Endpoints)
+	}
+	store, supplier := report.Endpoints[0], report.Endpoints[1]
+	if store.SuccessRate != 50 || store.AttemptSuccessRate != 25 || store.AvgDurationMs != 500 {
+		t.Errorf("store = %+v, expected 50%% / 25%% / 500ms", store)
+	}
+	if supplier.SuccessRate != 90 || supplier.AvgDurationMs != 100 {
+		t.Errorf("supplier = %+v, expected 90%% / 100ms", supplier)
+	}
+
+	summary := report.Summary
+	if summary.Deliveries != 15 || summary.SuccessRate != 78.57 || summary.AttemptSuccessRate != 55 || summary.AvgDurationMs != 260 {
+		t.Errorf("summary = %+v", summary)
+	}
+	if summary.MaxDurationMs != 2000 || !summary.LastSuccessAt.Equal(late) || !summary.LastFailureAt.Equal(late) {
+		t.Errorf("summary max/last = %v/%v/%v", summary.MaxDurationMs, summary.LastSuccessAt, summary.LastFailureAt)
+	}
+}
@@ -0,0 +1,810 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/project/backend/models"
+	"github.com/project/backend/types"
+	"github.com/project/backend/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 通用 JSON 推送的请求头，与 middleware.SignatureMiddleware 校验的请求头一致
+const (
+	WebhookHeaderID        = "X-Webhook-Id" // 推送记录ID，重试时不变，可用于去重
+	WebhookHeaderTimestamp = "X-Timestamp"
+	WebhookHeaderNonce     = "X-Nonce"
+	WebhookHeaderSignature = "X-Signature"
+)
+
+// Webhook 推送错误
+var (
+	ErrWebhookNotEnabled = errors.New("未开启Webhook推送")
+	ErrWebhookSecretKey  = errors.New("签名密钥加密配置错误")
+)
+
+// Webhook 推送参数
+const (
+	webhookBatchSize       = 100
+	webhookWorkers         = 8
+	webhookMaxRetryDelay   = 6 * time.Hour
+	webhookClaimMargin     = 30 * time.Second
+	webhookResponseLimit   = 2000
+	defaultWebhookTimeout  = 10 * time.Second
+	defaultWebhookRetries  = 3
+	defaultWebhookInterval = 5 * time.Minute
+)
+
+// webhookStatusEvents 订单进入该状态时推送的事件
+// 订单创建时为待支付，支付成功(或赊账下单)进入待确认后才作为新订单推送
+var webhookStatusEvents = map[models.OrderStatus]models.WebhookEventType{
+	models.OrderStatusPendingConfirm: models.WebhookEventOrderCreated,
+	models.OrderStatusConfirmed:      models.WebhookEventOrderConfirmed,
+	models.OrderStatusDelivering:     models.WebhookEventOrderDelivering,
+	models.OrderStatusCompleted:      models.WebhookEventOrderCompleted,
+	models.OrderStatusCancelled:      models.WebhookEventOrderCancelled,
+}
+
+// webhookEventTitles 事件在群消息中的标题
+var webhookEventTitles = map[models.WebhookEventType]string{
+	models.WebhookEventOrderCreated:    "新订单通知",
+	models.WebhookEventOrderConfirmed:  "订单已确认",
+	models.WebhookEventOrderDelivering: "订单配送中",
+	models.WebhookEventOrderCompleted:  "订单已完成",
+	models.WebhookEventOrderCancelled:  "订单取消通知",
+	models.WebhookEventOrderRestored:   "订单恢复通知",
+	models.WebhookEventPriceUpdated:    "价格变动通知",
+}
+
+// webhookTransitionEvent 状态变更对应的推送事件，已取消订单恢复为待支付时推送恢复事件
+func webhookTransitionEvent(from, to models.OrderStatus) (models.WebhookEventType, bool) {
+	if from == models.OrderStatusCancelled && to == models.OrderStatusPendingPayment {
+		return models.WebhookEventOrderRestored, true
+	}
+	event, ok := webhookStatusEvents[to]
+	return event, ok
+}
+
+// webhookTarget 推送目标及其重试设置
+type webhookTarget struct {
+	Type         models.WebhookTargetType
+	ID           uint64
+	Name         string
+	ContactPhone string
+	URL          string
+	Events       models.WebhookEvents
+	MaxRetries   int
+	Interval     time.Duration
+	Timeout      time.Duration
+	Signed       bool   // 通用 JSON 推送，带时间戳、随机串和签名请求头
+	Secret       string // 解密后的签名密钥，未生成时为空，不带签名
+	SecretError  error
+}
+
+// subscribed 目标是否订阅该事件，未配置事件时订阅全部
+func (t *webhookTarget) subscribed(event models.WebhookEventType) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == string(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// supplierWebhookTarget 供应商推送目标，重试间隔和超时为秒
+func supplierWebhookTarget(supplier *models.Supplier) *webhookTarget {
+	if !supplier.HasWebhook() {
+		return nil
+	}
+	target := &webhookTarget{
+		Type:         models.WebhookTargetSupplier,
+		ID:           supplier.ID,
+		Name:         supplier.Name,
+		ContactPhone: supplier.ContactPhone,
+		URL:          *supplier.WechatWebhookURL,
+		Events:       supplier.WebhookEvents,
+		MaxRetries:   supplier.WebhookRetryTimes,
+		Interval:     time.Duration(supplier.WebhookRetryInterval) * time.Second,
+		Timeout:      time.Duration(supplier.WebhookTimeout) * time.Second,
+		Signed:       supplier.UsesSignedWebhook(),
+	}
+	if target.MaxRetries < 0 {
+		target.MaxRetries = 0
+	}
+	if target.Interval <= 0 {
+		target.Interval = defaultWebhookInterval
+	}
+	if target.Timeout <= 0 {
+		target.Timeout = defaultWebhookTimeout
+	}
+	return target
+}
+
+// storeWebhookTarget 门店推送目标，重试次数和间隔使用系统配置
+func storeWebhookTarget(store *models.Store, retries int, interval time.Duration) *webhookTarget {
+	if !store.HasWebhook() {
+		return nil
+	}
+	return &webhookTarget{
+		Type:         models.WebhookTargetStore,
+		ID:           store.ID,
+		Name:         store.Name,
+		ContactPhone: store.ContactPhone,
+		URL:          *store.WechatWebhookURL,
+		Events:       store.WebhookEvents,
+		MaxRetries:   retries,
+		Interval:     interval,
+		Timeout:      defaultWebhookTimeout,
+	}
+}
+
+// storeWebhookRetrySettings 门店推送的重试次数和间隔(系统配置，间隔为分钟)
+func storeWebhookRetrySettings(db *gorm.DB) (int, time.Duration) {
+	retries, interval := defaultWebhookRetries, defaultWebhookInterval
+	configs := NewSystemConfigService(db)
+	if val, err := configs.GetConfig(models.ConfigKeyWebhookRetryTimes); err == nil {
+		if v, e := strconv.Atoi(val); e == nil && v >= 0 {
+			retries = v
+		}
+	}
+	if val, err := configs.GetConfig(models.ConfigKeyWebhookRetryInterval); err == nil {
+		if v, e := strconv.Atoi(val); e == nil && v > 0 {
+			interval = time.Duration(v) * time.Minute
+		}
+	}
+	return retries, interval
+}
+
+// enqueueOrderWebhooksTx 在订单状态变更的事务中为订阅该事件的门店和供应商写入待推送记录，
+// 与状态变更一起提交，由 WebhookService.DeliverDue 投递
+func enqueueOrderWebhooksTx(tx *gorm.DB, order *models.Order, event models.WebhookEventType) error {
+	var store models.Store
+	if err := tx.First(&store, order.StoreID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var supplier models.Supplier
+	if err := tx.First(&supplier, order.SupplierID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	subscribed := subscribedWebhookTargets(tx, &store, &supplier, event)
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	tpl, err := loadWebhookTemplate(tx, event)
+	if err != nil {
+		return err
+	}
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Order("id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	data := newWebhookMessageData(event, order, items, store.Name, supplierDisplayName(&supplier), now)
+
+	for _, target := range subscribed {
+		var messages []models.JSON
+		if target.Signed {
+			messages = []models.JSON{genericOrderWebhookBody(event, order, items, data)}
+		} else {
+			if messages, err = renderWebhookMessages(tpl, data, webhookTemplateMentions(tpl, target.ContactPhone)); err != nil {
+				return err
+			}
+		}
+		if err := createWebhookLogs(tx, target, event, order.ID, messages, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SupplierPriceChange 供应商报价调价明细
+type SupplierPriceChange struct {
+	MaterialSkuID uint64
+	OldPrice      float64
+	NewPrice      float64
+}
+
+// EnqueuePriceWebhooksTx 在调价事务中为订阅价格变动的供应商写入待推送记录，一次调价推送一条消息，
+// 价格未变的明细不推送
+func EnqueuePriceWebhooksTx(tx *gorm.DB, supplierID uint64, changes []SupplierPriceChange) error {
+	var changed []SupplierPriceChange
+	for _, change := range changes {
+		if change.OldPrice != change.NewPrice {
+			changed = append(changed, change)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var supplier models.Supplier
+	if err := tx.First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	event := models.WebhookEventPriceUpdated
+	subscribed := subscribedWebhookTargets(tx, nil, &supplier, event)
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	tpl, err := loadWebhookTemplate(tx, event)
+	if err != nil {
+		return err
+	}
+	skuIDs := make([]uint64, len(changed))
+	for i, change := range changed {
+		skuIDs[i] = change.MaterialSkuID
+	}
+	var skus []models.MaterialSku
+	if err := tx.Where("id IN ?", skuIDs).Preload("Material").Find(&skus).Error; err != nil {
+		return err
+	}
+	skuMap := make(map[uint64]*models.MaterialSku, len(skus))
+	for i := range skus {
+		skuMap[skus[i].ID] = &skus[i]
+	}
+	now := time.Now()
+	data := newPriceWebhookMessageData(changed, skuMap, supplierDisplayName(&supplier), now)
+
+	for _, target := range subscribed {
+		var messages []models.JSON
+		if target.Signed {
+			messages = []models.JSON{genericPriceWebhookBody(&supplier, changed, skuMap)}
+		} else {
+			if messages, err = renderWebhookMessages(tpl, data, webhookTemplateMentions(tpl, target.ContactPhone)); err != nil {
+				return err
+			}
+		}
+		if err := createWebhookLogs(tx, target, event, 0, messages, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribedWebhookTargets 门店和供应商中订阅该事件的推送目标，store 为 nil 时只看供应商
+func subscribedWebhookTargets(tx *gorm.DB, store *models.Store, supplier *models.Supplier, event models.WebhookEventType) []*webhookTarget {
+	var targets []*webhookTarget
+	if store != nil && store.ID > 0 && store.HasWebhook() {
+		retries, interval := storeWebhookRetrySettings(tx)
+		targets = append(targets, storeWebhookTarget(store, retries, interval))
+	}
+	if supplier.ID > 0 {
+		if target := supplierWebhookTarget(supplier); target != nil {
+			targets = append(targets, target)
+		}
+	}
+	var subscribed []*webhookTarget
+	for _, target := range targets {
+		if target.subscribed(event) {
+			subscribed = append(subscribed, target)
+		}
+	}
+	return subscribed
+}
+
+// createWebhookLogs 为推送目标写入待推送记录，非订单事件的 orderID 为 0
+func createWebhookLogs(tx *gorm.DB, target *webhookTarget, event models.WebhookEventType, orderID uint64, messages []models.JSON, now time.Time) error {
+	for _, message := range messages {
+		log := &models.WebhookLog{
+			TargetType:     target.Type,
+			TargetID:       target.ID,
+			EventType:      event,
+			OrderID:        orderID,
+			WebhookURL:     target.URL,
+			RequestHeaders: models.JSON{"Content-Type": "application/json"},
+			RequestBody:    message,
+			Status:         models.WebhookStatusPending,
+			MaxRetryCount:  target.MaxRetries,
+			NextRetryAt:    &now,
+		}
+		if err := tx.Create(log).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genericWebhookEvents 通用 JSON 推送中的事件名
+var genericWebhookEvents = map[models.WebhookEventType]types.WebhookEvent{
+	models.WebhookEventOrderCreated:    types.WebhookNewOrder,
+	models.WebhookEventOrderConfirmed:  types.WebhookOrderConfirmed,
+	models.WebhookEventOrderDelivering: types.WebhookOrderDelivered,
+	models.WebhookEventOrderCompleted:  types.WebhookOrderCompleted,
+	models.WebhookEventOrderCancelled:  types.WebhookOrderCancelled,
+	models.WebhookEventOrderRestored:   types.WebhookOrderRestored,
+	models.WebhookEventPriceUpdated:    types.WebhookPriceUpdated,
+}
+
+// genericOrderWebhookBody 通用 JSON 推送内容，签名通过请求头传递，不写入 signature 字段
+func genericOrderWebhookBody(event models.WebhookEventType, order *models.Order, items []models.OrderItem, data *WebhookMessageData) models.JSON {
+	orderData := types.OrderWebhookData{
+		OrderNo:      order.OrderNo,
+		OrderID:      order.ID,
+		Status:       types.OrderStatus(order.Status),
+		TotalAmount:  order.TotalAmount,
+		ItemCount:    len(items),
+		StoreName:    data.StoreName,
+		SupplierName: data.SupplierName,
+		DeliveryDate: data.DeliveryDate,
+	}
+	if order.Remark != nil {
+		orderData.Remark = *order.Remark
+	}
+	if data.CancelReason != "" {
+		orderData.Remark = data.CancelReason
+	}
+	return webhookPayloadBody(&types.WebhookPayload{
+		Event:     genericWebhookEvents[event],
+		Timestamp: time.Now().Unix(),
+		Data:      orderData,
+	})
+}
+
+// genericPriceWebhookBody 通用 JSON 推送的调价内容，data 为调价明细列表
+func genericPriceWebhookBody(supplier *models.Supplier, changes []SupplierPriceChange, skus map[uint64]*models.MaterialSku) models.JSON {
+	data := make([]types.PriceWebhookData, 0, len(changes))
+	for _, change := range changes {
+		item := types.PriceWebhookData{
+			MaterialSkuID: change.MaterialSkuID,
+			OldPrice:      change.OldPrice,
+			NewPrice:      change.NewPrice,
+			SupplierID:    supplier.ID,
+			SupplierName:  supplierDisplayName(supplier),
+		}
+		if sku := skus[change.MaterialSkuID]; sku != nil {
+			item.Brand = sku.Brand
+			item.Spec = sku.Spec
+			if sku.Material != nil {
+				item.MaterialName = sku.Material.Name
+			}
+		}
+		data = append(data, item)
+	}
+	return webhookPayloadBody(&types.WebhookPayload{
+		Event:     types.WebhookPriceUpdated,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	})
+}
+
+// webhookPayloadBody 推送载荷转换为日志中保存的请求内容
+func webhookPayloadBody(payload *types.WebhookPayload) models.JSON {
+	var body models.JSON
+	raw, _ := json.Marshal(payload)
+	_ = json.Unmarshal(raw, &body)
+	return body
+}
+
+// WebhookService Webhook 推送服务
+// 待推送记录保存在 webhook_logs 中，到期(next_retry_at)的记录由后台任务投递，进程重启后继续处理
+type WebhookService struct {
+	db        *gorm.DB
+	client    *http.Client
+	logger    *zap.Logger
+	secretKey string // 签名密钥的 AES 加密密钥
+}
+
+// NewWebhookService 创建 Webhook 推送服务
+func NewWebhookService(db *gorm.DB, logger *zap.Logger, secretKey string) *WebhookService {
+	return &WebhookService{
+		db:        db,
+		client:    &http.Client{},
+		logger:    logger,
+		secretKey: secretKey,
+	}
+}
+
+// DeliverDue 投递到期的推送记录，供后台任务调用
+func (s *WebhookService) DeliverDue(ctx context.Context) error {
+	if err := s.scheduleProbes(); err != nil {
+		s.logger.Warn("schedule webhook probes failed", zap.Error(err))
+	}
+	for {
+		var logs []models.WebhookLog
+		if err := s.db.
+			Where("status IN ? AND next_retry_at <= ?",
+				[]models.WebhookStatus{models.WebhookStatusPending, models.WebhookStatusFailed}, time.Now()).
+			Order("next_retry_at ASC").
+			Limit(webhookBatchSize).
+			Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		jobs := make(chan *models.WebhookLog)
+		var wg sync.WaitGroup
+		for i := 0; i < webhookWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for log := range jobs {
+					s.Deliver(ctx, log)
+				}
+			}()
+		}
+		for i := range logs {
+			if ctx.Err() != nil {
+				break
+			}
+			jobs <- &logs[i]
+		}
+		close(jobs)
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(logs) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+// Deliver 投递一条推送记录并记录本次尝试；失败时按目标设置安排重试，重试用尽后标记失败，
+// 推送地址熔断时转入死信
+// 未能抢占(已被其他实例处理)时返回 false
+func (s *WebhookService) Deliver(ctx context.Context, log *models.WebhookLog) bool {
+	target, err := s.loadTarget(log.TargetType, log.TargetID)
+	if err != nil {
+		s.logger.Warn("load webhook target failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+		return false
+	}
+	timeout := defaultWebhookTimeout
+	if target != nil {
+		timeout = target.Timeout
+	}
+	leaseUntil := time.Now().Add(timeout + webhookClaimMargin)
+	if !s.claim(log, leaseUntil) {
+		return false
+	}
+
+	var result webhookAttemptResult
+	circuitOpen := false
+	if target == nil {
+		result.ErrorMsg = "推送目标不存在或已关闭Webhook"
+	} else {
+		admitted, err := s.admit(log, leaseUntil)
+		if err != nil {
+			// 租约到期后再次投递
+			s.logger.Warn("check webhook circuit failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+			return true
+		}
+		if !admitted {
+			s.deadLetter(log)
+			return true
+		}
+		result = s.send(ctx, log, target)
+		circuitOpen = s.recordOutcome(log, &result)
+	}
+
+	// 手动重发会重置重试次数，尝试序号按已有尝试记录累计
+	var attempts int64
+	if err := s.db.Model(&models.WebhookAttempt{}).Where("webhook_log_id = ?", log.ID).Count(&attempts).Error; err != nil {
+		s.logger.Warn("count webhook attempts failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+	}
+	attempt := &models.WebhookAttempt{
+		WebhookLogID: log.ID,
+		Attempt:      int(attempts) + 1,
+		ResponseCode: result.ResponseCode,
+		ResponseBody: result.ResponseBody,
+		Success:      result.Success,
+		ErrorMsg:     result.ErrorMsg,
+		DurationMs:   result.DurationMs,
+		CreatedAt:    time.Now(),
+	}
+
+	if result.Success {
+		log.MarkSuccess(result.ResponseCode, result.ResponseBody, result.DurationMs)
+		log.ErrorMsg = ""
+	} else {
+		log.MarkFailed(result.ResponseCode, result.ResponseBody, result.ErrorMsg, result.DurationMs)
+		log.NextRetryAt = nil
+		if circuitOpen {
+			log.Status = models.WebhookStatusDead
+		} else if target != nil && log.CanRetry() {
+			log.SetNextRetryAfter(webhookRetryDelay(target.Interval, log.RetryCount))
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.WebhookLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+			"status":        log.Status,
+			"response_code": log.ResponseCode,
+			"response_body": log.ResponseBody,
+			"error_msg":     log.ErrorMsg,
+			"duration_ms":   log.DurationMs,
+			"retry_count":   log.RetryCount,
+			"next_retry_at": log.NextRetryAt,
+		}).Error
+	})
+	if err != nil {
+		// 记录失败时租约到期后会再次投递
+		s.logger.Error("save webhook attempt failed", zap.Uint64("webhookLogId", log.ID), zap.Error(err))
+	}
+	return true
+}
+
+// claim 以 next_retry_at 为条件延后到租约到期时间，保证同一记录同时只有一处投递；
+// 投递过程中进程退出时，租约到期后重新投递
+func (s *WebhookService) claim(log *models.WebhookLog, leaseUntil time.Time) bool {
+	query := s.db.Model(&models.WebhookLog{}).
+		Where("id = ? AND status = ?", log.ID, log.Status)
+	if log.NextRetryAt == nil {
+		query = query.Where("next_retry_at IS NULL")
+	} else {
+		query = query.Where("next_retry_at = ?", *log.NextRetryAt)
+	}
+	result := query.Update("next_retry_at", leaseUntil)
+	if result.Error != nil {
+		s.logger.Warn("claim webhook failed", zap.Uint64("webhookLogId", log.ID), zap.Error(result.Error))
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// loadTarget 读取推送目标的当前设置，目标不存在或已关闭 Webhook 时返回 nil
+func (s *WebhookService) loadTarget(targetType models.WebhookTargetType, targetID uint64) (*webhookTarget, error) {
+	switch targetType {
+	case models.WebhookTargetSupplier:
+		var supplier models.Supplier
+		if err := s.db.First(&supplier, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		target := supplierWebhookTarget(&supplier)
+		if target != nil && target.Signed && supplier.WebhookSecret != nil && *supplier.WebhookSecret != "" {
+			target.Secret, target.SecretError = utils.AESDecrypt(*supplier.WebhookSecret, s.secretKey)
+		}
+		return target, nil
+	case models.WebhookTargetStore:
+		var store models.Store
+		if err := s.db.First(&store, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		retries, interval := storeWebhookRetrySettings(s.db)
+		return storeWebhookTarget(&store, retries, interval), nil
+	}
+	return nil, nil
+}
+
+// webhookAttemptResult 一次推送的结果
+type webhookAttemptResult struct {
+	Success      bool
+	ResponseCode int
+	ResponseBody string
+	ErrorMsg     string
+	DurationMs   int
+}
+
+// send 发送请求，推送地址以记录中的为准；通用 JSON 推送在发送时按目标当前的密钥签名
+func (s *WebhookService) send(ctx context.Context, log *models.WebhookLog, target *webhookTarget) webhookAttemptResult {
+	var result webhookAttemptResult
+	if target.SecretError != nil {
+		result.ErrorMsg = "签名密钥解密失败"
+		return result
+	}
+
+	body, err := json.Marshal(log.RequestBody)
+	if err != nil {
+		result.ErrorMsg = "请求内容序列化失败: " + err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, target.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, log.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		result.ErrorMsg = truncateRunes("请求创建失败: "+err.Error(), 500)
+		return result
+	}
+	for key, value := range log.RequestHeaders {
+		if v, ok := value.(string); ok {
+			req.Header.Set(key, v)
+		}
+	}
+	if target.Signed {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := utils.GenerateRandomString(16)
+		if log.ID > 0 {
+			req.Header.Set(WebhookHeaderID, strconv.FormatUint(log.ID, 10))
+		}
+		req.Header.Set(WebhookHeaderTimestamp, timestamp)
+		req.Header.Set(WebhookHeaderNonce, nonce)
+		if target.Secret != "" {
+			req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(target.Secret, timestamp, nonce, body))
+		}
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	result.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		result.ErrorMsg = truncateRunes("请求失败: "+err.Error(), 500)
+		return result
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	result.DurationMs = int(time.Since(start).Milliseconds())
+	result.ResponseCode = resp.StatusCode
+	result.ResponseBody = truncateRunes(string(respBody), webhookResponseLimit)
+	if msg := webhookResponseError(resp.StatusCode, respBody); msg != "" {
+		result.ErrorMsg = truncateRunes(msg, 500)
+		return result
+	}
+	result.Success = true
+	return result
+}
+
+// webhookResponseError 判断响应是否成功：HTTP 2xx，且企业微信返回的 errcode 为 0(非 JSON 响应只看状态码)
+func webhookResponseError(statusCode int, body []byte) string {
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Sprintf("HTTP %d", statusCode)
+	}
+	var resp struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.ErrCode != nil && *resp.ErrCode != 0 {
+		return fmt.Sprintf("errcode %d: %s", *resp.ErrCode, resp.ErrMsg)
+	}
+	return ""
+}
+
+// webhookRetryDelay 第 retry 次重试的等待时间：间隔按 2 的幂递增，最长 webhookMaxRetryDelay
+func webhookRetryDelay(interval time.Duration, retry int) time.Duration {
+	delay := interval
+	for i := 1; i < retry && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxRetryDelay {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// truncateRunes 按字符截断
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// SignWebhookPayload 计算推送签名：以密钥对 时间戳+随机串+请求体 做 HMAC-SHA256，结果为十六进制小写
+func SignWebhookPayload(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte(nonce))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookSecretResult 新生成的签名密钥，明文只在生成时返回一次
+type WebhookSecretResult struct {
+	Secret    string    `json:"secret"`
+	RotatedAt time.Time `json:"rotatedAt"`
+}
+
+// RotateSupplierSecret 为供应商生成新的签名密钥并加密保存，旧密钥立即失效，
+// 尚未投递的推送在发送时使用新密钥签名
+func (s *WebhookService) RotateSupplierSecret(supplierID uint64) (*WebhookSecretResult, error) {
+	var supplier models.Supplier
+	if err := s.db.Select("id").First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+
+	secret := "whsec_" + utils.GenerateAPISecret()
+	encrypted, err := utils.AESEncrypt(secret, s.secretKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookSecretKey, err)
+	}
+	now := time.Now()
+	if err := s.db.Model(&models.Supplier{}).Where("id = ?", supplierID).Updates(map[string]interface{}{
+		"webhook_secret":            encrypted,
+		"webhook_secret_rotated_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &WebhookSecretResult{Secret: secret, RotatedAt: now}, nil
+}
+
+// WebhookTestResult 测试推送结果
+type WebhookTestResult struct {
+	Success      bool                   `json:"success"`
+	Signed       bool                   `json:"signed"`
+	WebhookURL   string                 `json:"webhookUrl"`
+	RequestBody  map[string]interface{} `json:"requestBody"`
+	ResponseCode int                    `json:"responseCode,omitempty"`
+	ResponseBody string                 `json:"responseBody,omitempty"`
+	ErrorMsg     string                 `json:"errorMsg,omitempty"`
+	DurationMs   int                    `json:"durationMs"`
+}
+
+// SendSupplierTest 向供应商当前的推送地址发送一条测试消息，同步返回结果，不写推送日志
+func (s *WebhookService) SendSupplierTest(ctx context.Context, supplierID uint64) (*WebhookTestResult, error) {
+	var supplier models.Supplier
+	if err := s.db.First(&supplier, supplierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+	target, err := s.loadTarget(models.WebhookTargetSupplier, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrWebhookNotEnabled
+	}
+
+	var body models.JSON
+	if target.Signed {
+		body = webhookPayloadBody(&types.WebhookPayload{
+			Event:     types.WebhookTest,
+			Timestamp: time.Now().Unix(),
+			Data: map[string]interface{}{
+				"supplierId": supplier.ID,
+				"message":    "这是一条测试消息",
+			},
+		})
+	} else {
+		body = models.JSON{
+			"msgtype": "text",
+			"text":    map[string]interface{}{"content": fmt.Sprintf("【测试消息】%s 的订单通知已接入", supplierDisplayName(&supplier))},
+		}
+	}
+
+	log := &models.WebhookLog{
+		WebhookURL:     target.URL,
+		RequestHeaders: models.JSON{"Content-Type": "application/json"},
+		RequestBody:    body,
+	}
+	result := s.send(ctx, log, target)
+	return &WebhookTestResult{
+		Success:      result.Success,
+		Signed:       target.Signed && target.Secret != "",
+		WebhookURL:   target.URL,
+		RequestBody:  body,
+		ResponseCode: result.ResponseCode,
+		ResponseBody: result.ResponseBody,
+		ErrorMsg:     result.ErrorMsg,
+		DurationMs:   result.DurationMs,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/project/backend/middleware"
+	"github.com/project/backend/models"
+)
+
+func TestWebhookTransitionEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     models.OrderStatus
+		to       models.OrderStatus
+		expected models.WebhookEventType
+		ok       bool
+	}{
+		{"paid", models.OrderStatusPendingPayment, models.OrderStatusPendingConfirm, models.WebhookEventOrderCreated, true},
+		{"confirmed", models.OrderStatusPendingConfirm, models.OrderStatusConfirmed, models.WebhookEventOrderConfirmed, true},
+		{"cancelled", models.OrderStatusConfirmed, models.OrderStatusCancelled, models.WebhookEventOrderCancelled, true},
+		{"restored", models.OrderStatusCancelled, models.OrderStatusPendingPayment, models.WebhookEventOrderRestored, true},
+		{"no event", models.OrderStatusPendingConfirm, models.OrderStatusPendingPayment, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := webhookTransitionEvent(tt.from, tt.to)
+			if event != tt.expected || ok != tt.ok {
+				t.Errorf("webhookTransitionEvent() = %v, %v, expected %v, %v", event, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWebhookTargetSubscribed(t *testing.T) {
+	all := &webhookTarget{}
+	if !all.subscribed(models.WebhookEventOrderCompleted) {
+		t.Error("target without events should receive every event")
+	}
+
+	target := &webhookTarget{Events: models.WebhookEvents{"order.created", "order.cancelled"}}
+	if !target.subscribed(models.WebhookEventOrderCancelled) {
+		t.Error("expected order.cancelled to be subscribed")
+	}
+	if target.subscribed(models.WebhookEventOrderConfirmed) {
+		t.Error("expected order.confirmed not to be subscribed")
+	}
+}
+
+func TestSupplierWebhookTarget(t *testing.T) {
+	url := "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=test"
+	supplier := &models.Supplier{
+		ID:                   9,
+		WechatWebhookURL:     &url,
+		WebhookEnabled:       1,
+		WebhookRetryTimes:    2,
+		WebhookRetryInterval: 30,
+	}
+	target := supplierWebhookTarget(supplier)
+	if target == nil {
+		t.Fatal("expected a target for an enabled supplier")
+	}
+	if target.MaxRetries != 2 || target.Interval != 30*time.Second || target.Timeout != defaultWebhookTimeout {
+		t.Errorf("target = %+v, expected 2 retries every 30s with the default timeout", target)
+	}
+
+	supplier.WebhookEnabled = 0
+	if supplierWebhookTarget(supplier) != nil {
+		t.Error("expected no target when the webhook is disabled")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{20, webhookMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(time.Minute, tt.retry); got != tt.expected {
+			t.Errorf("webhookRetryDelay(1m, %d) = %v, expected %v", tt.retry, got, tt.expected)
+		}
+	}
+}
+
+func TestWebhookResponseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		failed bool
+	}{
+		{"ok", 200, `{"errcode":0,"errmsg":"ok"}`, false},
+		{"plain body", 204, ``, false},
+		{"errcode", 200, `{"errcode":93000,"errmsg":"invalid webhook url"}`, true},
+		{"http error", 502, `bad gateway`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookResponseError(tt.status, []byte(tt.body)); (got != "") != tt.failed {
+				t.Errorf("webhookResponseError() = %q, expected failed=%v", got, tt.failed)
+			}
+		})
+	}
+}
+
+func TestSignedWebhookVerifiedByMiddleware(t *testing.T) {
+	e := echo.New()
+	e.POST("/hook", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{"errcode": 0})
+	}, middleware.SignatureMiddleware(middleware.SignatureConfig{
+		SecretKey:          "whsec_test",
+		TimestampTolerance: 5 * time.Minute,
+	}))
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	service := &WebhookService{client: server.Client()}
+	log := &models.WebhookLog{
+		ID:             1,
+		WebhookURL:     server.URL + "/hook",
+		RequestHeaders: models.JSON{"Content-Type": "application/json"},
+		RequestBody:    models.JSON{"event": "new_order", "data": map[string]interface{}{"orderNo": "SO1"}},
+	}
+
+	target := &webhookTarget{Signed: true, Secret: "whsec_test", Timeout: 5 * time.Second}
+	if result := service.send(context.Background(), log, target); !result.Success {
+		t.Errorf("send() = %+v, expected the middleware to accept the signature", result)
+	}
+
+	target.Secret = "whsec_other"
+	if result := service.send(context.Background(), log, target); result.Success || result.ResponseCode != http.StatusUnauthorized {
+		t.Errorf("send() = %+v, expected 401 with a different secret", result)
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000abc{"a":1}' | openssl dgst -sha256 -hmac secret
+	expected := "44909f3aade33d9ad924c3a62d014fe9989b96548cb0aa7800c17903c4690c33"
+	if got := SignWebhookPayload("secret", "1700000000", "abc", []byte(`{"a":1}`)); got != expected {
+		t.Errorf("SignWebhookPayload() = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,541 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/project/backend/models"
+	"gorm.io/gorm"
+)
+
+// Webhook 模板错误
+var (
+	ErrWebhookTemplateInvalid = errors.New("消息模板无效")
+	ErrWebhookEventInvalid    = errors.New("不支持的推送事件")
+)
+
+// 企业微信群机器人消息长度限制(UTF-8 字节)
+const (
+	webhookMarkdownLimit = 4096
+	webhookTextLimit     = 2048
+)
+
+// webhookTemplateEvents 可配置模板的事件，按展示顺序
+var webhookTemplateEvents = []models.WebhookEventType{
+	models.WebhookEventOrderCreated,
+	models.WebhookEventOrderConfirmed,
+	models.WebhookEventOrderDelivering,
+	models.WebhookEventOrderCompleted,
+	models.WebhookEventOrderCancelled,
+	models.WebhookEventOrderRestored,
+	models.WebhookEventPriceUpdated,
+}
+
+// defaultWebhookTemplateContent 内置 markdown 模板，所有订单事件共用，标题和取消原因按事件区分
+const defaultWebhookTemplateContent = `**【{{.Title}}】**
+> 订单编号：{{.OrderNo}}
+> 门店：{{.StoreName}}
+> 供应商：{{.SupplierName}}
+> 订单金额：<font color="warning">¥{{.Amount}}</font>
+{{- if .DeliveryDate}}
+> 期望配送：{{.DeliveryDate}}
+{{- end}}
+{{- if .CancelReason}}
+> 取消原因：{{.CancelReason}}
+{{- end}}
+{{- if .Items}}
+
+商品明细（共{{.ItemCount}}项）：
+{{- range .Items}}
+- {{.Name}}{{if .Spec}} {{.Spec}}{{end}} × {{.Quantity}}{{.Unit}}　¥{{.Subtotal}}
+{{- end}}
+{{- if .MoreItems}}
+- ……另有{{.MoreItems}}项未显示
+{{- end}}
+{{- end}}
+
+> 时间：{{.Time}}`
+
+// defaultPriceWebhookTemplateContent 价格变动事件的内置 markdown 模板
+const defaultPriceWebhookTemplateContent = `**【{{.Title}}】**
+> 供应商：{{.SupplierName}}
+> 调价商品：{{.ItemCount}}项
+{{- range .Items}}
+- {{.Name}}{{if .Spec}} {{.Spec}}{{end}}：¥{{.OldPrice}} → <font color="warning">¥{{.Price}}</font>{{if .Unit}}/{{.Unit}}{{end}}
+{{- end}}
+{{- if .MoreItems}}
+- ……另有{{.MoreItems}}项未显示
+{{- end}}
+
+> 时间：{{.Time}}`
+
+var webhookMobilePattern = regexp.MustCompile(`^1\d{10}$`)
+
+// WebhookMessageData 消息模板可用的字段
+type WebhookMessageData struct {
+	Event        string               `json:"event"`
+	Title        string               `json:"title"`
+	OrderNo      string               `json:"orderNo"`
+	StoreName    string               `json:"storeName"`
+	SupplierName string               `json:"supplierName"`
+	Amount       string               `json:"amount"`
+	DeliveryDate string               `json:"deliveryDate"`
+	CancelReason string               `json:"cancelReason"`
+	Time         string               `json:"time"`
+	Items        []WebhookMessageItem `json:"items"`
+	ItemCount    int                  `json:"itemCount"`
+	MoreItems    int                  `json:"moreItems"` // 超出长度限制未显示的商品数
+}
+
+// WebhookMessageItem 消息中的商品明细
+type WebhookMessageItem struct {
+	Name     string `json:"name"`
+	Spec     string `json:"spec"`
+	Unit     string `json:"unit"`
+	Quantity int    `json:"quantity"`
+	Price    string `json:"price"`
+	OldPrice string `json:"oldPrice"` // 价格变动事件的调价前单价
+	Subtotal string `json:"subtotal"`
+}
+
+// newWebhookMessageData 由订单生成模板数据
+func newWebhookMessageData(event models.WebhookEventType, order *models.Order, items []models.OrderItem, storeName, supplierName string, at time.Time) *WebhookMessageData {
+	data := &WebhookMessageData{
+		Event:        string(event),
+		Title:        webhookEventTitles[event],
+		OrderNo:      order.OrderNo,
+		StoreName:    storeName,
+		SupplierName: supplierName,
+		Amount:       fmt.Sprintf("%.2f", order.TotalAmount),
+		Time:         at.Format("2006-01-02 15:04:05"),
+		ItemCount:    len(items),
+	}
+	if order.ExpectedDeliveryDate != nil {
+		data.DeliveryDate = order.ExpectedDeliveryDate.Format("2006-01-02")
+	}
+	if event == models.WebhookEventOrderCancelled && order.CancelReason != nil {
+		data.CancelReason = *order.CancelReason
+	}
+	for _, item := range items {
+		data.Items = append(data.Items, WebhookMessageItem{
+			Name:     item.MaterialName,
+			Spec:     item.Spec,
+			Unit:     item.Unit,
+			Quantity: item.Quantity,
+			Price:    fmt.Sprintf("%.2f", item.FinalPrice),
+			Subtotal: fmt.Sprintf("%.2f", item.Subtotal),
+		})
+	}
+	return data
+}
+
+// newPriceWebhookMessageData 由调价明细生成模板数据，Price 为调价后单价
+func newPriceWebhookMessageData(changes []SupplierPriceChange, skus map[uint64]*models.MaterialSku, supplierName string, at time.Time) *WebhookMessageData {
+	data := &WebhookMessageData{
+		Event:        string(models.WebhookEventPriceUpdated),
+		Title:        webhookEventTitles[models.WebhookEventPriceUpdated],
+		SupplierName: supplierName,
+		Time:         at.Format("2006-01-02 15:04:05"),
+		ItemCount:    len(changes),
+	}
+	for _, change := range changes {
+		item := WebhookMessageItem{
+			Price:    fmt.Sprintf("%.2f", change.NewPrice),
+			OldPrice: fmt.Sprintf("%.2f", change.OldPrice),
+		}
+		if sku := skus[change.MaterialSkuID]; sku != nil {
+			item.Spec = sku.Spec
+			item.Unit = sku.Unit
+			if sku.Material != nil {
+				item.Name = sku.Material.Name
+			}
+		}
+		if item.Name == "" {
+			item.Name = fmt.Sprintf("SKU %d", change.MaterialSkuID)
+		}
+		data.Items = append(data.Items, item)
+	}
+	return data
+}
+
+// sampleWebhookMessageData 预览和校验模板用的示例数据
+func sampleWebhookMessageData(event models.WebhookEventType) *WebhookMessageData {
+	if event == models.WebhookEventPriceUpdated {
+		data := &WebhookMessageData{
+			Event:        string(event),
+			Title:        webhookEventTitles[event],
+			SupplierName: "示例供应商",
+			Time:         time.Now().Format("2006-01-02 15:04:05"),
+			Items: []WebhookMessageItem{
+				{Name: "大白菜", Spec: "约5kg/袋", Unit: "袋", OldPrice: "32.00", Price: "30.00"},
+				{Name: "土豆", Spec: "10kg/箱", Unit: "箱", OldPrice: "70.00", Price: "75.00"},
+			},
+		}
+		data.ItemCount = len(data.Items)
+		return data
+	}
+	data := &WebhookMessageData{
+		Event:        string(event),
+		Title:        webhookEventTitles[event],
+		OrderNo:      "SO202601010001",
+		StoreName:    "示例门店",
+		SupplierName: "示例供应商",
+		Amount:       "268.00",
+		DeliveryDate: time.Now().AddDate(0, 0, 1).Format("2006-01-02"),
+		Time:         time.Now().Format("2006-01-02 15:04:05"),
+		Items: []WebhookMessageItem{
+			{Name: "大白菜", Spec: "约5kg/袋", Unit: "袋", Quantity: 4, Price: "32.00", Subtotal: "128.00"},
+			{Name: "土豆", Spec: "10kg/箱", Unit: "箱", Quantity: 2, Price: "70.00", Subtotal: "140.00"},
+		},
+	}
+	data.ItemCount = len(data.Items)
+	if event == models.WebhookEventOrderCancelled {
+		data.CancelReason = "门店临时闭店"
+	}
+	return data
+}
+
+// defaultWebhookTemplate 内置模板
+func defaultWebhookTemplate(event models.WebhookEventType) *models.WebhookTemplate {
+	content := defaultWebhookTemplateContent
+	if event == models.WebhookEventPriceUpdated {
+		content = defaultPriceWebhookTemplateContent
+	}
+	return &models.WebhookTemplate{
+		EventType: event,
+		MsgType:   models.WebhookMsgMarkdown,
+		Content:   content,
+		IsActive:  true,
+	}
+}
+
+// loadWebhookTemplate 读取事件的消息模板，未配置或已停用时使用内置模板
+func loadWebhookTemplate(db *gorm.DB, event models.WebhookEventType) (*models.WebhookTemplate, error) {
+	var tpl models.WebhookTemplate
+	err := db.Where("event_type = ? AND is_active = ?", event, true).First(&tpl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultWebhookTemplate(event), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// webhookTemplateMentions 模板需要@的手机号：推送目标联系人及额外配置的手机号，去重
+func webhookTemplateMentions(tpl *models.WebhookTemplate, contactPhone string) []string {
+	var mentions []string
+	seen := make(map[string]bool)
+	add := func(mobile string) {
+		mobile = strings.TrimSpace(mobile)
+		if mobile == "" || seen[mobile] {
+			return
+		}
+		seen[mobile] = true
+		mentions = append(mentions, mobile)
+	}
+	if tpl.MentionContact {
+		add(contactPhone)
+	}
+	for _, mobile := range tpl.MentionMobiles {
+		add(mobile)
+	}
+	return mentions
+}
+
+// renderWebhookMessages 按模板生成企业微信群机器人消息
+// 超出长度限制时先减少显示的商品明细，仍超出则截断；
+// markdown 消息不支持按手机号@，有需要@的手机号时追加一条 text 提醒消息
+func renderWebhookMessages(tpl *models.WebhookTemplate, data *WebhookMessageData, mentions []string) ([]models.JSON, error) {
+	t, err := parseWebhookTemplate(tpl.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := webhookMarkdownLimit
+	if tpl.MsgType == models.WebhookMsgText {
+		limit = webhookTextLimit
+	}
+	content, err := fitWebhookContent(t, data, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if tpl.MsgType == models.WebhookMsgText {
+		text := map[string]interface{}{"content": content}
+		if len(mentions) > 0 {
+			text["mentioned_mobile_list"] = mentions
+		}
+		return []models.JSON{{"msgtype": "text", "text": text}}, nil
+	}
+
+	messages := []models.JSON{{
+		"msgtype":  "markdown",
+		"markdown": map[string]interface{}{"content": content},
+	}}
+	if len(mentions) > 0 {
+		reminder := fmt.Sprintf("%s：%s，请及时处理", data.Title, data.OrderNo)
+		if data.OrderNo == "" {
+			reminder = fmt.Sprintf("%s：%s调整了%d项商品价格，请及时查看", data.Title, data.SupplierName, data.ItemCount)
+		}
+		messages = append(messages, models.JSON{
+			"msgtype": "text",
+			"text": map[string]interface{}{
+				"content":               reminder,
+				"mentioned_mobile_list": mentions,
+			},
+		})
+	}
+	return messages, nil
+}
+
+// parseWebhookTemplate 解析模板
+func parseWebhookTemplate(content string) (*template.Template, error) {
+	t, err := template.New("webhook").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebhookTemplateInvalid, err)
+	}
+	return t, nil
+}
+
+// fitWebhookContent 渲染模板，内容超出 limit 字节时逐个减少显示的商品明细
+func fitWebhookContent(t *template.Template, data *WebhookMessageData, limit int) (string, error) {
+	for shown := len(data.Items); shown >= 0; shown-- {
+		d := *data
+		d.Items = data.Items[:shown]
+		d.MoreItems = data.MoreItems + len(data.Items) - shown
+
+		var b strings.Builder
+		if err := t.Execute(&b, &d); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrWebhookTemplateInvalid, err)
+		}
+		content := b.String()
+		if len(content) <= limit {
+			return content, nil
+		}
+		if shown == 0 {
+			return truncateBytes(content, limit), nil
+		}
+	}
+	return "", nil
+}
+
+// truncateBytes 按字节截断 UTF-8 字符串并以省略号结尾，不拆分字符
+func truncateBytes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	const ellipsis = "…"
+	cut := limit - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
+
+// WebhookTemplateService Webhook 消息模板管理
+type WebhookTemplateService struct {
+	db *gorm.DB
+}
+
+// NewWebhookTemplateService 创建消息模板服务
+func NewWebhookTemplateService(db *gorm.DB) *WebhookTemplateService {
+	return &WebhookTemplateService{db: db}
+}
+
+// WebhookTemplateRequest 修改消息模板或预览
+type WebhookTemplateRequest struct {
+	MsgType        models.WebhookMsgType `json:"msgType" validate:"required,oneof=markdown text"`
+	Content        string                `json:"content" validate:"required"`
+	MentionContact bool                  `json:"mentionContact"`
+	MentionMobiles []string              `json:"mentionMobiles"`
+	IsActive       bool                  `json:"isActive"`
+}
+
+// WebhookTemplateView 模板列表项，未自定义的事件返回内置模板
+type WebhookTemplateView struct {
+	models.WebhookTemplate
+	Title     string `json:"title"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// WebhookTemplatePreview 模板预览结果
+type WebhookTemplatePreview struct {
+	Messages []models.JSON `json:"messages"`
+	Bytes    int           `json:"bytes"` // 主消息内容字节数
+	Limit    int           `json:"limit"`
+}
+
+// List 所有事件的模板
+func (s *WebhookTemplateService) List() ([]WebhookTemplateView, error) {
+	var templates []models.WebhookTemplate
+	if err := s.db.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	custom := make(map[models.WebhookEventType]models.WebhookTemplate, len(templates))
+	for _, tpl := range templates {
+		custom[tpl.EventType] = tpl
+	}
+
+	views := make([]WebhookTemplateView, 0, len(webhookTemplateEvents))
+	for _, event := range webhookTemplateEvents {
+		view := WebhookTemplateView{Title: webhookEventTitles[event]}
+		if tpl, ok := custom[event]; ok {
+			view.WebhookTemplate = tpl
+		} else {
+			view.WebhookTemplate = *defaultWebhookTemplate(event)
+			view.IsDefault = true
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Save 保存事件的模板，保存前用示例数据校验
+func (s *WebhookTemplateService) Save(event models.WebhookEventType, req *WebhookTemplateRequest, adminID uint64) (*models.WebhookTemplate, error) {
+	if err := validateWebhookTemplateRequest(event, req); err != nil {
+		return nil, err
+	}
+
+	var tpl models.WebhookTemplate
+	err := s.db.Where("event_type = ?", event).First(&tpl).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	tpl.EventType = event
+	tpl.MsgType = req.MsgType
+	tpl.Content = req.Content
+	tpl.MentionContact = req.MentionContact
+	tpl.MentionMobiles = models.JSONStringArray(req.MentionMobiles)
+	tpl.IsActive = req.IsActive
+	tpl.UpdatedBy = adminID
+	if err := s.db.Save(&tpl).Error; err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// Reset 删除自定义模板，恢复为内置模板
+func (s *WebhookTemplateService) Reset(event models.WebhookEventType) error {
+	if !isWebhookTemplateEvent(event) {
+		return ErrWebhookEventInvalid
+	}
+	return s.db.Where("event_type = ?", event).Delete(&models.WebhookTemplate{}).Error
+}
+
+// Preview 预览模板，订单事件指定订单时使用订单数据，否则使用示例数据
+func (s *WebhookTemplateService) Preview(event models.WebhookEventType, req *WebhookTemplateRequest, orderID uint64) (*WebhookTemplatePreview, error) {
+	if err := validateWebhookTemplateRequest(event, req); err != nil {
+		return nil, err
+	}
+
+	data := sampleWebhookMessageData(event)
+	if orderID > 0 && event != models.WebhookEventPriceUpdated {
+		var order models.Order
+		if err := s.db.First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrOrderNotFound
+			}
+			return nil, err
+		}
+		d, err := loadWebhookMessageData(s.db, event, &order, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		data = d
+	}
+
+	tpl := &models.WebhookTemplate{
+		EventType:      event,
+		MsgType:        req.MsgType,
+		Content:        req.Content,
+		MentionContact: req.MentionContact,
+		MentionMobiles: models.JSONStringArray(req.MentionMobiles),
+	}
+	messages, err := renderWebhookMessages(tpl, data, webhookTemplateMentions(tpl, "13800000000"))
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &WebhookTemplatePreview{Messages: messages, Limit: webhookMarkdownLimit}
+	if req.MsgType == models.WebhookMsgText {
+		preview.Limit = webhookTextLimit
+	}
+	if body, ok := messages[0][string(req.MsgType)].(map[string]interface{}); ok {
+		if content, ok := body["content"].(string); ok {
+			preview.Bytes = len(content)
+		}
+	}
+	return preview, nil
+}
+
+// loadWebhookMessageData 读取订单的门店、供应商和商品明细生成模板数据
+func loadWebhookMessageData(db *gorm.DB, event models.WebhookEventType, order *models.Order, at time.Time) (*WebhookMessageData, error) {
+	var store models.Store
+	if err := db.Select("id", "name").First(&store, order.StoreID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	var supplier models.Supplier
+	if err := db.Select("id", "name", "display_name").First(&supplier, order.SupplierID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	var items []models.OrderItem
+	if err := db.Where("order_id = ?", order.ID).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return newWebhookMessageData(event, order, items, store.Name, supplierDisplayName(&supplier), at), nil
+}
+
+// supplierDisplayName 供应商展示名称，未设置时使用名称
+func supplierDisplayName(supplier *models.Supplier) string {
+	if supplier.DisplayName != "" {
+		return supplier.DisplayName
+	}
+	return supplier.Name
+}
+
+// validateWebhookTemplateRequest 校验事件、手机号，并用示例数据试渲染
+func validateWebhookTemplateRequest(event models.WebhookEventType, req *WebhookTemplateRequest) error {
+	if !isWebhookTemplateEvent(event) {
+		return ErrWebhookEventInvalid
+	}
+	if req.MsgType != models.WebhookMsgMarkdown && req.MsgType != models.WebhookMsgText {
+		return fmt.Errorf("%w: 消息类型只能是 markdown 或 text", ErrWebhookTemplateInvalid)
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return fmt.Errorf("%w: 模板内容不能为空", ErrWebhookTemplateInvalid)
+	}
+	for _, mobile := range req.MentionMobiles {
+		if mobile != "@all" && !webhookMobilePattern.MatchString(mobile) {
+			return fmt.Errorf("%w: 手机号 %s 格式错误", ErrWebhookTemplateInvalid, mobile)
+		}
+	}
+
+	t, err := parseWebhookTemplate(req.Content)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, sampleWebhookMessageData(event)); err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookTemplateInvalid, err)
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return fmt.Errorf("%w: 模板渲染结果为空", ErrWebhookTemplateInvalid)
+	}
+	return nil
+}
+
+// isWebhookTemplateEvent 是否为可配置模板的事件
+func isWebhookTemplateEvent(event models.WebhookEventType) bool {
+	for _, e := range webhookTemplateEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
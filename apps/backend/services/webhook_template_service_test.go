@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project/backend/models"
+)
+
+func TestRenderWebhookMessages(t *testing.T) {
+	reason := "门店临时闭店"
+	delivery := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	order := &models.Order{OrderNo: "SO202610170001", TotalAmount: 128.5, CancelReason: &reason, ExpectedDeliveryDate: &delivery}
+	items := []models.OrderItem{{MaterialName: "大白菜", Spec: "约5kg/袋", Unit: "袋", Quantity: 2, Subtotal: 64}}
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	data := newWebhookMessageData(models.WebhookEventOrderCancelled, order, items, "朝阳店", "鲜蔬供应", at)
+
+	tpl := defaultWebhookTemplate(models.WebhookEventOrderCancelled)
+	messages, err := renderWebhookMessages(tpl, data, nil)
+	if err != nil {
+		t.Fatalf("renderWebhookMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0]["msgtype"] != "markdown" {
+		t.Fatalf("messages = %v, expected one markdown message", messages)
+	}
+	content := messages[0]["markdown"].(map[string]interface{})["content"].(string)
+	for _, want := range []string{"订单取消通知", "SO202610170001", "朝阳店", "鲜蔬供应", "¥128.50", "2026-10-18", reason, "大白菜 约5kg/袋 × 2袋", "2026-10-17 09:30:00"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content missing %q:\n%s", want, content)
+		}
+	}
+
+	// markdown 不支持@手机号，追加 text 提醒
+	messages, _ = renderWebhookMessages(tpl, data, []string{"13800000000"})
+	if len(messages) != 2 || messages[1]["msgtype"] != "text" {
+		t.Fatalf("messages = %v, expected a text reminder after the markdown message", messages)
+	}
+
+	text := &models.WebhookTemplate{MsgType: models.WebhookMsgText, Content: "{{.Title}} {{.OrderNo}}"}
+	messages, _ = renderWebhookMessages(text, data, []string{"13800000000", "@all"})
+	body := messages[0]["text"].(map[string]interface{})
+	if len(messages) != 1 || body["content"] != "订单取消通知 SO202610170001" {
+		t.Errorf("text message = %v", messages)
+	}
+	if mentions := body["mentioned_mobile_list"].([]string); len(mentions) != 2 {
+		t.Errorf("mentioned_mobile_list = %v, expected 2 mobiles", mentions)
+	}
+}
+
+func TestRenderWebhookMessagesLimit(t *testing.T) {
+	order := &models.Order{OrderNo: "SO202610170002", TotalAmount: 9999}
+	var items []models.OrderItem
+	for i := 0; i < 200; i++ {
+		items = append(items, models.OrderItem{MaterialName: fmt.Sprintf("测试商品%03d", i), Unit: "箱", Quantity: 1, Subtotal: 50})
+	}
+	data := newWebhookMessageData(models.WebhookEventOrderCreated, order, items, "门店", "供应商", time.Now())
+
+	messages, err := renderWebhookMessages(defaultWebhookTemplate(models.WebhookEventOrderCreated), data, nil)
+	if err != nil {
+		t.Fatalf("renderWebhookMessages() error = %v", err)
+	}
+	content := messages[0]["markdown"].(map[string]interface{})["content"].(string)
+	if len(content) > webhookMarkdownLimit {
+		t.Errorf("content is %d bytes, expected at most %d", len(content), webhookMarkdownLimit)
+	}
+	if !strings.Contains(content, "未显示") || !strings.Contains(content, "共200项") {
+		t.Errorf("expected the item list to be shortened with a summary:\n%s", content)
+	}
+
+	text := &models.WebhookTemplate{MsgType: models.WebhookMsgText, Content: strings.Repeat("订单", 1000)}
+	messages, _ = renderWebhookMessages(text, data, nil)
+	content = messages[0]["text"].(map[string]interface{})["content"].(string)
+	if len(content) > webhookTextLimit || !strings.HasSuffix(content, "…") {
+		t.Errorf("text content is %d bytes, expected truncation within %d", len(content), webhookTextLimit)
+	}
+}
+
+func TestRenderPriceWebhookMessages(t *testing.T) {
+	skus := map[uint64]*models.MaterialSku{
+		3: {ID: 3, Spec: "约5kg/袋", Unit: "袋", Material: &models.Material{Name: "大白菜"}},
+	}
+	changes := []SupplierPriceChange{
+		{MaterialSkuID: 3, OldPrice: 32, NewPrice: 30.5},
+		{MaterialSkuID: 9, OldPrice: 10, NewPrice: 12},
+	}
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.Local)
+	data := newPriceWebhookMessageData(changes, skus, "鲜蔬供应", at)
+
+	tpl := defaultWebhookTemplate(models.WebhookEventPriceUpdated)
+	messages, err := renderWebhookMessages(tpl, data, []string{"13800000000"})
+	if err != nil {
+		t.Fatalf("renderWebhookMessages() error = %v", err)
+	}
+	if len(messages) != 2 || messages[0]["msgtype"] != "markdown" || messages[1]["msgtype"] != "text" {
+		t.Fatalf("messages = %v, expected markdown with a text reminder", messages)
+	}
+	content := messages[0]["markdown"].(map[string]interface{})["content"].(string)
+	for _, want := range []string{"价格变动通知", "鲜蔬供应", "调价商品：2项", "大白菜 约5kg/袋：¥32.00 → ", "¥30.50</font>/袋", "SKU 9：¥10.00 → ", "2026-10-17 09:30:00"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "订单") {
+		t.Errorf("price message should not contain order fields:\n%s", content)
+	}
+	reminder := messages[1]["text"].(map[string]interface{})["content"].(string)
+	if reminder != "价格变动通知：鲜蔬供应调整了2项商品价格，请及时查看" {
+		t.Errorf("reminder = %q", reminder)
+	}
+
+	// 大批量调价按长度限制减少显示的明细
+	changes = nil
+	for i := uint64(1); i <= 300; i++ {
+		changes = append(changes, SupplierPriceChange{MaterialSkuID: i, OldPrice: 10, NewPrice: 11})
+	}
+	data = newPriceWebhookMessageData(changes, nil, "鲜蔬供应", at)
+	messages, _ = renderWebhookMessages(tpl, data, nil)
+	content = messages[0]["markdown"].(map[string]interface{})["content"].(string)
+	if len(content) > webhookMarkdownLimit || !strings.Contains(content, "未显示") || !strings.Contains(content, "调价商品：300项") {
+		t.Errorf("content is %d bytes, expected a shortened list within %d:\n%s", len(content), webhookMarkdownLimit, content)
+	}
+}
+
+func TestGenericPriceWebhookBody(t *testing.T) {
+	supplier := &models.Supplier{ID: 5, Name: "鲜蔬供应"}
+	skus := map[uint64]*models.MaterialSku{3: {ID: 3, Brand: "农家", Spec: "约5kg/袋", Material: &models.Material{Name: "大白菜"}}}
+	body := genericPriceWebhookBody(supplier, []SupplierPriceChange{{MaterialSkuID: 3, OldPrice: 32, NewPrice: 30.5}}, skus)
+	if body["event"] != "price_updated" {
+		t.Errorf("event = %v, expected price_updated", body["event"])
+	}
+	data, ok := body["data"].([]interface{})
+	if !ok || len(data) != 1 {
+		t.Fatalf("data = %v, expected one change", body["data"])
+	}
+	item := data[0].(map[string]interface{})
+	if item["materialName"] != "大白菜" || item["oldPrice"] != 32.0 || item["newPrice"] != 30.5 || item["supplierId"] != 5.0 {
+		t.Errorf("change = %v", item)
+	}
+}
+
+func TestWebhookTemplateMentions(t *testing.T) {
+	tpl := &models.WebhookTemplate{MentionContact: true, MentionMobiles: models.JSONStringArray{"13900000000", "13800000000"}}
+	mentions := webhookTemplateMentions(tpl, "13800000000")
+	if len(mentions) != 2 || mentions[0] != "13800000000" || mentions[1] != "13900000000" {
+		t.Errorf("mentions = %v, expected contact first without duplicates", mentions)
+	}
+
+	tpl.MentionContact = false
+	if mentions := webhookTemplateMentions(tpl, "13700000000"); len(mentions) != 2 {
+		t.Errorf("mentions = %v, expected only the configured mobiles", mentions)
+	}
+}
+
+func TestValidateWebhookTemplateRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		event models.WebhookEventType
+		req   WebhookTemplateRequest
+		err   error
+	}{
+		{"default", models.WebhookEventOrderCreated, WebhookTemplateRequest{MsgType: models.WebhookMsgMarkdown, Content: defaultWebhookTemplateContent}, nil},
+		{"price default", models.WebhookEventPriceUpdated, WebhookTemplateRequest{MsgType: models.WebhookMsgMarkdown, Content: defaultPriceWebhookTemplateContent}, nil},
+		{"unknown event", "stock.changed", WebhookTemplateRequest{MsgType: models.WebhookMsgText, Content: "x"}, ErrWebhookEventInvalid},
+		{"parse error", models.WebhookEventOrderCreated, WebhookTemplateRequest{MsgType: models.WebhookMsgText, Content: "{{.OrderNo"}, ErrWebhookTemplateInvalid},
+		{"unknown field", models.WebhookEventOrderCreated, WebhookTemplateRequest{MsgType: models.WebhookMsgText, Content: "{{.Foo}}"}, ErrWebhookTemplateInvalid},
+		{"bad mobile", models.WebhookEventOrderCreated, WebhookTemplateRequest{MsgType: models.WebhookMsgText, Content: "{{.OrderNo}}", MentionMobiles: []string{"12345"}}, ErrWebhookTemplateInvalid},
+		{"mention all", models.WebhookEventOrderCreated, WebhookTemplateRequest{MsgType: models.WebhookMsgText, Content: "{{.OrderNo}}", MentionMobiles: []string{"@all"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhookTemplateRequest(tt.event, &tt.req)
+			if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("validateWebhookTemplateRequest() = %v, expected %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -22,13 +22,14 @@ import (
 
 // WeChatPayService 微信支付服务
 type WeChatPayService struct {
-	client        *core.Client
-	notifyHandler *notify.Handler
-	appID         string
-	mchID         string
-	notifyURL     string
-	mchAPIV3Key   string
-	privateKey    *rsa.PrivateKey
+	client          *core.Client
+	notifyHandler   *notify.Handler
+	appID           string
+	mchID           string
+	notifyURL       string
+	refundNotifyURL string
+	mchAPIV3Key     string
+	privateKey      *rsa.PrivateKey
 }
 
 // NativePaymentRequest 原生支付请求
@@ -49,6 +50,8 @@ type NativePaymentResponse struct {
 type WeChatPayNotification struct {
 	OutTradeNo    string    // 商户订单号
 	TransactionId string    // 微信支付订单号
+	AppID         string    // 应用ID
+	MchID         string    // 商户号
 	TradeState    string    // 交易状态
 	Amount        int64     // 金额(分)
 	PayTime       time.Time // 支付时间
@@ -79,6 +82,29 @@ type WeChatRefundResponse struct {
 	RefundStatus string // 退款状态
 }
 
+// WeChatRefundNotification 微信退款结果通知
+type WeChatRefundNotification struct {
+	OutTradeNo   string    // 商户订单号
+	OutRefundNo  string    // 商户退款单号
+	RefundId     string    // 微信退款单号
+	RefundStatus string    // 退款状态
+	RefundAmount int64     // 退款金额(分)
+	SuccessTime  time.Time // 退款成功时间
+	RawData      string    // 原始数据
+}
+
+// wechatRefundResource 退款通知解密后的资源
+type wechatRefundResource struct {
+	OutTradeNo   string `json:"out_trade_no"`
+	OutRefundNo  string `json:"out_refund_no"`
+	RefundId     string `json:"refund_id"`
+	RefundStatus string `json:"refund_status"`
+	SuccessTime  string `json:"success_time"`
+	Amount       struct {
+		Refund int64 `json:"refund"`
+	} `json:"amount"`
+}
+
 // NewWeChatPayService 创建微信支付服务
 func NewWeChatPayService(cfg *config.WeChatPayConfig) (*WeChatPayService, error) {
 	if cfg.AppID == "" || cfg.MchID == "" {
@@ -123,13 +149,14 @@ func NewWeChatPayService(cfg *config.WeChatPayConfig) (*WeChatPayService, error)
 	}
 
 	return &WeChatPayService{
-		client:        client,
-		notifyHandler: notifyHandler,
-		appID:         cfg.AppID,
-		mchID:         cfg.MchID,
-		notifyURL:     cfg.NotifyURL,
-		mchAPIV3Key:   cfg.MchAPIV3Key,
-		privateKey:    privateKey,
+		client:          client,
+		notifyHandler:   notifyHandler,
+		appID:           cfg.AppID,
+		mchID:           cfg.MchID,
+		notifyURL:       cfg.NotifyURL,
+		refundNotifyURL: cfg.RefundNotifyURL,
+		mchAPIV3Key:     cfg.MchAPIV3Key,
+		privateKey:      privateKey,
 	}, nil
 }
 
@@ -168,7 +195,7 @@ func (s *WeChatPayService) CreateNativePayment(ctx context.Context, req *NativeP
 func (s *WeChatPayService) VerifyCallback(ctx context.Context, req *http.Request) (*WeChatPayNotification, error) {
 	// 解析回调通知
 	transaction := new(payments.Transaction)
-	_, err := s.notifyHandler.ParseNotifyRequest(ctx, req, transaction)
+	notifyReq, err := s.notifyHandler.ParseNotifyRequest(ctx, req, transaction)
 	if err != nil {
 		return nil, fmt.Errorf("parse notify failed: %w", err)
 	}
@@ -178,6 +205,15 @@ func (s *WeChatPayService) VerifyCallback(ctx context.Context, req *http.Request
 		TransactionId: *transaction.TransactionId,
 		TradeState:    string(*transaction.TradeState),
 	}
+	if notifyReq.Resource != nil {
+		notification.RawData = notifyReq.Resource.Plaintext
+	}
+	if transaction.Appid != nil {
+		notification.AppID = *transaction.Appid
+	}
+	if transaction.Mchid != nil {
+		notification.MchID = *transaction.Mchid
+	}
 
 	if transaction.Amount != nil && transaction.Amount.Total != nil {
 		notification.Amount = int64(*transaction.Amount.Total)
@@ -227,7 +263,7 @@ func (s *WeChatPayService) QueryOrder(ctx context.Context, orderNo string) (*WeC
 func (s *WeChatPayService) RefundOrder(ctx context.Context, req *WeChatRefundRequest) (*WeChatRefundResponse, error) {
 	svc := refunddomestic.RefundsApiService{Client: s.client}
 
-	resp, _, err := svc.Create(ctx, refunddomestic.CreateRequest{
+	request := refunddomestic.CreateRequest{
 		OutTradeNo:  core.String(req.OrderNo),
 		OutRefundNo: core.String(req.RefundNo),
 		Reason:      core.String(req.RefundReason),
@@ -236,7 +272,12 @@ func (s *WeChatPayService) RefundOrder(ctx context.Context, req *WeChatRefundReq
 			Total:    core.Int64(req.TotalAmount),
 			Currency: core.String("CNY"),
 		},
-	})
+	}
+	if s.refundNotifyURL != "" {
+		request.NotifyUrl = core.String(s.refundNotifyURL)
+	}
+
+	resp, _, err := svc.Create(ctx, request)
 
 	if err != nil {
 		return nil, fmt.Errorf("refund failed: %w", err)
@@ -249,6 +290,36 @@ func (s *WeChatPayService) RefundOrder(ctx context.Context, req *WeChatRefundReq
 	}, nil
 }
 
+// VerifyRefundCallback 验证退款结果通知
+func (s *WeChatPayService) VerifyRefundCallback(ctx context.Context, req *http.Request) (*WeChatRefundNotification, error) {
+	resource := new(wechatRefundResource)
+	notifyReq, err := s.notifyHandler.ParseNotifyRequest(ctx, req, resource)
+	if err != nil {
+		return nil, fmt.Errorf("parse refund notify failed: %w", err)
+	}
+
+	notification := &WeChatRefundNotification{
+		OutTradeNo:   resource.OutTradeNo,
+		OutRefundNo:  resource.OutRefundNo,
+		RefundId:     resource.RefundId,
+		RefundStatus: resource.RefundStatus,
+		RefundAmount: resource.Amount.Refund,
+	}
+	if notifyReq.Resource != nil {
+		notification.RawData = notifyReq.Resource.Plaintext
+	}
+
+	if resource.SuccessTime != "" {
+		successTime, err := time.Parse(time.RFC3339, resource.SuccessTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid success_time: %w", err)
+		}
+		notification.SuccessTime = successTime
+	}
+
+	return notification, nil
+}
+
 // CloseOrder 关闭订单
 func (s *WeChatPayService) CloseOrder(ctx context.Context, orderNo string) error {
 	svc := native.NativeApiService{Client: s.client}
@@ -265,6 +336,11 @@ func (s *WeChatPayService) CloseOrder(ctx context.Context, orderNo string) error
 	return nil
 }
 
+// Merchant 应用ID和商户号
+func (s *WeChatPayService) Merchant() (appID, mchID string) {
+	return s.appID, s.mchID
+}
+
 // IsPaymentSuccess 判断交易状态是否成功
 func (n *WeChatPayNotification) IsPaymentSuccess() bool {
 	return n.TradeState == "SUCCESS"
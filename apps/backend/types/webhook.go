@@ -11,8 +11,10 @@ const (
 	WebhookOrderDelivered WebhookEvent = "order_delivered"
 	WebhookOrderCompleted WebhookEvent = "order_completed"
 	WebhookOrderCancelled WebhookEvent = "order_cancelled"
+	WebhookOrderRestored  WebhookEvent = "order_restored"
 	WebhookPriceUpdated   WebhookEvent = "price_updated"
 	WebhookStockChanged   WebhookEvent = "stock_changed"
+	WebhookTest           WebhookEvent = "test"
 )
 
 // WebhookTargetType Webhook目标类型
@@ -30,6 +32,7 @@ const (
 	WebhookPending WebhookStatus = "pending"
 	WebhookSuccess WebhookStatus = "success"
 	WebhookFailed  WebhookStatus = "failed"
+	WebhookDead    WebhookStatus = "dead"
 )
 
 // WebhookPayload Webhook推送载荷
@@ -65,6 +68,7 @@ type WebhookQueryParams struct {
 	TargetID   uint64            `json:"targetId" query:"targetId"`
 	Event      WebhookEvent      `json:"event" query:"event"`
 	Status     WebhookStatus     `json:"status" query:"status"`
+	OrderNo    string            `json:"orderNo" query:"orderNo"`
 	StartDate  string            `json:"startDate" query:"startDate"`
 	EndDate    string            `json:"endDate" query:"endDate"`
 }